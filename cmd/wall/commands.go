@@ -0,0 +1,496 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/backup"
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/export"
+	"github.com/guohuiyuan/qzonewall-go/internal/feed"
+	"github.com/guohuiyuan/qzonewall-go/internal/importer"
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+	"github.com/guohuiyuan/qzonewall-go/internal/publish"
+	"github.com/guohuiyuan/qzonewall-go/internal/render"
+	"github.com/guohuiyuan/qzonewall-go/internal/site"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+	"github.com/guohuiyuan/qzonewall-go/internal/task"
+	"github.com/guohuiyuan/qzonewall-go/internal/web"
+)
+
+// commands 子命令表，例如 `wall migrate --dry-run`
+var commands = map[string]func(args []string) error{
+	"migrate": runMigrate,
+	"backup":  runBackup,
+	"restore": runRestore,
+	"export":  runExport,
+	"import":  runImport,
+	"account": runAccount,
+	"site":    runSite,
+}
+
+// newFlagSet 创建带 --config/-c 参数的子命令参数解析器
+func newFlagSet(name string, cfgPath *string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.StringVar(cfgPath, "config", "data/config.json", "配置文件路径")
+	fs.StringVar(cfgPath, "c", "data/config.json", "配置文件路径 (简写)")
+	return fs
+}
+
+// runMigrate 执行数据库升级，--dry-run 时只列出待执行的步骤
+func runMigrate(args []string) error {
+	var cfgPath string
+	fs := newFlagSet("migrate", &cfgPath)
+	dryRun := fs.Bool("dry-run", false, "只列出待执行的升级步骤，不修改数据库")
+	_ = fs.Parse(args)
+
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		current, pending, err := store.PendingMigrations(cfg.Database.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("数据库 %s 尚不存在，首次启动时会创建并升级到 v%d\n", cfg.Database.Path, store.LatestSchemaVersion())
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("当前数据库版本: v%d, 程序支持: v%d\n", current, store.LatestSchemaVersion())
+		if len(pending) == 0 {
+			fmt.Println("没有待执行的升级步骤")
+			return nil
+		}
+		for _, m := range pending {
+			fmt.Printf("  待执行 v%d %s\n", m.Version, m.Name)
+		}
+		return nil
+	}
+
+	st, err := store.New(cfg.Database.Path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = st.Close()
+	}()
+	v, err := st.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("数据库已是最新版本: v%d\n", v)
+	return nil
+}
+
+// runBackup 立即创建一份备份，--list 时只列出已有备份
+func runBackup(args []string) error {
+	var cfgPath string
+	fs := newFlagSet("backup", &cfgPath)
+	list := fs.Bool("list", false, "列出已有备份")
+	dir := fs.String("o", "", "备份输出目录，默认使用配置中的 backup.dir")
+	_ = fs.Parse(args)
+
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return err
+	}
+	if *dir != "" {
+		cfg.Backup.Dir = *dir
+	}
+
+	if *list {
+		infos, err := backup.List(cfg.Backup.Dir)
+		if err != nil {
+			return err
+		}
+		if len(infos) == 0 {
+			fmt.Printf("%s 下没有备份\n", cfg.Backup.Dir)
+		}
+		for _, info := range infos {
+			fmt.Printf("  %s  %8.1f KB  %s\n", info.Name, float64(info.Size)/1024,
+				time.Unix(info.CreateTime, 0).Format("2006-01-02 15:04:05"))
+		}
+		return nil
+	}
+
+	st, err := store.New(cfg.Database.Path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = st.Close()
+	}()
+	path, err := task.RunBackup(cfg.Backup, st, uploadDir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("备份完成: %s\n", path)
+	return nil
+}
+
+// runRestore 从备份包恢复数据库和上传目录，必须先停止服务
+func runRestore(args []string) error {
+	var cfgPath string
+	fs := newFlagSet("restore", &cfgPath)
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: wall restore [-c config] <backup.tar.gz>")
+	}
+
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return err
+	}
+	manifest, err := backup.Restore(fs.Arg(0), cfg.Database.Path, uploadDir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("恢复完成: 数据库 v%d, 上传文件 %d 个, 备份时间 %s\n",
+		manifest.SchemaVersion, manifest.Uploads,
+		time.Unix(manifest.CreateTime, 0).Format("2006-01-02 15:04:05"))
+	fmt.Println("原数据库和上传目录已重命名为 *.bak-<时间>，确认无误后可手动删除")
+	if manifest.SchemaVersion < store.LatestSchemaVersion() {
+		fmt.Printf("备份版本低于程序版本 v%d，下次启动时会自动升级\n", store.LatestSchemaVersion())
+	}
+	return nil
+}
+
+// runExport 按状态和日期导出投稿，-o 为空时输出到标准输出
+func runExport(args []string) error {
+	var cfgPath string
+	fs := newFlagSet("export", &cfgPath)
+	formatStr := fs.String("format", "jsonl", "导出格式: jsonl / csv / zip")
+	status := fs.String("status", "", "只导出指定状态，如 published；为空时导出回收站以外的全部投稿")
+	since := fs.String("since", "", "投稿日期起 (YYYY-MM-DD，含)")
+	until := fs.String("until", "", "投稿日期止 (YYYY-MM-DD，含)")
+	out := fs.String("o", "", "输出文件，为空时输出到标准输出")
+	wallID := fs.String("wall", "", "只导出指定表白墙的投稿，为空时导出所有墙")
+	_ = fs.Parse(args)
+
+	format, err := export.ParseFormat(*formatStr)
+	if err != nil {
+		return err
+	}
+	filter := store.SearchQuery{Status: model.PostStatus(*status)}
+	if filter.Since, err = parseDate(*since, false); err != nil {
+		return err
+	}
+	if filter.Until, err = parseDate(*until, true); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return err
+	}
+	st, err := store.New(cfg.Database.Path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = st.Close()
+	}()
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		w = f
+	} else if format == export.FormatZIP {
+		return errors.New("zip export requires -o <file>")
+	}
+
+	var renderer *render.Renderer
+	if format == export.FormatZIP {
+		renderer = render.NewRenderer()
+	}
+	if *wallID != "" {
+		st = st.ForWall(*wallID)
+	}
+	n, err := export.NewExporter(st, renderer, uploadDir).Export(w, format, filter)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "导出完成: %d 条\n", n)
+	return nil
+}
+
+// runImport 从 JSON/CSV 文件导入投稿，已导入过的外部编号会被跳过
+func runImport(args []string) error {
+	var cfgPath string
+	fs := newFlagSet("import", &cfgPath)
+	dryRun := fs.Bool("dry-run", false, "只校验并列出将要插入的行，不写数据库")
+	source := fs.String("source", "", "来源标识，作为外部编号前缀，例如 oldbot")
+	imagesDir := fs.String("images", "", "本地图片的根目录，只读取该目录下的文件，默认为导入文件所在目录")
+	wallID := fs.String("wall", model.DefaultWall, "导入到的表白墙")
+	keepApproved := fs.Bool("keep-approved", false, "保留未发布的 approved 状态（导入后会被自动发布），默认改为 pending 重新审核")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: wall import [-c config] [-dry-run] [-source name] [-images dir] [-wall id] [-keep-approved] <file.json|file.jsonl|file.csv>")
+	}
+	file := fs.Arg(0)
+	if *imagesDir == "" {
+		*imagesDir = filepath.Dir(file)
+	}
+
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return err
+	}
+	st, err := store.New(cfg.Database.Path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = st.Close()
+	}()
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	report, err := importer.NewImporter(st.ForWall(*wallID), uploadDir).Import(f, importer.FormatOf(file), importer.Options{
+		Source:       *source,
+		BaseDir:      *imagesDir,
+		DryRun:       *dryRun,
+		KeepApproved: *keepApproved,
+		ActorName:    "wall import",
+		AuditSource:  model.SourceCLI,
+	})
+	if report != nil {
+		for _, r := range report.Rows {
+			switch r.Action {
+			case importer.ActionError:
+				fmt.Printf("  第 %d 行 失败: %s\n", r.Row, r.Error)
+			case importer.ActionWouldInsert:
+				fmt.Printf("  第 %d 行 将插入 (external_id=%s)\n", r.Row, r.ExternalID)
+			case importer.ActionSkipped:
+				fmt.Printf("  第 %d 行 已存在，跳过 (external_id=%s, #%d)\n", r.Row, r.ExternalID, r.PostID)
+			}
+		}
+		verb := "插入"
+		if *dryRun {
+			verb = "将插入"
+		}
+		fmt.Printf("%s %d 条，跳过 %d 条，失败 %d 条\n", verb, report.Inserted, report.Skipped, report.Failed)
+	}
+	return err
+}
+
+// runAccount 管理网页账号，例如 `wall account add -role reviewer alice 123456`，
+// 再把用户名加入 walls[].admins 即可让该账号管理对应的表白墙。
+// 忘记管理员密码或网页上停用了所有人时可以用 reset、enable 恢复
+func runAccount(args []string) error {
+	const usage = "usage: wall account add [-c config] [-role admin|reviewer|viewer] <username> <password>\n" +
+		"       wall account reset [-c config] <username> <password>\n" +
+		"       wall account disable|enable [-c config] <username>"
+	var cfgPath string
+	fs := newFlagSet("account", &cfgPath)
+	role := fs.String("role", model.RoleReviewer, "账号角色: admin 管理员，reviewer 审核员，viewer 只读。admin 可以管理所有墙，其他角色只能管理 walls[].admins 中包含它的墙")
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	sub := args[0]
+	_ = fs.Parse(args[1:])
+	switch {
+	case (sub == "add" || sub == "reset") && fs.NArg() == 2:
+	case (sub == "disable" || sub == "enable") && fs.NArg() == 1:
+	default:
+		return errors.New(usage)
+	}
+	if !model.ValidRole(*role) {
+		return fmt.Errorf("unknown role %q", *role)
+	}
+
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return err
+	}
+	st, err := store.New(cfg.Database.Path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = st.Close()
+	}()
+
+	username := fs.Arg(0)
+	switch sub {
+	case "reset":
+		if err := web.ResetPassword(st, username, fs.Arg(1)); err != nil {
+			return err
+		}
+		fmt.Printf("已重置账号 %s 的密码\n", username)
+		return nil
+	case "disable", "enable":
+		account, err := st.GetAccount(username)
+		if err != nil || account == nil {
+			return fmt.Errorf("账号不存在: %s", username)
+		}
+		if err := st.SetAccountDisabled(account.ID, sub == "disable"); err != nil {
+			return err
+		}
+		if sub == "disable" {
+			fmt.Printf("已停用账号 %s\n", username)
+		} else {
+			fmt.Printf("已启用账号 %s\n", username)
+		}
+		return nil
+	}
+
+	if err := web.CreateAccount(st, username, fs.Arg(1), *role); err != nil {
+		return err
+	}
+	fmt.Printf("已创建账号 %s (%s)\n", username, model.RoleName(*role))
+	var walls []string
+	for _, w := range cfg.WallList() {
+		for _, admin := range w.Admins {
+			if admin == username {
+				walls = append(walls, w.ID)
+			}
+		}
+	}
+	switch {
+	case *role == model.RoleAdmin:
+		fmt.Println("管理员可以管理所有墙")
+	case len(walls) > 0:
+		fmt.Printf("可以管理的墙: %s\n", strings.Join(walls, ", "))
+	default:
+		fmt.Println("该账号还不能管理任何墙，请在配置文件 walls[].admins 中加入用户名")
+	}
+	return nil
+}
+
+// runSite 把已发布的稿件生成静态网站，例如 `wall site build -o public -base-url https://example.com`。
+// 多个墙且未指定 -wall 时每个墙生成到 <dir>/<墙ID>/，根目录的 index.html 列出各个墙
+func runSite(args []string) error {
+	const usage = "usage: wall site build [-c config] [-o dir] [-wall id] [-base-url url] [-full] [-cards dir]"
+	var cfgPath string
+	fs := newFlagSet("site", &cfgPath)
+	out := fs.String("o", "site", "输出目录")
+	wallID := fs.String("wall", "", "只生成指定表白墙，为空时生成所有墙")
+	baseURL := fs.String("base-url", "", "网站的绝对地址，设置后额外生成 RSS/Atom/JSON Feed")
+	full := fs.Bool("full", false, "忽略上次的构建记录，全部重新生成")
+	cardsDir := fs.String("cards", "data/cards", "卡片图片缓存目录，与网页的公开稿件页共用")
+	if len(args) == 0 || args[0] != "build" {
+		return errors.New(usage)
+	}
+	_ = fs.Parse(args[1:])
+	if fs.NArg() != 0 {
+		return errors.New(usage)
+	}
+
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return err
+	}
+	entries := cfg.WallList()
+	if *wallID != "" {
+		var found []config.WallEntry
+		for _, e := range entries {
+			if e.ID == *wallID {
+				found = append(found, e)
+			}
+		}
+		if len(found) == 0 {
+			return fmt.Errorf("unknown wall %q", *wallID)
+		}
+		entries = found
+	}
+
+	st, err := store.New(cfg.Database.Path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = st.Close()
+	}()
+
+	renderer := render.NewRenderer()
+	if !renderer.Available() {
+		return errors.New("renderer not available (font missing)")
+	}
+	cards := feed.NewCards(*cardsDir)
+	multi := len(entries) > 1
+	var links []site.WallLink
+	for _, e := range entries {
+		dir, base := *out, *baseURL
+		if multi {
+			dir = filepath.Join(*out, e.ID)
+			if base != "" {
+				base = strings.TrimRight(base, "/") + "/" + e.ID
+			}
+			links = append(links, site.WallLink{Name: e.Name, Dir: e.ID})
+		}
+		report, err := site.NewBuilder(st.ForWall(e.ID), cards, siteRenderFunc(renderer.WithTheme(e.Theme)), site.Options{
+			Dir:     dir,
+			Title:   e.Name,
+			BaseURL: base,
+			Full:    *full,
+		}).Build()
+		if err != nil {
+			return fmt.Errorf("build site for wall %s: %w", e.ID, err)
+		}
+		fmt.Printf("[%s] %s: 已发布 %d 条，生成稿件页 %d 个，删除 %d 个，话题页 %d 个\n",
+			e.ID, dir, report.Posts, report.Rendered, report.Removed, report.Tags)
+	}
+	if multi {
+		if err := site.WriteWallIndex(*out, links); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// siteRenderFunc 渲染静态网站的卡片："/uploads/xxx" 转为本地绝对路径，其余图片交给 publish.ResolveImageURL
+func siteRenderFunc(renderer *render.Renderer) feed.RenderFunc {
+	absUploadDir, err := filepath.Abs(uploadDir)
+	if err != nil {
+		absUploadDir = uploadDir
+	}
+	return func(p *model.Post) ([]byte, error) {
+		clone := *p
+		clone.Images = make([]string, len(p.Images))
+		for i, img := range p.Images {
+			if strings.HasPrefix(img, "/uploads/") {
+				clone.Images[i] = filepath.Join(absUploadDir, path.Base(img))
+			} else {
+				clone.Images[i] = publish.ResolveImageURL(img)
+			}
+		}
+		return renderer.RenderPost(&clone)
+	}
+}
+
+// parseDate 解析 YYYY-MM-DD，endOfDay 为 true 时取当天最后一秒；空字符串返回 0
+func parseDate(s string, endOfDay bool) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("invalid date %q: %w", s, err)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return t.Unix(), nil
+}
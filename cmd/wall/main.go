@@ -6,22 +6,38 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	qzone "github.com/guohuiyuan/qzone-go"
 	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/publish"
 	"github.com/guohuiyuan/qzonewall-go/internal/render"
 	"github.com/guohuiyuan/qzonewall-go/internal/source"
 	"github.com/guohuiyuan/qzonewall-go/internal/store"
 	"github.com/guohuiyuan/qzonewall-go/internal/task"
+	"github.com/guohuiyuan/qzonewall-go/internal/wall"
 	"github.com/guohuiyuan/qzonewall-go/internal/web"
 )
 
 //go:embed example_config.json
 var exampleConfig string
 
+// uploadDir 投稿图片上传目录
+const uploadDir = "data/uploads"
+
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
+	// 子命令模式：wall <command> [flags]
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				log.Fatalf("%s failed: %v", os.Args[1], err)
+			}
+			return
+		}
+	}
+
 	cfgPath := "data/config.json"
 	for i := 1; i < len(os.Args); i++ {
 		switch os.Args[i] {
@@ -36,7 +52,7 @@ func main() {
 	}
 
 	// 确保 data 目录存在
-	if err := os.MkdirAll("data/uploads", 0755); err != nil {
+	if err := os.MkdirAll(uploadDir, 0755); err != nil {
 		log.Fatalf("create data directory failed: %v", err)
 	}
 
@@ -66,9 +82,6 @@ func main() {
 	}()
 	log.Println("[Main] sqlite ready")
 
-	censorWords := store.LoadCensorWords(cfg.Censor.Words, cfg.Censor.WordsFile)
-	log.Printf("[Main] loaded censor words: %d", len(censorWords))
-
 	renderer := render.NewRenderer()
 	if renderer.Available() {
 		log.Println("[Main] renderer enabled")
@@ -76,61 +89,84 @@ func main() {
 		log.Println("[Main] renderer disabled")
 	}
 
-	qqBot := source.NewQQBot(cfg.Bot, cfg.Wall, cfg.Qzone, st, renderer, nil, censorWords)
-	if err := qqBot.Start(); err != nil {
-		log.Fatalf("start qq bot failed: %v", err)
-	}
-	log.Println("[Main] qq bot started")
+	// 每个墙一个号池：账号拥有独立的客户端、Cookie 来源和熔断器，按配置顺序作为主号和备用号。
+	// 优先使用上次保存的 Cookie；没有时用占位 Cookie 启动，避免阻塞。
+	// 真正的 Cookie 引导 (GetCookies -> QR fallback) 在下面异步执行，只有第一个账号会在终端弹扫码。
+	vault := task.NewCookieVault(cfg.Qzone, st)
+	walls := wall.NewRegistry(st)
+	first := true
+	for _, entry := range cfg.WallList() {
+		qzPool := publish.NewPool()
+		for _, accCfg := range cfg.WallAccounts(entry) {
+			initCookie := publish.BootstrapCookie
+			restored := false
+			// 未配置QQ号的账号只有排在第一个时才恢复最近保存的 Cookie，避免多个账号共用同一个登录态
+			if accCfg.UIN == 0 && !first {
+				log.Printf("[Main] account %s has no uin, skip restoring saved cookie", accCfg.Name)
+			} else if cookie, qs := vault.Load(accCfg.UIN); cookie != "" {
+				initCookie = cookie
+				restored = true
+				log.Printf("[Main] account %s restored saved cookie, uin=%d, last validated %s",
+					accCfg.Name, qs.UIN, time.Unix(qs.ValidatedTime, 0).Format("2006-01-02 15:04:05"))
+			}
+
+			client, err := qzone.NewClient(initCookie,
+				qzone.WithTimeout(cfg.Qzone.Timeout.Duration),
+				qzone.WithMaxRetry(cfg.Qzone.MaxRetry),
+				qzone.WithOnSessionExpired(task.RefreshCookie(accCfg)),
+			)
+			if err != nil {
+				log.Fatalf("[Main] qzone client for account %s create failed: %v", accCfg.Name, err)
+			}
+			acc := qzPool.Add(accCfg, client)
+			log.Printf("[Main] qzone account %s created for wall %s", acc.Name, entry.ID)
 
-	// Start with a valid-form placeholder cookie to avoid blocking startup.
-	// Real cookie bootstrap (GetCookies -> QR fallback) runs asynchronously below.
-	initCookie := "uin=o1;skey=@bootstrap;p_skey=bootstrap"
+			go task.Bootstrap(cfg.Qzone, acc, vault, restored, first)
+			first = false
+		}
 
-	qzClient, err := qzone.NewClient(initCookie,
-		qzone.WithTimeout(cfg.Qzone.Timeout.Duration),
-		qzone.WithMaxRetry(cfg.Qzone.MaxRetry),
-		qzone.WithOnSessionExpired(task.RefreshCookie(cfg.Bot)),
-	)
-	if err != nil {
-		log.Fatalf("[Main] qzone client create failed: %v", err)
+		// Bot 过稿、Web 批量过稿和 Worker 共用墙的发布服务
+		w := wall.New(cfg, entry, st, qzPool, renderer, uploadDir)
+		walls.Add(w)
+		log.Printf("[Main] wall %s ready: groups=%v, manage group=%d, accounts=%d, censor words=%d",
+			w, w.Groups, w.ManageGroup, len(qzPool.Accounts()), len(w.CensorWords))
 	}
 
-	if qzClient == nil {
-		log.Fatal("[Main] qzone client is nil after initialization")
+	qqBot := source.NewQQBot(cfg.Bot, cfg.Wall, walls)
+	qqBot.SetCookieVault(vault)
+	if err := qqBot.Start(); err != nil {
+		log.Fatalf("start qq bot failed: %v", err)
 	}
-	log.Println("[Main] qzone client created")
-
-	go func() {
-		log.Println("[Main] async cookie bootstrap started")
-		res := <-task.TryGetCookieAsync(cfg.Qzone)
-		if res.Err != nil {
-			log.Printf("[Main] async cookie bootstrap failed: %v", res.Err)
-			log.Println("[Main] use /扫码 or web admin QR login to refresh cookie")
-			return
-		}
-		if err := qzClient.UpdateCookie(res.Cookie); err != nil {
-			log.Printf("[Main] async cookie update failed: %v", err)
-			return
-		}
-		log.Printf("[Main] async cookie bootstrap success, uin=%d", qzClient.UIN())
+	log.Println("[Main] qq bot started")
 
-		if err := task.EnsureCookieValidOnStartup(cfg.Qzone, cfg.Bot, qzClient); err != nil {
-			log.Printf("[Main] startup cookie validation failed: %v", err)
+	for _, w := range walls.All() {
+		worker := task.NewWorker(cfg.Worker, w.Store, w.Publisher)
+		worker.Start()
+		defer worker.Stop()
+
+		// 每个账号一个 KeepAlive，Cookie 失效时号池切换到备用号，全部失效时暂停该墙的发布
+		for _, acc := range w.Pool.Accounts() {
+			keepAlive := task.NewKeepAlive(cfg.Qzone, w.Pool, acc, vault)
+			keepAlive.Start()
+			defer keepAlive.Stop()
 		}
-	}()
+	}
 
-	qqBot.SetClient(qzClient)
+	purger := task.NewPurger(cfg.Wall, st, uploadDir)
+	purger.Start()
+	defer purger.Stop()
 
-	worker := task.NewWorker(cfg.Worker, cfg.Wall, qzClient, st, renderer)
-	worker.Start()
-	defer worker.Stop()
+	backuper := task.NewBackuper(cfg.Backup, st, uploadDir)
+	backuper.Start()
+	defer backuper.Stop()
 
-	keepAlive := task.NewKeepAlive(cfg.Qzone, cfg.Bot, qzClient)
-	keepAlive.Start()
-	defer keepAlive.Stop()
+	webhooks := task.NewWebhookDispatcher(cfg.Webhook, st)
+	webhooks.Start()
+	defer webhooks.Stop()
 
 	if cfg.Web.Enable {
-		webServer := web.NewServer(cfg, cfgPath, st, qzClient, renderer)
+		webServer := web.NewServer(cfg, cfgPath, walls)
+		webServer.SetCookieVault(vault)
 		go func() {
 			if err := webServer.Start(); err != nil {
 				log.Printf("[Main] web server stopped: %v", err)
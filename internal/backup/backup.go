@@ -0,0 +1,200 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+const (
+	filePrefix   = "wall-"
+	fileSuffix   = ".tar.gz"
+	dbEntry      = "data.db"
+	uploadsEntry = "uploads"
+	manifestName = "manifest.json"
+)
+
+// Manifest 备份包描述信息
+type Manifest struct {
+	SchemaVersion int   `json:"schema_version"`
+	CreateTime    int64 `json:"create_time"`
+	Uploads       int   `json:"uploads"` // 上传文件数量
+}
+
+// Info 已有备份文件信息
+type Info struct {
+	Name       string `json:"name"`
+	Size       int64  `json:"size"`
+	CreateTime int64  `json:"create_time"`
+}
+
+// Snapshot 在线创建一份备份: 用 VACUUM INTO 导出一致的数据库快照, 与上传目录一起打包为
+// destDir/wall-YYYYMMDD-HHMMSS.tar.gz, 返回备份文件路径。
+func Snapshot(st *store.Store, uploadDir, destDir string) (string, error) {
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return "", fmt.Errorf("create backup dir: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp(destDir, ".snapshot-")
+	if err != nil {
+		return "", fmt.Errorf("create temp dir: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+
+	dbFile := filepath.Join(tmpDir, dbEntry)
+	if err := st.VacuumInto(dbFile); err != nil {
+		return "", fmt.Errorf("vacuum into: %w", err)
+	}
+	version, err := st.SchemaVersion()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	name := filePrefix + now.Format("20060102-150405") + fileSuffix
+	target := filepath.Join(destDir, name)
+	partial := target + ".part"
+
+	f, err := os.Create(partial)
+	if err != nil {
+		return "", fmt.Errorf("create backup file: %w", err)
+	}
+	manifest := Manifest{SchemaVersion: version, CreateTime: now.Unix()}
+	if err := writeArchive(f, dbFile, uploadDir, &manifest); err != nil {
+		_ = f.Close()
+		_ = os.Remove(partial)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(partial)
+		return "", err
+	}
+	if err := os.Rename(partial, target); err != nil {
+		_ = os.Remove(partial)
+		return "", err
+	}
+	return target, nil
+}
+
+func writeArchive(w io.Writer, dbFile, uploadDir string, manifest *Manifest) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := addFile(tw, dbFile, dbEntry); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(uploadDir); err == nil {
+		err := filepath.Walk(uploadDir, func(p string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() || !fi.Mode().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(uploadDir, p)
+			if err != nil {
+				return err
+			}
+			manifest.Uploads++
+			return addFile(tw, p, uploadsEntry+"/"+filepath.ToSlash(rel))
+		})
+		if err != nil {
+			return fmt.Errorf("archive uploads: %w", err)
+		}
+	}
+
+	data, _ := json.MarshalIndent(manifest, "", "  ")
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    manifestName,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Unix(manifest.CreateTime, 0),
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addFile(tw *tar.Writer, src, name string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(fi, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// List 列出目录下的备份文件（最新在前）
+func List(dir string) ([]Info, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var infos []Info
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, Info{Name: name, Size: fi.Size(), CreateTime: fi.ModTime().Unix()})
+	}
+	// 文件名带时间戳, 按名称倒序即最新在前
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name > infos[j].Name })
+	return infos, nil
+}
+
+// Prune 只保留最新的 keep 份备份, 返回删除的文件名
+func Prune(dir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	infos, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for i := keep; i < len(infos); i++ {
+		if err := os.Remove(filepath.Join(dir, infos[i].Name)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, infos[i].Name)
+	}
+	return removed, nil
+}
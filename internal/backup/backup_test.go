@@ -0,0 +1,141 @@
+package backup
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+// TestSnapshotRestore 测试备份后修改数据, 再恢复回备份时的状态
+// 运行方法: go test -v ./internal/backup/
+func TestSnapshotRestore(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "data.db")
+	uploadDir := filepath.Join(dir, "uploads")
+	backupDir := filepath.Join(dir, "backups")
+
+	st, err := store.New(dbPath)
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	post := &model.Post{Text: "备份前", Status: model.StatusPending}
+	if err := st.SavePost(post); err != nil {
+		t.Fatalf("保存失败: %v", err)
+	}
+	_ = os.MkdirAll(uploadDir, 0755)
+	_ = os.WriteFile(filepath.Join(uploadDir, "a.jpg"), []byte("img"), 0644)
+
+	archive, err := Snapshot(st, uploadDir, backupDir)
+	if err != nil {
+		t.Fatalf("备份失败: %v", err)
+	}
+
+	// 备份之后的修改在恢复后应当消失
+	post.Text = "备份后"
+	_ = st.SavePost(post)
+	_ = os.Remove(filepath.Join(uploadDir, "a.jpg"))
+	_ = st.Close()
+
+	manifest, err := Restore(archive, dbPath, uploadDir)
+	if err != nil {
+		t.Fatalf("恢复失败: %v", err)
+	}
+	if manifest.SchemaVersion != store.LatestSchemaVersion() || manifest.Uploads != 1 {
+		t.Fatalf("备份描述异常: %+v", manifest)
+	}
+	if data, err := os.ReadFile(filepath.Join(uploadDir, "a.jpg")); err != nil || string(data) != "img" {
+		t.Fatalf("上传文件未恢复: %v", err)
+	}
+
+	st, err = store.New(dbPath)
+	if err != nil {
+		t.Fatalf("打开恢复后的数据库失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	got, _ := st.GetPost(post.ID)
+	if got == nil || got.Text != "备份前" {
+		t.Fatalf("数据未恢复到备份时的状态: %+v", got)
+	}
+
+	if infos, _ := List(backupDir); len(infos) != 1 {
+		t.Fatalf("期望 1 份备份, 实际 %d", len(infos))
+	}
+}
+
+// TestRestoreTooNew 测试拒绝恢复版本高于程序的备份, 且不改动现有文件
+func TestRestoreTooNew(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "data.db")
+	st, err := store.New(dbPath)
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	_ = st.Close()
+
+	// 构造一个版本高于程序的数据库并打包
+	futureDB := filepath.Join(dir, "future.db")
+	db, err := sql.Open("sqlite", futureDB)
+	if err != nil {
+		t.Fatalf("打开失败: %v", err)
+	}
+	_, err = db.Exec("CREATE TABLE schema_version (version INTEGER PRIMARY KEY); INSERT INTO schema_version VALUES (?)",
+		store.LatestSchemaVersion()+1)
+	_ = db.Close()
+	if err != nil {
+		t.Fatalf("写入版本失败: %v", err)
+	}
+	archive := filepath.Join(dir, "too-new.tar.gz")
+	f, _ := os.Create(archive)
+	if err := writeArchive(f, futureDB, filepath.Join(dir, "uploads"), &Manifest{}); err != nil {
+		t.Fatalf("打包失败: %v", err)
+	}
+	_ = f.Close()
+
+	if _, err := Restore(archive, dbPath, filepath.Join(dir, "uploads")); !errors.Is(err, store.ErrSchemaTooNew) {
+		t.Fatalf("期望 ErrSchemaTooNew, 实际 %v", err)
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		t.Fatalf("失败的恢复不应移走现有数据库: %v", err)
+	}
+}
+
+// TestRestoreRollback 测试换入上传目录失败时回滚: 已换入的数据库被删除, 原数据库改回原名
+// 运行方法: go test -v ./internal/backup/ -run TestRestoreRollback
+func TestRestoreRollback(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "data.db")
+	st, err := store.New(dbPath)
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	archive, err := Snapshot(st, filepath.Join(dir, "uploads"), filepath.Join(dir, "backups"))
+	if err != nil {
+		t.Fatalf("备份失败: %v", err)
+	}
+	post := &model.Post{Text: "备份后", Status: model.StatusPending}
+	if err := st.SavePost(post); err != nil {
+		t.Fatalf("保存失败: %v", err)
+	}
+	_ = st.Close()
+
+	// 上传目录的父目录不存在, 换入上传目录时会失败
+	if _, err := Restore(archive, dbPath, filepath.Join(dir, "missing", "uploads")); err == nil {
+		t.Fatal("期望恢复失败")
+	}
+	if baks, _ := filepath.Glob(dbPath + ".bak-*"); len(baks) != 0 {
+		t.Fatalf("回滚后不应留下 .bak 文件: %v", baks)
+	}
+	st, err = store.New(dbPath)
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	if got, _ := st.GetPost(post.ID); got == nil || got.Text != "备份后" {
+		t.Fatalf("回滚后应保留原数据库: %+v", got)
+	}
+}
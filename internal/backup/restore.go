@@ -0,0 +1,177 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+// Restore 用备份包替换数据库与上传目录。调用前必须停止服务。
+//
+// 先解压到临时目录并检查数据库版本不高于当前程序, 再把现有的数据库和上传目录
+// 重命名为 *.bak-YYYYMMDD-HHMMSS 后换入备份内容。换入时失败会删掉已换入的内容并把
+// 移走的文件改回原名; 如果连改回也失败, 返回的错误中列出仍留在 .bak 路径下的原文件。
+func Restore(archive, dbPath, uploadDir string) (*Manifest, error) {
+	workDir, err := os.MkdirTemp(filepath.Dir(dbPath), ".restore-")
+	if err != nil {
+		return nil, fmt.Errorf("create temp dir: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(workDir)
+	}()
+
+	manifest, err := extract(archive, workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	restoredDB := filepath.Join(workDir, dbEntry)
+	if _, err := os.Stat(restoredDB); err != nil {
+		return nil, fmt.Errorf("backup has no %s", dbEntry)
+	}
+	version, _, err := store.PendingMigrations(restoredDB)
+	if err != nil {
+		return nil, fmt.Errorf("check schema: %w", err)
+	}
+	if manifest.SchemaVersion != 0 && manifest.SchemaVersion != version {
+		return nil, fmt.Errorf("manifest schema v%d does not match database v%d", manifest.SchemaVersion, version)
+	}
+
+	restoredUploads := filepath.Join(workDir, uploadsEntry)
+	if err := os.MkdirAll(restoredUploads, 0755); err != nil {
+		return nil, err
+	}
+
+	sw := &swapper{suffix: ".bak-" + time.Now().Format("20060102-150405")}
+	// 数据库: WAL/SHM 属于旧库, 一并移走
+	for _, p := range []string{dbPath, dbPath + "-wal", dbPath + "-shm"} {
+		if err := sw.moveAside(p); err != nil {
+			return nil, sw.rollback(err)
+		}
+	}
+	if err := sw.place(restoredDB, dbPath); err != nil {
+		return nil, sw.rollback(fmt.Errorf("swap database: %w", err))
+	}
+	if err := sw.moveAside(uploadDir); err != nil {
+		return nil, sw.rollback(err)
+	}
+	if err := sw.place(restoredUploads, uploadDir); err != nil {
+		return nil, sw.rollback(fmt.Errorf("swap uploads: %w", err))
+	}
+	return manifest, nil
+}
+
+// swapper 记录恢复过程中移走的原文件和换入的备份内容, 失败时用于回滚
+type swapper struct {
+	suffix string
+	moved  []string // 已重命名为 p+suffix 的原路径
+	placed []string // 已换入备份内容的路径
+}
+
+func (sw *swapper) moveAside(p string) error {
+	if _, err := os.Stat(p); os.IsNotExist(err) {
+		return nil
+	}
+	if err := os.Rename(p, p+sw.suffix); err != nil {
+		return fmt.Errorf("move aside %s: %w", p, err)
+	}
+	sw.moved = append(sw.moved, p)
+	return nil
+}
+
+func (sw *swapper) place(src, dst string) error {
+	if err := os.Rename(src, dst); err != nil {
+		return err
+	}
+	sw.placed = append(sw.placed, dst)
+	return nil
+}
+
+// rollback 删除已换入的备份内容并把原文件改回原名, 返回带上无法还原的 .bak 路径的 cause
+func (sw *swapper) rollback(cause error) error {
+	for _, p := range sw.placed {
+		_ = os.RemoveAll(p)
+	}
+	var left []string
+	for i := len(sw.moved) - 1; i >= 0; i-- {
+		p := sw.moved[i]
+		if err := os.Rename(p+sw.suffix, p); err != nil {
+			left = append(left, p+sw.suffix)
+		}
+	}
+	if len(left) > 0 {
+		return fmt.Errorf("%w; rollback failed, original files are left at %s", cause, strings.Join(left, ", "))
+	}
+	return cause
+}
+
+func extract(archive, dir string) (*Manifest, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("open gzip: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	var manifest *Manifest
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(hdr.Name)
+		if path.IsAbs(name) || strings.HasPrefix(name, "../") || name == ".." {
+			return nil, fmt.Errorf("unsafe path in archive: %s", hdr.Name)
+		}
+
+		if name == manifestName {
+			var m Manifest
+			if err := json.NewDecoder(tr).Decode(&m); err != nil {
+				return nil, fmt.Errorf("parse manifest: %w", err)
+			}
+			manifest = &m
+			continue
+		}
+
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return nil, err
+		}
+		out, err := os.Create(dst)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(out, tr); err != nil {
+			_ = out.Close()
+			return nil, err
+		}
+		if err := out.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("backup has no %s", manifestName)
+	}
+	return manifest, nil
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
+	"slices"
 	"time"
 )
 
@@ -45,7 +47,113 @@ type Config struct {
 	Web      WebConfig      `json:"web"`
 	Censor   CensorConfig   `json:"censor"`
 	Worker   WorkerConfig   `json:"worker"`
+	Backup   BackupConfig   `json:"backup"`
+	Notify   NotifyConfig   `json:"notify"`
+	Webhook  WebhookConfig  `json:"webhook"`
 	Log      LogConfig      `json:"log"`
+
+	// Walls 同一进程中运行的多个表白墙；为空时只有一个默认墙（兼容旧配置）
+	Walls []WallEntry `json:"walls,omitempty"`
+}
+
+// WallEntry 一个独立的表白墙：拥有自己的来源群、管理群、QQ空间账号、卡片主题和敏感词，稿件按墙 ID 隔离
+type WallEntry struct {
+	ID          string       `json:"id"`           // 唯一标识，只能包含字母、数字、下划线和短横线，保存在稿件中，不要随意修改
+	Name        string       `json:"name"`         // 显示名称，默认同 ID
+	Groups      []int64      `json:"groups"`       // 来源群，这些群里的 /投稿 进入本墙；为空表示接收其他墙未认领的所有群
+	ManageGroup int64        `json:"manage_group"` // 管理群，接收新投稿和账号失效通知；0 表示使用 bot.manage_group
+	Accounts    []string     `json:"accounts"`     // 使用的QQ空间账号（qzone.accounts 中的名称），每个账号只能属于一个墙
+	Theme       string       `json:"theme"`        // 卡片主题，见 render.Themes，为空使用默认主题
+	Censor      CensorConfig `json:"censor"`       // 本墙额外的敏感词，与全局 censor 合并使用
+	Admins      []string     `json:"admins"`       // 可以管理本墙的网页账号（用户名），管理员角色可以管理所有墙
+}
+
+// wallIDPattern 墙 ID 的合法字符
+var wallIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// WallList 返回所有表白墙，未填写的名称和管理群使用默认值。
+// 未配置 walls 时返回单个默认墙，使用全部账号和 bot.manage_group（兼容旧配置）
+func (c *Config) WallList() []WallEntry {
+	if len(c.Walls) == 0 {
+		return []WallEntry{{ID: "default", Name: "默认", ManageGroup: c.Bot.ManageGroup}}
+	}
+	walls := make([]WallEntry, len(c.Walls))
+	for i, w := range c.Walls {
+		if w.Name == "" {
+			w.Name = w.ID
+		}
+		if w.ManageGroup == 0 {
+			w.ManageGroup = c.Bot.ManageGroup
+		}
+		walls[i] = w
+	}
+	return walls
+}
+
+// WallAccounts 返回墙使用的QQ空间账号。只有一个墙且未指定账号时使用号池中的全部账号
+func (c *Config) WallAccounts(w WallEntry) []QzoneAccount {
+	all := c.Qzone.AccountList()
+	if len(w.Accounts) == 0 && len(c.WallList()) == 1 {
+		return all
+	}
+	var accounts []QzoneAccount
+	for _, name := range w.Accounts {
+		for _, acc := range all {
+			if acc.Name == name {
+				accounts = append(accounts, acc)
+			}
+		}
+	}
+	return accounts
+}
+
+// validateWalls 检查多墙配置：ID 合法且不重复，账号存在且只属于一个墙，来源群不重复，最多一个墙不限来源群
+func (c *Config) validateWalls() error {
+	if len(c.Walls) == 0 {
+		return nil
+	}
+	accounts := make(map[string]bool)
+	for _, acc := range c.Qzone.AccountList() {
+		accounts[acc.Name] = true
+	}
+	ids := make(map[string]bool)
+	owner := make(map[string]string)
+	groups := make(map[int64]string)
+	catchAll := ""
+	for _, w := range c.Walls {
+		if !wallIDPattern.MatchString(w.ID) {
+			return fmt.Errorf("wall id %q 只能包含字母、数字、下划线和短横线", w.ID)
+		}
+		if ids[w.ID] {
+			return fmt.Errorf("wall id %q 重复", w.ID)
+		}
+		ids[w.ID] = true
+		if len(w.Accounts) == 0 && len(c.Walls) > 1 {
+			return fmt.Errorf("wall %s 没有配置 accounts", w.ID)
+		}
+		for _, name := range w.Accounts {
+			if !accounts[name] {
+				return fmt.Errorf("wall %s 的账号 %q 不在 qzone.accounts 中", w.ID, name)
+			}
+			if other, ok := owner[name]; ok {
+				return fmt.Errorf("账号 %q 同时属于 wall %s 和 %s", name, other, w.ID)
+			}
+			owner[name] = w.ID
+		}
+		if len(w.Groups) == 0 {
+			if catchAll != "" {
+				return fmt.Errorf("wall %s 和 %s 都没有配置 groups，最多只能有一个墙接收其余的群", catchAll, w.ID)
+			}
+			catchAll = w.ID
+		}
+		for _, g := range w.Groups {
+			if other, ok := groups[g]; ok {
+				return fmt.Errorf("群 %d 同时属于 wall %s 和 %s", g, other, w.ID)
+			}
+			groups[g] = w.ID
+		}
+	}
+	return nil
 }
 
 // QzoneConfig QQ空间账号配置
@@ -53,6 +161,31 @@ type QzoneConfig struct {
 	KeepAlive Duration `json:"keep_alive"`
 	MaxRetry  int      `json:"max_retry"`
 	Timeout   Duration `json:"timeout"`
+	CookieKey string   `json:"cookie_key"` // 加密保存 Cookie 的密钥，环境变量 QZONEWALL_COOKIE_KEY 优先；为空时不保存
+
+	// Accounts 多账号号池，按顺序依次作为主号和备用号；为空时使用单个默认账号
+	Accounts []QzoneAccount `json:"accounts,omitempty"`
+}
+
+// Cookie 来源
+const (
+	CookieSourceBot = "bot" // 从 QQ 号相同的机器人 GetCookies，失败时可扫码
+	CookieSourceQR  = "qr"  // 只通过扫码登录
+)
+
+// QzoneAccount 号池中的一个QQ空间账号
+type QzoneAccount struct {
+	Name   string `json:"name"`   // 显示名称，用于后台和 /扫码 命令选择账号
+	UIN    int64  `json:"uin"`    // QQ 号，0 表示以登录结果为准（仅单账号时适用）
+	Source string `json:"source"` // Cookie 来源：bot（默认）或 qr
+}
+
+// AccountList 返回号池账号，未配置 accounts 时返回单个默认账号（兼容旧配置）
+func (c QzoneConfig) AccountList() []QzoneAccount {
+	if len(c.Accounts) == 0 {
+		return []QzoneAccount{{Name: "默认", Source: CookieSourceBot}}
+	}
+	return c.Accounts
 }
 
 // BotConfig QQ机器人配置
@@ -86,6 +219,7 @@ type WallConfig struct {
 	MaxImageSize int64    `json:"max_image_size"` // 以 MB 为单位
 	MaxTextLen   int      `json:"max_text_len"`
 	PublishDelay Duration `json:"publish_delay"`
+	RecycleDays  int      `json:"recycle_days"` // 回收站保留天数，超过后彻底删除；负数表示永不清理
 }
 
 // DatabaseConfig 数据库配置
@@ -108,11 +242,126 @@ type CensorConfig struct {
 
 // WorkerConfig 任务调度配置
 type WorkerConfig struct {
-	Workers      int      `json:"workers"`
-	RetryCount   int      `json:"retry_count"`
-	RetryDelay   Duration `json:"retry_delay"`
-	RateLimit    Duration `json:"rate_limit"`
-	PollInterval Duration `json:"poll_interval"`
+	Workers      int           `json:"workers"`
+	RetryCount   int           `json:"retry_count"`
+	RetryDelay   Duration      `json:"retry_delay"`
+	RateLimit    Duration      `json:"rate_limit"`
+	PollInterval Duration      `json:"poll_interval"`
+	LeaseTimeout Duration      `json:"lease_timeout"` // 发布租约时长，超时未完成的稿件会被退回重新发布
+	Digest       DigestConfig  `json:"digest"`
+	Quota        QuotaConfig   `json:"quota"`
+	Backoff      BackoffConfig `json:"backoff"`
+}
+
+// BackoffConfig 发布失败后的退避重试：临时错误（网络、频率限制）按指数退避重新排队，
+// 内容被拒等永久错误和超过次数的稿件标记为 failed
+type BackoffConfig struct {
+	MaxAttempts int      `json:"max_attempts"` // 每条稿件最多发布几轮（每轮含 retry_count 次立即重试），默认 5
+	BaseDelay   Duration `json:"base_delay"`   // 第一次退避的等待时间，之后每次翻倍，默认 1m
+	MaxDelay    Duration `json:"max_delay"`    // 退避等待时间上限，默认 1h
+}
+
+// QuotaConfig 发布时段与说说数量限制，超出的稿件顺延到下一个可发布时间
+type QuotaConfig struct {
+	Windows   []string `json:"windows"`    // 允许发布的时间段，如 ["08:00-23:30"]，可跨零点（"22:00-02:00"）；为空表示全天
+	Timezone  string   `json:"timezone"`   // 时段和额度使用的时区，如 "Asia/Shanghai"；为空使用本机时区
+	DailyMax  int      `json:"daily_max"`  // 每天最多发布几条说说（合集算一条），0 表示不限
+	HourlyMax int      `json:"hourly_max"` // 每小时最多发布几条说说，0 表示不限
+}
+
+// DigestConfig 合集模式配置：将多条已通过稿件合并为一条说说发布
+type DigestConfig struct {
+	Enable    bool     `json:"enable"`
+	MaxCards  int      `json:"max_cards"` // 每条说说最多几张卡片（上限 9）
+	Threshold int      `json:"threshold"` // 积压达到几条时立即发布，0 表示只按时间发布
+	Interval  Duration `json:"interval"`  // 按固定间隔发布（如 "1h"）
+	Times     []string `json:"times"`     // 按每天固定时刻发布（如 ["12:00","18:00","22:00"]），优先于 interval
+	Template  string   `json:"template"`  // 说说正文模板（text/template）
+}
+
+// BackupConfig 备份配置
+type BackupConfig struct {
+	Dir      string   `json:"dir"`      // 备份文件存放目录
+	Interval Duration `json:"interval"` // 定时备份间隔，0 表示不定时备份
+	Keep     int      `json:"keep"`     // 保留最近几份备份，超出的自动删除；0 表示全部保留
+}
+
+// WebhookConfig 事件回调投递：订阅在网页后台管理，事件先写入数据库发件箱，
+// 再由后台任务投递，失败时按指数退避重试（backoff.max_attempts 为最多投递次数）
+type WebhookConfig struct {
+	PollInterval Duration      `json:"poll_interval"` // 扫描发件箱的间隔，默认 5s
+	Timeout      Duration      `json:"timeout"`       // 单次投递超时，默认 10s
+	Backoff      BackoffConfig `json:"backoff"`       // 默认最多 8 次，首次等待 30s，上限 1h
+}
+
+// 通知渠道类型
+const (
+	NotifyQQGroup   = "qq_group"   // QQ 群消息
+	NotifyQQPrivate = "qq_private" // QQ 私聊超级用户
+	NotifyWebhook   = "webhook"    // HTTP 回调，带 HMAC 签名
+	NotifySMTP      = "smtp"       // 邮件
+)
+
+// NotifyConfig 管理员通知：新投稿、发布失败、Cookie 失效等提醒按类型发送到不同的渠道，失败时重试。
+// 不配置 channels 时所有提醒发到墙的管理群（兼容旧配置）
+type NotifyConfig struct {
+	Channels   []NotifyChannel     `json:"channels"`
+	Routes     map[string][]string `json:"routes"`      // 提醒类型 -> 渠道名称，"*" 为未单独配置的类型；为空时发到所有渠道
+	Retry      int                 `json:"retry"`       // 发送失败后重试几次，默认 2，负数表示不重试
+	RetryDelay Duration            `json:"retry_delay"` // 重试间隔，默认 5s
+}
+
+// NotifyChannel 一个通知渠道，按 type 使用对应的字段
+type NotifyChannel struct {
+	Name string `json:"name"` // 渠道名称，用于 routes
+	Type string `json:"type"` // qq_group / qq_private / webhook / smtp
+
+	Group int64   `json:"group,omitempty"` // qq_group: 群号，0 表示墙的管理群
+	Users []int64 `json:"users,omitempty"` // qq_private: 接收的QQ号，为空表示 bot.zero.super_users
+
+	URL    string `json:"url,omitempty"`    // webhook: 回调地址
+	Secret string `json:"secret,omitempty"` // webhook: HMAC-SHA256 签名密钥
+
+	Addr     string   `json:"addr,omitempty"`     // smtp: 服务器地址，如 smtp.qq.com:587
+	Username string   `json:"username,omitempty"` // smtp: 登录用户名，为空时不认证
+	Password string   `json:"password,omitempty"` // smtp: 登录密码或授权码
+	From     string   `json:"from,omitempty"`     // smtp: 发件人
+	To       []string `json:"to,omitempty"`       // smtp: 收件人
+}
+
+// validateNotify 检查通知渠道：名称不重复，必填字段完整，routes 中的渠道存在
+func (c *Config) validateNotify() error {
+	names := make(map[string]bool)
+	for _, ch := range c.Notify.Channels {
+		if ch.Name == "" {
+			return fmt.Errorf("channel name is required")
+		}
+		if names[ch.Name] {
+			return fmt.Errorf("channel %q 重复", ch.Name)
+		}
+		names[ch.Name] = true
+		switch ch.Type {
+		case NotifyQQGroup, NotifyQQPrivate:
+		case NotifyWebhook:
+			if ch.URL == "" {
+				return fmt.Errorf("channel %s 没有配置 url", ch.Name)
+			}
+		case NotifySMTP:
+			if ch.Addr == "" || ch.From == "" || len(ch.To) == 0 {
+				return fmt.Errorf("channel %s 需要配置 addr、from 和 to", ch.Name)
+			}
+		default:
+			return fmt.Errorf("channel %s 的类型 %q 不支持", ch.Name, ch.Type)
+		}
+	}
+	for alert, route := range c.Notify.Routes {
+		for _, name := range route {
+			if !names[name] {
+				return fmt.Errorf("routes.%s 中的渠道 %q 不存在", alert, name)
+			}
+		}
+	}
+	return nil
 }
 
 // LogConfig 日志配置
@@ -133,9 +382,65 @@ func Load(path string) (*Config, error) {
 	}
 
 	cfg.setDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
 
+// Validate 检查多墙和通知渠道配置，Load 和网页保存配置前都会调用，避免保存后无法启动
+func (c *Config) Validate() error {
+	if err := c.validateWalls(); err != nil {
+		return fmt.Errorf("invalid walls: %w", err)
+	}
+	if err := c.validateNotify(); err != nil {
+		return fmt.Errorf("invalid notify: %w", err)
+	}
+	return nil
+}
+
+// Redacted 返回隐藏了密钥（cookie_key、WS access_token、通知渠道的密钥和密码）的配置副本，用于网页展示
+func (c *Config) Redacted() *Config {
+	r := *c
+	r.Qzone.CookieKey = ""
+	r.Bot.WS = slices.Clone(c.Bot.WS)
+	for i := range r.Bot.WS {
+		r.Bot.WS[i].AccessToken = ""
+	}
+	r.Notify.Channels = slices.Clone(c.Notify.Channels)
+	for i := range r.Notify.Channels {
+		r.Notify.Channels[i].Secret = ""
+		r.Notify.Channels[i].Password = ""
+	}
+	return &r
+}
+
+// KeepSecrets 网页保存配置时留空的密钥沿用 old 中的值（WS 按顺序、通知渠道按名称对应），与 Redacted 配合使用
+func (c *Config) KeepSecrets(old *Config) {
+	if c.Qzone.CookieKey == "" {
+		c.Qzone.CookieKey = old.Qzone.CookieKey
+	}
+	for i := range c.Bot.WS {
+		if c.Bot.WS[i].AccessToken == "" && i < len(old.Bot.WS) {
+			c.Bot.WS[i].AccessToken = old.Bot.WS[i].AccessToken
+		}
+	}
+	for i := range c.Notify.Channels {
+		ch := &c.Notify.Channels[i]
+		for _, o := range old.Notify.Channels {
+			if o.Name != ch.Name {
+				continue
+			}
+			if ch.Secret == "" {
+				ch.Secret = o.Secret
+			}
+			if ch.Password == "" {
+				ch.Password = o.Password
+			}
+		}
+	}
+}
+
 // Save 将配置序列化为 JSON 写入文件
 func (c *Config) Save(path string) error {
 	data, err := json.MarshalIndent(c, "", "  ")
@@ -158,6 +463,15 @@ func (c *Config) setDefaults() {
 	if c.Qzone.Timeout.Duration == 0 {
 		c.Qzone.Timeout.Duration = 30 * time.Second
 	}
+	for i := range c.Qzone.Accounts {
+		acc := &c.Qzone.Accounts[i]
+		if acc.Name == "" {
+			acc.Name = fmt.Sprintf("账号%d", i+1)
+		}
+		if acc.Source != CookieSourceQR {
+			acc.Source = CookieSourceBot
+		}
+	}
 	if c.Bot.Zero.CommandPrefix == "" {
 		c.Bot.Zero.CommandPrefix = "/"
 	}
@@ -173,6 +487,9 @@ func (c *Config) setDefaults() {
 	if c.Wall.MaxTextLen == 0 {
 		c.Wall.MaxTextLen = 2000
 	}
+	if c.Wall.RecycleDays == 0 {
+		c.Wall.RecycleDays = 30
+	}
 	if c.Database.Path == "" {
 		c.Database.Path = "data/data.db"
 	}
@@ -194,6 +511,45 @@ func (c *Config) setDefaults() {
 	if c.Worker.PollInterval.Duration == 0 {
 		c.Worker.PollInterval.Duration = 5 * time.Second
 	}
+	if c.Worker.LeaseTimeout.Duration == 0 {
+		c.Worker.LeaseTimeout.Duration = 10 * time.Minute
+	}
+	if c.Worker.Digest.MaxCards <= 0 || c.Worker.Digest.MaxCards > 9 {
+		c.Worker.Digest.MaxCards = 9
+	}
+	if c.Worker.Backoff.MaxAttempts <= 0 {
+		c.Worker.Backoff.MaxAttempts = 5
+	}
+	if c.Worker.Backoff.BaseDelay.Duration <= 0 {
+		c.Worker.Backoff.BaseDelay.Duration = time.Minute
+	}
+	if c.Worker.Backoff.MaxDelay.Duration <= 0 {
+		c.Worker.Backoff.MaxDelay.Duration = time.Hour
+	}
+	if c.Backup.Dir == "" {
+		c.Backup.Dir = "data/backups"
+	}
+	if c.Notify.Retry == 0 {
+		c.Notify.Retry = 2
+	}
+	if c.Notify.RetryDelay.Duration <= 0 {
+		c.Notify.RetryDelay.Duration = 5 * time.Second
+	}
+	if c.Webhook.PollInterval.Duration <= 0 {
+		c.Webhook.PollInterval.Duration = 5 * time.Second
+	}
+	if c.Webhook.Timeout.Duration <= 0 {
+		c.Webhook.Timeout.Duration = 10 * time.Second
+	}
+	if c.Webhook.Backoff.MaxAttempts <= 0 {
+		c.Webhook.Backoff.MaxAttempts = 8
+	}
+	if c.Webhook.Backoff.BaseDelay.Duration <= 0 {
+		c.Webhook.Backoff.BaseDelay.Duration = 30 * time.Second
+	}
+	if c.Webhook.Backoff.MaxDelay.Duration <= 0 {
+		c.Webhook.Backoff.MaxDelay.Duration = time.Hour
+	}
 	if c.Log.Level == "" {
 		c.Log.Level = "info"
 	}
@@ -0,0 +1,371 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+	"github.com/guohuiyuan/qzonewall-go/internal/render"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+// Format 导出格式
+type Format string
+
+const (
+	FormatJSONL Format = "jsonl"
+	FormatCSV   Format = "csv"
+	FormatZIP   Format = "zip"
+)
+
+// batchSize 每次从数据库读取的条数
+const batchSize = 200
+
+// timeLayout CSV 与 HTML 中的时间格式
+const timeLayout = "2006-01-02 15:04:05"
+
+// CSVHeader CSV 导出的列顺序
+var CSVHeader = []string{
+	"id", "uin", "name", "group_id", "anon", "status", "reason", "tid",
+	"text", "images", "create_time", "update_time", "external_id",
+}
+
+// Record 导出的单条投稿
+type Record struct {
+	ID         int64            `json:"id"`
+	UIN        int64            `json:"uin"`
+	Name       string           `json:"name"`
+	GroupID    int64            `json:"group_id,omitempty"`
+	Anon       bool             `json:"anon"`
+	Status     model.PostStatus `json:"status"`
+	Reason     string           `json:"reason,omitempty"`
+	TID        string           `json:"tid,omitempty"`
+	Text       string           `json:"text"`
+	Images     []string         `json:"images,omitempty"`
+	CreateTime int64            `json:"create_time"`
+	UpdateTime int64            `json:"update_time,omitempty"`
+	ExternalID string           `json:"external_id,omitempty"`
+}
+
+// NewRecord 从投稿生成导出记录
+func NewRecord(p *model.Post) Record {
+	return Record{
+		ID:         p.ID,
+		UIN:        p.UIN,
+		Name:       p.Name,
+		GroupID:    p.GroupID,
+		Anon:       p.Anon,
+		Status:     p.Status,
+		Reason:     p.Reason,
+		TID:        p.TID,
+		Text:       p.Text,
+		Images:     p.Images,
+		CreateTime: p.CreateTime,
+		UpdateTime: p.UpdateTime,
+		ExternalID: p.ExternalID,
+	}
+}
+
+// csvRow 生成与 CSVHeader 对应的一行
+func (r Record) csvRow() []string {
+	return []string{
+		strconv.FormatInt(r.ID, 10),
+		strconv.FormatInt(r.UIN, 10),
+		r.Name,
+		strconv.FormatInt(r.GroupID, 10),
+		strconv.FormatBool(r.Anon),
+		string(r.Status),
+		r.Reason,
+		r.TID,
+		r.Text,
+		strings.Join(r.Images, ";"),
+		formatTime(r.CreateTime),
+		formatTime(r.UpdateTime),
+		r.ExternalID,
+	}
+}
+
+func formatTime(ts int64) string {
+	if ts <= 0 {
+		return ""
+	}
+	return time.Unix(ts, 0).Format(timeLayout)
+}
+
+// Exporter 按条件流式导出投稿
+type Exporter struct {
+	store     *store.Store
+	renderer  *render.Renderer
+	uploadDir string
+
+	// ResolveImage 把非本地图片（如 QQ 图片 file ID）解析为可下载的 URL，为空时原样交给渲染器
+	ResolveImage func(img string) string
+}
+
+// NewExporter creates an exporter. renderer 仅在导出 ZIP 时使用。
+func NewExporter(st *store.Store, renderer *render.Renderer, uploadDir string) *Exporter {
+	return &Exporter{store: st, renderer: renderer, uploadDir: uploadDir}
+}
+
+// ParseFormat 解析导出格式，空字符串视为 JSONL
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case "", "json", FormatJSONL:
+		return FormatJSONL, nil
+	case FormatCSV, FormatZIP:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown export format: %s", s)
+	}
+}
+
+// ContentType 导出格式对应的 MIME 类型
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatZIP:
+		return "application/zip"
+	default:
+		return "application/x-ndjson; charset=utf-8"
+	}
+}
+
+// FileName 导出文件名，例如 wall-export-20250701.zip
+func (f Format) FileName(now time.Time) string {
+	return "wall-export-" + now.Format("20060102") + "." + string(f)
+}
+
+// Export 把符合条件的投稿按指定格式写入 w，返回导出条数
+func (e *Exporter) Export(w io.Writer, f Format, q store.SearchQuery) (int, error) {
+	switch f {
+	case FormatJSONL:
+		return e.writeJSONL(w, q)
+	case FormatCSV:
+		return e.writeCSV(w, q)
+	case FormatZIP:
+		return e.writeZIP(w, q)
+	default:
+		return 0, fmt.Errorf("unknown export format: %s", f)
+	}
+}
+
+func (e *Exporter) writeJSONL(w io.Writer, q store.SearchQuery) (int, error) {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	n := 0
+	err := e.store.EachPost(q, batchSize, func(p *model.Post) error {
+		n++
+		return enc.Encode(NewRecord(p))
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, bw.Flush()
+}
+
+func (e *Exporter) writeCSV(w io.Writer, q store.SearchQuery) (int, error) {
+	// UTF-8 BOM，便于 Excel 正确识别中文
+	if _, err := io.WriteString(w, "\xef\xbb\xbf"); err != nil {
+		return 0, err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVHeader); err != nil {
+		return 0, err
+	}
+	n := 0
+	err := e.store.EachPost(q, batchSize, func(p *model.Post) error {
+		n++
+		return cw.Write(NewRecord(p).csvRow())
+	})
+	if err != nil {
+		return n, err
+	}
+	cw.Flush()
+	return n, cw.Error()
+}
+
+// writeZIP 输出 cards/<id>.jpg 卡片图、posts.jsonl 和离线浏览用的 index.html。
+//
+// zip 同一时间只能写一个条目，index.html 与 posts.jsonl 先写入临时文件，遍历结束后再追加。
+func (e *Exporter) writeZIP(w io.Writer, q store.SearchQuery) (int, error) {
+	if e.renderer == nil || !e.renderer.Available() {
+		return 0, fmt.Errorf("renderer not available")
+	}
+
+	htmlTmp, err := os.CreateTemp("", "wall-export-*.html")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = htmlTmp.Close()
+		_ = os.Remove(htmlTmp.Name())
+	}()
+	jsonTmp, err := os.CreateTemp("", "wall-export-*.jsonl")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = jsonTmp.Close()
+		_ = os.Remove(jsonTmp.Name())
+	}()
+
+	zw := zip.NewWriter(w)
+	htmlBuf := bufio.NewWriter(htmlTmp)
+	jsonBuf := bufio.NewWriter(jsonTmp)
+	enc := json.NewEncoder(jsonBuf)
+	enc.SetEscapeHTML(false)
+
+	n := 0
+	err = e.store.EachPost(q, batchSize, func(p *model.Post) error {
+		n++
+		if err := enc.Encode(NewRecord(p)); err != nil {
+			return err
+		}
+
+		card := ""
+		img, err := e.renderer.RenderPost(e.resolveImages(p))
+		if err != nil {
+			log.Printf("[Export] 渲染稿件 #%d 失败: %v", p.ID, err)
+		} else {
+			card = fmt.Sprintf("cards/%d.jpg", p.ID)
+			fw, err := zw.Create(card)
+			if err != nil {
+				return err
+			}
+			if _, err := fw.Write(img); err != nil {
+				return err
+			}
+		}
+		return postTmpl.Execute(htmlBuf, indexPost{
+			ID:     p.ID,
+			Author: p.ShowName(),
+			Time:   formatTime(p.CreateTime),
+			Status: p.Status,
+			Text:   p.Text,
+			Card:   card,
+		})
+	})
+	if err != nil {
+		return n, err
+	}
+	if err := htmlBuf.Flush(); err != nil {
+		return n, err
+	}
+	if err := jsonBuf.Flush(); err != nil {
+		return n, err
+	}
+
+	if err := copyToZip(zw, "posts.jsonl", jsonTmp); err != nil {
+		return n, err
+	}
+
+	fw, err := zw.Create("index.html")
+	if err != nil {
+		return n, err
+	}
+	if err := headerTmpl.Execute(fw, indexHeader{Count: n, Time: time.Now().Format(timeLayout)}); err != nil {
+		return n, err
+	}
+	if _, err := htmlTmp.Seek(0, io.SeekStart); err != nil {
+		return n, err
+	}
+	if _, err := io.Copy(fw, htmlTmp); err != nil {
+		return n, err
+	}
+	if _, err := io.WriteString(fw, indexFooter); err != nil {
+		return n, err
+	}
+	return n, zw.Close()
+}
+
+func copyToZip(zw *zip.Writer, name string, f *os.File) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	fw, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, f)
+	return err
+}
+
+// resolveImages 把 "/uploads/xxx" 转成本地绝对路径供渲染器读取，其余交给 ResolveImage
+func (e *Exporter) resolveImages(p *model.Post) *model.Post {
+	clone := *p
+	clone.Images = make([]string, len(p.Images))
+	absUploadDir, err := filepath.Abs(e.uploadDir)
+	if err != nil {
+		absUploadDir = e.uploadDir
+	}
+	for i, img := range p.Images {
+		switch {
+		case strings.HasPrefix(img, "/uploads/"):
+			clone.Images[i] = filepath.Join(absUploadDir, path.Base(img))
+		case e.ResolveImage != nil:
+			clone.Images[i] = e.ResolveImage(img)
+		default:
+			clone.Images[i] = img
+		}
+	}
+	return &clone
+}
+
+type indexHeader struct {
+	Count int
+	Time  string
+}
+
+type indexPost struct {
+	ID     int64
+	Author string
+	Time   string
+	Status model.PostStatus
+	Text   string
+	Card   string
+}
+
+var headerTmpl = template.Must(template.New("header").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>表白墙归档</title>
+<style>
+body { font-family: -apple-system, "PingFang SC", "Microsoft YaHei", sans-serif; background: #f1f5f9; margin: 0; padding: 24px; color: #0f172a; }
+h1 { font-size: 22px; margin: 0 0 4px; }
+.meta { color: #64748b; font-size: 13px; margin-bottom: 20px; }
+.post { background: #fff; border-radius: 12px; padding: 16px; margin: 0 auto 16px; max-width: 720px; box-shadow: 0 4px 14px rgba(15,23,42,0.06); }
+.post header { display: flex; gap: 12px; color: #64748b; font-size: 13px; margin-bottom: 8px; }
+.post header b { color: #0f172a; }
+.post p { white-space: pre-wrap; word-break: break-word; margin: 0 0 12px; }
+.post img { max-width: 100%; border-radius: 8px; }
+</style>
+</head>
+<body>
+<h1>表白墙归档</h1>
+<div class="meta">共 {{.Count}} 条 · 导出于 {{.Time}}</div>
+`))
+
+var postTmpl = template.Must(template.New("post").Parse(`<article class="post" id="post-{{.ID}}">
+<header><b>#{{.ID}}</b><span>{{.Author}}</span><span>{{.Time}}</span><span>{{.Status}}</span></header>
+<p>{{.Text}}</p>
+{{if .Card}}<a href="{{.Card}}"><img src="{{.Card}}" alt="#{{.ID}}" loading="lazy"></a>{{end}}
+</article>
+`))
+
+const indexFooter = "</body>\n</html>\n"
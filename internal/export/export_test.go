@@ -0,0 +1,118 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+	"github.com/guohuiyuan/qzonewall-go/internal/render"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+func newTestStore(t *testing.T) *store.Store {
+	t.Helper()
+	st, err := store.New(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	return st
+}
+
+// TestExportJSONLAndCSV 测试跨批次分页导出与状态过滤
+// 运行方法: go test -v ./internal/export/
+func TestExportJSONLAndCSV(t *testing.T) {
+	st := newTestStore(t)
+	const published = batchSize + 5
+	for i := 0; i < published; i++ {
+		_ = st.SavePost(&model.Post{Text: "已发布", Status: model.StatusPublished})
+	}
+	_ = st.SavePost(&model.Post{Text: "待审核", Status: model.StatusPending})
+
+	exp := NewExporter(st, nil, t.TempDir())
+	q := store.SearchQuery{Status: model.StatusPublished}
+
+	var buf bytes.Buffer
+	n, err := exp.Export(&buf, FormatJSONL, q)
+	if err != nil || n != published {
+		t.Fatalf("JSONL 期望 %d 条, 实际 %d (%v)", published, n, err)
+	}
+	lines := 0
+	var lastID int64
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		var r Record
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatalf("解析 JSONL 失败: %v", err)
+		}
+		if r.Status != model.StatusPublished || r.ID <= lastID {
+			t.Fatalf("记录异常: %+v (上一条 #%d)", r, lastID)
+		}
+		lastID = r.ID
+		lines++
+	}
+	if lines != published {
+		t.Fatalf("JSONL 行数期望 %d, 实际 %d", published, lines)
+	}
+
+	buf.Reset()
+	if _, err := exp.Export(&buf, FormatCSV, store.SearchQuery{Status: model.StatusPending}); err != nil {
+		t.Fatalf("CSV 导出失败: %v", err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\xef\xbb\xbf"))).ReadAll()
+	if err != nil {
+		t.Fatalf("解析 CSV 失败: %v", err)
+	}
+	if len(rows) != 2 || rows[1][8] != "待审核" {
+		t.Fatalf("CSV 内容异常: %v", rows)
+	}
+}
+
+// TestExportZIP 测试 ZIP 中包含卡片图、posts.jsonl 和不泄露匿名者信息的 index.html
+func TestExportZIP(t *testing.T) {
+	renderer := render.NewRenderer()
+	if !renderer.Available() {
+		t.Skip("渲染器不可用")
+	}
+	st := newTestStore(t)
+	_ = st.SavePost(&model.Post{UIN: 10001, Name: "小明", Text: "匿名的话", Anon: true, Status: model.StatusPublished})
+	_ = st.SavePost(&model.Post{UIN: 10002, Name: "小红", Text: "署名的话", Status: model.StatusPublished})
+
+	var buf bytes.Buffer
+	n, err := NewExporter(st, renderer, t.TempDir()).Export(&buf, FormatZIP, store.SearchQuery{})
+	if err != nil || n != 2 {
+		t.Fatalf("ZIP 期望 2 条, 实际 %d (%v)", n, err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("打开 ZIP 失败: %v", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	for _, name := range []string{"index.html", "posts.jsonl", "cards/1.jpg", "cards/2.jpg"} {
+		if files[name] == nil {
+			t.Fatalf("ZIP 中缺少 %s", name)
+		}
+	}
+
+	rc, _ := files["index.html"].Open()
+	index, _ := io.ReadAll(rc)
+	_ = rc.Close()
+	html := string(index)
+	if !strings.Contains(html, "署名的话") || !strings.Contains(html, "小红") {
+		t.Fatalf("index.html 缺少投稿内容")
+	}
+	if strings.Contains(html, "小明") || strings.Contains(html, "10001") {
+		t.Fatalf("index.html 泄露了匿名投稿者信息")
+	}
+}
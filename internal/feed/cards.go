@@ -0,0 +1,68 @@
+package feed
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+// RenderFunc 渲染稿件卡片，通常为墙的 publish.Service.RenderCard（使用墙的主题并解析图片地址）
+type RenderFunc func(*model.Post) ([]byte, error)
+
+// Cards 公开卡片图片的磁盘缓存，按墙分目录保存为 <id>-<更新时间>.jpg，稿件更新后重新渲染。
+// 渲染前稿件会先经过 Public，匿名稿件的卡片不会带出头像和昵称。
+type Cards struct {
+	dir string
+	mu  sync.Mutex // 同一时间只渲染一张，避免同一稿件被并发请求重复渲染
+}
+
+// NewCards 创建卡片缓存，dir 不存在时在第一次写入时创建
+func NewCards(dir string) *Cards {
+	return &Cards{dir: dir}
+}
+
+// Get 返回稿件的卡片图片，缓存中没有时调用 render 渲染并保存
+func (c *Cards) Get(p *model.Post, render RenderFunc) ([]byte, error) {
+	path := c.path(p)
+	if b, err := os.ReadFile(path); err == nil {
+		return b, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if b, err := os.ReadFile(path); err == nil {
+		return b, nil
+	}
+	b, err := render(Public(p))
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("create card dir: %w", err)
+	}
+	// 清理该稿件旧版本的卡片
+	if old, _ := filepath.Glob(filepath.Join(filepath.Dir(path), fmt.Sprintf("%d-*.jpg", p.ID))); len(old) > 0 {
+		for _, f := range old {
+			_ = os.Remove(f)
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return nil, fmt.Errorf("write card: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, fmt.Errorf("write card: %w", err)
+	}
+	return b, nil
+}
+
+func (c *Cards) path(p *model.Post) string {
+	wall := p.WallID
+	if wall == "" {
+		wall = model.DefaultWall
+	}
+	return filepath.Join(c.dir, wall, fmt.Sprintf("%d-%d.jpg", p.ID, p.UpdateTime))
+}
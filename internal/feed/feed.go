@@ -0,0 +1,336 @@
+// Package feed 公开的稿件订阅：已发布且未下架的稿件，输出 RSS 2.0、Atom 1.0 和 JSON Feed 1.1。
+//
+// 订阅中只包含 Item 里的字段，匿名稿件不会带出投稿者的QQ号、昵称和头像。
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+// Limit 订阅中的最多条数
+const Limit = 50
+
+// 订阅格式的 MIME 类型
+const (
+	ContentTypeRSS  = "application/rss+xml; charset=utf-8"
+	ContentTypeAtom = "application/atom+xml; charset=utf-8"
+	ContentTypeJSON = "application/feed+json; charset=utf-8"
+)
+
+// anonName 匿名稿件的作者名，与卡片上显示的一致
+const anonName = "匿名用户"
+
+// Item 订阅中的一条稿件，只包含可以公开的信息
+type Item struct {
+	ID     int64
+	Author string // 匿名稿件为 "匿名用户"
+	Text   string
+	Time   time.Time // 发布时间
+	URL    string    // 稿件页面地址
+	Card   string    // 卡片图片地址
+}
+
+// Channel 一个墙的订阅
+type Channel struct {
+	Title       string
+	Description string
+	Link        string // 网页地址
+	FeedURL     string // 本订阅的地址
+	Items       []Item
+}
+
+// Public 返回可以公开的稿件副本：匿名稿件去掉QQ号、昵称、头像和来源群，
+// 并清空审核和发布相关的内部字段。渲染公开的卡片图片前也要先经过这里。
+func Public(p *model.Post) *model.Post {
+	c := &model.Post{
+		ID:         p.ID,
+		UIN:        p.UIN,
+		Name:       p.Name,
+		GroupID:    p.GroupID,
+		Text:       p.Text,
+		Images:     p.Images,
+		Anon:       p.Anon,
+		Status:     p.Status,
+		AvatarURL:  p.AvatarURL,
+		CreateTime: p.CreateTime,
+		UpdateTime: p.UpdateTime,
+		WallID:     p.WallID,
+	}
+	if c.Anon {
+		c.UIN, c.Name, c.AvatarURL, c.GroupID = 0, "", "", 0
+	}
+	return c
+}
+
+// Visible 稿件是否出现在公开订阅中：已发布的稿件，下架或删除后不再出现
+func Visible(p *model.Post) bool {
+	return p != nil && p.Status == model.StatusPublished
+}
+
+// NewItem 从稿件生成订阅条目，url 和 card 为稿件页面和卡片图片的地址
+func NewItem(p *model.Post, url, card string) Item {
+	p = Public(p)
+	author := p.Name
+	if p.Anon || author == "" {
+		author = anonName
+	}
+	// 发布时会更新稿件，更新时间即发布时间
+	ts := p.UpdateTime
+	if ts == 0 {
+		ts = p.CreateTime
+	}
+	return Item{ID: p.ID, Author: author, Text: p.Text, Time: time.Unix(ts, 0), URL: url, Card: card}
+}
+
+// Title 条目标题：编号加正文第一行的前 30 个字
+func (it Item) Title() string {
+	line, _, _ := strings.Cut(strings.TrimSpace(it.Text), "\n")
+	if r := []rune(line); len(r) > 30 {
+		line = string(r[:30]) + "…"
+	}
+	if line == "" {
+		return fmt.Sprintf("#%d", it.ID)
+	}
+	return fmt.Sprintf("#%d %s", it.ID, line)
+}
+
+// HTML 条目正文：转义后的文字和卡片图片
+func (it Item) HTML() string {
+	var b strings.Builder
+	if it.Text != "" {
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(it.Text), "\n", "<br>"))
+		b.WriteString("</p>")
+	}
+	if it.Card != "" {
+		fmt.Fprintf(&b, `<p><img src="%s" alt="#%d"></p>`, html.EscapeString(it.Card), it.ID)
+	}
+	return b.String()
+}
+
+// updated 订阅的更新时间：最新一条的发布时间
+func (c *Channel) updated() time.Time {
+	var t time.Time
+	for _, it := range c.Items {
+		if it.Time.After(t) {
+			t = it.Time
+		}
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t
+}
+
+// ──────────────────────────────────────────
+// RSS 2.0
+// ──────────────────────────────────────────
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	AtomLink      atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        rssGUID       `xml:"guid"`
+	Author      string        `xml:"dc:creator"`
+	PubDate     string        `xml:"pubDate"`
+	Description string        `xml:"description"`
+	Enclosure   *rssEnclosure `xml:"enclosure,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int    `xml:"length,attr"`
+}
+
+// WriteRSS 输出 RSS 2.0
+func WriteRSS(w io.Writer, c *Channel) error {
+	doc := rss{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         c.Title,
+			Link:          c.Link,
+			Description:   c.Description,
+			AtomLink:      atomLink{Href: c.FeedURL, Rel: "self", Type: "application/rss+xml"},
+			LastBuildDate: c.updated().Format(time.RFC1123Z),
+		},
+	}
+	for _, it := range c.Items {
+		item := rssItem{
+			Title:       it.Title(),
+			Link:        it.URL,
+			GUID:        rssGUID{IsPermaLink: true, Value: it.URL},
+			Author:      it.Author,
+			PubDate:     it.Time.Format(time.RFC1123Z),
+			Description: it.HTML(),
+		}
+		if it.Card != "" {
+			item.Enclosure = &rssEnclosure{URL: it.Card, Type: "image/jpeg"}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+	return writeXML(w, doc)
+}
+
+// ──────────────────────────────────────────
+// Atom 1.0
+// ──────────────────────────────────────────
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	ID       string      `xml:"id"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Link      atomLink    `xml:"link"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Author    atomAuthor  `xml:"author"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteAtom 输出 Atom 1.0
+func WriteAtom(w io.Writer, c *Channel) error {
+	doc := atomFeed{
+		Title:    c.Title,
+		Subtitle: c.Description,
+		ID:       c.FeedURL,
+		Updated:  c.updated().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: c.Link, Rel: "alternate", Type: "text/html"},
+			{Href: c.FeedURL, Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+	for _, it := range c.Items {
+		ts := it.Time.Format(time.RFC3339)
+		doc.Entries = append(doc.Entries, atomEntry{
+			Title:     it.Title(),
+			ID:        it.URL,
+			Link:      atomLink{Href: it.URL, Rel: "alternate", Type: "text/html"},
+			Published: ts,
+			Updated:   ts,
+			Author:    atomAuthor{Name: it.Author},
+			Content:   atomContent{Type: "html", Value: it.HTML()},
+		})
+	}
+	return writeXML(w, doc)
+}
+
+// writeXML 输出 XML 声明和文档
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
+
+// ──────────────────────────────────────────
+// JSON Feed 1.1
+// ──────────────────────────────────────────
+
+type jsonFeed struct {
+	Version     string     `json:"version"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	HomePageURL string     `json:"home_page_url"`
+	FeedURL     string     `json:"feed_url"`
+	Items       []jsonItem `json:"items"`
+}
+
+type jsonItem struct {
+	ID            string       `json:"id"`
+	URL           string       `json:"url"`
+	Title         string       `json:"title"`
+	ContentHTML   string       `json:"content_html"`
+	ContentText   string       `json:"content_text"`
+	Image         string       `json:"image,omitempty"`
+	DatePublished string       `json:"date_published"`
+	Authors       []jsonAuthor `json:"authors"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+// WriteJSON 输出 JSON Feed 1.1
+func WriteJSON(w io.Writer, c *Channel) error {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       c.Title,
+		Description: c.Description,
+		HomePageURL: c.Link,
+		FeedURL:     c.FeedURL,
+		Items:       []jsonItem{},
+	}
+	for _, it := range c.Items {
+		doc.Items = append(doc.Items, jsonItem{
+			ID:            it.URL,
+			URL:           it.URL,
+			Title:         it.Title(),
+			ContentHTML:   it.HTML(),
+			ContentText:   it.Text,
+			Image:         it.Card,
+			DatePublished: it.Time.Format(time.RFC3339),
+			Authors:       []jsonAuthor{{Name: it.Author}},
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,92 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+// TestFeedAnonymity 测试三种订阅格式都能正确解析，匿名稿件不会带出QQ号、昵称和头像，卡片也按匿名渲染
+// 运行方法: go test -v ./internal/feed/ -run TestFeedAnonymity
+func TestFeedAnonymity(t *testing.T) {
+	anon := &model.Post{
+		ID: 7, UIN: 123456789, Name: "小明", AvatarURL: "https://avatar.example/secret.jpg", GroupID: 5550001,
+		Text: "今天在图书馆看到你了\n<b>第二行</b>", Anon: true, Status: model.StatusPublished,
+		CreateTime: 1700000000, UpdateTime: 1700000600, WallID: "a",
+	}
+	named := &model.Post{ID: 8, UIN: 10001, Name: "小红", Text: "谢谢大家", Status: model.StatusPublished, CreateTime: 1700001000}
+
+	ch := &Channel{
+		Title:   "一中",
+		Link:    "https://example.com/wall/feed",
+		FeedURL: "https://example.com/wall/feed/rss.xml",
+		Items: []Item{
+			NewItem(anon, "https://example.com/wall/feed/7", "https://example.com/wall/feed/cards/7.jpg"),
+			NewItem(named, "https://example.com/wall/feed/8", "https://example.com/wall/feed/cards/8.jpg"),
+		},
+	}
+	if ch.Items[0].Author != "匿名用户" || ch.Items[1].Author != "小红" {
+		t.Fatalf("作者名错误: %q %q", ch.Items[0].Author, ch.Items[1].Author)
+	}
+	if got := ch.Items[0].Title(); got != "#7 今天在图书馆看到你了" {
+		t.Fatalf("标题错误: %q", got)
+	}
+
+	for name, write := range map[string]func(*bytes.Buffer) error{
+		"rss":  func(b *bytes.Buffer) error { return WriteRSS(b, ch) },
+		"atom": func(b *bytes.Buffer) error { return WriteAtom(b, ch) },
+		"json": func(b *bytes.Buffer) error { return WriteJSON(b, ch) },
+	} {
+		var b bytes.Buffer
+		if err := write(&b); err != nil {
+			t.Fatalf("%s 生成失败: %v", name, err)
+		}
+		out := b.String()
+		for _, leak := range []string{"123456789", "小明", "secret.jpg", "5550001"} {
+			if strings.Contains(out, leak) {
+				t.Fatalf("%s 中泄露了匿名稿件的 %q:\n%s", name, leak, out)
+			}
+		}
+		if !strings.Contains(out, "小红") || !strings.Contains(out, "cards/7.jpg") {
+			t.Fatalf("%s 缺少稿件内容:\n%s", name, out)
+		}
+		if name == "json" {
+			var v map[string]interface{}
+			if err := json.Unmarshal(b.Bytes(), &v); err != nil {
+				t.Fatalf("JSON Feed 格式错误: %v", err)
+			}
+		} else if err := xml.Unmarshal(b.Bytes(), new(struct{})); err != nil {
+			t.Fatalf("%s 不是有效的 XML: %v", name, err)
+		}
+		// JSON Feed 的 content_text 是纯文本，原样输出
+		if name != "json" && strings.Contains(out, "<b>第二行</b>") {
+			t.Fatalf("%s 中的正文没有转义", name)
+		}
+	}
+
+	// 卡片按 Public 后的稿件渲染
+	var rendered *model.Post
+	cards := NewCards(t.TempDir())
+	render := func(p *model.Post) ([]byte, error) {
+		rendered = p
+		return []byte("jpeg"), nil
+	}
+	if _, err := cards.Get(anon, render); err != nil {
+		t.Fatalf("渲染卡片失败: %v", err)
+	}
+	if rendered.UIN != 0 || rendered.Name != "" || rendered.AvatarURL != "" || rendered.QQAvatarURL() != "" {
+		t.Fatalf("匿名稿件的卡片不应包含投稿者信息: %+v", rendered)
+	}
+	rendered = nil
+	if b, err := cards.Get(anon, render); err != nil || string(b) != "jpeg" || rendered != nil {
+		t.Fatalf("第二次应读取缓存: %q %v", b, err)
+	}
+	anon.UpdateTime++
+	if _, _ = cards.Get(anon, render); rendered == nil {
+		t.Fatalf("稿件更新后应重新渲染")
+	}
+}
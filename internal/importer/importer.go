@@ -0,0 +1,449 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+// maxImageSize 单张图片的大小上限，网络图片和本地图片相同
+const maxImageSize = 20 << 20
+
+// 单行的处理结果
+const (
+	ActionInserted    = "inserted"
+	ActionWouldInsert = "would_insert"
+	ActionSkipped     = "skipped"
+	ActionError       = "error"
+)
+
+// Options 导入选项
+type Options struct {
+	Source  string // 来源标识，作为外部编号前缀区分不同来源，例如 "oldbot"
+	BaseDir string // 本地图片的根目录，只接受该目录下的文件；为空时只接受网络图片
+	DryRun  bool   // 只校验并报告，不写数据库、不复制图片
+
+	// KeepApproved 保留未发布的 approved/publishing 状态，导入后会被 Worker 发布到QQ空间；
+	// 默认按 pending 导入，需要重新审核
+	KeepApproved bool
+
+	// 审计日志中的操作者
+	ActorID     int64
+	ActorName   string
+	AuditSource model.AuditSource
+}
+
+// RowResult 单行导入结果
+type RowResult struct {
+	Row        int    `json:"row"`
+	ExternalID string `json:"external_id,omitempty"`
+	PostID     int64  `json:"post_id,omitempty"`
+	Action     string `json:"action"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Report 导入报告
+type Report struct {
+	Inserted int         `json:"inserted"` // dry-run 时为将要插入的条数
+	Skipped  int         `json:"skipped"`  // 外部编号已存在
+	Failed   int         `json:"failed"`
+	Rows     []RowResult `json:"rows"`
+}
+
+func (r *Report) add(res RowResult) {
+	switch res.Action {
+	case ActionInserted, ActionWouldInsert:
+		r.Inserted++
+	case ActionSkipped:
+		r.Skipped++
+	case ActionError:
+		r.Failed++
+	}
+	r.Rows = append(r.Rows, res)
+}
+
+// Importer 把其他工具导出的 JSON/CSV 导入为投稿
+type Importer struct {
+	store     *store.Store
+	uploadDir string
+	client    *http.Client
+}
+
+// NewImporter 创建导入器，图片复制到 uploadDir。
+// 网络图片只从公网地址下载（包括重定向），导入文件不能让服务器访问本机或内网地址
+func NewImporter(st *store.Store, uploadDir string) *Importer {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: publicOnly}
+	return &Importer{
+		store:     st,
+		uploadDir: uploadDir,
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+		},
+	}
+}
+
+// publicOnly 拒绝连接回环、内网、链路本地等非公网地址。在解析域名之后检查，域名指向内网时同样拒绝
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("non-public address not allowed: %s", host)
+	}
+	return nil
+}
+
+// FormatOf 按文件名判断格式: .csv 为 CSV，其余按 JSON / JSON Lines 处理
+func FormatOf(name string) string {
+	if strings.EqualFold(filepath.Ext(name), ".csv") {
+		return "csv"
+	}
+	return "json"
+}
+
+// Import 按格式（"csv" 或 "json"）读取并导入。单行出错记入报告并继续，只有文件本身无法解析时才返回错误。
+func (im *Importer) Import(r io.Reader, format string, opt Options) (*Report, error) {
+	if format == "csv" {
+		return im.importCSV(r, opt)
+	}
+	return im.importJSON(r, opt)
+}
+
+// importJSON 支持 JSON 数组和 JSON Lines（每行一个对象）
+func (im *Importer) importJSON(r io.Reader, opt Options) (*Report, error) {
+	br := bufio.NewReader(r)
+	skipBOM(br)
+	dec := json.NewDecoder(br)
+	dec.UseNumber()
+
+	isArray := false
+	if b, err := peekNonSpace(br); err == nil && b == '[' {
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		isArray = true
+	}
+
+	report := &Report{}
+	for row := 1; ; row++ {
+		if isArray && !dec.More() {
+			break
+		}
+		var obj map[string]json.RawMessage
+		if err := dec.Decode(&obj); err == io.EOF {
+			break
+		} else if err != nil {
+			return report, fmt.Errorf("row %d: %w", row, err)
+		}
+		raw, err := rawFromJSON(obj)
+		if err != nil {
+			report.add(RowResult{Row: row, Action: ActionError, Error: err.Error()})
+			continue
+		}
+		report.add(im.importRow(row, raw, opt))
+	}
+	return report, nil
+}
+
+// importCSV 第一行为表头，列名见 README「导入」一节，顺序不限
+func (im *Importer) importCSV(r io.Reader, opt Options) (*Report, error) {
+	br := bufio.NewReader(r)
+	skipBOM(br)
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	report := &Report{}
+	for row := 1; ; row++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, fmt.Errorf("row %d: %w", row, err)
+		}
+		raw := rawRow{fields: map[string]string{}}
+		for i, v := range rec {
+			if i < len(header) {
+				raw.fields[header[i]] = strings.TrimSpace(v)
+			}
+		}
+		raw.images = splitImages(raw.fields["images"])
+		report.add(im.importRow(row, raw, opt))
+	}
+	return report, nil
+}
+
+// importRow 校验并导入一行
+func (im *Importer) importRow(row int, raw rawRow, opt Options) RowResult {
+	res := RowResult{Row: row, Action: ActionError}
+
+	post, err := raw.toPost(opt.KeepApproved)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if opt.Source != "" {
+		post.ExternalID = opt.Source + ":" + post.ExternalID
+	}
+	res.ExternalID = post.ExternalID
+
+	existing, err := im.store.GetPostByExternalID(post.ExternalID)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if existing != nil {
+		res.Action = ActionSkipped
+		res.PostID = existing.ID
+		return res
+	}
+
+	sources := make([]string, len(raw.images))
+	for i, img := range raw.images {
+		src, err := im.locateImage(img, opt.BaseDir)
+		if err != nil {
+			res.Error = fmt.Sprintf("image %d: %v", i+1, err)
+			return res
+		}
+		sources[i] = src
+	}
+
+	if opt.DryRun {
+		res.Action = ActionWouldInsert
+		return res
+	}
+
+	var copied []string
+	cleanup := func() {
+		for _, img := range copied {
+			_ = os.Remove(filepath.Join(im.uploadDir, path.Base(img)))
+		}
+	}
+	for i, src := range sources {
+		img, err := im.copyImage(src)
+		if err != nil {
+			cleanup()
+			res.Error = fmt.Sprintf("image %d: %v", i+1, err)
+			return res
+		}
+		copied = append(copied, img)
+	}
+	post.Images = copied
+
+	if err := im.store.SavePost(post); err != nil {
+		cleanup()
+		res.Error = err.Error()
+		return res
+	}
+	_ = im.store.AddAuditEvent(&model.AuditEvent{
+		ActorID:   opt.ActorID,
+		ActorName: opt.ActorName,
+		Source:    opt.AuditSource,
+		Action:    model.ActionImport,
+		PostID:    post.ID,
+		NewStatus: post.Status,
+		Reason:    "external_id=" + post.ExternalID,
+	})
+	res.Action = ActionInserted
+	res.PostID = post.ID
+	return res
+}
+
+// locateImage 检查图片来源: 网络图片原样返回，本地图片返回 baseDir 下存在的文件路径。
+// 未指定 baseDir（例如网页上传的导入文件）时不读取任何本地文件；
+// 文件不存在和不在 baseDir 下返回同样的错误，不泄露 baseDir 之外的文件是否存在
+func (im *Importer) locateImage(img, baseDir string) (string, error) {
+	if strings.HasPrefix(img, "http://") || strings.HasPrefix(img, "https://") {
+		return img, nil
+	}
+	if baseDir == "" {
+		return "", fmt.Errorf("local image not allowed: %s", img)
+	}
+	root, err := filepath.Abs(baseDir)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		return "", fmt.Errorf("images dir: %w", err)
+	}
+
+	candidates := []string{}
+	if filepath.IsAbs(img) {
+		candidates = append(candidates, img)
+	}
+	candidates = append(candidates, filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(img, "/"))))
+	for _, p := range candidates {
+		resolved, err := filepath.EvalSymlinks(p)
+		if err != nil || !within(root, resolved) {
+			continue
+		}
+		fi, err := os.Stat(resolved)
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		if fi.Size() > maxImageSize {
+			return "", fmt.Errorf("%s: larger than %d MB", img, maxImageSize>>20)
+		}
+		return resolved, nil
+	}
+	return "", fmt.Errorf("not found in images dir: %s", img)
+}
+
+// within 判断 p 是否位于目录 root 之下
+func within(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// copyImage 把图片复制/下载到上传目录，返回 "/uploads/<文件名>"
+func (im *Importer) copyImage(src string) (string, error) {
+	var data []byte
+	var err error
+	ext := strings.ToLower(path.Ext(src))
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		if u := strings.IndexAny(src, "?#"); u >= 0 {
+			ext = strings.ToLower(path.Ext(src[:u]))
+		}
+		data, err = im.download(src)
+	} else {
+		data, err = readLocal(src)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch ext {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp":
+	default:
+		ext = extByContent(data)
+	}
+	if ext == "" {
+		return "", errors.New("not an image")
+	}
+
+	if err := os.MkdirAll(im.uploadDir, 0755); err != nil {
+		return "", err
+	}
+	filename := fmt.Sprintf("%d_%s%s", time.Now().UnixNano(), randomHex(8), ext)
+	if err := os.WriteFile(filepath.Join(im.uploadDir, filename), data, 0644); err != nil {
+		return "", err
+	}
+	return "/uploads/" + filename, nil
+}
+
+func (im *Importer) download(url string) ([]byte, error) {
+	resp, err := im.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s: HTTP %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageSize {
+		return nil, fmt.Errorf("download %s: larger than %d MB", url, maxImageSize>>20)
+	}
+	return data, nil
+}
+
+// readLocal 读取本地图片，超过 maxImageSize 时报错（文件可能在校验之后变大）
+func readLocal(name string) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	data, err := io.ReadAll(io.LimitReader(f, maxImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageSize {
+		return nil, fmt.Errorf("%s: larger than %d MB", name, maxImageSize>>20)
+	}
+	return data, nil
+}
+
+func extByContent(data []byte) string {
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	}
+	return ""
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func skipBOM(br *bufio.Reader) {
+	if b, err := br.Peek(3); err == nil && bytes.Equal(b, []byte{0xEF, 0xBB, 0xBF}) {
+		_, _ = br.Discard(3)
+	}
+}
+
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return b, br.UnreadByte()
+		}
+	}
+}
+
+func splitImages(s string) []string {
+	var imgs []string
+	for _, v := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == '\n' }) {
+		if v = strings.TrimSpace(v); v != "" {
+			imgs = append(imgs, v)
+		}
+	}
+	return imgs
+}
@@ -0,0 +1,209 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+// 1x1 PNG
+var pngData = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89\x00\x00\x00\rIDATx\x9cc\xf8\x0f\x00\x00\x01\x01\x00\x05\x18\xd8N\x00\x00\x00\x00IEND\xaeB`\x82")
+
+// TestImportJSON 测试 JSON Lines 导入: 保留时间/匿名/状态、复制图片、逐行报错、dry-run 与重复导入去重
+// 运行方法: go test -v ./internal/importer/
+func TestImportJSON(t *testing.T) {
+	dir := t.TempDir()
+	st, err := store.New(filepath.Join(dir, "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	if err := os.WriteFile(filepath.Join(dir, "a.png"), pngData, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := `{"id": 1, "uin": 10001, "name": "小明", "text": "第一条", "anon": true, "status": "published", "create_time": "2024-03-01 12:30:00", "images": ["a.png"]}
+{"external_id": "x-2", "text": "第二条", "status": "rejected", "reason": "重复", "create_time": 1700000000}
+{"id": 3, "text": "状态错误", "status": "whatever"}
+{"text": "没有编号"}
+`
+	uploadDir := filepath.Join(dir, "uploads")
+	im := NewImporter(st, uploadDir)
+	opt := Options{Source: "old", BaseDir: dir, AuditSource: model.SourceCLI}
+
+	// 1. dry-run 不写入任何数据
+	opt.DryRun = true
+	report, err := im.Import(strings.NewReader(input), "json", opt)
+	if err != nil {
+		t.Fatalf("dry-run 失败: %v", err)
+	}
+	if report.Inserted != 2 || report.Failed != 2 {
+		t.Fatalf("dry-run 期望 2 条将插入、2 条失败, 实际 %+v", report)
+	}
+	if n, _ := st.CountAll(); n != 0 {
+		t.Fatalf("dry-run 不应写入数据库, 实际 %d 条", n)
+	}
+
+	// 2. 正式导入
+	opt.DryRun = false
+	report, err = im.Import(strings.NewReader(input), "json", opt)
+	if err != nil || report.Inserted != 2 || report.Failed != 2 {
+		t.Fatalf("导入结果异常: %+v (%v)", report, err)
+	}
+	if report.Rows[2].Row != 3 || report.Rows[2].Error == "" {
+		t.Fatalf("第 3 行应报告错误: %+v", report.Rows[2])
+	}
+
+	p, _ := st.GetPostByExternalID("old:1")
+	if p == nil {
+		t.Fatal("找不到导入的稿件 old:1")
+	}
+	want := time.Date(2024, 3, 1, 12, 30, 0, 0, time.Local).Unix()
+	if !p.Anon || p.Status != model.StatusPublished || p.CreateTime != want || p.UIN != 10001 {
+		t.Fatalf("字段未保留: %+v", p)
+	}
+	if len(p.Images) != 1 || !strings.HasPrefix(p.Images[0], "/uploads/") {
+		t.Fatalf("图片未复制到上传目录: %v", p.Images)
+	}
+	if _, err := os.Stat(filepath.Join(uploadDir, filepath.Base(p.Images[0]))); err != nil {
+		t.Fatalf("上传目录中缺少图片: %v", err)
+	}
+	if p2, _ := st.GetPostByExternalID("old:x-2"); p2 == nil || p2.CreateTime != 1700000000 || p2.Reason != "重复" {
+		t.Fatalf("稿件 old:x-2 异常: %+v", p2)
+	}
+
+	// 3. 重复导入全部跳过
+	report, err = im.Import(strings.NewReader(input), "json", opt)
+	if err != nil || report.Inserted != 0 || report.Skipped != 2 {
+		t.Fatalf("重复导入应跳过 2 条, 实际 %+v (%v)", report, err)
+	}
+}
+
+// TestImportCSV 测试带 BOM 的 CSV 与 JSON 数组
+func TestImportCSV(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	im := NewImporter(st, t.TempDir())
+
+	csvInput := "\xef\xbb\xbfid,text,anon,create_time\n7,\"含有,逗号\",是,2024/05/20 13:14\n"
+	report, err := im.Import(strings.NewReader(csvInput), "csv", Options{})
+	if err != nil || report.Inserted != 1 {
+		t.Fatalf("CSV 导入异常: %+v (%v)", report, err)
+	}
+	p, _ := st.GetPostByExternalID("7")
+	if p == nil || p.Text != "含有,逗号" || !p.Anon || p.Status != model.StatusPublished {
+		t.Fatalf("CSV 字段异常: %+v", p)
+	}
+
+	report, err = im.Import(strings.NewReader(`[{"id": "a", "text": "数组"}, {"id": "b", "text": "格式"}]`), "json", Options{})
+	if err != nil || report.Inserted != 2 {
+		t.Fatalf("JSON 数组导入异常: %+v (%v)", report, err)
+	}
+}
+
+// TestImportLocalImages 测试本地图片只能来自 BaseDir：网页导入（未指定 BaseDir）不读取本地文件，
+// 命令行导入拒绝目录之外的绝对路径和 ../，且不存在与越界返回相同的错误
+// 运行方法: go test -v ./internal/importer/ -run TestImportLocalImages
+func TestImportLocalImages(t *testing.T) {
+	dir := t.TempDir()
+	st, err := store.New(filepath.Join(dir, "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	baseDir := filepath.Join(dir, "images")
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	inside := filepath.Join(baseDir, "a.png")
+	outside := filepath.Join(dir, "secret.png")
+	for _, p := range []string{inside, outside} {
+		if err := os.WriteFile(p, pngData, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	im := NewImporter(st, filepath.Join(dir, "uploads"))
+
+	if _, err := im.locateImage(inside, ""); err == nil {
+		t.Fatal("未指定 BaseDir 时不应接受本地图片")
+	}
+	if p, err := im.locateImage(inside, baseDir); err != nil || filepath.Base(p) != "a.png" {
+		t.Fatalf("BaseDir 内的绝对路径应被接受: %q (%v)", p, err)
+	}
+	_, errOutside := im.locateImage(outside, baseDir)
+	_, errDotDot := im.locateImage("../secret.png", baseDir)
+	_, errMissing := im.locateImage(filepath.Join(dir, "missing.png"), baseDir)
+	if errOutside == nil || errDotDot == nil {
+		t.Fatalf("BaseDir 之外的图片应被拒绝: %v / %v", errOutside, errDotDot)
+	}
+	if strings.ReplaceAll(errOutside.Error(), "secret", "missing") != errMissing.Error() {
+		t.Fatalf("越界与不存在的错误应相同: %q / %q", errOutside, errMissing)
+	}
+}
+
+// TestImportGuards 测试网络图片不能指向本机地址，外部编号按墙去重
+// 运行方法: go test -v ./internal/importer/ -run TestImportGuards
+func TestImportGuards(t *testing.T) {
+	dir := t.TempDir()
+	st, err := store.New(filepath.Join(dir, "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+
+	if _, err := NewImporter(st, dir).download("http://127.0.0.1:1/a.png"); err == nil || !strings.Contains(err.Error(), "non-public") {
+		t.Fatalf("应拒绝下载本机地址的图片, 实际 %v", err)
+	}
+
+	input := `{"id": 1, "text": "第一条"}`
+	for _, wallID := range []string{"school", "city", "school"} {
+		im := NewImporter(st.ForWall(wallID), filepath.Join(dir, "uploads"))
+		report, err := im.Import(strings.NewReader(input), "json", Options{Source: "old"})
+		if err != nil || report.Failed != 0 {
+			t.Fatalf("导入 %s 失败: %+v (%v)", wallID, report, err)
+		}
+	}
+	if n, _ := st.CountAll(); n != 2 {
+		t.Fatalf("两个墙应各导入一条, 实际 %d 条", n)
+	}
+}
+
+// TestImportApprovedStatus 测试未发布的 approved/publishing 行默认按 pending 导入，带 TID 的视为已发布，
+// 只有 KeepApproved 时才进入发布队列
+// 运行方法: go test -v ./internal/importer/ -run TestImportApprovedStatus
+func TestImportApprovedStatus(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	im := NewImporter(st, t.TempDir())
+
+	input := `{"id": 1, "text": "a", "status": "approved"}
+{"id": 2, "text": "b", "status": "publishing"}
+{"id": 3, "text": "c", "status": "approved", "tid": "abc"}
+`
+	if _, err := im.Import(strings.NewReader(input), "json", Options{Source: "d"}); err != nil {
+		t.Fatalf("导入失败: %v", err)
+	}
+	if _, err := im.Import(strings.NewReader(input), "json", Options{Source: "k", KeepApproved: true}); err != nil {
+		t.Fatalf("导入失败: %v", err)
+	}
+	want := map[string]model.PostStatus{
+		"d:1": model.StatusPending, "d:2": model.StatusPending, "d:3": model.StatusPublished,
+		"k:1": model.StatusApproved, "k:2": model.StatusApproved, "k:3": model.StatusPublished,
+	}
+	for id, status := range want {
+		if p, _ := st.GetPostByExternalID(id); p == nil || p.Status != status {
+			t.Fatalf("%s 应导入为 %s, 实际 %+v", id, status, p)
+		}
+	}
+}
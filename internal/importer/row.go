@@ -0,0 +1,173 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+// rawRow 一行原始数据，JSON 与 CSV 统一转换为字符串字段后再解析
+type rawRow struct {
+	fields map[string]string
+	images []string
+}
+
+// rawFromJSON 把 JSON 对象的标量字段转为字符串，images 接受字符串数组或以 ; 分隔的字符串
+func rawFromJSON(obj map[string]json.RawMessage) (rawRow, error) {
+	raw := rawRow{fields: map[string]string{}}
+	for k, v := range obj {
+		k = strings.ToLower(k)
+		if k == "images" {
+			var list []string
+			if err := json.Unmarshal(v, &list); err == nil {
+				raw.images = list
+				continue
+			}
+			var s string
+			if err := json.Unmarshal(v, &s); err != nil {
+				return raw, fmt.Errorf("images: expect array of strings")
+			}
+			raw.images = splitImages(s)
+			continue
+		}
+
+		var val interface{}
+		dec := json.NewDecoder(strings.NewReader(string(v)))
+		dec.UseNumber()
+		if err := dec.Decode(&val); err != nil {
+			return raw, fmt.Errorf("%s: %w", k, err)
+		}
+		switch x := val.(type) {
+		case nil:
+		case string:
+			raw.fields[k] = strings.TrimSpace(x)
+		case json.Number:
+			raw.fields[k] = x.String()
+		case bool:
+			raw.fields[k] = strconv.FormatBool(x)
+		default:
+			// 嵌套对象/数组不属于导入格式，忽略
+		}
+	}
+	return raw, nil
+}
+
+// toPost 按导入格式解析为投稿，返回的 ExternalID 尚未加来源前缀。
+// 没有 TID 的 approved/publishing 行默认按 pending 导入，keepApproved 时才进入发布队列
+func (raw rawRow) toPost(keepApproved bool) (*model.Post, error) {
+	f := raw.fields
+	p := &model.Post{
+		Name:   f["name"],
+		Text:   f["text"],
+		Reason: f["reason"],
+		TID:    f["tid"],
+	}
+
+	p.ExternalID = f["external_id"]
+	if p.ExternalID == "" {
+		p.ExternalID = f["id"]
+	}
+	if p.ExternalID == "" {
+		return nil, errors.New("missing external_id or id")
+	}
+	if p.Text == "" && len(raw.images) == 0 {
+		return nil, errors.New("text and images are both empty")
+	}
+
+	var err error
+	if p.UIN, err = parseInt(f["uin"]); err != nil {
+		return nil, fmt.Errorf("uin: %w", err)
+	}
+	if p.GroupID, err = parseInt(f["group_id"]); err != nil {
+		return nil, fmt.Errorf("group_id: %w", err)
+	}
+	if p.Anon, err = parseBool(f["anon"]); err != nil {
+		return nil, fmt.Errorf("anon: %w", err)
+	}
+	if p.Status, err = parseStatus(f["status"]); err != nil {
+		return nil, err
+	}
+	if p.Status == model.StatusApproved {
+		switch {
+		case p.TID != "":
+			// 已有说说 ID 说明原系统已经发出，只是状态没来得及更新
+			p.Status = model.StatusPublished
+		case !keepApproved:
+			p.Status = model.StatusPending
+		}
+	}
+	if p.CreateTime, err = parseTime(f["create_time"]); err != nil {
+		return nil, fmt.Errorf("create_time: %w", err)
+	}
+	if p.CreateTime == 0 {
+		p.CreateTime = time.Now().Unix()
+	}
+	return p, nil
+}
+
+func parseInt(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "", "0", "false", "no", "n", "否":
+		return false, nil
+	case "1", "true", "yes", "y", "是", "匿名":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid bool %q", s)
+}
+
+// parseStatus 空状态视为已发布; publishing 没有对应的 Worker 租约，按 approved 导入
+func parseStatus(s string) (model.PostStatus, error) {
+	st := model.PostStatus(strings.ToLower(s))
+	switch st {
+	case "":
+		return model.StatusPublished, nil
+	case model.StatusPublishing:
+		return model.StatusApproved, nil
+	case model.StatusPending, model.StatusApproved, model.StatusRejected, model.StatusFailed, model.StatusPublished, model.StatusRetracted:
+		return st, nil
+	}
+	return "", fmt.Errorf("unsupported status %q", s)
+}
+
+// timeLayouts 支持的时间格式（无时区时按本地时间）
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006/1/2 15:04",
+	"2006/01/02",
+}
+
+// parseTime 支持 Unix 秒/毫秒时间戳和常见日期格式，空字符串返回 0
+func parseTime(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n > 1e12 {
+			n /= 1000
+		}
+		return n, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t.Unix(), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time %q", s)
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 )
@@ -13,20 +14,33 @@ import (
 type PostStatus string
 
 const (
-	StatusPending   PostStatus = "pending"   // 待审核
-	StatusApproved  PostStatus = "approved"  // 已通过（等待发布）
-	StatusRejected  PostStatus = "rejected"  // 已拒绝
-	StatusFailed    PostStatus = "failed"    // 发布失败
-	StatusPublished PostStatus = "published" // 已发布到QQ空间
+	StatusPending    PostStatus = "pending"    // 待审核
+	StatusApproved   PostStatus = "approved"   // 已通过（等待发布）
+	StatusPublishing PostStatus = "publishing" // 发布中（已被 Worker 领取）
+	StatusRejected   PostStatus = "rejected"   // 已拒绝
+	StatusFailed     PostStatus = "failed"     // 发布失败
+	StatusPublished  PostStatus = "published"  // 已发布到QQ空间
+	StatusDeleted    PostStatus = "deleted"    // 已删除（回收站）
+	StatusRetracted  PostStatus = "retracted"  // 已下架（已从QQ空间删除说说）
+)
+
+var (
+	// ApprovableStatuses 可以过稿的状态
+	ApprovableStatuses = []PostStatus{StatusPending, StatusRejected}
+	// RejectableStatuses 可以拒稿的状态：已通过但还没被 Worker 领取的稿件也可以拒绝
+	RejectableStatuses = []PostStatus{StatusPending, StatusApproved}
 )
 
 // ──────────────────────────────────────────
 // Post 投稿/说说
 // ──────────────────────────────────────────
 
+// DefaultWall 未配置多墙时唯一的表白墙 ID，升级前的稿件也属于它
+const DefaultWall = "default"
+
 type Post struct {
 	ID         int64      `json:"id"`
-	TID        string     `json:"tid,omitempty"`      // QQ空间说说ID（发布后回填）
+	TID        string     `json:"tid,omitempty"`      // QQ空间说说ID（发布后回填），已发布但为空表示QQ空间未返回 TID
 	UIN        int64      `json:"uin"`                // 投稿者QQ
 	Name       string     `json:"name"`               // 投稿者昵称
 	GroupID    int64      `json:"group_id,omitempty"` // 来源群号
@@ -38,6 +52,31 @@ type Post struct {
 	AvatarURL  string     `json:"avatar_url,omitempty"` // 头像URL
 	CreateTime int64      `json:"create_time"`
 	UpdateTime int64      `json:"update_time,omitempty"`
+
+	LeaseOwner  string `json:"lease_owner,omitempty"`  // 发布租约持有者（Worker 标识）
+	LeaseExpire int64  `json:"lease_expire,omitempty"` // 发布租约到期时间
+
+	DeletedFrom PostStatus `json:"deleted_from,omitempty"` // 删除前的状态（恢复时还原）
+	DeletedBy   string     `json:"deleted_by,omitempty"`   // 删除者
+	DeleteTime  int64      `json:"delete_time,omitempty"`  // 删除时间
+
+	ExternalID string `json:"external_id,omitempty"` // 导入来源中的编号，用于重复导入时去重
+	PublishAt  int64  `json:"publish_at,omitempty"`  // 定时发布时间，0 表示通过后立即发布
+
+	Attempts    int   `json:"attempts,omitempty"`     // 已失败的发布轮数（退避重试计数，手动重发时清零）
+	NextAttempt int64 `json:"next_attempt,omitempty"` // 退避结束时间，之前 Worker 不会领取
+
+	WallID string `json:"wall_id,omitempty"` // 所属表白墙，见 DefaultWall
+}
+
+// IsScheduled 是否为尚未到时间的定时发布稿件
+func (p *Post) IsScheduled() bool {
+	return p.Status == StatusApproved && p.PublishAt > time.Now().Unix()
+}
+
+// IsBackingOff 是否为发布失败后正在退避等待重试的稿件
+func (p *Post) IsBackingOff() bool {
+	return p.Status == StatusApproved && p.NextAttempt > time.Now().Unix()
 }
 
 // ShowName 显示名称
@@ -98,6 +137,9 @@ func (p *Post) String() string {
 	if p.Status == StatusPending {
 		fmt.Fprintf(&b, "\n⏳ 待审核")
 	}
+	if p.IsScheduled() {
+		fmt.Fprintf(&b, "\n⏰ 定时发布: %s", time.Unix(p.PublishAt, 0).Format("2006-01-02 15:04"))
+	}
 	if p.Reason != "" {
 		fmt.Fprintf(&b, "\n理由: %s", p.Reason)
 	}
@@ -113,11 +155,258 @@ type Account struct {
 	Username     string `json:"username"`
 	PasswordHash string `json:"-"`
 	Salt         string `json:"-"`
-	Role         string `json:"role"` // "admin" or "user"
+	Role         string `json:"role"`     // 见 Roles
+	Disabled     bool   `json:"disabled"` // 已停用：不能登录，会话和 API 密钥失效
 	CreateTime   int64  `json:"create_time"`
 }
 
+// 网页账号角色。管理员可以管理所有墙，其他角色只能管理 walls[].admins 中包含其用户名的墙
+const (
+	RoleAdmin    = "admin"    // 管理员：全部权限
+	RoleReviewer = "reviewer" // 审核员：审核、发布和删除稿件
+	RoleViewer   = "viewer"   // 只读：查看稿件和操作日志
+)
+
+// Roles 所有角色
+var Roles = []string{RoleAdmin, RoleReviewer, RoleViewer}
+
+// Permission 网页账号的操作权限
+type Permission string
+
+const (
+	PermView     Permission = "view"     // 查看稿件、搜索、导出和操作日志
+	PermReview   Permission = "review"   // 过稿（进入待发布队列）、拒稿、取消定时、修改稿件内容、接口投稿
+	PermPublish  Permission = "publish"  // 立即发布、重发失败稿件、导入投稿
+	PermDelete   Permission = "delete"   // 删除、恢复、彻底删除稿件和从QQ空间下架
+	PermConfig   Permission = "config"   // 系统设置、备份和事件回调
+	PermAccounts Permission = "accounts" // 创建、停用网页账号，修改角色和重置密码
+	PermQzone    Permission = "qzone"    // QQ空间扫码登录和刷新 Cookie
+)
+
+// Permissions 所有权限
+var Permissions = []Permission{PermView, PermReview, PermPublish, PermDelete, PermConfig, PermAccounts, PermQzone}
+
+// rolePermissions 各角色拥有的权限
+var rolePermissions = map[string][]Permission{
+	RoleAdmin:    Permissions,
+	RoleReviewer: {PermView, PermReview, PermPublish, PermDelete},
+	RoleViewer:   {PermView},
+}
+
+// roleNames 角色的中文名称
+var roleNames = map[string]string{
+	RoleAdmin:    "管理员",
+	RoleReviewer: "审核员",
+	RoleViewer:   "只读",
+}
+
+// ValidRole 是否为已知角色
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleName 角色的中文名称
+func RoleName(role string) string {
+	if n, ok := roleNames[role]; ok {
+		return n
+	}
+	return role
+}
+
 // IsAdmin 是否为管理员
 func (a *Account) IsAdmin() bool {
-	return a.Role == "admin"
+	return a.Role == RoleAdmin
+}
+
+// Can 账号是否拥有权限 p，停用的账号没有任何权限
+func (a *Account) Can(p Permission) bool {
+	if a == nil || a.Disabled {
+		return false
+	}
+	return slices.Contains(rolePermissions[a.Role], p)
+}
+
+// API 密钥权限范围
+const (
+	ScopePostsRead   = "posts:read"   // 查看稿件
+	ScopePostsWrite  = "posts:write"  // 投稿、修改和删除稿件
+	ScopePostsReview = "posts:review" // 过稿、拒稿
+	ScopeAuditRead   = "audit:read"   // 查看操作日志
+)
+
+// APIScopes 所有 API 密钥权限范围
+var APIScopes = []string{ScopePostsRead, ScopePostsWrite, ScopePostsReview, ScopeAuditRead}
+
+// APIKey 网页账号的 API 密钥，用于 /api/v1 接口认证。只保存密钥的哈希，明文只在创建时返回一次
+type APIKey struct {
+	ID         int64    `json:"id"`
+	AccountID  int64    `json:"account_id"`
+	Name       string   `json:"name"`   // 用途备注
+	Prefix     string   `json:"prefix"` // 密钥开头几位，用于在列表中辨认
+	Scopes     []string `json:"scopes"`
+	CreateTime int64    `json:"create_time"`
+	LastUsed   int64    `json:"last_used,omitempty"`
+	RevokeTime int64    `json:"revoke_time,omitempty"` // 吊销时间，0 表示有效
+}
+
+// HasScope 密钥是否有该权限范围
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// ──────────────────────────────────────────
+// AuditEvent 操作审计日志
+// ──────────────────────────────────────────
+
+// AuditSource 操作来源
+type AuditSource string
+
+const (
+	SourceWeb    AuditSource = "web"    // Web 后台/投稿页
+	SourceBot    AuditSource = "bot"    // QQ 机器人命令
+	SourceWorker AuditSource = "worker" // 后台发布任务
+	SourceCLI    AuditSource = "cli"    // 命令行子命令
+	SourceAPI    AuditSource = "api"    // /api/v1 接口（API 密钥）
+)
+
+// 审计动作
+const (
+	ActionCreate   = "create"   // 投稿
+	ActionApprove  = "approve"  // 过稿
+	ActionReject   = "reject"   // 拒稿
+	ActionDelete   = "delete"   // 删除/撤稿（移入回收站）
+	ActionRestore  = "restore"  // 从回收站恢复
+	ActionPurge    = "purge"    // 彻底删除
+	ActionClaim    = "claim"    // Worker 领取
+	ActionPublish  = "publish"  // 发布成功
+	ActionFail     = "fail"     // 发布失败
+	ActionRecover  = "recover"  // 租约过期退回
+	ActionConfig   = "config"   // 修改配置
+	ActionPassword = "password" // 修改密码
+	ActionLogin    = "login"    // QQ空间登录/刷新 Cookie
+	ActionBackup   = "backup"   // 手动备份
+	ActionImport   = "import"   // 导入
+	ActionSchedule = "schedule" // 定时过稿
+	ActionCancel   = "cancel"   // 取消定时
+	ActionRetract  = "retract"  // 从QQ空间下架
+	ActionDefer    = "defer"    // 超出发布时段/额度，顺延发布
+	ActionBackoff  = "backoff"  // 发布失败，退避后自动重试
+	ActionRetry    = "retry"    // 手动重发失败稿件
+	ActionEdit     = "edit"     // 修改稿件内容
+	ActionAPIKey   = "apikey"   // 创建/吊销 API 密钥
+	ActionAccount  = "account"  // 创建、停用网页账号，修改角色和重置密码
+)
+
+type AuditEvent struct {
+	ID         int64       `json:"id"`
+	ActorID    int64       `json:"actor_id"`   // Web 账号 ID 或 QQ 号（Worker 为 0）
+	ActorName  string      `json:"actor_name"` // 用户名/昵称/Worker 标识
+	Source     AuditSource `json:"source"`
+	Action     string      `json:"action"`
+	PostID     int64       `json:"post_id,omitempty"`
+	OldStatus  PostStatus  `json:"old_status,omitempty"`
+	NewStatus  PostStatus  `json:"new_status,omitempty"`
+	Reason     string      `json:"reason,omitempty"`
+	WallID     string      `json:"wall_id,omitempty"` // 所属表白墙，与稿件无关的操作（配置、登录等）为空
+	CreateTime int64       `json:"create_time"`
+}
+
+// String 单行描述，用于机器人消息
+func (e *AuditEvent) String() string {
+	t := time.Unix(e.CreateTime, 0).Format("01-02 15:04")
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s [%s] %s", t, e.Source, e.Action)
+	if e.OldStatus != "" || e.NewStatus != "" {
+		fmt.Fprintf(&b, " %s→%s", e.OldStatus, e.NewStatus)
+	}
+	if e.ActorName != "" {
+		fmt.Fprintf(&b, " by %s", e.ActorName)
+	} else if e.ActorID > 0 {
+		fmt.Fprintf(&b, " by %d", e.ActorID)
+	}
+	if e.Reason != "" {
+		fmt.Fprintf(&b, " (%s)", e.Reason)
+	}
+	return b.String()
+}
+
+// ──────────────────────────────────────────
+// Webhook 事件回调
+// ──────────────────────────────────────────
+
+// 回调事件类型
+const (
+	EventPostCreated   = "post.created"   // 新投稿
+	EventPostApproved  = "post.approved"  // 过稿（含定时过稿）
+	EventPostRejected  = "post.rejected"  // 拒稿
+	EventPostPublished = "post.published" // 发布成功
+	EventPostFailed    = "post.failed"    // 发布失败（不再自动重试）
+	EventCookieExpired = "cookie.expired" // QQ空间账号 Cookie 失效
+)
+
+// WebhookEvents 所有回调事件类型
+var WebhookEvents = []string{
+	EventPostCreated, EventPostApproved, EventPostRejected,
+	EventPostPublished, EventPostFailed, EventCookieExpired,
+}
+
+// WebhookSubscription 回调订阅，事件发生时向 URL 发送带签名的 JSON
+type WebhookSubscription struct {
+	ID         int64    `json:"id"`
+	URL        string   `json:"url"`
+	Secret     string   `json:"-"`       // HMAC-SHA256 签名密钥
+	Events     []string `json:"events"`  // 订阅的事件，为空表示全部
+	WallID     string   `json:"wall_id"` // 只接收该墙的事件，为空表示全部
+	Enabled    bool     `json:"enabled"`
+	CreateTime int64    `json:"create_time"`
+}
+
+// 回调投递状态
+const (
+	DeliveryPending   = "pending"   // 等待投递（含退避重试中）
+	DeliveryDelivered = "delivered" // 已投递
+	DeliveryFailed    = "failed"    // 超过最大次数，不再重试
+)
+
+// WebhookDelivery 发件箱中的一次投递，同一事件按订阅各有一条
+type WebhookDelivery struct {
+	ID             int64  `json:"id"`
+	SubscriptionID int64  `json:"subscription_id"`
+	EventID        string `json:"event_id"`
+	Event          string `json:"event"`
+	WallID         string `json:"wall_id"`
+	Payload        string `json:"payload"`
+	Status         string `json:"status"`
+	Attempts       int    `json:"attempts"`
+	NextAttempt    int64  `json:"next_attempt"`
+	ResponseCode   int    `json:"response_code"`
+	LastError      string `json:"last_error,omitempty"`
+	CreateTime     int64  `json:"create_time"`
+	UpdateTime     int64  `json:"update_time"`
+}
+
+// WebhookPayload 回调请求体
+type WebhookPayload struct {
+	ID      string          `json:"id"` // 事件 ID，重试和重新投递时不变，可用于去重
+	Event   string          `json:"event"`
+	WallID  string          `json:"wall_id"`
+	Time    int64           `json:"time"`
+	Post    *Post           `json:"post,omitempty"`    // post.* 事件发生后的稿件
+	Actor   *WebhookActor   `json:"actor,omitempty"`   // post.* 事件的操作者
+	Reason  string          `json:"reason,omitempty"`  // 拒稿理由、失败原因等
+	Account *WebhookAccount `json:"account,omitempty"` // cookie.expired 事件的QQ空间账号
+}
+
+// WebhookActor 触发事件的操作者
+type WebhookActor struct {
+	ID     int64       `json:"id,omitempty"`
+	Name   string      `json:"name,omitempty"`
+	Source AuditSource `json:"source"`
+}
+
+// WebhookAccount cookie.expired 事件中的QQ空间账号
+type WebhookAccount struct {
+	Name string `json:"name"`
+	UIN  int64  `json:"uin"`
 }
@@ -0,0 +1,212 @@
+// Package notify 管理员通知。
+// 新投稿、发布失败、Cookie 失效等提醒按类型路由到 QQ 群、QQ 私聊、HTTP 回调和邮件等渠道，
+// 发送失败时按配置重试，机器人掉线时仍可以通过回调和邮件收到提醒。
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+)
+
+// Alert 提醒类型，用于 notify.routes 路由
+type Alert string
+
+const (
+	AlertNewPost       Alert = "new_post"       // 收到新投稿
+	AlertPublishFailed Alert = "publish_failed" // 稿件发布失败（不再自动重试）
+	AlertCookie        Alert = "cookie"         // QQ空间账号 Cookie 失效或恢复
+)
+
+// Title 提醒类型的中文名称，用于邮件标题
+func (a Alert) Title() string {
+	switch a {
+	case AlertNewPost:
+		return "新投稿"
+	case AlertPublishFailed:
+		return "发布失败"
+	case AlertCookie:
+		return "账号状态"
+	}
+	return string(a)
+}
+
+// Message 一条提醒
+type Message struct {
+	Alert Alert     `json:"alert"`
+	Wall  string    `json:"wall"` // 墙的名称，如 "一中(school1)"
+	Text  string    `json:"text"`
+	Time  time.Time `json:"time"`
+}
+
+// Channel 通知渠道
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// sendTimeout 单次发送的超时时间
+const sendTimeout = 15 * time.Second
+
+// Notifier 按提醒类型把消息发送到配置的渠道，每个墙一个实例
+type Notifier struct {
+	wall       string
+	channels   []Channel
+	routes     map[Alert][]Channel
+	fallback   []Channel // 没有单独配置路由的提醒类型
+	retry      int
+	retryDelay time.Duration
+}
+
+// New 按配置创建通知器。未配置渠道时所有提醒发到墙的管理群 manageGroup；
+// qq_group 渠道的群号为 0 时同样使用 manageGroup，qq_private 未指定QQ号时使用 superUsers。
+// 配置已在 config.Load 中校验，不存在的渠道名称会被忽略。
+func New(cfg config.NotifyConfig, wall string, manageGroup int64, superUsers []int64) *Notifier {
+	n := &Notifier{
+		wall:       wall,
+		routes:     make(map[Alert][]Channel),
+		retry:      max(cfg.Retry, 0),
+		retryDelay: cfg.RetryDelay.Duration,
+	}
+	chCfgs := cfg.Channels
+	if len(chCfgs) == 0 {
+		chCfgs = []config.NotifyChannel{{Name: "manage_group", Type: config.NotifyQQGroup}}
+	}
+	byName := make(map[string]Channel)
+	for _, c := range chCfgs {
+		var ch Channel
+		switch c.Type {
+		case config.NotifyQQGroup:
+			group := c.Group
+			if group == 0 {
+				group = manageGroup
+			}
+			if group <= 0 {
+				continue
+			}
+			ch = &QQGroup{ID: c.Name, Group: group}
+		case config.NotifyQQPrivate:
+			users := c.Users
+			if len(users) == 0 {
+				users = superUsers
+			}
+			ch = &QQPrivate{ID: c.Name, Users: users}
+		case config.NotifyWebhook:
+			ch = NewWebhook(c.Name, c.URL, c.Secret)
+		case config.NotifySMTP:
+			ch = &SMTP{ID: c.Name, Addr: c.Addr, Username: c.Username, Password: c.Password, From: c.From, To: c.To}
+		default:
+			continue
+		}
+		n.channels = append(n.channels, ch)
+		byName[c.Name] = ch
+	}
+
+	n.fallback = n.channels
+	for alert, names := range cfg.Routes {
+		var chs []Channel
+		for _, name := range names {
+			if ch, ok := byName[name]; ok {
+				chs = append(chs, ch)
+			}
+		}
+		if alert == "*" {
+			n.fallback = chs
+			continue
+		}
+		n.routes[Alert(alert)] = chs
+	}
+	return n
+}
+
+// NewWithChannels 使用给定的渠道创建通知器，所有提醒发到全部渠道
+func NewWithChannels(wall string, retry int, retryDelay time.Duration, channels ...Channel) *Notifier {
+	return &Notifier{
+		wall:       wall,
+		channels:   channels,
+		routes:     make(map[Alert][]Channel),
+		fallback:   channels,
+		retry:      retry,
+		retryDelay: retryDelay,
+	}
+}
+
+// Route 把提醒类型路由到指定名称的渠道
+func (n *Notifier) Route(alert Alert, names ...string) {
+	var chs []Channel
+	for _, name := range names {
+		for _, ch := range n.channels {
+			if ch.Name() == name {
+				chs = append(chs, ch)
+			}
+		}
+	}
+	n.routes[alert] = chs
+}
+
+// Channels 提醒类型对应的渠道
+func (n *Notifier) Channels(alert Alert) []Channel {
+	if chs, ok := n.routes[alert]; ok {
+		return chs
+	}
+	return n.fallback
+}
+
+// Send 同步发送提醒到路由的所有渠道，每个渠道失败时按配置重试，返回各渠道最终的错误
+func (n *Notifier) Send(ctx context.Context, alert Alert, text string) error {
+	if n == nil {
+		return nil
+	}
+	msg := Message{Alert: alert, Wall: n.wall, Text: text, Time: time.Now()}
+	var errs []error
+	for _, ch := range n.Channels(alert) {
+		if err := n.sendWithRetry(ctx, ch, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ch.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Notify 异步发送提醒，失败只记录日志；n 为 nil 时不发送
+func (n *Notifier) Notify(alert Alert, text string) {
+	if n == nil {
+		return
+	}
+	go func() {
+		if err := n.Send(context.Background(), alert, text); err != nil {
+			log.Printf("[Notify] 发送提醒 %s 失败: %v", alert, err)
+		}
+	}()
+}
+
+// Func 返回只发送某一类提醒的函数，用于号池等只接受 func(string) 的地方
+func (n *Notifier) Func(alert Alert) func(text string) {
+	return func(text string) {
+		n.Notify(alert, text)
+	}
+}
+
+func (n *Notifier) sendWithRetry(ctx context.Context, ch Channel, msg Message) error {
+	var err error
+	for attempt := 0; attempt <= n.retry; attempt++ {
+		if attempt > 0 {
+			log.Printf("[Notify] 渠道 %s 第 %d 次重试: %v", ch.Name(), attempt, err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(n.retryDelay):
+			}
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		err = ch.Send(sendCtx, msg)
+		cancel()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
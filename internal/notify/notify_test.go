@@ -0,0 +1,206 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+)
+
+// TestWebhookSignatureAndRetry 测试回调带 HMAC 签名，失败时按配置重试
+// 运行方法: go test -v ./internal/notify/ -run TestWebhookSignatureAndRetry
+func TestWebhookSignatureAndRetry(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	var got Message
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !Verify("s3cret", r.Header.Get(HeaderTimestamp), body, r.Header.Get(HeaderSignature)) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.Unmarshal(body, &got)
+	}))
+	defer srv.Close()
+
+	n := NewWithChannels("一中(school)", 2, time.Millisecond, NewWebhook("hook", srv.URL, "s3cret"))
+	if err := n.Send(context.Background(), AlertCookie, "Cookie 已失效"); err != nil {
+		t.Fatalf("重试后应发送成功: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("应在第 2 次发送成功, 实际 %d 次", calls)
+	}
+	if got.Alert != AlertCookie || got.Wall != "一中(school)" || got.Text != "Cookie 已失效" {
+		t.Fatalf("回调内容错误: %+v", got)
+	}
+
+	bad := NewWithChannels("", 1, time.Millisecond, NewWebhook("hook", srv.URL, "wrong"))
+	if err := bad.Send(context.Background(), AlertCookie, "x"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("签名错误时应返回 401, 实际 %v", err)
+	}
+}
+
+// TestSMTP 测试通过本地 SMTP 接收端发送邮件
+// 运行方法: go test -v ./internal/notify/ -run TestSMTP
+func TestSMTP(t *testing.T) {
+	sink := newSMTPSink(t)
+	ch := &SMTP{ID: "mail", Addr: sink.addr, From: "wall@example.com", To: []string{"admin@example.com", "ops@example.com"}}
+	n := NewWithChannels("一中", 0, 0, ch)
+	if err := n.Send(context.Background(), AlertPublishFailed, "稿件 #3 发布失败\n内容被拒"); err != nil {
+		t.Fatalf("发送邮件失败: %v", err)
+	}
+
+	mail := sink.wait(t)
+	if mail.from != "wall@example.com" || len(mail.to) != 2 {
+		t.Fatalf("发件人或收件人错误: %+v", mail)
+	}
+	if !strings.Contains(mail.data, "Subject: =?UTF-8?b?") || !strings.Contains(mail.data, "稿件 #3 发布失败\r\n内容被拒") {
+		t.Fatalf("邮件内容错误:\n%s", mail.data)
+	}
+}
+
+// TestRoutes 测试按提醒类型路由到渠道，"*" 为其他类型的默认渠道，未配置渠道时 QQ 群使用管理群
+// 运行方法: go test -v ./internal/notify/ -run TestRoutes
+func TestRoutes(t *testing.T) {
+	cfg := config.NotifyConfig{
+		Channels: []config.NotifyChannel{
+			{Name: "group", Type: config.NotifyQQGroup},
+			{Name: "su", Type: config.NotifyQQPrivate},
+			{Name: "hook", Type: config.NotifyWebhook, URL: "http://127.0.0.1:1"},
+		},
+		Routes: map[string][]string{
+			"cookie": {"su", "hook"},
+			"*":      {"group"},
+		},
+	}
+	n := New(cfg, "", 900, []int64{10001})
+	if chs := n.Channels(AlertCookie); len(chs) != 2 || chs[0].Name() != "su" || chs[1].Name() != "hook" {
+		t.Fatalf("cookie 应发到 su 和 hook: %v", chs)
+	}
+	chs := n.Channels(AlertNewPost)
+	if len(chs) != 1 || chs[0].(*QQGroup).Group != 900 {
+		t.Fatalf("其他提醒应发到管理群 900: %v", chs)
+	}
+	if su := n.Channels(AlertCookie)[0].(*QQPrivate); len(su.Users) != 1 || su.Users[0] != 10001 {
+		t.Fatalf("未指定QQ号时应发给超级用户: %v", su.Users)
+	}
+
+	if chs := New(config.NotifyConfig{}, "", 0, nil).Channels(AlertNewPost); len(chs) != 0 {
+		t.Fatalf("没有管理群时不应有默认渠道: %v", chs)
+	}
+
+	// 机器人未连接时 QQ 渠道返回错误，不影响其他渠道
+	err := NewWithChannels("", 0, 0, &QQGroup{ID: "group", Group: 900}).Send(context.Background(), AlertNewPost, "x")
+	if !errors.Is(err, ErrBotOffline) {
+		t.Fatalf("机器人未连接时应返回 ErrBotOffline, 实际 %v", err)
+	}
+}
+
+// sinkMail SMTP 接收端收到的一封邮件
+type sinkMail struct {
+	from string
+	to   []string
+	data string
+}
+
+// smtpSink 只实现发送一封邮件所需命令的本地 SMTP 接收端
+type smtpSink struct {
+	addr  string
+	mails chan sinkMail
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	s := &smtpSink{addr: ln.Addr().String(), mails: make(chan sinkMail, 1)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+	reply("220 sink ready")
+	var mail sinkMail
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 sink")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			mail.from = strings.Trim(strings.TrimSpace(line)[len("MAIL FROM:"):], "<>")
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			mail.to = append(mail.to, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 end with .")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				b.WriteString(l)
+			}
+			mail.data = b.String()
+			s.mails <- mail
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *smtpSink) wait(t *testing.T) sinkMail {
+	t.Helper()
+	select {
+	case m := <-s.mails:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatalf("没有收到邮件")
+	}
+	return sinkMail{}
+}
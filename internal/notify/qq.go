@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/message"
+)
+
+// ErrBotOffline 没有已连接的 QQ 机器人
+var ErrBotOffline = errors.New("没有已连接的QQ机器人")
+
+// QQGroup 通过 QQ 机器人发送群消息
+type QQGroup struct {
+	ID    string
+	Group int64
+}
+
+// Name 渠道名称
+func (c *QQGroup) Name() string { return c.ID }
+
+// Send 使用第一个已连接的机器人发送群消息
+func (c *QQGroup) Send(ctx context.Context, msg Message) error {
+	return sendByBot(func(bot *zero.Ctx) int64 {
+		return bot.SendGroupMessage(c.Group, message.Text(msg.Text))
+	}, fmt.Sprintf("群 %d", c.Group))
+}
+
+// QQPrivate 通过 QQ 机器人私聊发送给超级用户
+type QQPrivate struct {
+	ID    string
+	Users []int64
+}
+
+// Name 渠道名称
+func (c *QQPrivate) Name() string { return c.ID }
+
+// Send 逐个私聊发送，任一用户发送失败时返回错误（重试时会再发给所有用户）
+func (c *QQPrivate) Send(ctx context.Context, msg Message) error {
+	for _, uin := range c.Users {
+		err := sendByBot(func(bot *zero.Ctx) int64 {
+			return bot.SendPrivateMessage(uin, message.Text(msg.Text))
+		}, fmt.Sprintf("QQ %d", uin))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendByBot 使用第一个已连接的机器人发送消息，send 返回消息 ID，0 表示发送失败
+func sendByBot(send func(bot *zero.Ctx) int64, target string) error {
+	connected := false
+	var id int64
+	zero.RangeBot(func(_ int64, bot *zero.Ctx) bool {
+		connected = true
+		id = send(bot)
+		return false
+	})
+	if !connected {
+		return ErrBotOffline
+	}
+	if id == 0 {
+		return fmt.Errorf("发送到%s失败", target)
+	}
+	return nil
+}
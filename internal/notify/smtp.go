@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTP 通过邮件发送提醒。服务器支持 STARTTLS 时自动加密，465 端口使用 SMTPS
+type SMTP struct {
+	ID       string
+	Addr     string // host:port
+	Username string // 为空时不认证
+	Password string
+	From     string
+	To       []string
+}
+
+// Name 渠道名称
+func (c *SMTP) Name() string { return c.ID }
+
+// Send 发送一封纯文本邮件，标题为 "[表白墙] <提醒类型>"
+func (c *SMTP) Send(ctx context.Context, msg Message) error {
+	host, port, err := net.SplitHostPort(c.Addr)
+	if err != nil {
+		return fmt.Errorf("smtp addr: %w", err)
+	}
+
+	var conn net.Conn
+	dialer := &net.Dialer{}
+	if port == "465" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", c.Addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", c.Addr)
+	}
+	if err != nil {
+		return fmt.Errorf("smtp dial: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && port != "465" {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+	if c.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.Username, c.Password, host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err := client.Mail(c.From); err != nil {
+		return fmt.Errorf("smtp mail from: %w", err)
+	}
+	for _, to := range c.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("smtp rcpt %s: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := w.Write(c.body(msg)); err != nil {
+		return fmt.Errorf("smtp write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	return client.Quit()
+}
+
+// body 生成邮件内容，标题按 RFC 2047 编码
+func (c *SMTP) body(msg Message) []byte {
+	subject := "[表白墙] " + msg.Alert.Title()
+	if msg.Wall != "" {
+		subject = fmt.Sprintf("[表白墙 %s] %s", msg.Wall, msg.Alert.Title())
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", c.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(c.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", msg.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Text, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
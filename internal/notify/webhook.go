@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 回调请求头
+const (
+	HeaderTimestamp = "X-Wall-Timestamp" // 发送时间（Unix 秒）
+	HeaderSignature = "X-Wall-Signature" // "sha256=" + HMAC-SHA256(secret, timestamp + "." + body) 的十六进制
+)
+
+// Sign 计算回调签名，接收方用同样的方法校验请求体未被篡改
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验回调签名
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Webhook 以 JSON POST 提醒到 HTTP 地址，配置了密钥时带 HMAC 签名
+type Webhook struct {
+	ID     string
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+// NewWebhook 创建 HTTP 回调渠道
+func NewWebhook(name, url, secret string) *Webhook {
+	return &Webhook{ID: name, URL: url, Secret: secret, Client: &http.Client{Timeout: sendTimeout}}
+}
+
+// Name 渠道名称
+func (c *Webhook) Name() string { return c.ID }
+
+// Send 发送 Message 的 JSON，非 2xx 响应视为失败
+func (c *Webhook) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = PostSigned(ctx, c.Client, c.URL, c.Secret, body, nil)
+	return err
+}
+
+// HTTPError 回调地址返回了非 2xx 响应
+type HTTPError struct {
+	Code int
+	Body string // 响应体开头，最多 200 字节
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("http %d: %s", e.Code, e.Body)
+}
+
+// PostSigned 发送带签名的 JSON 请求，header 为额外的请求头。返回响应状态码，非 2xx 响应返回 *HTTPError
+func PostSigned(ctx context.Context, client *http.Client, url, secret string, body []byte, header http.Header) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(HeaderTimestamp, ts)
+	if secret != "" {
+		req.Header.Set(HeaderSignature, Sign(secret, ts, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return resp.StatusCode, &HTTPError{Code: resp.StatusCode, Body: string(bytes.TrimSpace(snippet))}
+	}
+	return resp.StatusCode, nil
+}
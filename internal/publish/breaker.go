@@ -0,0 +1,74 @@
+package publish
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	qzone "github.com/guohuiyuan/qzone-go"
+)
+
+// ErrSessionExpired QQ空间登录态失效且自动刷新失败
+var ErrSessionExpired = errors.New("QQ空间 Cookie 已失效")
+
+// IsSessionError 判断发布错误是否由登录态失效引起（重试无意义，需要重新登录）
+func IsSessionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrSessionExpired) ||
+		errors.Is(err, qzone.ErrLoginExpired) ||
+		errors.Is(err, qzone.ErrInvalidCookie) ||
+		errors.Is(err, qzone.ErrSessionNotReady) {
+		return true
+	}
+	var qe *qzone.Error
+	return errors.As(err, &qe) && qe.Code == qzone.ErrLoginExpired.Code
+}
+
+// Breaker 单个账号的登录态熔断器，由 KeepAlive 和发布流程共享。
+// Cookie 失效时断开，期间号池跳过该账号；Cookie 校验通过后由 KeepAlive 恢复。
+// Trip/Reset 只在状态变化时返回 true，由号池据此通知管理员，保证每次断开和恢复只通知一次。
+type Breaker struct {
+	mu     sync.Mutex
+	open   bool
+	since  time.Time
+	reason string
+}
+
+// Trip 标记登录态失效，已经断开时返回 false
+func (b *Breaker) Trip(reason string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.open {
+		return false
+	}
+	b.open, b.since, b.reason = true, time.Now(), reason
+	return true
+}
+
+// Reset Cookie 校验通过，返回断开的时长；本来就未断开时返回 false
+func (b *Breaker) Reset() (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return 0, false
+	}
+	down := time.Since(b.since).Round(time.Second)
+	b.open, b.since, b.reason = false, time.Time{}, ""
+	return down, true
+}
+
+// Open 是否处于断开状态
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
+
+// State 返回断开状态、断开时间和原因
+func (b *Breaker) State() (open bool, since time.Time, reason string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open, b.since, b.reason
+}
@@ -0,0 +1,71 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	qzone "github.com/guohuiyuan/qzone-go"
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+// TestBreaker 测试熔断期间暂停发布、每次失效只通知一次、恢复后继续
+// 运行方法: go test -v ./internal/publish/ -run TestBreaker
+func TestBreaker(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+
+	pool := NewPool()
+	acc := pool.Add(config.QzoneAccount{Name: "主号"}, newTestClient(t, 10001))
+	svc := NewService(config.WorkerConfig{}, config.WallConfig{}, pool, st, nil, t.TempDir())
+	var msgs []string
+	pool.Notify = func(msg string) { msgs = append(msgs, msg) }
+
+	pool.Trip(acc, "code=-3000")
+	pool.Trip(acc, "code=-3000")
+	if !acc.Health.Open() || len(msgs) != 1 {
+		t.Fatalf("重复断开应只通知一次, 实际 %d 条", len(msgs))
+	}
+
+	var deferred *DeferredError
+	if _, err := svc.PublishText(context.Background(), "hello", nil); !errors.As(err, &deferred) || !deferred.Next.IsZero() {
+		t.Fatalf("熔断期间应返回 DeferredError, 实际 %v", err)
+	}
+
+	pool.Reset(acc)
+	pool.Reset(acc)
+	if acc.Health.Open() || len(msgs) != 2 {
+		t.Fatalf("恢复应只通知一次, 实际 %d 条", len(msgs))
+	}
+	if svc.Pool().Primary() != acc {
+		t.Fatalf("恢复后应继续使用该账号发布")
+	}
+}
+
+// TestIsSessionError 测试登录态失效错误的识别
+// 运行方法: go test -v ./internal/publish/ -run TestIsSessionError
+func TestIsSessionError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{qzone.ErrLoginExpired, true},
+		{fmt.Errorf("publish: %w", qzone.ErrLoginExpired), true},
+		{fmt.Errorf("session refresh failed: %w", fmt.Errorf("scan QR: %w", ErrSessionExpired)), true},
+		{&qzone.Error{Code: -3000, Message: "登录已过期"}, true},
+		{qzone.ErrUploadFailed, false},
+		{errors.New("publish failed: code=-1, msg=系统繁忙"), false},
+		{nil, false},
+	}
+	for _, c := range cases {
+		if got := IsSessionError(c.err); got != c.want {
+			t.Fatalf("IsSessionError(%v) = %v, 期望 %v", c.err, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package publish
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	qzone "github.com/guohuiyuan/qzone-go"
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+)
+
+// BootstrapCookie 启动时尚未获取到 Cookie 的占位值，使用它的账号视为未登录
+const BootstrapCookie = "uin=o1;skey=@bootstrap;p_skey=bootstrap"
+
+// Account 号池中的一个QQ空间账号，拥有独立的客户端、Cookie 来源和熔断器
+type Account struct {
+	Name   string
+	UIN    int64  // 配置的QQ号，0 表示以登录结果为准
+	Source string // Cookie 来源，见 config.CookieSourceBot / config.CookieSourceQR
+	Client *qzone.Client
+	Health *Breaker
+}
+
+// Config 账号配置，用于按账号刷新 Cookie
+func (a *Account) Config() config.QzoneAccount {
+	return config.QzoneAccount{Name: a.Name, UIN: a.UIN, Source: a.Source}
+}
+
+// LoggedIn 是否已登录（不是占位 Cookie，且与配置的QQ号一致）
+func (a *Account) LoggedIn() bool {
+	if a.Client == nil || a.Client.UIN() <= 0 {
+		return false
+	}
+	if strings.Contains(a.Client.Session().Cookie(), "p_skey=bootstrap") {
+		return false
+	}
+	return a.UIN == 0 || a.Client.UIN() == a.UIN
+}
+
+// Available 已登录且登录态未熔断，可以用来发布
+func (a *Account) Available() bool {
+	return a.LoggedIn() && !a.Health.Open()
+}
+
+// CurrentUIN 已登录时返回登录的QQ号，否则返回配置的QQ号
+func (a *Account) CurrentUIN() int64 {
+	if a.LoggedIn() {
+		return a.Client.UIN()
+	}
+	return a.UIN
+}
+
+// String 用于日志和通知，如 "主号(123456)"
+func (a *Account) String() string {
+	if uin := a.CurrentUIN(); uin > 0 {
+		return fmt.Sprintf("%s(%d)", a.Name, uin)
+	}
+	return a.Name
+}
+
+// Pool QQ空间多账号号池。
+// 按配置顺序选择第一个可用账号作为主号发布，主号登录态失效时自动切换到下一个可用的备用号。
+type Pool struct {
+	accounts []*Account
+
+	// Notify 账号失效/恢复时通知管理员，为 nil 时只记录日志
+	Notify func(msg string)
+	// OnTrip 账号登录态失效时调用（同一次失效只调用一次），用于发出 cookie.expired 回调事件
+	OnTrip func(acc *Account, reason string)
+}
+
+// NewPool 创建空号池，通过 Add 按优先级添加账号
+func NewPool() *Pool {
+	return &Pool{}
+}
+
+// Add 添加账号，先添加的优先级更高
+func (p *Pool) Add(cfg config.QzoneAccount, client *qzone.Client) *Account {
+	acc := &Account{
+		Name:   cfg.Name,
+		UIN:    cfg.UIN,
+		Source: cfg.Source,
+		Client: client,
+		Health: &Breaker{},
+	}
+	p.accounts = append(p.accounts, acc)
+	return acc
+}
+
+// Accounts 按优先级返回所有账号
+func (p *Pool) Accounts() []*Account {
+	if p == nil {
+		return nil
+	}
+	return p.accounts
+}
+
+// Get 按名称或QQ号查找账号，key 为空时返回第一个账号；找不到时返回 nil
+func (p *Pool) Get(key string) *Account {
+	accounts := p.Accounts()
+	if len(accounts) == 0 {
+		return nil
+	}
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return accounts[0]
+	}
+	for _, acc := range accounts {
+		if acc.Name == key {
+			return acc
+		}
+	}
+	if uin, err := strconv.ParseInt(key, 10, 64); err == nil {
+		return p.ByUIN(uin)
+	}
+	return nil
+}
+
+// ByUIN 按QQ号查找账号（登录的QQ号或配置的QQ号）
+func (p *Pool) ByUIN(uin int64) *Account {
+	if uin <= 0 {
+		return nil
+	}
+	for _, acc := range p.Accounts() {
+		if acc.CurrentUIN() == uin {
+			return acc
+		}
+	}
+	return nil
+}
+
+// Primary 返回优先级最高的可用账号，全部不可用时返回 nil
+func (p *Pool) Primary() *Account {
+	for _, acc := range p.Accounts() {
+		if acc.Available() {
+			return acc
+		}
+	}
+	return nil
+}
+
+// Trip 标记账号登录态失效。同一次失效只通知一次，通知中说明切换到的备用号或已暂停发布。
+func (p *Pool) Trip(acc *Account, reason string) {
+	if !acc.Health.Trip(reason) {
+		return
+	}
+	if p.OnTrip != nil {
+		p.OnTrip(acc, reason)
+	}
+	next := p.Primary()
+	if next != nil {
+		log.Printf("[Publish] 账号 %s 登录态失效，切换到 %s: %s", acc, next, reason)
+		p.notify(fmt.Sprintf("⚠️ QQ空间账号 %s 的 Cookie 已失效（%s），已切换到 %s 继续发布。\n请使用 /扫码 %s 重新登录",
+			acc, reason, next, acc.Name))
+		return
+	}
+	log.Printf("[Publish] 账号 %s 登录态失效，没有可用账号，暂停发布: %s", acc, reason)
+	p.notify(fmt.Sprintf("⚠️ QQ空间账号 %s 的 Cookie 已失效（%s），没有可用的账号，已暂停发布，已通过的稿件会保留在待发布队列。\n请使用 /扫码 %s 或 /刷新cookie 重新登录，恢复后自动继续发布",
+		acc, reason, acc.Name))
+}
+
+// Reset 账号 Cookie 校验通过，恢复使用；之前所有账号都不可用时通知继续发布
+func (p *Pool) Reset(acc *Account) {
+	paused := p.Primary() == nil
+	down, ok := acc.Health.Reset()
+	if !ok {
+		return
+	}
+	if paused {
+		log.Printf("[Publish] 账号 %s 登录态已恢复，继续发布（暂停了 %v）", acc, down)
+		p.notify(fmt.Sprintf("✅ QQ空间账号 %s 的 Cookie 已恢复，继续发布（暂停了 %v）", acc, down))
+		return
+	}
+	log.Printf("[Publish] 账号 %s 登录态已恢复（失效了 %v）", acc, down)
+	p.notify(fmt.Sprintf("✅ QQ空间账号 %s 的 Cookie 已恢复（失效了 %v）", acc, down))
+}
+
+// Login 用扫码或机器人获取的 Cookie 登录账号并恢复使用。
+// 账号配置了QQ号时，Cookie 必须属于该QQ号，避免把备用号登录成主号。
+func (p *Pool) Login(acc *Account, cookie string) error {
+	sess, err := qzone.NewSession(cookie)
+	if err != nil {
+		return fmt.Errorf("Cookie 无效: %w", err)
+	}
+	if acc.UIN > 0 && sess.UIN() != acc.UIN {
+		return fmt.Errorf("登录的QQ号 %d 与账号 %s 配置的QQ号 %d 不一致", sess.UIN(), acc.Name, acc.UIN)
+	}
+	if err := acc.Client.UpdateCookie(cookie); err != nil {
+		return fmt.Errorf("Cookie 更新失败: %w", err)
+	}
+	p.Reset(acc)
+	return nil
+}
+
+func (p *Pool) notify(msg string) {
+	if p.Notify != nil {
+		p.Notify(msg)
+	}
+}
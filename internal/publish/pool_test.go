@@ -0,0 +1,72 @@
+package publish
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	qzone "github.com/guohuiyuan/qzone-go"
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+)
+
+// newTestClient 创建已登录 uin 的客户端（不发起网络请求）
+func newTestClient(t *testing.T, uin int64) *qzone.Client {
+	t.Helper()
+	client, err := qzone.NewClient(fmt.Sprintf("uin=o%d;skey=@test;p_skey=test", uin))
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	return client
+}
+
+// TestPoolFailover 测试号池按优先级选择主号、失效时切换到备用号、全部失效时暂停发布
+// 运行方法: go test -v ./internal/publish/ -run TestPoolFailover
+func TestPoolFailover(t *testing.T) {
+	bootstrap, err := qzone.NewClient(BootstrapCookie)
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+
+	pool := NewPool()
+	pending := pool.Add(config.QzoneAccount{Name: "未登录"}, bootstrap)
+	wrong := pool.Add(config.QzoneAccount{Name: "错号", UIN: 30003}, newTestClient(t, 40004))
+	primary := pool.Add(config.QzoneAccount{Name: "主号", UIN: 10001}, newTestClient(t, 10001))
+	backup := pool.Add(config.QzoneAccount{Name: "备用"}, newTestClient(t, 20002))
+	var msgs []string
+	pool.Notify = func(msg string) { msgs = append(msgs, msg) }
+
+	if pending.LoggedIn() || wrong.LoggedIn() {
+		t.Fatalf("占位 Cookie 或QQ号与配置不符的账号不应视为已登录")
+	}
+	if got := pool.Primary(); got != primary {
+		t.Fatalf("主号应为第一个可用账号, 实际 %v", got)
+	}
+	if pool.Get("备用") != backup || pool.Get("20002") != backup || pool.Get("") != pending || pool.Get("不存在") != nil {
+		t.Fatalf("按名称/QQ号查找账号错误")
+	}
+
+	pool.Trip(primary, "code=-3000")
+	if got := pool.Primary(); got != backup {
+		t.Fatalf("主号失效后应切换到备用号, 实际 %v", got)
+	}
+	if len(msgs) != 1 || !strings.Contains(msgs[0], "已切换到 备用(20002)") {
+		t.Fatalf("切换通知错误: %v", msgs)
+	}
+
+	pool.Trip(backup, "KeepAlive 校验失败")
+	if pool.Primary() != nil {
+		t.Fatalf("全部失效时不应有可用账号")
+	}
+	if len(msgs) != 2 || !strings.Contains(msgs[1], "已暂停发布") {
+		t.Fatalf("暂停通知错误: %v", msgs)
+	}
+
+	pool.Reset(backup)
+	if pool.Primary() != backup || len(msgs) != 3 || !strings.Contains(msgs[2], "继续发布") {
+		t.Fatalf("备用号恢复后应继续发布: %v", msgs)
+	}
+	pool.Reset(primary)
+	if pool.Primary() != primary || len(msgs) != 4 || strings.Contains(msgs[3], "继续发布") {
+		t.Fatalf("主号恢复后应重新作为主号: %v", msgs)
+	}
+}
@@ -0,0 +1,661 @@
+// Package publish 统一的说说发布服务。
+// Bot 过稿、Web 批量过稿和 Worker 都通过 Service 发布，保证同一次过稿无论从哪里发起行为一致：
+// 渲染卡片、解析图片、发布时段与额度、多账号切换与登录态熔断、频率限制、重试与退避、回填 TID、失败回滚和通知投稿者。
+package publish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	qzone "github.com/guohuiyuan/qzone-go"
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+	"github.com/guohuiyuan/qzonewall-go/internal/render"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/message"
+)
+
+// ErrNothingToPublish 没有可发布的稿件（均已被处理或渲染失败）
+var ErrNothingToPublish = errors.New("没有可发布的稿件")
+
+// ErrNoAccount 号池中没有已登录且登录态有效的账号
+var ErrNoAccount = errors.New("没有可用的QQ空间账号")
+
+// Actor 发起发布的操作者，用于审计日志
+type Actor struct {
+	ID     int64
+	Name   string
+	Source model.AuditSource
+}
+
+// Result 一次发布的结果
+type Result struct {
+	TID       string        // 说说 TID，同一条说说中的稿件共享
+	Account   *Account      // 发布使用的账号
+	Text      string        // 说说正文
+	Cards     [][]byte      // 渲染后的卡片
+	Published []*model.Post // 发布成功的稿件
+	Failed    []*model.Post // 渲染或发布失败、已标记为 failed 的稿件
+	Requeued  []*model.Post // 临时失败或发布被取消、退回 approved 等待自动重试的稿件
+	Deferred  []*model.Post // 超出发布时段或额度、已退回 approved 顺延发布（或按 publish_delay 定时发布）的稿件
+	Err       error         // 发布失败或顺延的原因（顺延时为 *DeferredError）
+}
+
+// Service 发布服务，Bot、Web、Worker 共用同一个实例以共享频率限制。
+type Service struct {
+	cfg       config.WorkerConfig
+	wallCfg   config.WallConfig
+	pool      *Pool
+	store     *store.Store
+	renderer  *render.Renderer
+	uploadDir string
+	summary   *Summary
+	quota     *Quota
+
+	// Notify 通知投稿者，默认通过 QQ 机器人发送；为 nil 时不通知
+	Notify func(post *model.Post, msg string)
+	// Alert 稿件发布失败（不再自动重试）时提醒管理员；为 nil 时只记录日志
+	Alert func(msg string)
+
+	// post 发布一条说说并返回 TID，默认为 publishOnce，测试时替换
+	post func(ctx context.Context, client *qzone.Client, text string, images [][]byte) (string, error)
+
+	mu          sync.Mutex
+	lastPublish time.Time
+}
+
+// NewService 创建发布服务
+func NewService(
+	cfg config.WorkerConfig,
+	wallCfg config.WallConfig,
+	pool *Pool,
+	st *store.Store,
+	renderer *render.Renderer,
+	uploadDir string,
+) *Service {
+	s := &Service{
+		cfg:       cfg,
+		wallCfg:   wallCfg,
+		pool:      pool,
+		store:     st,
+		renderer:  renderer,
+		uploadDir: uploadDir,
+		summary:   NewSummary(cfg.Digest.Template),
+		quota:     NewQuota(cfg.Quota, st),
+		Notify:    NotifyByBot,
+	}
+	s.post = s.publishOnce
+	return s
+}
+
+// LeaseOwner 生成租约持有者标识（主机名+进程号+名称），跨进程唯一。
+func LeaseOwner(name string) string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s/%d/%s", host, os.Getpid(), name)
+}
+
+// Approve 过稿并立即合并发布：原子领取指定的待审核稿件（已处理的跳过），渲染后合并为说说发布。
+// 每条说说最多 max_cards 张卡片，超出时分多条发布，每条说说对应一个 Result。
+// 各条说说依次排队发布，期间为所有领取的稿件持续续约，避免排在后面的稿件租约过期被 Worker 接管。
+// 配置了 wall.publish_delay 时只过稿不发布，返回一个 Deferred 的 Result，由 Worker 到时间后发布。
+func (s *Service) Approve(ctx context.Context, ids []int64, actor Actor) ([]*Result, error) {
+	if at := s.DelayedPublishAt(time.Now()); at > 0 {
+		return s.approveLater(ids, at, actor)
+	}
+	owner := LeaseOwner(fmt.Sprintf("%s-%d", actor.Source, time.Now().UnixNano()))
+	posts, err := s.store.ClaimPendingPosts(ids, owner, s.cfg.LeaseTimeout.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("领取稿件失败: %w", err)
+	}
+	if len(posts) == 0 {
+		return nil, ErrNothingToPublish
+	}
+	for _, p := range posts {
+		s.audit(actor, model.ActionApprove, p.ID, model.StatusPending, model.StatusPublishing, "")
+	}
+
+	claimed := make([]int64, len(posts))
+	for i, p := range posts {
+		claimed[i] = p.ID
+	}
+	leaseCtx, stop := s.holdLeases(ctx, posts, owner)
+	var results []*Result
+	for len(posts) > 0 {
+		n := min(len(posts), s.cfg.Digest.MaxCards)
+		results = append(results, s.Publish(leaseCtx, posts[:n], owner, actor))
+		posts = posts[n:]
+	}
+	stop()
+	// 租约失效后未发布的稿件仍由 owner 持有，释放后交给 Worker 重新领取
+	if err := s.store.ReleaseLeases(claimed, owner); err != nil {
+		log.Printf("[Publish] 释放稿件租约失败: %v", err)
+	}
+	return results, nil
+}
+
+// DelayedPublishAt 过稿且未指定发布时间时的定时发布时间：按 wall.publish_delay 延迟，0 表示立即发布。
+// Bot、网页和 API 的过稿共用，保证同一次过稿无论从哪里发起行为一致
+func (s *Service) DelayedPublishAt(now time.Time) int64 {
+	if delay := s.wallCfg.PublishDelay.Duration; delay > 0 {
+		return now.Add(delay).Unix()
+	}
+	return 0
+}
+
+// approveLater 将待审核的稿件过稿并定时在 at 发布，已处理的稿件跳过
+func (s *Service) approveLater(ids []int64, at int64, actor Actor) ([]*Result, error) {
+	next := time.Unix(at, 0)
+	note := "定时 " + next.Format("2006-01-02 15:04")
+	var posts []*model.Post
+	for _, id := range ids {
+		err := s.store.SetPostStatus(id, model.StatusApproved, "", at, model.StatusPending)
+		if errors.Is(err, store.ErrStatusConflict) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("过稿失败: %w", err)
+		}
+		s.audit(actor, model.ActionSchedule, id, model.StatusPending, model.StatusApproved, note)
+		if p, err := s.store.GetPost(id); err == nil && p != nil {
+			posts = append(posts, p)
+		}
+	}
+	if len(posts) == 0 {
+		return nil, ErrNothingToPublish
+	}
+	return []*Result{{
+		Deferred: posts,
+		Err:      &DeferredError{Next: next, Reason: "已设置发布延迟"},
+	}}, nil
+}
+
+// Publish 发布已被 owner 领取（publishing）的稿件，多条稿件合并为一条说说。
+// 渲染失败（字体缺失等环境问题）的稿件单独按临时错误处理，不影响其他稿件；发布最终失败时，临时错误按指数退避重新排队，
+// 永久错误或超过 max_attempts 的稿件标记为 failed，原因记录在 Result.Err。
+// 超出发布时段、额度或所有账号登录态失效时稿件退回 approved，由 Worker 在可发布时重新领取。
+// 调用方取消 ctx 时释放租约把稿件退回 approved，不计入发布次数。
+// 成功时回填共享 TID 并通知投稿者。
+func (s *Service) Publish(ctx context.Context, posts []*model.Post, owner string, actor Actor) *Result {
+	res := &Result{}
+
+	// 先逐条渲染，渲染失败的稿件不影响其他稿件。渲染失败来自运行环境而不是稿件内容，按退避重试
+	var ready []*model.Post
+	var renderErr error
+	for _, p := range posts {
+		card, err := s.RenderCard(p)
+		if err != nil {
+			log.Printf("[Publish] 稿件 #%d 渲染失败: %v", p.ID, err)
+			renderErr = err
+			if s.fail(owner, actor, p, err) {
+				res.Failed = append(res.Failed, p)
+			} else {
+				res.Requeued = append(res.Requeued, p)
+			}
+			continue
+		}
+		res.Cards = append(res.Cards, card)
+		ready = append(ready, p)
+	}
+	if len(ready) == 0 {
+		res.Err = fmt.Errorf("%w: %v", ErrNothingToPublish, renderErr)
+		return res
+	}
+
+	// 排队等待发布、频率限制和重试可能超过租约时长，期间持续续约；租约被回收时放弃发布，
+	// 稿件已由其他 Worker 接管
+	leaseCtx, stop := s.holdLeases(ctx, ready, owner)
+	text, err := s.Text(ready, time.Now())
+	if err == nil {
+		res.Text = text
+		res.TID, res.Account, err = s.publishText(leaseCtx, text, res.Cards, ready, owner)
+	}
+	stop()
+	if err != nil && (errors.Is(err, store.ErrLeaseLost) || errors.Is(context.Cause(leaseCtx), store.ErrLeaseLost)) {
+		log.Printf("[Publish] 稿件租约已失效，放弃发布: %v", err)
+		res.Err = store.ErrLeaseLost
+		return res
+	}
+	if err != nil && ctx.Err() != nil {
+		// 调用方取消（客户端断开、进程退出）不是一次失败的发布：释放租约交给 Worker 重新领取
+		s.releasePosts(owner, actor, ready, fmt.Sprintf("发布被取消: %v", context.Cause(ctx)))
+		res.Requeued = ready
+		res.Err = err
+		return res
+	}
+	var deferred *DeferredError
+	if errors.As(err, &deferred) {
+		s.deferPosts(owner, actor, ready, deferred)
+		res.Deferred = ready
+		res.Err = err
+		return res
+	}
+	if err != nil {
+		for _, p := range ready {
+			if s.fail(owner, actor, p, err) {
+				res.Failed = append(res.Failed, p)
+			} else {
+				res.Requeued = append(res.Requeued, p)
+			}
+		}
+		res.Err = err
+		return res
+	}
+	tid := res.TID
+	tidNote := "tid=" + tid
+	if tid == "" {
+		tidNote = "tid 未知"
+	}
+
+	reason := tidNote
+	if len(ready) > 1 {
+		reason = fmt.Sprintf("%s 合集 %d 条", tidNote, len(ready))
+	}
+	if len(s.pool.Accounts()) > 1 {
+		reason += " 账号 " + res.Account.String()
+	}
+	for _, p := range ready {
+		note := reason
+		err := s.store.CompletePublish(p.ID, owner, tid)
+		if errors.Is(err, store.ErrLeaseLost) {
+			// 说说已经发到QQ空间但租约被回收：仍然回填 TID，避免稿件再被领取，并提醒管理员检查是否重复发布
+			err = s.store.ForcePublished(p.ID, tid)
+			note += "（发布期间租约已失效）"
+			msg := fmt.Sprintf("⚠️ 稿件 #%d 已发布（%s），但发布期间租约已失效，可能被重复发布，请检查QQ空间", p.ID, tidNote)
+			log.Printf("[Publish] %s", msg)
+			if s.Alert != nil {
+				s.Alert(msg)
+			}
+		}
+		if err != nil {
+			log.Printf("[Publish] 稿件 #%d 回填 TID 失败: %v", p.ID, err)
+			continue
+		}
+		p.Status = model.StatusPublished
+		p.TID = tid
+		s.audit(actor, model.ActionPublish, p.ID, model.StatusPublishing, model.StatusPublished, note)
+		res.Published = append(res.Published, p)
+		log.Printf("[Publish] 稿件 #%d 发布成功, %s", p.ID, tidNote)
+	}
+	s.notifyPublished(res.Published)
+	return res
+}
+
+// PublishText 按发布时段、额度、频率限制和重试次数发布一条说说，返回 TID。
+// 超出发布时段、额度或所有账号登录态失效时返回 *DeferredError。
+func (s *Service) PublishText(ctx context.Context, text string, images [][]byte) (string, error) {
+	tid, _, err := s.publishText(ctx, text, images, nil, "")
+	return tid, err
+}
+
+// publishText 使用号池中的主号发布说说并按账号记录发布历史，posts 为说说中由 owner 领取的稿件。
+// 每次发往QQ空间前同步续约，租约已被回收时返回 store.ErrLeaseLost。
+// 主号登录态失效时断开它的熔断器并换用下一个可用账号，不计入重试次数。
+func (s *Service) publishText(ctx context.Context, text string, images [][]byte, posts []*model.Post, owner string) (string, *Account, error) {
+	// 额度检查、频率限制与发布串行进行，多个入口同时发布时依次排队。
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, err := s.quota.Status(time.Now())
+	if err != nil {
+		return "", nil, err
+	}
+	if !st.Allowed() {
+		return "", nil, &DeferredError{Next: st.Next, Reason: st.Reason}
+	}
+	acc := s.pool.Primary()
+	if acc == nil {
+		return "", nil, &DeferredError{Reason: ErrNoAccount.Error()}
+	}
+	if !s.lastPublish.IsZero() {
+		if wait := s.cfg.RateLimit.Duration - time.Since(s.lastPublish); wait > 0 {
+			log.Printf("[Publish] 频率限制，等待 %v", wait)
+			if err := sleep(ctx, wait); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+
+	var lastErr error
+	for retry := 0; retry <= s.cfg.RetryCount; retry++ {
+		if ctx.Err() != nil {
+			return "", nil, context.Cause(ctx)
+		}
+		if retry > 0 {
+			log.Printf("[Publish] 重试第 %d 次...", retry)
+			if err := sleep(ctx, s.cfg.RetryDelay.Duration); err != nil {
+				return "", nil, err
+			}
+		}
+		if err := s.renewLeases(posts, owner); err != nil {
+			return "", nil, err
+		}
+		tid, err := s.post(ctx, acc.Client, text, images)
+		if err == nil {
+			s.lastPublish = time.Now()
+			if err := s.store.AddPublishRecord(tid, acc.Client.UIN(), len(posts)); err != nil {
+				log.Printf("[Publish] 记录发布历史失败: %v", err)
+			}
+			return tid, acc, nil
+		}
+		if IsSessionError(err) {
+			// 登录态失效时重试没有意义，断开该账号的熔断器并切换到备用号；
+			// 没有可用账号时稿件等待 Cookie 恢复后再发布
+			s.pool.Trip(acc, err.Error())
+			if acc = s.pool.Primary(); acc == nil {
+				return "", nil, &DeferredError{Reason: ErrNoAccount.Error()}
+			}
+			retry--
+			continue
+		}
+		lastErr = err
+		log.Printf("[Publish] 账号 %s 发布失败: %v", acc, err)
+		if IsPermanent(err) {
+			break
+		}
+	}
+	return "", nil, lastErr
+}
+
+// publishOnce 发布到 QQ 空间，返回说说 TID。
+func (s *Service) publishOnce(ctx context.Context, client *qzone.Client, text string, images [][]byte) (string, error) {
+	if client == nil {
+		return "", fmt.Errorf("publish: qzone client not ready")
+	}
+	var opt *qzone.PublishOption
+	if len(images) > 0 {
+		opt = &qzone.PublishOption{ImageBytes: images}
+	}
+	resp, err := client.Publish(ctx, text, opt)
+	if err != nil {
+		return "", fmt.Errorf("publish: %w", err)
+	}
+	if !resp.OK {
+		if resp.Code == qzone.ErrLoginExpired.Code {
+			return "", fmt.Errorf("publish failed: %w", ErrSessionExpired)
+		}
+		return "", &RejectedError{Code: resp.Code, Message: resp.Message}
+	}
+
+	// 回填 TID。
+	if tid := resp.GetString("tid"); tid != "" {
+		return tid, nil
+	}
+	if tid := resp.GetString("t1_tid"); tid != "" {
+		return tid, nil
+	}
+	// 接口未返回 TID：说说已发布但 TID 未知，留空，只能到QQ空间手动下架
+	return "", nil
+}
+
+// Text 生成说说正文：单条稿件直接使用原文，多条稿件使用合集模板。
+func (s *Service) Text(posts []*model.Post, now time.Time) (string, error) {
+	if len(posts) == 1 {
+		post := posts[0]
+		if s.wallCfg.ShowAuthor && !post.Anon {
+			return fmt.Sprintf("【来自 %s 的投稿】\n\n%s", post.ShowName(), post.Text), nil
+		}
+		return post.Text, nil
+	}
+	return s.summary.Execute(posts, now)
+}
+
+// RenderCard 渲染稿件卡片。只发布渲染后的截图，不直接发原图。
+func (s *Service) RenderCard(post *model.Post) ([]byte, error) {
+	if s.renderer == nil || !s.renderer.Available() {
+		return nil, fmt.Errorf("publish: renderer not available")
+	}
+	card, err := s.renderer.RenderPost(s.ResolvePostImages(post))
+	if err != nil {
+		return nil, fmt.Errorf("publish: render screenshot: %w", err)
+	}
+	return card, nil
+}
+
+// ResolvePostImages 克隆 Post 并把图片解析为渲染器可读取的地址（仅用于渲染，不保存回数据库）
+func (s *Service) ResolvePostImages(p *model.Post) *model.Post {
+	clone := *p
+	clone.Images = make([]string, len(p.Images))
+	for i, img := range p.Images {
+		clone.Images[i] = s.ResolveImage(img)
+	}
+	return &clone
+}
+
+// ResolveImage 解析单张图片：
+// Web 上传的 "/uploads/xxx.jpg" 转为本地绝对路径，http 链接原样返回，其余视为 QQ 图片 file ID 交给机器人解析。
+func (s *Service) ResolveImage(img string) string {
+	if strings.HasPrefix(img, "/uploads/") {
+		dir, err := filepath.Abs(s.uploadDir)
+		if err != nil {
+			dir = s.uploadDir
+		}
+		return filepath.Join(dir, path.Base(img))
+	}
+	return ResolveImageURL(img)
+}
+
+// ResolveImageURL 如果是 http 链接直接返回，如果是 file ID 则调用 Bot 解析
+func ResolveImageURL(img string) string {
+	if strings.HasPrefix(img, "http") {
+		return img
+	}
+	var resolved string
+	zero.RangeBot(func(id int64, ctx *zero.Ctx) bool {
+		resolved = ctx.GetImage(img).Get("url").String()
+		return true
+	})
+	if resolved != "" {
+		return resolved
+	}
+	return img
+}
+
+// Pool 发布使用的QQ空间号池，KeepAlive 和扫码登录据此更新各账号的登录态
+func (s *Service) Pool() *Pool {
+	return s.pool
+}
+
+// QuotaStatus 查询当前发布额度和下一次允许发布的时间
+func (s *Service) QuotaStatus(now time.Time) (*QuotaStatus, error) {
+	return s.quota.Status(now)
+}
+
+// deferPosts 超出发布时段或额度，释放租约把稿件退回 approved 等待下一个可发布时间
+func (s *Service) deferPosts(owner string, actor Actor, posts []*model.Post, cause *DeferredError) {
+	if s.releasePosts(owner, actor, posts, cause.Error()) {
+		log.Printf("[Publish] %d 条稿件顺延发布: %v", len(posts), cause)
+	}
+}
+
+// releasePosts 释放租约把稿件退回 approved，不计入发布次数
+func (s *Service) releasePosts(owner string, actor Actor, posts []*model.Post, reason string) bool {
+	ids := make([]int64, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	if err := s.store.ReleaseLeases(ids, owner); err != nil {
+		log.Printf("[Publish] 释放稿件租约失败: %v", err)
+		return false
+	}
+	for _, p := range posts {
+		p.Status = model.StatusApproved
+		s.audit(actor, model.ActionDefer, p.ID, model.StatusPublishing, model.StatusApproved, reason)
+	}
+	return true
+}
+
+// fail 处理发布失败的稿件（释放租约），稿件不会停留在 publishing/published：
+// 临时错误且未超过 max_attempts 时退回 approved 等待退避后重试，返回 false；
+// 否则标记为 failed，返回 true。
+func (s *Service) fail(owner string, actor Actor, p *model.Post, cause error) bool {
+	attempts := p.Attempts + 1
+	if !IsPermanent(cause) && attempts < s.cfg.Backoff.MaxAttempts {
+		delay := s.backoff(attempts)
+		next := time.Now().Add(delay)
+		reason := fmt.Sprintf("第 %d 次发布失败，%v 后重试: %v", attempts, delay, cause)
+		if err := s.store.RetryLater(p.ID, owner, reason, next.Unix()); err != nil {
+			log.Printf("[Publish] 稿件 #%d 更新状态失败: %v", p.ID, err)
+			return false
+		}
+		p.Status = model.StatusApproved
+		p.Reason = reason
+		p.Attempts = attempts
+		p.NextAttempt = next.Unix()
+		s.audit(actor, model.ActionBackoff, p.ID, model.StatusPublishing, model.StatusApproved, reason)
+		return false
+	}
+
+	reason := fmt.Sprintf("发布失败: %v", cause)
+	if IsPermanent(cause) {
+		reason += "（不再自动重试）"
+	} else {
+		reason += fmt.Sprintf("（已尝试 %d 次）", attempts)
+	}
+	if err := s.store.FailPublish(p.ID, owner, reason); err != nil {
+		log.Printf("[Publish] 稿件 #%d 更新状态失败: %v", p.ID, err)
+		return true
+	}
+	p.Status = model.StatusFailed
+	p.Reason = reason
+	p.Attempts = attempts
+	s.audit(actor, model.ActionFail, p.ID, model.StatusPublishing, model.StatusFailed, reason)
+	if s.Alert != nil {
+		s.Alert(fmt.Sprintf("❌ 稿件 #%d %s\n可使用 /重发 %d 重新发布", p.ID, reason, p.ID))
+	}
+	return true
+}
+
+// holdLeases 发布期间定期为 posts 续约。返回的 ctx 在租约被回收时以 store.ErrLeaseLost 取消，
+// 发布结束后调用 stop 停止续约
+func (s *Service) holdLeases(ctx context.Context, posts []*model.Post, owner string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	lease := s.cfg.LeaseTimeout.Duration
+	if lease <= 0 {
+		return ctx, func() { cancel(nil) }
+	}
+	ids := make([]int64, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(lease / 3)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			n, err := s.store.RenewLeases(ids, owner, lease)
+			if err != nil {
+				log.Printf("[Publish] 续约失败: %v", err)
+				continue
+			}
+			if n < len(ids) {
+				cancel(store.ErrLeaseLost)
+				return
+			}
+		}
+	}()
+	return ctx, func() {
+		close(done)
+		cancel(nil)
+	}
+}
+
+// renewLeases 同步为 posts 续约，任一租约已被回收时返回 store.ErrLeaseLost
+func (s *Service) renewLeases(posts []*model.Post, owner string) error {
+	lease := s.cfg.LeaseTimeout.Duration
+	if len(posts) == 0 || lease <= 0 {
+		return nil
+	}
+	ids := make([]int64, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	n, err := s.store.RenewLeases(ids, owner, lease)
+	if err != nil {
+		return fmt.Errorf("续约失败: %w", err)
+	}
+	if n < len(ids) {
+		return store.ErrLeaseLost
+	}
+	return nil
+}
+
+// notifyPublished 异步通知投稿者，间隔发送避免触发风控
+func (s *Service) notifyPublished(posts []*model.Post) {
+	if s.Notify == nil {
+		return
+	}
+	var targets []*model.Post
+	for _, p := range posts {
+		if p.UIN > 0 {
+			targets = append(targets, p)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+	go func() {
+		for i, p := range targets {
+			if i > 0 {
+				time.Sleep(500 * time.Millisecond)
+			}
+			s.Notify(p, fmt.Sprintf("🎉 您的投稿 #%d 已发布！", p.ID))
+		}
+	}()
+}
+
+// NotifyByBot 通过 QQ 机器人通知投稿者：群投稿发到来源群，否则私聊
+func NotifyByBot(post *model.Post, msg string) {
+	zero.RangeBot(func(id int64, ctx *zero.Ctx) bool {
+		if post.GroupID > 0 {
+			ctx.SendGroupMessage(post.GroupID, message.Text(msg))
+		} else {
+			ctx.SendPrivateMessage(post.UIN, message.Text(msg))
+		}
+		return false
+	})
+}
+
+// audit 记录发布相关的状态变更
+func (s *Service) audit(actor Actor, action string, postID int64, oldStatus, newStatus model.PostStatus, reason string) {
+	err := s.store.AddAuditEvent(&model.AuditEvent{
+		ActorID:   actor.ID,
+		ActorName: actor.Name,
+		Source:    actor.Source,
+		Action:    action,
+		PostID:    postID,
+		OldStatus: oldStatus,
+		NewStatus: newStatus,
+		Reason:    reason,
+	})
+	if err != nil {
+		log.Printf("[Publish] 写入审计日志失败: %v", err)
+	}
+}
+
+// sleep 可被 ctx 取消的等待
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
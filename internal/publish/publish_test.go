@@ -0,0 +1,227 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	qzone "github.com/guohuiyuan/qzone-go"
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+	"github.com/guohuiyuan/qzonewall-go/internal/render"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+// TestApproveRollback 测试过稿后发布失败时稿件标记为 failed（不会停留在 published），已处理的稿件被跳过
+// 运行方法: go test -v ./internal/publish/ -run TestApproveRollback
+func TestApproveRollback(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+
+	pending := &model.Post{Text: "待审核", Status: model.StatusPending}
+	rejected := &model.Post{Text: "已拒绝", Status: model.StatusRejected}
+	_ = st.SavePost(pending)
+	_ = st.SavePost(rejected)
+
+	// 没有渲染器和 QQ 空间客户端，发布必然失败
+	cfg := config.WorkerConfig{Digest: config.DigestConfig{MaxCards: 9}}
+	svc := NewService(cfg, config.WallConfig{}, nil, st, nil, t.TempDir())
+	svc.Notify = func(*model.Post, string) { t.Errorf("发布失败不应通知投稿者") }
+	var alerts []string
+	svc.Alert = func(msg string) { alerts = append(alerts, msg) }
+
+	actor := Actor{ID: 1, Name: "admin", Source: model.SourceWeb}
+	results, err := svc.Approve(context.Background(), []int64{pending.ID, rejected.ID}, actor)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("应返回 1 个结果, 实际 %d (%v)", len(results), err)
+	}
+	if res := results[0]; res.Err == nil || len(res.Published) != 0 || len(res.Failed) != 1 {
+		t.Fatalf("发布结果不符合预期: %+v", res)
+	}
+
+	got, _ := st.GetPost(pending.ID)
+	if got.Status != model.StatusFailed || got.TID != "" || got.LeaseOwner != "" {
+		t.Fatalf("发布失败后应标记为 failed 并释放租约: %+v", got)
+	}
+	if len(alerts) != 1 || !strings.Contains(alerts[0], fmt.Sprintf("#%d", pending.ID)) {
+		t.Fatalf("发布失败应提醒管理员一次, 实际 %v", alerts)
+	}
+	if got, _ := st.GetPost(rejected.ID); got.Status != model.StatusRejected {
+		t.Fatalf("非待审核稿件不应被处理: %+v", got)
+	}
+
+	events, _ := st.ListPostHistory(pending.ID)
+	if len(events) != 2 || events[0].Action != model.ActionApprove || events[1].Action != model.ActionFail {
+		t.Fatalf("审计日志应为 approve→fail, 实际 %d 条", len(events))
+	}
+
+	if _, err := svc.Approve(context.Background(), []int64{pending.ID}, actor); !errors.Is(err, ErrNothingToPublish) {
+		t.Fatalf("重复过稿应返回 ErrNothingToPublish, 实际 %v", err)
+	}
+}
+
+// TestRenderFailureRetries 测试渲染失败（渲染器不可用）按临时错误退避重试，不直接标记为 failed
+// 运行方法: go test -v ./internal/publish/ -run TestRenderFailureRetries
+func TestRenderFailureRetries(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+
+	pending := &model.Post{Text: "待审核", Status: model.StatusPending}
+	_ = st.SavePost(pending)
+
+	cfg := config.WorkerConfig{
+		Digest:  config.DigestConfig{MaxCards: 9},
+		Backoff: config.BackoffConfig{MaxAttempts: 3, BaseDelay: config.Duration{Duration: time.Minute}, MaxDelay: config.Duration{Duration: time.Hour}},
+	}
+	svc := NewService(cfg, config.WallConfig{}, nil, st, nil, t.TempDir())
+	svc.Alert = func(msg string) { t.Errorf("可重试的渲染失败不应提醒管理员: %s", msg) }
+
+	results, err := svc.Approve(context.Background(), []int64{pending.ID}, Actor{Name: "admin", Source: model.SourceWeb})
+	if err != nil || len(results) != 1 || len(results[0].Requeued) != 1 || len(results[0].Failed) != 0 {
+		t.Fatalf("渲染失败应退回重试, 实际 %+v (%v)", results, err)
+	}
+	got, _ := st.GetPost(pending.ID)
+	if got.Status != model.StatusApproved || got.Attempts != 1 || got.NextAttempt <= time.Now().Unix() || got.LeaseOwner != "" {
+		t.Fatalf("稿件应退回 approved 并等待退避: %+v", got)
+	}
+}
+
+// TestApproveHoldsLeases 测试批量过稿分多条说说依次发布时，前一条发布超过租约时长，
+// 排在后面的稿件租约仍被续约，不会被 Worker 当作过期稿件接管后重复发布
+// 运行方法: go test -v ./internal/publish/ -run TestApproveHoldsLeases
+func TestApproveHoldsLeases(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+
+	var ids []int64
+	for _, text := range []string{"第一条", "第二条"} {
+		p := &model.Post{Text: text, Anon: true, Status: model.StatusPending}
+		_ = st.SavePost(p)
+		ids = append(ids, p.ID)
+	}
+
+	pool := NewPool()
+	pool.Add(config.QzoneAccount{Name: "主号"}, newTestClient(t, 10001))
+	cfg := config.WorkerConfig{
+		Digest:       config.DigestConfig{MaxCards: 1},
+		LeaseTimeout: config.Duration{Duration: time.Second},
+	}
+	svc := NewService(cfg, config.WallConfig{}, pool, st, render.NewRenderer(), t.TempDir())
+	svc.Notify = nil
+	var alerts []string
+	svc.Alert = func(msg string) { alerts = append(alerts, msg) }
+
+	var recovered []int64
+	calls := 0
+	svc.post = func(ctx context.Context, client *qzone.Client, text string, images [][]byte) (string, error) {
+		calls++
+		if calls == 1 {
+			// 第一条说说发布超过租约时长，期间 Worker 回收过期租约
+			time.Sleep(2200 * time.Millisecond)
+			recovered, _ = st.RecoverExpiredLeases()
+		}
+		return fmt.Sprintf("t%d", calls), nil
+	}
+
+	actor := Actor{ID: 1, Name: "admin", Source: model.SourceWeb}
+	results, err := svc.Approve(context.Background(), ids, actor)
+	if err != nil || len(results) != 2 {
+		t.Fatalf("应返回 2 个结果, 实际 %d (%v)", len(results), err)
+	}
+	if len(recovered) != 0 {
+		t.Fatalf("排队中的稿件租约不应过期, 实际被回收 %v", recovered)
+	}
+	if len(alerts) != 0 {
+		t.Fatalf("不应出现租约失效提醒: %v", alerts)
+	}
+	for i, id := range ids {
+		got, _ := st.GetPost(id)
+		if got.Status != model.StatusPublished || got.TID != fmt.Sprintf("t%d", i+1) || got.LeaseOwner != "" {
+			t.Fatalf("稿件应发布一次并释放租约: %+v", got)
+		}
+	}
+}
+
+// TestApproveDelay 测试配置了 publish_delay 时过稿只定时不发布，已处理的稿件被跳过
+// 运行方法: go test -v ./internal/publish/ -run TestApproveDelay
+func TestApproveDelay(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+
+	pending := &model.Post{Text: "待审核", Status: model.StatusPending}
+	rejected := &model.Post{Text: "已拒绝", Status: model.StatusRejected}
+	_ = st.SavePost(pending)
+	_ = st.SavePost(rejected)
+
+	wallCfg := config.WallConfig{PublishDelay: config.Duration{Duration: 30 * time.Minute}}
+	svc := NewService(config.WorkerConfig{Digest: config.DigestConfig{MaxCards: 9}}, wallCfg, nil, st, nil, t.TempDir())
+	svc.post = func(context.Context, *qzone.Client, string, [][]byte) (string, error) {
+		t.Fatalf("延迟发布时过稿不应立即发布")
+		return "", nil
+	}
+
+	before := time.Now()
+	results, err := svc.Approve(context.Background(), []int64{pending.ID, rejected.ID}, Actor{Name: "bot", Source: model.SourceBot})
+	var deferred *DeferredError
+	if err != nil || len(results) != 1 || len(results[0].Deferred) != 1 || !errors.As(results[0].Err, &deferred) {
+		t.Fatalf("应返回 1 条定时稿件, 实际 %+v (%v)", results, err)
+	}
+	got, _ := st.GetPost(pending.ID)
+	if got.Status != model.StatusApproved || got.PublishAt < before.Add(30*time.Minute).Unix() {
+		t.Fatalf("稿件应通过并定时在 30 分钟后发布: %+v", got)
+	}
+	if got, _ := st.GetPost(rejected.ID); got.Status != model.StatusRejected {
+		t.Fatalf("非待审核稿件不应被处理: %+v", got)
+	}
+}
+
+// TestRetractGuards 测试下架前的检查：合集需确认、未发布和没有 TID 的稿件拒绝下架
+func TestRetractGuards(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+
+	a := &model.Post{Text: "合集一", Status: model.StatusPublished, TID: "t1"}
+	b := &model.Post{Text: "合集二", Status: model.StatusPublished, TID: "t1"}
+	noTID := &model.Post{Text: "旧稿", Status: model.StatusPublished}
+	pending := &model.Post{Text: "待审核", Status: model.StatusPending}
+	for _, p := range []*model.Post{a, b, noTID, pending} {
+		_ = st.SavePost(p)
+	}
+
+	svc := NewService(config.WorkerConfig{}, config.WallConfig{}, nil, st, nil, t.TempDir())
+	actor := Actor{Name: "admin", Source: model.SourceWeb}
+	ctx := context.Background()
+
+	r, err := svc.Retract(ctx, a.ID, "", false, actor)
+	if !errors.Is(err, ErrSharedTID) || r == nil || r.IDs() != fmt.Sprintf("#%d #%d", a.ID, b.ID) {
+		t.Fatalf("合集说说应提示确认, 实际 %+v (%v)", r, err)
+	}
+	if got, _ := st.GetPost(b.ID); got.Status != model.StatusPublished {
+		t.Fatalf("未确认时不应下架: %+v", got)
+	}
+	if _, err := svc.Retract(ctx, noTID.ID, "", true, actor); !errors.Is(err, ErrNoTID) {
+		t.Fatalf("没有真实 TID 应拒绝, 实际 %v", err)
+	}
+	if _, err := svc.Retract(ctx, pending.ID, "", true, actor); !errors.Is(err, ErrNotPublished) {
+		t.Fatalf("未发布稿件应拒绝, 实际 %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package publish
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+// DeferredError 当前不在发布时段、额度已用完或登录态失效，稿件应顺延到 Next 再发布
+type DeferredError struct {
+	Next   time.Time // 下次可发布时间，登录态失效时为零值（恢复登录后发布）
+	Reason string
+}
+
+func (e *DeferredError) Error() string {
+	if e.Next.IsZero() {
+		return e.Reason + "，恢复登录后自动发布"
+	}
+	return fmt.Sprintf("%s，将于 %s 自动发布", e.Reason, e.Next.Format("01-02 15:04"))
+}
+
+// window 每天允许发布的时间段 [start, end)，单位为距 0 点的分钟数；end<=start 表示跨零点
+type window struct {
+	start, end int
+}
+
+func (w window) contains(m int) bool {
+	if w.start < w.end {
+		return m >= w.start && m < w.end
+	}
+	return m >= w.start || m < w.end
+}
+
+// Quota 发布时段与每天/每小时说说数量限制
+type Quota struct {
+	cfg     config.QuotaConfig
+	loc     *time.Location
+	windows []window
+	store   *store.Store
+}
+
+// NewQuota 解析发布时段与时区，无效的配置项记录日志后忽略
+func NewQuota(cfg config.QuotaConfig, st *store.Store) *Quota {
+	q := &Quota{cfg: cfg, loc: time.Local, store: st}
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			log.Printf("[Publish] 无效的时区 %q，使用本机时区: %v", cfg.Timezone, err)
+		} else {
+			q.loc = loc
+		}
+	}
+	for _, s := range cfg.Windows {
+		w, err := parseWindow(s)
+		if err != nil {
+			log.Printf("[Publish] 忽略无效的发布时段 %q: %v", s, err)
+			continue
+		}
+		q.windows = append(q.windows, w)
+	}
+	return q
+}
+
+func parseWindow(s string) (window, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return window{}, fmt.Errorf("格式应为 HH:MM-HH:MM")
+	}
+	start, err := time.Parse("15:04", strings.TrimSpace(from))
+	if err != nil {
+		return window{}, err
+	}
+	end, err := time.Parse("15:04", strings.TrimSpace(to))
+	if err != nil {
+		return window{}, err
+	}
+	return window{start: start.Hour()*60 + start.Minute(), end: end.Hour()*60 + end.Minute()}, nil
+}
+
+// QuotaStatus 当前发布额度，用于后台展示和发布前检查
+type QuotaStatus struct {
+	InWindow   bool      `json:"in_window"`
+	Windows    []string  `json:"windows,omitempty"`
+	Timezone   string    `json:"timezone"`
+	DailyUsed  int       `json:"daily_used"`
+	DailyMax   int       `json:"daily_max"`
+	HourlyUsed int       `json:"hourly_used"`
+	HourlyMax  int       `json:"hourly_max"`
+	Next       time.Time `json:"next"` // 下一次允许发布的时间，当前可发布时等于查询时间
+	Reason     string    `json:"reason,omitempty"`
+}
+
+// Allowed 当前是否可以发布
+func (st *QuotaStatus) Allowed() bool {
+	return st.Reason == ""
+}
+
+// Limited 是否配置了发布时段或数量限制
+func (st *QuotaStatus) Limited() bool {
+	return len(st.Windows) > 0 || st.DailyMax > 0 || st.HourlyMax > 0
+}
+
+// DailyLeft 今天剩余可发布条数，-1 表示不限
+func (st *QuotaStatus) DailyLeft() int {
+	return left(st.DailyMax, st.DailyUsed)
+}
+
+// HourlyLeft 本小时剩余可发布条数，-1 表示不限
+func (st *QuotaStatus) HourlyLeft() int {
+	return left(st.HourlyMax, st.HourlyUsed)
+}
+
+func left(max, used int) int {
+	if max <= 0 {
+		return -1
+	}
+	if used >= max {
+		return 0
+	}
+	return max - used
+}
+
+// Status 统计 now 所在天/小时的已发布条数，并计算下一次允许发布的时间
+func (q *Quota) Status(now time.Time) (*QuotaStatus, error) {
+	now = now.In(q.loc)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, q.loc)
+	hour := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 0, 0, q.loc)
+
+	st := &QuotaStatus{
+		Windows:   q.cfg.Windows,
+		Timezone:  q.loc.String(),
+		DailyMax:  q.cfg.DailyMax,
+		HourlyMax: q.cfg.HourlyMax,
+	}
+	var err error
+	if q.cfg.DailyMax > 0 {
+		if st.DailyUsed, err = q.store.CountPublishedSince(day.Unix()); err != nil {
+			return nil, fmt.Errorf("统计今日发布数失败: %w", err)
+		}
+	}
+	if q.cfg.HourlyMax > 0 {
+		if st.HourlyUsed, err = q.store.CountPublishedSince(hour.Unix()); err != nil {
+			return nil, fmt.Errorf("统计本小时发布数失败: %w", err)
+		}
+	}
+
+	st.InWindow = q.inWindow(now)
+	switch {
+	case !st.InWindow:
+		st.Reason = "当前不在发布时段"
+	case st.DailyLeft() == 0:
+		st.Reason = "今日发布额度已用完"
+	case st.HourlyLeft() == 0:
+		st.Reason = "本小时发布额度已用完"
+	}
+	st.Next = q.next(now, day, hour, st)
+	return st, nil
+}
+
+// inWindow 是否在允许发布的时段内，未配置时段时全天可发布
+func (q *Quota) inWindow(t time.Time) bool {
+	if len(q.windows) == 0 {
+		return true
+	}
+	m := t.Hour()*60 + t.Minute()
+	for _, w := range q.windows {
+		if w.contains(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// next 从 now 开始找到第一个同时满足时段和额度的时间点。
+// 只统计了 now 所在天/小时的发布数，之后的天/小时额度视为未使用。
+func (q *Quota) next(now, day, hour time.Time, st *QuotaStatus) time.Time {
+	t := now
+	for i := 0; i < 24*60*2; i++ {
+		switch {
+		case !q.inWindow(t):
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		case st.DailyLeft() == 0 && t.Before(day.AddDate(0, 0, 1)):
+			t = day.AddDate(0, 0, 1)
+		case st.HourlyLeft() == 0 && t.Before(hour.Add(time.Hour)):
+			t = hour.Add(time.Hour)
+		default:
+			return t
+		}
+	}
+	return t
+}
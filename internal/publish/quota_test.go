@@ -0,0 +1,87 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+// TestQuotaWindow 测试发布时段（含跨零点）和下一次可发布时间
+// 运行方法: go test -v ./internal/publish/ -run TestQuotaWindow
+func TestQuotaWindow(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+
+	q := NewQuota(config.QuotaConfig{Windows: []string{"08:00-12:00", "22:00-02:00"}, Timezone: "UTC"}, st)
+	at := func(h, m int) time.Time { return time.Date(2026, 1, 1, h, m, 0, 0, time.UTC) }
+	cases := []struct {
+		now     time.Time
+		allowed bool
+		next    time.Time
+	}{
+		{at(7, 30), false, at(8, 0)},
+		{at(9, 0), true, at(9, 0)},
+		{at(12, 0), false, at(22, 0)},
+		{at(23, 59), true, at(23, 59)},
+		{at(1, 0), true, at(1, 0)},
+		{at(2, 0), false, at(8, 0)},
+	}
+	for _, c := range cases {
+		s, err := q.Status(c.now)
+		if err != nil {
+			t.Fatalf("查询额度失败: %v", err)
+		}
+		if s.Allowed() != c.allowed || !s.Next.Equal(c.next) {
+			t.Fatalf("%s: 期望 allowed=%v next=%s, 实际 allowed=%v next=%s (%s)",
+				c.now.Format("15:04"), c.allowed, c.next.Format("15:04"), s.Allowed(), s.Next.Format("15:04"), s.Reason)
+		}
+	}
+}
+
+// TestQuotaLimit 测试每天/每小时额度用完后顺延到次日 0 点或下一个整点，PublishText 返回 DeferredError
+// 运行方法: go test -v ./internal/publish/ -run TestQuotaLimit
+func TestQuotaLimit(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+
+	if err := st.AddPublishRecord("tid1", 10001, 1); err != nil {
+		t.Fatalf("记录发布历史失败: %v", err)
+	}
+	now := time.Now().UTC()
+
+	daily, err := NewQuota(config.QuotaConfig{Timezone: "UTC", DailyMax: 1}, st).Status(now)
+	if err != nil {
+		t.Fatalf("查询额度失败: %v", err)
+	}
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	if daily.Allowed() || daily.DailyLeft() != 0 || !daily.Next.Equal(tomorrow) {
+		t.Fatalf("每日额度用完应顺延到次日 0 点: %+v", daily)
+	}
+
+	hourly, err := NewQuota(config.QuotaConfig{Timezone: "UTC", DailyMax: 5, HourlyMax: 1}, st).Status(now)
+	if err != nil {
+		t.Fatalf("查询额度失败: %v", err)
+	}
+	nextHour := now.Truncate(time.Hour).Add(time.Hour)
+	if hourly.Allowed() || hourly.DailyLeft() != 4 || !hourly.Next.Equal(nextHour) {
+		t.Fatalf("每小时额度用完应顺延到下一个整点: %+v", hourly)
+	}
+
+	cfg := config.WorkerConfig{Quota: config.QuotaConfig{Timezone: "UTC", DailyMax: 1}}
+	svc := NewService(cfg, config.WallConfig{}, nil, st, nil, t.TempDir())
+	var deferred *DeferredError
+	if _, err := svc.PublishText(context.Background(), "hello", nil); !errors.As(err, &deferred) || !deferred.Next.Equal(tomorrow) {
+		t.Fatalf("额度用完时应返回 DeferredError, 实际 %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+var (
+	// ErrNotPublished 稿件不是已发布状态，无法下架
+	ErrNotPublished = errors.New("只能下架已发布的稿件")
+	// ErrNoTID 稿件没有记录真实的说说 TID（旧版本或接口未返回），只能到QQ空间手动删除
+	ErrNoTID = errors.New("稿件没有记录说说 TID，请到QQ空间手动删除")
+	// ErrSharedTID 说说中还有其他稿件（合集），需要确认后才能一起下架
+	ErrSharedTID = errors.New("该说说包含多条稿件，下架会一起删除")
+)
+
+// Retraction 下架结果
+type Retraction struct {
+	TID   string        // 被删除的说说 TID
+	Posts []*model.Post // 同一条说说中的全部已发布稿件（合集时多于一条）
+}
+
+// IDs 格式化稿件编号列表，如 "#1 #2"
+func (r *Retraction) IDs() string {
+	ids := make([]string, len(r.Posts))
+	for i, p := range r.Posts {
+		ids[i] = fmt.Sprintf("#%d", p.ID)
+	}
+	return strings.Join(ids, " ")
+}
+
+// Retract 按稿件记录的 TID 从QQ空间删除说说，并将说说中的稿件标记为 retracted。
+// 说说中有多条稿件（合集）且 force 为 false 时不删除，返回 ErrSharedTID 和受影响的稿件，由调用方提示确认。
+func (s *Service) Retract(ctx context.Context, id int64, reason string, force bool, actor Actor) (*Retraction, error) {
+	post, err := s.store.GetPost(id)
+	if err != nil || post == nil {
+		return nil, fmt.Errorf("稿件 #%d 不存在", id)
+	}
+	if post.Status != model.StatusPublished {
+		return nil, ErrNotPublished
+	}
+	if post.TID == "" {
+		return nil, ErrNoTID
+	}
+
+	siblings, err := s.store.ListByTID(post.TID)
+	if err != nil {
+		return nil, fmt.Errorf("查询同一说说的稿件失败: %w", err)
+	}
+	r := &Retraction{TID: post.TID}
+	for _, p := range siblings {
+		if p.Status == model.StatusPublished {
+			r.Posts = append(r.Posts, p)
+		}
+	}
+	if len(r.Posts) > 1 && !force {
+		return r, ErrSharedTID
+	}
+
+	acc, err := s.publisherOf(post.TID)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := acc.Client.Delete(ctx, post.TID)
+	if err != nil {
+		return nil, fmt.Errorf("删除说说失败: %w", err)
+	}
+	if !resp.OK {
+		return nil, fmt.Errorf("删除说说失败: code=%d, msg=%s", resp.Code, resp.Message)
+	}
+
+	ids, err := s.store.RetractByTID(post.TID, reason)
+	if err != nil {
+		return nil, fmt.Errorf("说说已删除，但更新稿件状态失败: %w", err)
+	}
+	note := reason
+	if len(ids) > 1 {
+		note = strings.TrimSpace(fmt.Sprintf("%s（合集 tid=%s 共 %d 条）", reason, post.TID, len(ids)))
+	}
+	for _, pid := range ids {
+		s.audit(actor, model.ActionRetract, pid, model.StatusPublished, model.StatusRetracted, note)
+	}
+	log.Printf("[Publish] 说说 tid=%s 已下架, 稿件 %s", post.TID, r.IDs())
+	return r, nil
+}
+
+// publisherOf 找到发布该说说的账号（只有它能删除）。没有发布记录的旧说说使用当前主号。
+func (s *Service) publisherOf(tid string) (*Account, error) {
+	uin, err := s.store.PublishUIN(tid)
+	if err != nil {
+		return nil, fmt.Errorf("查询发布账号失败: %w", err)
+	}
+	if uin <= 0 {
+		if acc := s.pool.Primary(); acc != nil {
+			return acc, nil
+		}
+		return nil, ErrNoAccount
+	}
+	acc := s.pool.ByUIN(uin)
+	if acc == nil {
+		return nil, fmt.Errorf("说说由QQ %d 发布，该账号不在号池中", uin)
+	}
+	if !acc.Available() {
+		return nil, fmt.Errorf("说说由账号 %s 发布，该账号当前未登录或 Cookie 已失效", acc)
+	}
+	return acc, nil
+}
@@ -0,0 +1,96 @@
+package publish
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+// RejectedError QQ空间接口返回的业务错误（请求已送达但发布被拒）
+type RejectedError struct {
+	Code    int
+	Message string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("publish failed: code=%d, msg=%s", e.Code, e.Message)
+}
+
+// PermanentError 重试也不会成功的错误（内容被拒），稿件直接标记为 failed
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Permanent 将错误标记为永久失败
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// rejectKeywords 接口返回这些提示时说明内容本身被拒，重试无意义
+var rejectKeywords = []string{"违规", "违反", "敏感", "不适宜", "不良信息", "审核", "禁止发表", "含有"}
+
+// rateLimitKeywords 频率限制提示，属于临时错误
+var rateLimitKeywords = []string{"频繁", "太快", "稍后", "繁忙"}
+
+// IsPermanent 判断发布错误是否为永久错误。
+// 网络错误、超时、频率限制和未知的接口错误视为临时错误，按退避策略重试（受 max_attempts 限制）；
+// 内容被拒视为永久错误；渲染失败多为字体缺失等环境问题，按临时错误重试。
+func IsPermanent(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pe *PermanentError
+	if errors.As(err, &pe) {
+		return true
+	}
+	var re *RejectedError
+	if !errors.As(err, &re) {
+		return false
+	}
+	for _, kw := range rateLimitKeywords {
+		if strings.Contains(re.Message, kw) {
+			return false
+		}
+	}
+	for _, kw := range rejectKeywords {
+		if strings.Contains(re.Message, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff 第 attempts 次失败后的等待时间：base_delay 每次翻倍，不超过 max_delay
+func (s *Service) backoff(attempts int) time.Duration {
+	d := s.cfg.Backoff.BaseDelay.Duration
+	for i := 1; i < attempts && d < s.cfg.Backoff.MaxDelay.Duration; i++ {
+		d *= 2
+	}
+	return min(d, s.cfg.Backoff.MaxDelay.Duration)
+}
+
+// Retry 手动重发：将发布失败的稿件退回 approved 并清零发布轮数，由 Worker 重新发布。
+// 不是 failed 状态的稿件会被跳过，返回实际重发的稿件 ID。
+func (s *Service) Retry(ids []int64, actor Actor) ([]int64, error) {
+	requeued, err := s.store.RequeueFailed(ids)
+	if err != nil {
+		return nil, fmt.Errorf("重发失败: %w", err)
+	}
+	for _, id := range requeued {
+		s.audit(actor, model.ActionRetry, id, model.StatusFailed, model.StatusApproved, "")
+	}
+	if len(requeued) > 0 {
+		log.Printf("[Publish] %s 重发 %d 条失败稿件: %v", actor.Name, len(requeued), requeued)
+	}
+	return requeued, nil
+}
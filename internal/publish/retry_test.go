@@ -0,0 +1,105 @@
+package publish
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+// TestIsPermanent 测试临时错误（网络、频率限制）与永久错误（内容被拒）的区分
+// 运行方法: go test -v ./internal/publish/ -run TestIsPermanent
+func TestIsPermanent(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("publish: dial tcp: i/o timeout"), false},
+		{&RejectedError{Code: -10000, Message: "操作过于频繁，请稍后再试"}, false},
+		{&RejectedError{Code: -1, Message: "系统繁忙"}, false},
+		{&RejectedError{Code: -4013, Message: "内容含有违规信息"}, true},
+		{fmt.Errorf("wrap: %w", &RejectedError{Code: -4013, Message: "包含敏感词"}), true},
+		{Permanent(errors.New("content rejected")), true},
+		{nil, false},
+	}
+	for _, c := range cases {
+		if got := IsPermanent(c.err); got != c.want {
+			t.Fatalf("IsPermanent(%v) = %v, 期望 %v", c.err, got, c.want)
+		}
+	}
+}
+
+// TestBackoff 测试发布失败后按指数退避重新排队，超过次数或永久错误时标记为 failed，手动重发清零
+// 运行方法: go test -v ./internal/publish/ -run TestBackoff
+func TestBackoff(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+
+	cfg := config.WorkerConfig{Backoff: config.BackoffConfig{
+		MaxAttempts: 3,
+		BaseDelay:   config.Duration{Duration: time.Minute},
+		MaxDelay:    config.Duration{Duration: 3 * time.Minute},
+	}}
+	svc := NewService(cfg, config.WallConfig{}, nil, st, nil, t.TempDir())
+	for i, want := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		if got := svc.backoff(i + 1); got != want {
+			t.Fatalf("第 %d 次退避应为 %v, 实际 %v", i+1, want, got)
+		}
+	}
+
+	actor := Actor{Name: "worker", Source: model.SourceWorker}
+	claim := func(text string) *model.Post {
+		_ = st.SavePost(&model.Post{Text: text, Status: model.StatusApproved})
+		p, err := st.ClaimApprovedPost("w", time.Minute)
+		if err != nil || p == nil || p.Text != text {
+			t.Fatalf("领取稿件失败: %+v (%v)", p, err)
+		}
+		return p
+	}
+
+	// 临时错误：重新排队，退避期间不会被领取
+	a := claim("临时失败")
+	if svc.fail("w", actor, a, errors.New("dial tcp: i/o timeout")) {
+		t.Fatalf("第 1 次临时失败应重新排队")
+	}
+	got, _ := st.GetPost(a.ID)
+	if got.Status != model.StatusApproved || got.Attempts != 1 || !got.IsBackingOff() {
+		t.Fatalf("退避状态错误: %+v", got)
+	}
+
+	// 已失败 2 次，第 3 次达到 max_attempts
+	b := claim("次数用完")
+	b.Attempts = 2
+	if !svc.fail("w", actor, b, errors.New("dial tcp: i/o timeout")) {
+		t.Fatalf("超过 max_attempts 应标记为 failed")
+	}
+	if got, _ := st.GetPost(b.ID); got.Status != model.StatusFailed {
+		t.Fatalf("应为 failed: %+v", got)
+	}
+
+	// 永久错误：第 1 次就标记为 failed
+	c := claim("内容被拒")
+	if !svc.fail("w", actor, c, &RejectedError{Code: -4013, Message: "内容含有违规信息"}) {
+		t.Fatalf("永久错误不应重试")
+	}
+	events, _ := st.ListPostHistory(c.ID)
+	if len(events) != 1 || events[0].Action != model.ActionFail {
+		t.Fatalf("审计日志应为 fail, 实际 %d 条", len(events))
+	}
+
+	ids, err := svc.Retry([]int64{a.ID, b.ID, c.ID}, Actor{Name: "admin", Source: model.SourceWeb})
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("应重发 2 条失败稿件, 实际 %v (%v)", ids, err)
+	}
+	if got, _ := st.GetPost(b.ID); got.Status != model.StatusApproved || got.Attempts != 0 {
+		t.Fatalf("重发后应退回 approved 并清零: %+v", got)
+	}
+}
@@ -0,0 +1,80 @@
+package publish
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+// DefaultSummaryTemplate 多条稿件合并发布时的默认说说正文模板
+const DefaultSummaryTemplate = "【表白墙更新】 {{.Date}}\n" +
+	"----------------\n" +
+	"{{range .Posts}}#{{.ID}}: {{excerpt .}}\n{{end}}" +
+	"----------------\n" +
+	"详情见图 👇"
+
+// SummaryData 合集正文模板可用的字段
+type SummaryData struct {
+	Count int           // 本条说说的稿件数
+	Date  string        // 发布日期 (01/02)
+	Time  string        // 发布时间 (01-02 15:04)
+	IDs   string        // 稿件编号，如 "#1 #2 #3"
+	Posts []*model.Post // 本条说说的稿件（按发布顺序）
+}
+
+// Summary 合集正文模板（text/template），额外提供 excerpt 函数截取稿件前 20 个字
+type Summary struct {
+	tmpl *template.Template
+}
+
+var summaryFuncs = template.FuncMap{"excerpt": excerpt}
+
+// NewSummary 解析合集正文模板，为空或解析失败时使用默认模板
+func NewSummary(text string) *Summary {
+	if text == "" {
+		text = DefaultSummaryTemplate
+	}
+	tmpl, err := template.New("summary").Funcs(summaryFuncs).Parse(text)
+	if err != nil {
+		log.Printf("[Publish] 合集模板解析失败，使用默认模板: %v", err)
+		tmpl = template.Must(template.New("summary").Funcs(summaryFuncs).Parse(DefaultSummaryTemplate))
+	}
+	return &Summary{tmpl: tmpl}
+}
+
+// Execute 生成合集正文
+func (s *Summary) Execute(posts []*model.Post, now time.Time) (string, error) {
+	ids := make([]string, len(posts))
+	for i, p := range posts {
+		ids[i] = fmt.Sprintf("#%d", p.ID)
+	}
+	var buf bytes.Buffer
+	err := s.tmpl.Execute(&buf, SummaryData{
+		Count: len(posts),
+		Date:  now.Format("01/02"),
+		Time:  now.Format("01-02 15:04"),
+		IDs:   strings.Join(ids, " "),
+		Posts: posts,
+	})
+	if err != nil {
+		return "", fmt.Errorf("summary template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// excerpt 稿件摘要：超过 20 个字截断，纯图片稿件显示 [图片]
+func excerpt(p *model.Post) string {
+	content := []rune(p.Text)
+	if len(content) > 20 {
+		return string(content[:20]) + "..."
+	}
+	if p.Text == "" {
+		return "[图片]"
+	}
+	return p.Text
+}
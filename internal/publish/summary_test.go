@@ -0,0 +1,53 @@
+package publish
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+// TestSummary 测试合集正文：默认模板沿用原有的批量过稿格式，也支持自定义模板
+// 运行方法: go test -v ./internal/publish/
+func TestSummary(t *testing.T) {
+	posts := []*model.Post{
+		{ID: 3, Name: "张三", Text: "今天在图书馆看到一个穿白衬衫的男生"},
+		{ID: 7, Anon: true},
+	}
+	now := time.Date(2024, 5, 1, 18, 0, 0, 0, time.Local)
+
+	text, err := NewSummary("").Execute(posts, now)
+	if err != nil {
+		t.Fatalf("生成正文失败: %v", err)
+	}
+	want := "【表白墙更新】 05/01\n----------------\n#3: 今天在图书馆看到一个穿白衬衫的男生\n#7: [图片]\n----------------\n详情见图 👇"
+	if text != want {
+		t.Fatalf("默认模板正文不符合预期:\n%s", text)
+	}
+
+	text, _ = NewSummary("{{.Time}} 共 {{.Count}} 条 {{.IDs}}: {{range .Posts}}{{.ShowName}};{{end}}").Execute(posts, now)
+	if text != "05-01 18:00 共 2 条 #3 #7: 张三;匿名用户;" {
+		t.Fatalf("自定义模板正文不符合预期: %q", text)
+	}
+
+	// 模板有误时退回默认模板
+	if text, _ = NewSummary("{{.Nope").Execute(posts, now); !strings.HasPrefix(text, "【表白墙更新】") {
+		t.Fatalf("无效模板应使用默认模板: %q", text)
+	}
+}
+
+// TestResolveImage 测试 Web 上传的图片解析为本地路径，网络图片原样返回
+func TestResolveImage(t *testing.T) {
+	dir := t.TempDir()
+	svc := NewService(config.WorkerConfig{}, config.WallConfig{}, nil, nil, nil, dir)
+
+	if got := svc.ResolveImage("/uploads/a.jpg"); got != filepath.Join(dir, "a.jpg") {
+		t.Fatalf("上传图片应解析为本地路径, 实际 %s", got)
+	}
+	if got := svc.ResolveImage("https://example.com/b.jpg"); got != "https://example.com/b.jpg" {
+		t.Fatalf("网络图片应原样返回, 实际 %s", got)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	_ "embed"
 	"fmt"
 	"image"
-	"image/color"
 	"image/draw" // 标准库
 	"image/jpeg"
 	"log"
@@ -28,16 +27,18 @@ import (
 var fontData []byte
 
 type Renderer struct {
-	font *truetype.Font
+	font  *truetype.Font
+	theme Theme
 }
 
+// NewRenderer 使用默认主题创建渲染器，其他主题见 WithTheme
 func NewRenderer() *Renderer {
 	f, err := truetype.Parse(fontData)
 	if err != nil {
 		log.Printf("[Renderer] ❌ 严重错误: 内置字体解析失败: %v", err)
-		return &Renderer{font: nil}
+		return &Renderer{font: nil, theme: Themes[DefaultTheme]}
 	}
-	return &Renderer{font: f}
+	return &Renderer{font: f, theme: Themes[DefaultTheme]}
 }
 
 func (r *Renderer) Available() bool {
@@ -157,7 +158,7 @@ func (r *Renderer) RenderPost(post *model.Post) ([]byte, error) {
 
 	// ── 3. 开始绘制 ──
 	dc := gg.NewContext(int(CanvasWidth), totalH)
-	dc.SetHexColor("#F5F5F5")
+	dc.SetHexColor(r.theme.Background)
 	dc.Clear()
 
 	startX := Padding
@@ -173,7 +174,7 @@ func (r *Renderer) RenderPost(post *model.Post) ([]byte, error) {
 		if avatarImg != nil {
 			dc.DrawImageAnchored(avatarImg, int(startX+AvatarSize/2), int(startY+AvatarSize/2), 0.5, 0.5)
 		} else {
-			dc.SetHexColor("#DCDCDC")
+			dc.SetHexColor(r.theme.Avatar)
 			dc.DrawRectangle(startX, startY, AvatarSize, AvatarSize)
 			dc.Fill()
 		}
@@ -184,14 +185,14 @@ func (r *Renderer) RenderPost(post *model.Post) ([]byte, error) {
 
 	// 3.2 绘制昵称
 	dc.SetFontFace(r.getFace(SizeName))
-	dc.SetHexColor("#555555")
+	dc.SetHexColor(r.theme.Name)
 	dc.DrawString(post.ShowName(), contentX, startY+SizeName-5)
 
 	currContentY := contentStartY
 
 	// 3.3 绘制文字气泡
 	if bubbleH > 0 {
-		dc.SetColor(color.White)
+		dc.SetHexColor(r.theme.Bubble)
 		dc.DrawRoundedRectangle(contentX, currContentY, contentMaxW, bubbleH, 16)
 		dc.Fill()
 
@@ -204,7 +205,7 @@ func (r *Renderer) RenderPost(post *model.Post) ([]byte, error) {
 
 		// 文字
 		dc.SetFontFace(textFace)
-		dc.SetHexColor("#000000")
+		dc.SetHexColor(r.theme.Text)
 
 		metrics := textFace.Metrics()
 		ascent := float64(metrics.Ascent.Ceil())
@@ -283,7 +284,7 @@ func (r *Renderer) RenderPost(post *model.Post) ([]byte, error) {
 	// 3.5 水印
 	wmFace := r.getFace(SizeMeta)
 	dc.SetFontFace(wmFace)
-	dc.SetHexColor("#AAAAAA")
+	dc.SetHexColor(r.theme.Watermark)
 	wmText := fmt.Sprintf("#%d  %s", post.ID, time.Now().Format("2006-01-02 15:04"))
 	wmW, _ := dc.MeasureString(wmText)
 	descent := float64(wmFace.Metrics().Descent.Ceil())
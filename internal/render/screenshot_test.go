@@ -1,6 +1,10 @@
 package render
 
 import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"image/jpeg"
 	"os"
 	"testing"
 	"time"
@@ -67,3 +71,48 @@ func TestRenderPost(t *testing.T) {
 	t.Logf("📂 图片已保存为: %s/%s", "internal/render", outputFile)
 	t.Logf("👉 请务必使用「微软雅黑」作为 font.ttf 以支持 Emoji 显示。")
 }
+
+// TestRenderTheme 测试卡片主题：背景色随主题变化，未知主题回退到默认主题
+// 运行方法: go test -v ./internal/render/ -run TestRenderTheme
+func TestRenderTheme(t *testing.T) {
+	r := NewRenderer()
+	if !r.Available() {
+		t.Fatal("❌ 渲染器不可用，请检查 font.ttf 是否正确嵌入")
+	}
+	post := &model.Post{ID: 1, Text: "主题测试", Anon: true, CreateTime: time.Now().Unix()}
+
+	corner := func(r *Renderer) color.RGBA {
+		data, err := r.RenderPost(post)
+		if err != nil {
+			t.Fatalf("❌ 渲染失败: %v", err)
+		}
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("❌ 解码失败: %v", err)
+		}
+		cr, cg, cb, _ := img.At(2, 2).RGBA()
+		return color.RGBA{R: uint8(cr >> 8), G: uint8(cg >> 8), B: uint8(cb >> 8)}
+	}
+	near := func(c color.RGBA, hex string) bool {
+		var want color.RGBA
+		_, _ = fmt.Sscanf(hex, "#%02X%02X%02X", &want.R, &want.G, &want.B)
+		diff := func(a, b uint8) int {
+			if a > b {
+				return int(a - b)
+			}
+			return int(b - a)
+		}
+		// JPEG 有损压缩，允许少量误差
+		return diff(c.R, want.R) <= 4 && diff(c.G, want.G) <= 4 && diff(c.B, want.B) <= 4
+	}
+
+	if c := corner(r); !near(c, Themes[DefaultTheme].Background) {
+		t.Fatalf("默认主题背景色错误: %v", c)
+	}
+	if c := corner(r.WithTheme("dark")); !near(c, Themes["dark"].Background) {
+		t.Fatalf("dark 主题背景色错误: %v", c)
+	}
+	if c := corner(r.WithTheme("不存在")); !near(c, Themes[DefaultTheme].Background) {
+		t.Fatalf("未知主题应回退到默认主题: %v", c)
+	}
+}
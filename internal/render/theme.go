@@ -0,0 +1,79 @@
+package render
+
+import "sort"
+
+// Theme 卡片配色，颜色均为 "#RRGGBB"
+type Theme struct {
+	Background string // 画布背景
+	Avatar     string // 头像加载失败时的占位色
+	Name       string // 昵称
+	Bubble     string // 文字气泡
+	Text       string // 正文
+	Watermark  string // 编号与时间水印
+}
+
+// DefaultTheme 未指定或主题不存在时使用的主题
+const DefaultTheme = "default"
+
+// Themes 内置主题，多墙时每个墙可以在 walls[].theme 中选择
+var Themes = map[string]Theme{
+	DefaultTheme: {
+		Background: "#F5F5F5",
+		Avatar:     "#DCDCDC",
+		Name:       "#555555",
+		Bubble:     "#FFFFFF",
+		Text:       "#000000",
+		Watermark:  "#AAAAAA",
+	},
+	"dark": {
+		Background: "#1E1E1E",
+		Avatar:     "#3A3A3A",
+		Name:       "#BBBBBB",
+		Bubble:     "#2D2D2D",
+		Text:       "#EEEEEE",
+		Watermark:  "#777777",
+	},
+	"pink": {
+		Background: "#FFF0F5",
+		Avatar:     "#F5D0DC",
+		Name:       "#A0526D",
+		Bubble:     "#FFFFFF",
+		Text:       "#3A2A30",
+		Watermark:  "#D8A7B8",
+	},
+	"blue": {
+		Background: "#EAF3FB",
+		Avatar:     "#C9DDF0",
+		Name:       "#3D6A94",
+		Bubble:     "#FFFFFF",
+		Text:       "#1F2D3A",
+		Watermark:  "#9DB8D2",
+	},
+	"green": {
+		Background: "#EEF7EE",
+		Avatar:     "#CFE5CF",
+		Name:       "#4A7A4A",
+		Bubble:     "#FFFFFF",
+		Text:       "#1F2F1F",
+		Watermark:  "#A3C4A3",
+	},
+}
+
+// ThemeNames 按名称排序的内置主题列表
+func ThemeNames() []string {
+	names := make([]string, 0, len(Themes))
+	for name := range Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithTheme 返回使用指定主题的渲染器，与原渲染器共享字体；主题不存在时使用默认主题
+func (r *Renderer) WithTheme(name string) *Renderer {
+	theme, ok := Themes[name]
+	if !ok {
+		theme = Themes[DefaultTheme]
+	}
+	return &Renderer{font: r.font, theme: theme}
+}
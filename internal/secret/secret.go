@@ -0,0 +1,64 @@
+// Package secret 提供基于 AES-256-GCM 的对称加密，用于在数据库中保存敏感数据（如 QQ空间 Cookie）。
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrNoKey 未配置加密密钥
+var ErrNoKey = errors.New("secret key is empty")
+
+// Box 用固定密钥加解密
+type Box struct {
+	aead cipher.AEAD
+}
+
+// NewBox 由任意长度的口令派生 256 位密钥（SHA-256）
+func NewBox(key string) (*Box, error) {
+	if key == "" {
+		return nil, ErrNoKey
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Seal 加密并返回 base64(nonce || ciphertext)
+func (b *Box) Seal(plain []byte) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	out := b.aead.Seal(nonce, nonce, plain, nil)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// Open 解密 Seal 的输出，密钥不匹配或数据被篡改时返回错误
+func (b *Box) Open(sealed string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	n := b.aead.NonceSize()
+	if len(data) < n {
+		return nil, errors.New("ciphertext too short")
+	}
+	plain, err := b.aead.Open(nil, data[:n], data[n:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+	return plain, nil
+}
@@ -0,0 +1,34 @@
+package secret
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestBox 测试加解密往返, 以及密钥不匹配时无法解密
+// 运行方法: go test -v ./internal/secret/
+func TestBox(t *testing.T) {
+	if _, err := NewBox(""); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("空密钥应返回 ErrNoKey, 实际 %v", err)
+	}
+
+	box, _ := NewBox("correct horse")
+	sealed, err := box.Seal([]byte("uin=o123;skey=@abc;p_skey=xyz"))
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	again, _ := box.Seal([]byte("uin=o123;skey=@abc;p_skey=xyz"))
+	if sealed == again {
+		t.Fatal("相同明文两次加密结果不应相同")
+	}
+
+	plain, err := box.Open(sealed)
+	if err != nil || string(plain) != "uin=o123;skey=@abc;p_skey=xyz" {
+		t.Fatalf("解密结果异常: %q (%v)", plain, err)
+	}
+
+	other, _ := NewBox("battery staple")
+	if _, err := other.Open(sealed); err == nil {
+		t.Fatal("错误的密钥不应解密成功")
+	}
+}
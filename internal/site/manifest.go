@@ -0,0 +1,46 @@
+package site
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// siteVersion 页面模板或目录结构变化时加一，旧的构建结果会全部重新生成
+const siteVersion = 1
+
+// manifestFile 输出目录中的构建记录
+const manifestFile = ".wall-site.json"
+
+// manifest 上次构建的记录，用于增量构建
+type manifest struct {
+	Version int                    `json:"version"`
+	Options string                 `json:"options"`
+	Posts   map[int64]manifestPost `json:"posts"`
+}
+
+// manifestPost 已生成的稿件：生成时的更新时间（-1 表示卡片渲染失败，下次重试）和话题
+type manifestPost struct {
+	Updated int64    `json:"updated"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+// loadManifest 读取构建记录，不存在或损坏时返回空记录（即全部重新生成）
+func loadManifest(dir string) *manifest {
+	m := &manifest{}
+	if b, err := os.ReadFile(filepath.Join(dir, manifestFile)); err == nil {
+		_ = json.Unmarshal(b, m)
+	}
+	if m.Posts == nil {
+		m.Posts = make(map[int64]manifestPost)
+	}
+	return m
+}
+
+func (m *manifest) save(dir string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, manifestFile), b)
+}
@@ -0,0 +1,399 @@
+// Package site 把一个墙已发布的稿件生成静态网站：分页首页、稿件页、话题页和卡片图片，
+// 可以直接放到任意静态托管上，不需要运行本程序。
+//
+// 输出目录中的 .wall-site.json 记录上次构建时每条稿件的版本，重复构建时只重新生成
+// 新发布或有修改的稿件，并删除已下架、已删除的稿件页。卡片图片与网页的公开稿件页共用
+// feed.Cards 缓存，匿名稿件的页面和卡片都不包含投稿者的QQ号、昵称和头像。
+package site
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/feed"
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var tmpl = template.Must(template.New("").Funcs(template.FuncMap{"tagHref": tagHref}).ParseFS(templateFS, "templates/*.html"))
+
+// Options 构建选项
+type Options struct {
+	Dir      string // 输出目录
+	Title    string // 网站标题，通常为墙的名称
+	BaseURL  string // 网站的绝对地址，如 https://example.com/archive；设置后额外生成 RSS/Atom/JSON Feed
+	PageSize int    // 首页每页条数，默认 20
+	Full     bool   // 忽略上次的构建记录，全部重新生成
+}
+
+// Report 构建结果
+type Report struct {
+	Posts    int // 已发布的稿件数
+	Rendered int // 重新生成的稿件页
+	Removed  int // 删除的稿件页（下架、删除等）
+	Tags     int // 重新生成的话题页
+	Pages    int // 重新生成的首页页数，没有变化时为 0
+}
+
+// Builder 静态网站生成器
+type Builder struct {
+	store  *store.Store
+	cards  *feed.Cards
+	render feed.RenderFunc
+	opt    Options
+}
+
+// NewBuilder 创建生成器。st 为墙的存储视图，render 渲染卡片（通常为使用墙主题的 render.Renderer），
+// 渲染结果缓存在 cards 中
+func NewBuilder(st *store.Store, cards *feed.Cards, render feed.RenderFunc, opt Options) *Builder {
+	if opt.PageSize <= 0 {
+		opt.PageSize = 20
+	}
+	opt.BaseURL = strings.TrimRight(opt.BaseURL, "/")
+	return &Builder{store: st, cards: cards, render: render, opt: opt}
+}
+
+// tagPattern 正文中的话题，如 "#失物招领"；纯数字（引用稿件编号，如 #12）不算话题
+var tagPattern = regexp.MustCompile(`(?:^|[\s，。！？、,.!?])#([\p{L}\p{N}_]{1,20})`)
+
+// Tags 提取正文中的话题，按出现顺序去重
+func Tags(text string) []string {
+	var tags []string
+	for _, m := range tagPattern.FindAllStringSubmatch(text, -1) {
+		tag := m[1]
+		if strings.Trim(tag, "0123456789") == "" || slices.Contains(tags, tag) {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// page 模板数据
+type page struct {
+	Site      string // 网站标题
+	Title     string // 页面标题
+	Root      string // 到网站根目录的相对路径，如 "../"
+	Items     []feed.Item
+	Tags      []tagCount
+	PostTags  []string // 稿件页的话题
+	Page      int
+	Pages     int
+	Prev      string
+	Next      string
+	HasFeed   bool
+	Generated string
+}
+
+type tagCount struct {
+	Name  string
+	Count int
+}
+
+// Build 生成或增量更新网站
+func (b *Builder) Build() (*Report, error) {
+	if err := os.MkdirAll(b.opt.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("create site dir: %w", err)
+	}
+	var posts []*model.Post
+	err := b.store.EachPost(store.SearchQuery{Status: model.StatusPublished}, 200, func(p *model.Post) error {
+		posts = append(posts, p)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load posts: %w", err)
+	}
+	// 最新在前
+	slices.Reverse(posts)
+
+	old := loadManifest(b.opt.Dir)
+	full := b.opt.Full || old.Version != siteVersion || old.Options != b.fingerprint()
+	cur := &manifest{Version: siteVersion, Options: b.fingerprint(), Posts: make(map[int64]manifestPost, len(posts))}
+	report := &Report{Posts: len(posts)}
+	dirtyTags := make(map[string]bool)
+
+	for _, p := range posts {
+		tags := Tags(p.Text)
+		entry := manifestPost{Updated: p.UpdateTime, Tags: tags}
+		prev, ok := old.Posts[p.ID]
+		if !full && ok && prev.Updated == p.UpdateTime && b.exists(postPath(p.ID)) && b.exists(cardPath(p.ID)) {
+			cur.Posts[p.ID] = prev
+			continue
+		}
+		if err := b.writeCard(p); err != nil {
+			// 卡片渲染失败时仍生成稿件页，不记录版本，下次构建重试
+			log.Printf("[Site] 渲染稿件 #%d 的卡片失败: %v", p.ID, err)
+			entry.Updated = -1
+		}
+		if err := b.writePost(p, tags); err != nil {
+			return nil, err
+		}
+		cur.Posts[p.ID] = entry
+		report.Rendered++
+		for _, t := range slices.Concat(tags, prev.Tags) {
+			dirtyTags[t] = true
+		}
+	}
+
+	for id, prev := range old.Posts {
+		if _, ok := cur.Posts[id]; ok {
+			continue
+		}
+		_ = os.Remove(filepath.Join(b.opt.Dir, postPath(id)))
+		_ = os.Remove(filepath.Join(b.opt.Dir, cardPath(id)))
+		report.Removed++
+		for _, t := range prev.Tags {
+			dirtyTags[t] = true
+		}
+	}
+
+	if full || report.Rendered > 0 || report.Removed > 0 {
+		n, err := b.writeIndex(posts)
+		if err != nil {
+			return nil, err
+		}
+		report.Pages = n
+		if err := b.writeFeeds(posts); err != nil {
+			return nil, err
+		}
+	}
+
+	byTag := make(map[string][]*model.Post)
+	for _, p := range posts {
+		for _, t := range cur.Posts[p.ID].Tags {
+			byTag[t] = append(byTag[t], p)
+		}
+	}
+	if full {
+		for t := range byTag {
+			dirtyTags[t] = true
+		}
+	}
+	for t := range dirtyTags {
+		if err := b.writeTag(t, byTag[t]); err != nil {
+			return nil, err
+		}
+		report.Tags++
+	}
+	if len(dirtyTags) > 0 || full {
+		if err := b.writeTagIndex(byTag); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cur.save(b.opt.Dir); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// fingerprint 影响所有页面的选项，变化时全部重新生成
+func (b *Builder) fingerprint() string {
+	return fmt.Sprintf("%s|%s|%d", b.opt.Title, b.opt.BaseURL, b.opt.PageSize)
+}
+
+func postPath(id int64) string  { return filepath.Join("posts", fmt.Sprintf("%d.html", id)) }
+func cardPath(id int64) string  { return filepath.Join("cards", fmt.Sprintf("%d.jpg", id)) }
+func tagPath(tag string) string { return filepath.Join("tags", tag+".html") }
+
+func (b *Builder) exists(rel string) bool {
+	_, err := os.Stat(filepath.Join(b.opt.Dir, rel))
+	return err == nil
+}
+
+// item 页面中的稿件条目，链接相对于网站根目录
+func item(p *model.Post) feed.Item {
+	return feed.NewItem(p, fmt.Sprintf("posts/%d.html", p.ID), fmt.Sprintf("cards/%d.jpg", p.ID))
+}
+
+func (b *Builder) writeCard(p *model.Post) error {
+	img, err := b.cards.Get(p, b.render)
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(b.opt.Dir, cardPath(p.ID)), img)
+}
+
+func (b *Builder) writePost(p *model.Post, tags []string) error {
+	it := item(p)
+	return b.execute(postPath(p.ID), "post.html", &page{
+		Title:    it.Title(),
+		Root:     "../",
+		Items:    []feed.Item{it},
+		PostTags: tags,
+	})
+}
+
+// writeIndex 生成分页首页 index.html、page/2.html…，并删除多出来的旧分页，返回页数
+func (b *Builder) writeIndex(posts []*model.Post) (int, error) {
+	pages := max(1, (len(posts)+b.opt.PageSize-1)/b.opt.PageSize)
+	for n := 1; n <= pages; n++ {
+		chunk := posts[(n-1)*b.opt.PageSize : min(n*b.opt.PageSize, len(posts))]
+		pg := &page{Page: n, Pages: pages, HasFeed: b.opt.BaseURL != ""}
+		for _, p := range chunk {
+			pg.Items = append(pg.Items, item(p))
+		}
+		rel := "index.html"
+		if n > 1 {
+			rel = filepath.Join("page", fmt.Sprintf("%d.html", n))
+			pg.Root = "../"
+			pg.Title = fmt.Sprintf("第 %d 页", n)
+		}
+		pg.Prev, pg.Next = pageLink(n-1, pages), pageLink(n+1, pages)
+		if err := b.execute(rel, "index.html", pg); err != nil {
+			return 0, err
+		}
+	}
+	old, _ := filepath.Glob(filepath.Join(b.opt.Dir, "page", "*.html"))
+	for _, f := range old {
+		var n int
+		if _, err := fmt.Sscanf(filepath.Base(f), "%d.html", &n); err == nil && n > pages {
+			_ = os.Remove(f)
+		}
+	}
+	return pages, nil
+}
+
+// pageLink 第 n 页相对于网站根目录的地址，超出范围时为空
+func pageLink(n, pages int) string {
+	switch {
+	case n < 1 || n > pages:
+		return ""
+	case n == 1:
+		return "index.html"
+	default:
+		return fmt.Sprintf("page/%d.html", n)
+	}
+}
+
+// writeTag 生成话题页，话题下已经没有稿件时删除
+func (b *Builder) writeTag(tag string, posts []*model.Post) error {
+	if len(posts) == 0 {
+		_ = os.Remove(filepath.Join(b.opt.Dir, tagPath(tag)))
+		return nil
+	}
+	pg := &page{Title: "#" + tag, Root: "../"}
+	for _, p := range posts {
+		pg.Items = append(pg.Items, item(p))
+	}
+	return b.execute(tagPath(tag), "tag.html", pg)
+}
+
+// writeTagIndex 生成话题列表 tags/index.html，按稿件数倒序
+func (b *Builder) writeTagIndex(byTag map[string][]*model.Post) error {
+	pg := &page{Title: "话题", Root: "../"}
+	for t, posts := range byTag {
+		pg.Tags = append(pg.Tags, tagCount{Name: t, Count: len(posts)})
+	}
+	sort.Slice(pg.Tags, func(i, j int) bool {
+		if pg.Tags[i].Count != pg.Tags[j].Count {
+			return pg.Tags[i].Count > pg.Tags[j].Count
+		}
+		return pg.Tags[i].Name < pg.Tags[j].Name
+	})
+	return b.execute(filepath.Join("tags", "index.html"), "tags.html", pg)
+}
+
+// writeFeeds 设置了 BaseURL 时生成 rss.xml、atom.xml 和 feed.json（最新 feed.Limit 条），否则删除以前生成的订阅
+func (b *Builder) writeFeeds(posts []*model.Post) error {
+	outputs := []struct {
+		name  string
+		write func(io.Writer, *feed.Channel) error
+	}{
+		{"rss.xml", feed.WriteRSS},
+		{"atom.xml", feed.WriteAtom},
+		{"feed.json", feed.WriteJSON},
+	}
+	base := b.opt.BaseURL + "/"
+	for _, o := range outputs {
+		if b.opt.BaseURL == "" {
+			_ = os.Remove(filepath.Join(b.opt.Dir, o.name))
+			continue
+		}
+		ch := &feed.Channel{
+			Title:       b.opt.Title,
+			Description: b.opt.Title + "已发布的投稿",
+			Link:        base,
+			FeedURL:     base + o.name,
+		}
+		for _, p := range posts[:min(len(posts), feed.Limit)] {
+			it := item(p)
+			it.URL, it.Card = base+it.URL, base+it.Card
+			ch.Items = append(ch.Items, it)
+		}
+		if err := writeWith(filepath.Join(b.opt.Dir, o.name), func(f *os.File) error { return o.write(f, ch) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execute 用模板 name 生成 rel 页面
+func (b *Builder) execute(rel, name string, pg *page) error {
+	pg.Site = b.opt.Title
+	pg.Generated = time.Now().Format("2006-01-02 15:04")
+	return writeWith(filepath.Join(b.opt.Dir, rel), func(f *os.File) error {
+		return tmpl.ExecuteTemplate(f, name, pg)
+	})
+}
+
+// WallLink 多墙时根目录 index.html 中的一个墙
+type WallLink struct {
+	Name string
+	Dir  string // 墙的网站所在的子目录
+}
+
+// WriteWallIndex 多墙时在 dir 生成列出各个墙的 index.html
+func WriteWallIndex(dir string, walls []WallLink) error {
+	return writeWith(filepath.Join(dir, "index.html"), func(f *os.File) error {
+		return tmpl.ExecuteTemplate(f, "walls.html", walls)
+	})
+}
+
+// writeWith 先写入临时文件再改名，构建中途失败不会留下写了一半的页面
+func writeWith(path string, write func(*os.File) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func writeFile(path string, data []byte) error {
+	return writeWith(path, func(f *os.File) error {
+		_, err := f.Write(data)
+		return err
+	})
+}
+
+// tagHref 话题页相对于网站根目录的地址
+func tagHref(tag string) string {
+	return "tags/" + url.PathEscape(tag) + ".html"
+}
@@ -0,0 +1,112 @@
+package site
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/feed"
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+// TestBuildIncremental 测试静态网站的增量构建：未变化时不重新渲染，修改和下架的稿件分别重新生成和删除，
+// 匿名稿件的页面和卡片不包含投稿者信息
+// 运行方法: go test -v ./internal/site/ -run TestBuildIncremental
+func TestBuildIncremental(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	ws := st.ForWall(model.DefaultWall)
+
+	anon := &model.Post{UIN: 123456789, Name: "小明", AvatarURL: "https://avatar.example/secret.jpg",
+		Text: "在图书馆捡到一张校园卡 #失物招领", Anon: true, Status: model.StatusPublished}
+	named := &model.Post{UIN: 10001, Name: "小红", Text: "回复 #12 #失物招领 已经找到失主了", Status: model.StatusPublished}
+	pending := &model.Post{UIN: 10002, Name: "小刚", Text: "还没审核", Status: model.StatusPending}
+	for _, p := range []*model.Post{anon, named, pending} {
+		if err := ws.SavePost(p); err != nil {
+			t.Fatalf("保存稿件失败: %v", err)
+		}
+	}
+
+	var calls int
+	render := func(p *model.Post) ([]byte, error) {
+		calls++
+		if p.Anon && (p.UIN != 0 || p.Name != "") {
+			t.Fatalf("匿名稿件的卡片不应包含投稿者信息: %+v", p)
+		}
+		return []byte("jpeg"), nil
+	}
+	dir := filepath.Join(t.TempDir(), "site")
+	build := func() *Report {
+		r, err := NewBuilder(ws, feed.NewCards(filepath.Join(t.TempDir(), "cards")), render, Options{
+			Dir: dir, Title: "一中", BaseURL: "https://example.com/archive",
+		}).Build()
+		if err != nil {
+			t.Fatalf("构建失败: %v", err)
+		}
+		return r
+	}
+	read := func(rel string) string {
+		b, err := os.ReadFile(filepath.Join(dir, rel))
+		if err != nil {
+			t.Fatalf("缺少 %s: %v", rel, err)
+		}
+		return string(b)
+	}
+	exists := func(rel string) bool {
+		_, err := os.Stat(filepath.Join(dir, rel))
+		return err == nil
+	}
+
+	r := build()
+	if r.Posts != 2 || r.Rendered != 2 || calls != 2 || r.Pages != 1 {
+		t.Fatalf("第一次构建结果错误: %+v, 渲染 %d 次", r, calls)
+	}
+	for _, rel := range []string{"index.html", postPath(anon.ID), cardPath(anon.ID), "tags/index.html", tagPath("失物招领"), "rss.xml", "atom.xml", "feed.json"} {
+		read(rel)
+	}
+	if exists(postPath(pending.ID)) || exists(tagPath("12")) {
+		t.Fatalf("不应生成未发布稿件和纯数字话题的页面")
+	}
+	for _, rel := range []string{"index.html", postPath(anon.ID), tagPath("失物招领"), "rss.xml", "feed.json"} {
+		out := read(rel)
+		for _, leak := range []string{"123456789", "小明", "secret.jpg"} {
+			if strings.Contains(out, leak) {
+				t.Fatalf("%s 中泄露了匿名稿件的 %q", rel, leak)
+			}
+		}
+	}
+	if !strings.Contains(read("index.html"), "小红") || !strings.Contains(read("rss.xml"), "https://example.com/archive/posts/") {
+		t.Fatalf("首页或订阅内容错误")
+	}
+
+	// 没有变化时不重新生成
+	if r := build(); r.Rendered != 0 || r.Removed != 0 || r.Pages != 0 || calls != 2 {
+		t.Fatalf("未变化时不应重新生成: %+v, 渲染 %d 次", r, calls)
+	}
+
+	// 修改一条、下架一条（update_time 精确到秒）
+	time.Sleep(time.Second)
+	named.Text = "已经找到失主了"
+	anon.Status = model.StatusRetracted
+	for _, p := range []*model.Post{anon, named} {
+		if err := ws.SavePost(p); err != nil {
+			t.Fatalf("保存稿件失败: %v", err)
+		}
+	}
+	r = build()
+	if r.Posts != 1 || r.Rendered != 1 || r.Removed != 1 || calls != 3 {
+		t.Fatalf("增量构建结果错误: %+v, 渲染 %d 次", r, calls)
+	}
+	if exists(postPath(anon.ID)) || exists(cardPath(anon.ID)) || exists(tagPath("失物招领")) {
+		t.Fatalf("下架稿件的页面、卡片和没有稿件的话题页应被删除")
+	}
+	if !strings.Contains(read(postPath(named.ID)), "已经找到失主了") {
+		t.Fatalf("修改后的稿件页未更新")
+	}
+}
@@ -3,10 +3,12 @@ package source
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -14,8 +16,11 @@ import (
 	qzone "github.com/guohuiyuan/qzone-go"
 	"github.com/guohuiyuan/qzonewall-go/internal/config"
 	"github.com/guohuiyuan/qzonewall-go/internal/model"
-	"github.com/guohuiyuan/qzonewall-go/internal/render"
+	"github.com/guohuiyuan/qzonewall-go/internal/notify"
+	"github.com/guohuiyuan/qzonewall-go/internal/publish"
 	"github.com/guohuiyuan/qzonewall-go/internal/store"
+	"github.com/guohuiyuan/qzonewall-go/internal/task"
+	"github.com/guohuiyuan/qzonewall-go/internal/wall"
 
 	zero "github.com/wdvxdr1123/ZeroBot"
 	"github.com/wdvxdr1123/ZeroBot/driver"
@@ -23,41 +28,33 @@ import (
 )
 
 // QQBot 基于 NapCat + ZeroBot 的 QQ 数据源
+//
+// 投稿按来源群进入对应的表白墙；管理命令在墙的管理群或来源群中只处理本墙的稿件，
+// 私聊和多个墙共用的管理群中处理所有墙的稿件，过稿等操作使用稿件所属墙的发布服务。
 type QQBot struct {
-	botCfg      config.BotConfig
-	wallCfg     config.WallConfig
-	qzoneCfg    config.QzoneConfig
-	store       *store.Store
-	renderer    *render.Renderer
-	qzClient    *qzone.Client
-	censorWords []string
-	engine      *zero.Engine
+	botCfg  config.BotConfig
+	wallCfg config.WallConfig
+	walls   *wall.Registry
+	vault   *task.CookieVault
+	engine  *zero.Engine
 }
 
-// NewQQBot 创建 QQ 机器人
+// NewQQBot 创建 QQ 机器人，walls 可以在启动后再添加墙
 func NewQQBot(
 	botCfg config.BotConfig,
 	wallCfg config.WallConfig,
-	qzoneCfg config.QzoneConfig,
-	st *store.Store,
-	renderer *render.Renderer,
-	qzClient *qzone.Client,
-	censorWords []string,
+	walls *wall.Registry,
 ) *QQBot {
 	return &QQBot{
-		botCfg:      botCfg,
-		wallCfg:     wallCfg,
-		qzoneCfg:    qzoneCfg,
-		store:       st,
-		renderer:    renderer,
-		qzClient:    qzClient,
-		censorWords: censorWords,
+		botCfg:  botCfg,
+		wallCfg: wallCfg,
+		walls:   walls,
 	}
 }
 
-// SetClient 设置/替换 QQ空间客户端 (用于延迟初始化)
-func (b *QQBot) SetClient(client *qzone.Client) {
-	b.qzClient = client
+// SetCookieVault 设置 Cookie 持久化，扫码登录成功后保存
+func (b *QQBot) SetCookieVault(vault *task.CookieVault) {
+	b.vault = vault
 }
 
 // Start 启动 ZeroBot 并注册命令
@@ -115,12 +112,30 @@ func (b *QQBot) registerCommands() {
 	b.engine.OnCommand("过稿", zero.SuperUserPermission).SetBlock(true).Handle(func(ctx *zero.Ctx) {
 		b.handleApprove(ctx)
 	})
+	b.engine.OnCommand("定时过稿", zero.SuperUserPermission).SetBlock(true).Handle(func(ctx *zero.Ctx) {
+		b.handleScheduleApprove(ctx)
+	})
+	b.engine.OnCommand("定时列表", zero.SuperUserPermission).SetBlock(true).Handle(func(ctx *zero.Ctx) {
+		b.handleListScheduled(ctx)
+	})
+	b.engine.OnCommand("取消定时", zero.SuperUserPermission).SetBlock(true).Handle(func(ctx *zero.Ctx) {
+		b.handleCancelSchedule(ctx)
+	})
+	b.engine.OnCommand("下架", zero.SuperUserPermission).SetBlock(true).Handle(func(ctx *zero.Ctx) {
+		b.handleRetract(ctx)
+	})
+	b.engine.OnCommand("重发", zero.SuperUserPermission).SetBlock(true).Handle(func(ctx *zero.Ctx) {
+		b.handleRetry(ctx)
+	})
 	b.engine.OnCommand("拒稿", zero.SuperUserPermission).SetBlock(true).Handle(func(ctx *zero.Ctx) {
 		b.handleReject(ctx)
 	})
 	b.engine.OnCommand("待审核", zero.SuperUserPermission).SetBlock(true).Handle(func(ctx *zero.Ctx) {
 		b.handleListPending(ctx)
 	})
+	b.engine.OnCommand("搜稿", zero.SuperUserPermission).SetBlock(true).Handle(func(ctx *zero.Ctx) {
+		b.handleSearch(ctx)
+	})
 	b.engine.OnCommand("发说说", zero.SuperUserPermission).SetBlock(true).Handle(func(ctx *zero.Ctx) {
 		b.handleDirectPublish(ctx)
 	})
@@ -130,6 +145,9 @@ func (b *QQBot) registerCommands() {
 	b.engine.OnCommand("刷新cookie", zero.SuperUserPermission).SetBlock(true).Handle(func(ctx *zero.Ctx) {
 		b.handleRefreshCookie(ctx)
 	})
+	b.engine.OnCommand("账号", zero.SuperUserPermission).SetBlock(true).Handle(func(ctx *zero.Ctx) {
+		b.handleAccounts(ctx)
+	})
 	b.engine.OnCommandGroup([]string{"帮助", "help"}).SetBlock(true).Handle(func(ctx *zero.Ctx) {
 		b.handleHelp(ctx)
 	})
@@ -158,8 +176,13 @@ func (b *QQBot) handleContribute(ctx *zero.Ctx, anon bool) {
 		return
 	}
 
-	if len(b.censorWords) > 0 {
-		if hit, word := store.CheckCensor(text, b.censorWords); hit {
+	w := b.contributeWall(ctx)
+	if w == nil {
+		ctx.Send(message.Text("❌ 无法确定投稿的表白墙，请在表白墙所在的群里投稿"))
+		return
+	}
+	if len(w.CensorWords) > 0 {
+		if hit, word := store.CheckCensor(text, w.CensorWords); hit {
 			ctx.Send(message.Text(fmt.Sprintf("❌ 投稿包含违禁词: %s", word)))
 			return
 		}
@@ -175,17 +198,15 @@ func (b *QQBot) handleContribute(ctx *zero.Ctx, anon bool) {
 		Status:     model.StatusPending,
 		CreateTime: time.Now().Unix(),
 	}
-	if err := b.store.SavePost(post); err != nil {
+	if err := w.Store.SavePost(post); err != nil {
 		ctx.Send(message.Text("❌ 保存失败: " + err.Error()))
 		return
 	}
+	b.audit(ctx, model.ActionCreate, post.ID, "", model.StatusPending, "")
 
-	ctx.Send(message.Text(fmt.Sprintf("✅ 投稿成功！编号 #%d，等待审核...", post.ID)))
+	ctx.Send(message.Text(fmt.Sprintf("✅ 投稿成功！%s编号 #%d，等待审核...", b.wallTag(post), post.ID)))
 
-	if b.botCfg.ManageGroup > 0 {
-		notifyMsg := fmt.Sprintf("📬 收到新投稿 #%d\n%s", post.ID, post.Summary())
-		ctx.SendGroupMessage(b.botCfg.ManageGroup, message.Text(notifyMsg))
-	}
+	w.Notifier.Notify(notify.AlertNewPost, fmt.Sprintf("📬 %s收到新投稿 #%d\n%s", b.wallTag(post), post.ID, post.Summary()))
 }
 
 // handleRecall 撤稿
@@ -200,7 +221,8 @@ func (b *QQBot) handleRecall(ctx *zero.Ctx) {
 		ctx.Send(message.Text("❌ 编号格式不正确"))
 		return
 	}
-	post, err := b.store.GetPost(id)
+	st := b.storeFor(ctx)
+	post, err := st.GetPost(id)
 	if err != nil || post == nil {
 		ctx.Send(message.Text(fmt.Sprintf("❌ 稿件 #%d 不存在", id)))
 		return
@@ -209,15 +231,25 @@ func (b *QQBot) handleRecall(ctx *zero.Ctx) {
 		ctx.Send(message.Text("❌ 你只能撤回自己的稿件"))
 		return
 	}
-	if post.Status == model.StatusPublished {
-		ctx.Send(message.Text("❌ 已发布的稿件无法撤回"))
+	if post.Status == model.StatusPublished || post.Status == model.StatusPublishing {
+		ctx.Send(message.Text("❌ 已发布或发布中的稿件无法撤回，如需从QQ空间删除请联系管理员 /下架"))
+		return
+	}
+	if post.Status == model.StatusDeleted {
+		ctx.Send(message.Text(fmt.Sprintf("❌ 稿件 #%d 已撤回", id)))
 		return
 	}
 
-	if err := b.store.DeletePost(id); err != nil {
+	ok, err := st.SoftDeletePost(id, fmt.Sprintf("qq:%d", ctx.Event.UserID))
+	if err != nil {
 		ctx.Send(message.Text("❌ 撤回失败: " + err.Error()))
 		return
 	}
+	if !ok {
+		ctx.Send(message.Text(fmt.Sprintf("❌ 稿件 #%d 已开始发布或已被处理，无法撤回", id)))
+		return
+	}
+	b.audit(ctx, model.ActionDelete, id, post.Status, model.StatusDeleted, "撤稿")
 	ctx.Send(message.Text(fmt.Sprintf("✅ 稿件 #%d 已撤回", id)))
 }
 
@@ -233,18 +265,19 @@ func (b *QQBot) handleViewPost(ctx *zero.Ctx) {
 		ctx.Send(message.Text("❌ 编号格式不正确"))
 		return
 	}
-	post, err := b.store.GetPost(id)
+	post, err := b.storeFor(ctx).GetPost(id)
 	if err != nil || post == nil {
 		ctx.Send(message.Text(fmt.Sprintf("❌ 稿件 #%d 不存在", id)))
 		return
 	}
 
-	if b.renderer.Available() {
-		// 解析图片地址后再渲染
-		renderPost := resolvePostImages(post)
-		if imgData, err := b.renderer.RenderPost(renderPost); err == nil {
+	if w := b.wallOfPost(post); w != nil && w.Renderer.Available() {
+		// 解析图片地址后使用所属墙的主题渲染
+		renderPost := w.Publisher.ResolvePostImages(post)
+		if imgData, err := w.Renderer.RenderPost(renderPost); err == nil {
 			b64 := base64.StdEncoding.EncodeToString(imgData)
 			ctx.Send(message.Image("base64://" + b64))
+			b.sendPostHistory(ctx, post)
 			return
 		} else {
 			ctx.Send(message.Text("❌ 渲染失败: " + err.Error()))
@@ -259,6 +292,26 @@ func (b *QQBot) handleViewPost(ctx *zero.Ctx) {
 		segs = append(segs, message.Image(img))
 	}
 	ctx.Send(segs)
+	b.sendPostHistory(ctx, post)
+}
+
+// sendPostHistory 发送稿件的状态与操作记录
+func (b *QQBot) sendPostHistory(ctx *zero.Ctx, post *model.Post) {
+	events, err := b.walls.Store().ListPostHistory(post.ID)
+	if err != nil {
+		log.Printf("[QQBot] 查询稿件 #%d 历史失败: %v", post.ID, err)
+		return
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📜 稿件 #%d 当前状态: %s", post.ID, post.Status)
+	if len(events) == 0 {
+		sb.WriteString("\n（暂无操作记录）")
+	}
+	for _, e := range events {
+		sb.WriteString("\n")
+		sb.WriteString(e.String())
+	}
+	ctx.Send(message.Text(sb.String()))
 }
 
 // handleApprove 过稿
@@ -270,7 +323,7 @@ func (b *QQBot) handleApprove(ctx *zero.Ctx) {
 		return
 	}
 
-	posts, err := b.store.GetPostsByIDs(ids)
+	posts, err := b.storeFor(ctx).GetPostsByIDs(ids)
 	if err != nil {
 		ctx.Send(message.Text("❌ 数据库查询失败: " + err.Error()))
 		return
@@ -288,108 +341,227 @@ func (b *QQBot) handleApprove(ctx *zero.Ctx) {
 		return
 	}
 
-	ctx.Send(message.Text(fmt.Sprintf("⏳ 正在处理 %d 条稿件，合并发布中...", len(validPosts))))
-
-	var summaryBuilder strings.Builder
-	fmt.Fprintf(&summaryBuilder, "【表白墙更新】 %s\n", time.Now().Format("01/02"))
-	summaryBuilder.WriteString("----------------\n")
-
-	// 收集图片数据
-	var imagesData [][]byte
+	batches, orphan := b.byWall(validPosts)
+	if len(orphan) > 0 {
+		ctx.Send(message.Text(fmt.Sprintf("❌ 稿件 %s 所属的表白墙未配置，已跳过", postIDs(orphan))))
+	}
+	if len(batches) == 0 {
+		return
+	}
 
-	for _, post := range validPosts {
-		// A. 渲染图片
-		var imgData []byte
-		var renderErr error
+	ctx.Send(message.Text(fmt.Sprintf("⏳ 正在处理 %d 条稿件，合并发布中...", len(validPosts)-len(orphan))))
 
-		if b.renderer.Available() {
-			// 解析图片地址后再渲染
-			renderPost := resolvePostImages(post)
-			imgData, renderErr = b.renderer.RenderPost(renderPost)
-		}
+	actor := publish.Actor{ID: ctx.Event.UserID, Source: model.SourceBot}
+	if ctx.Event.Sender != nil {
+		actor.Name = ctx.Event.Sender.NickName
+	}
 
-		if renderErr != nil || imgData == nil {
-			log.Printf("渲染失败 #%d: %v", post.ID, renderErr)
-			ctx.Send(message.Text(fmt.Sprintf("❌ 稿件 #%d 渲染失败，跳过", post.ID)))
-			continue
+	go func() {
+		// 不同墙的稿件分别用各自的账号发布
+		var results []*publish.Result
+		for _, batch := range batches {
+			res, err := batch.wall.Publisher.Approve(context.Background(), batch.ids, actor)
+			if err != nil {
+				ctx.Send(message.Text("❌ " + err.Error()))
+				continue
+			}
+			results = append(results, res...)
 		}
-
-		imagesData = append(imagesData, imgData)
-
-		// B. 拼接摘要
-		content := []rune(post.Text)
-		if len(content) > 20 {
-			fmt.Fprintf(&summaryBuilder, "#%d: %s...\n", post.ID, string(content[:20]))
-		} else {
-			if post.Text == "" {
-				fmt.Fprintf(&summaryBuilder, "#%d: [图片]\n", post.ID)
-			} else {
-				fmt.Fprintf(&summaryBuilder, "#%d: %s\n", post.ID, post.Text)
+		for _, res := range results {
+			if len(res.Deferred) > 0 {
+				sendRenderFailures(ctx, res)
+				ctx.Send(message.Text(fmt.Sprintf("⏳ 稿件 %s 已通过，%v", postIDs(res.Deferred), res.Err)))
+				continue
 			}
+			if res.Err != nil {
+				log.Printf("发布说说失败: %v", res.Err)
+				msg := fmt.Sprintf("❌ 发布到空间失败: %v", res.Err)
+				if len(res.Requeued) > 0 {
+					msg += fmt.Sprintf("\n稿件 %s 稍后自动重试", postIDs(res.Requeued))
+				}
+				if len(res.Failed) > 0 {
+					msg += fmt.Sprintf("\n稿件 %s 已标记为发布失败，可用 /重发 重新发布", postIDs(res.Failed))
+				}
+				ctx.Send(message.Text(msg))
+				continue
+			}
+			sendRenderFailures(ctx, res)
+
+			// 发布成功：群内反馈
+			var msgSegments message.Message
+			msgSegments = append(msgSegments, message.Text("✅ 批量过稿成功！已发布到空间：\n"+res.Text))
+			for _, img := range res.Cards {
+				b64 := base64.StdEncoding.EncodeToString(img)
+				msgSegments = append(msgSegments, message.Image("base64://"+b64))
+			}
+			ctx.Send(msgSegments)
 		}
+	}()
+}
 
-		// C. 标记为已发布
-		post.Status = model.StatusPublished
-		if err := b.store.SavePost(post); err != nil {
-			log.Printf("保存稿件状态失败 #%d: %v", post.ID, err)
+// handleRetry 重发: /重发 1-4，将发布失败的稿件退回待发布队列，由 Worker 重新发布
+func (b *QQBot) handleRetry(ctx *zero.Ctx) {
+	ids, err := parseIDs(getArgs(ctx))
+	if err != nil {
+		ctx.Send(message.Text("❌ " + err.Error() + "\n用法: /重发 1-4 或 /重发 1,2,5"))
+		return
+	}
+	actor := publish.Actor{ID: ctx.Event.UserID, Source: model.SourceBot}
+	if ctx.Event.Sender != nil {
+		actor.Name = ctx.Event.Sender.NickName
+	}
+	posts, err := b.storeFor(ctx).GetPostsByIDs(ids)
+	if err != nil {
+		ctx.Send(message.Text("❌ 数据库查询失败: " + err.Error()))
+		return
+	}
+	batches, _ := b.byWall(posts)
+	var requeued []int64
+	for _, batch := range batches {
+		done, err := batch.wall.Publisher.Retry(batch.ids, actor)
+		if err != nil {
+			ctx.Send(message.Text("❌ " + err.Error()))
+			return
 		}
+		requeued = append(requeued, done...)
 	}
-
-	if len(imagesData) == 0 {
-		ctx.Send(message.Text("❌ 没有成功渲染的图片，取消发布"))
+	if len(requeued) == 0 {
+		ctx.Send(message.Text("⚠️ 没有找到[发布失败]的稿件"))
 		return
 	}
+	ctx.Send(message.Text(fmt.Sprintf("✅ 已重新排队 %d 条稿件，将按顺序重新发布", len(requeued))))
+}
 
-	summaryBuilder.WriteString("----------------\n")
-	summaryBuilder.WriteString("详情见图 👇")
-	finalText := summaryBuilder.String()
-
-	go func() {
-		// 修正：直接使用 ImageBytes 字段，让 qzone 库处理上传逻辑
-		opts := &qzone.PublishOption{
-			ImageBytes: imagesData,
-		}
+// handleRetract 下架: /下架 12 [理由]，按 TID 从QQ空间删除已发布的说说。
+// 合集说说会先提示同一条说说中的全部稿件，需 /下架 12 确认 [理由] 才会一起删除。
+func (b *QQBot) handleRetract(ctx *zero.Ctx) {
+	const usage = "\n用法: /下架 <编号> [理由]"
+	fields := strings.Fields(getArgs(ctx))
+	if len(fields) < 1 {
+		ctx.Send(message.Text("❌ 请提供编号" + usage))
+		return
+	}
+	id, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		ctx.Send(message.Text("❌ 编号格式不正确" + usage))
+		return
+	}
+	fields = fields[1:]
+	force := len(fields) > 0 && fields[0] == "确认"
+	if force {
+		fields = fields[1:]
+	}
+	reason := strings.Join(fields, " ")
 
-		// 调用发布接口
-		_, publishErr := b.qzClient.Publish(context.Background(), finalText, opts)
+	actor := publish.Actor{ID: ctx.Event.UserID, Source: model.SourceBot}
+	if ctx.Event.Sender != nil {
+		actor.Name = ctx.Event.Sender.NickName
+	}
+	post, err := b.storeFor(ctx).GetPost(id)
+	if err != nil || post == nil {
+		ctx.Send(message.Text(fmt.Sprintf("❌ 稿件 #%d 不存在", id)))
+		return
+	}
+	w := b.wallOfPost(post)
+	if w == nil {
+		ctx.Send(message.Text(fmt.Sprintf("❌ 稿件 #%d 所属的表白墙 %s 未配置", id, post.WallID)))
+		return
+	}
+	r, err := w.Publisher.Retract(context.Background(), id, reason, force, actor)
+	if errors.Is(err, publish.ErrSharedTID) {
+		confirm := strings.TrimSpace(fmt.Sprintf("/下架 %d 确认 %s", id, reason))
+		ctx.Send(message.Text(fmt.Sprintf("⚠️ 稿件 #%d 所在的说说是合集，包含 %s 共 %d 条稿件，下架会一起删除。\n确认请发送: %s",
+			id, r.IDs(), len(r.Posts), confirm)))
+		return
+	}
+	if err != nil {
+		ctx.Send(message.Text("❌ " + err.Error()))
+		return
+	}
+	ctx.Send(message.Text(fmt.Sprintf("✅ 已从QQ空间删除说说，下架稿件 %s", r.IDs())))
+}
 
-		if publishErr != nil {
-			log.Printf("发布说说失败: %v", publishErr)
-			ctx.Send(message.Text("❌ 发布到空间失败: " + publishErr.Error()))
+// handleScheduleApprove 定时过稿: /定时过稿 12 21:00，到时间后由 Worker 逐条发布
+func (b *QQBot) handleScheduleApprove(ctx *zero.Ctx) {
+	const usage = "\n用法: /定时过稿 12 21:00 或 /定时过稿 1-4 2025-01-02 21:00"
+	fields := strings.Fields(getArgs(ctx))
+	if len(fields) < 2 {
+		ctx.Send(message.Text("❌ 请提供编号和时间" + usage))
+		return
+	}
+	ids, err := parseIDs(fields[0])
+	if err != nil {
+		ctx.Send(message.Text("❌ " + err.Error() + usage))
+		return
+	}
+	at, err := parseScheduleTime(strings.Join(fields[1:], " "), time.Now())
+	if err != nil {
+		ctx.Send(message.Text("❌ " + err.Error() + usage))
+		return
+	}
 
-			// 失败回滚
-			for _, p := range validPosts {
-				p.Status = model.StatusPending
-				if err := b.store.SavePost(p); err != nil {
-					log.Printf("回滚稿件状态失败 #%d: %v", p.ID, err)
-				}
-			}
-			return
+	st := b.storeFor(ctx)
+	posts, err := st.GetPostsByIDs(ids)
+	if err != nil {
+		ctx.Send(message.Text("❌ 数据库查询失败: " + err.Error()))
+		return
+	}
+	var done []string
+	for _, post := range posts {
+		if post.Status != model.StatusPending {
+			continue
+		}
+		if err := st.SetPostStatus(post.ID, model.StatusApproved, "", at.Unix(), model.StatusPending); err != nil {
+			log.Printf("保存稿件状态失败 #%d: %v", post.ID, err)
+			continue
 		}
+		b.audit(ctx, model.ActionSchedule, post.ID, model.StatusPending, model.StatusApproved, "定时 "+at.Format("2006-01-02 15:04"))
+		done = append(done, fmt.Sprintf("#%d", post.ID))
+	}
+	if len(done) == 0 {
+		ctx.Send(message.Text("⚠️ 没有找到[待审核]的稿件，可能已处理"))
+		return
+	}
+	ctx.Send(message.Text(fmt.Sprintf("⏰ 已定时 %s，将于 %s 发布", strings.Join(done, ","), at.Format("01-02 15:04"))))
+}
 
-		// 发布成功：群内反馈
-		var msgSegments message.Message
-		msgSegments = append(msgSegments, message.Text("✅ 批量过稿成功！已发布到空间：\n"+finalText))
+// handleListScheduled 查看尚未发布的定时稿件
+func (b *QQBot) handleListScheduled(ctx *zero.Ctx) {
+	posts, err := b.storeFor(ctx).ListScheduled()
+	if err != nil {
+		ctx.Send(message.Text("❌ 查询失败"))
+		return
+	}
+	if len(posts) == 0 {
+		ctx.Send(message.Text("📭 没有定时稿件"))
+		return
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "⏰ 定时稿件 (%d):\n", len(posts))
+	for _, p := range posts {
+		fmt.Fprintf(&sb, "\n[%s] %s%s", time.Unix(p.PublishAt, 0).Format("01-02 15:04"), b.wallTag(p), p.Summary())
+	}
+	ctx.Send(message.Text(sb.String()))
+}
 
-		for _, img := range imagesData {
-			b64 := base64.StdEncoding.EncodeToString(img)
-			msgSegments = append(msgSegments, message.Image("base64://"+b64))
-		}
-		ctx.Send(msgSegments)
-
-		// 通知投稿者
-		for _, p := range validPosts {
-			if p.UIN > 0 {
-				notifyMsg := fmt.Sprintf("🎉 您的投稿 #%d 已发布！", p.ID)
-				time.Sleep(500 * time.Millisecond)
-				if p.GroupID > 0 {
-					ctx.SendGroupMessage(p.GroupID, message.Text(notifyMsg))
-				} else {
-					ctx.SendPrivateMessage(p.UIN, message.Text(notifyMsg))
-				}
-			}
-		}
-	}()
+// handleCancelSchedule 取消定时，稿件退回待审核
+func (b *QQBot) handleCancelSchedule(ctx *zero.Ctx) {
+	id, err := strconv.ParseInt(getArgs(ctx), 10, 64)
+	if err != nil {
+		ctx.Send(message.Text("❌ 用法: /取消定时 <编号>"))
+		return
+	}
+	ok, err := b.storeFor(ctx).CancelSchedule(id)
+	if err != nil {
+		ctx.Send(message.Text("❌ 取消失败: " + err.Error()))
+		return
+	}
+	if !ok {
+		ctx.Send(message.Text(fmt.Sprintf("⚠️ #%d 不是未到时间的定时稿件，可能已在发布", id)))
+		return
+	}
+	b.audit(ctx, model.ActionCancel, id, model.StatusApproved, model.StatusPending, "")
+	ctx.Send(message.Text(fmt.Sprintf("⏹ 已取消 #%d 的定时，退回待审核", id)))
 }
 
 // handleReject 拒稿
@@ -405,13 +577,14 @@ func (b *QQBot) handleReject(ctx *zero.Ctx) {
 		ctx.Send(message.Text("❌ 编号格式不正确"))
 		return
 	}
-	post, err := b.store.GetPost(id)
+	st := b.storeFor(ctx)
+	post, err := st.GetPost(id)
 	if err != nil || post == nil {
 		ctx.Send(message.Text(fmt.Sprintf("❌ 稿件 #%d 不存在", id)))
 		return
 	}
-	if post.Status == model.StatusPublished {
-		ctx.Send(message.Text(fmt.Sprintf("稿件 #%d 已发布，无法拒绝", id)))
+	if !slices.Contains(model.RejectableStatuses, post.Status) {
+		ctx.Send(message.Text(fmt.Sprintf("稿件 #%d 状态为[%s]，只能拒绝待审核或已通过未发布的稿件", id, post.Status)))
 		return
 	}
 
@@ -420,12 +593,17 @@ func (b *QQBot) handleReject(ctx *zero.Ctx) {
 		reason = strings.Join(args[1:], " ")
 	}
 
-	post.Status = model.StatusRejected
-	post.Reason = reason
-	if err := b.store.SavePost(post); err != nil {
+	oldStatus := post.Status
+	err = st.SetPostStatus(post.ID, model.StatusRejected, reason, 0, model.RejectableStatuses...)
+	if errors.Is(err, store.ErrStatusConflict) {
+		ctx.Send(message.Text(fmt.Sprintf("⚠️ 稿件 #%d 已被处理或正在发布，无法拒绝", id)))
+		return
+	}
+	if err != nil {
 		ctx.Send(message.Text("❌ 更新稿件状态失败: " + err.Error()))
 		return
 	}
+	b.audit(ctx, model.ActionReject, post.ID, oldStatus, model.StatusRejected, reason)
 
 	msg := fmt.Sprintf("❌ 稿件 #%d 已拒绝", id)
 	if reason != "" {
@@ -448,7 +626,7 @@ func (b *QQBot) handleReject(ctx *zero.Ctx) {
 
 // handleListPending 待审核列表
 func (b *QQBot) handleListPending(ctx *zero.Ctx) {
-	posts, err := b.store.ListByStatus(model.StatusPending)
+	posts, err := b.storeFor(ctx).ListByStatus(model.StatusPending)
 	if err != nil {
 		ctx.Send(message.Text("❌ 查询失败: " + err.Error()))
 		return
@@ -460,6 +638,34 @@ func (b *QQBot) handleListPending(ctx *zero.Ctx) {
 	var sb strings.Builder
 	fmt.Fprintf(&sb, "📋 待审核稿件 (%d 件):\n\n", len(posts))
 	for _, p := range posts {
+		sb.WriteString(b.wallTag(p))
+		sb.WriteString(p.Summary())
+		sb.WriteString("---\n")
+	}
+	ctx.Send(message.Text(sb.String()))
+}
+
+// handleSearch 搜稿
+func (b *QQBot) handleSearch(ctx *zero.Ctx) {
+	keyword := getArgs(ctx)
+	if keyword == "" {
+		ctx.Send(message.Text("用法: /搜稿 <关键词>"))
+		return
+	}
+	const limit = 10
+	posts, err := b.storeFor(ctx).SearchPosts(store.SearchQuery{Keyword: keyword}, limit, 0)
+	if err != nil {
+		ctx.Send(message.Text("❌ 搜索失败: " + err.Error()))
+		return
+	}
+	if len(posts) == 0 {
+		ctx.Send(message.Text(fmt.Sprintf("🔍 没有找到包含「%s」的稿件", keyword)))
+		return
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🔍 「%s」的搜索结果 (最近 %d 条):\n\n", keyword, len(posts))
+	for _, p := range posts {
+		sb.WriteString(b.wallTag(p))
 		sb.WriteString(p.Summary())
 		sb.WriteString("---\n")
 	}
@@ -475,6 +681,11 @@ func (b *QQBot) handleDirectPublish(ctx *zero.Ctx) {
 		ctx.Send(message.Text("❌ 内容不能为空"))
 		return
 	}
+	w := b.wallOf(ctx)
+	if w == nil {
+		ctx.Send(message.Text("❌ 请在表白墙的管理群中使用 /发说说，以确定使用哪个墙的账号"))
+		return
+	}
 
 	go func() {
 		var imagesData [][]byte
@@ -484,7 +695,7 @@ func (b *QQBot) handleDirectPublish(ctx *zero.Ctx) {
 			client := &http.Client{Timeout: 20 * time.Second}
 			for _, imgStr := range images {
 				// 同样需要解析可能的 file ID
-				imgURL := resolveImageURL(imgStr)
+				imgURL := publish.ResolveImageURL(imgStr)
 
 				resp, err := client.Get(imgURL)
 				if err != nil {
@@ -503,17 +714,13 @@ func (b *QQBot) handleDirectPublish(ctx *zero.Ctx) {
 			}
 		}
 
-		// 3. 构建发布选项
-		var opts *qzone.PublishOption
-		if len(imagesData) > 0 {
-			opts = &qzone.PublishOption{
-				ImageBytes: imagesData,
-			}
-		}
-
-		// 4. 调用发布 (传入 opts)
-		_, err := b.qzClient.Publish(context.Background(), text, opts)
-		if err != nil {
+		// 3. 通过发布服务发布（与过稿共享频率限制和重试）
+		_, err := w.Publisher.PublishText(context.Background(), text, imagesData)
+		var deferred *publish.DeferredError
+		if errors.As(err, &deferred) {
+			// 说说不进入待发布队列，不会自动补发
+			ctx.Send(message.Text("❌ 暂时不能发布: " + deferred.Reason))
+		} else if err != nil {
 			ctx.Send(message.Text("❌ 发布失败: " + err.Error()))
 		} else {
 			ctx.Send(message.Text("✅ 说说已发布"))
@@ -521,9 +728,14 @@ func (b *QQBot) handleDirectPublish(ctx *zero.Ctx) {
 	}()
 }
 
-// handleScanQR 扫码登录QQ空间
+// handleScanQR 扫码登录QQ空间，/扫码 [账号名或QQ号]，省略时登录第一个账号
 func (b *QQBot) handleScanQR(ctx *zero.Ctx) {
-	ctx.Send(message.Text("🔄 正在获取二维码..."))
+	w, acc := b.walls.Account(getArgs(ctx))
+	if acc == nil {
+		ctx.Send(message.Text("❌ 账号不存在，使用 /账号 查看号池"))
+		return
+	}
+	ctx.Send(message.Text("🔄 正在获取账号 " + acc.Name + " 的二维码..."))
 
 	qr, err := qzone.GetQRCode()
 	if err != nil {
@@ -543,11 +755,13 @@ func (b *QQBot) handleScanQR(ctx *zero.Ctx) {
 				continue
 			}
 			if state == qzone.LoginSuccess {
-				if updateErr := b.qzClient.UpdateCookie(cookie); updateErr != nil {
-					ctx.Send(message.Text("❌ Cookie更新失败: " + updateErr.Error()))
+				if loginErr := w.Pool.Login(acc, cookie); loginErr != nil {
+					ctx.Send(message.Text("❌ 登录失败: " + loginErr.Error()))
 					return
 				}
-				ctx.Send(message.Text(fmt.Sprintf("✅ QQ空间登录成功！UIN=%d", b.qzClient.UIN())))
+				b.vault.Save(acc.Client)
+				b.audit(ctx, model.ActionLogin, 0, "", "", fmt.Sprintf("扫码登录账号 %s UIN=%d", acc.Name, acc.Client.UIN()))
+				ctx.Send(message.Text(fmt.Sprintf("✅ QQ空间账号 %s 登录成功！UIN=%d", acc.Name, acc.Client.UIN())))
 				return
 			}
 			if state == qzone.LoginExpired {
@@ -564,6 +778,38 @@ func (b *QQBot) handleRefreshCookie(ctx *zero.Ctx) {
 	ctx.Send(message.Text("⚠️ 暂不支持自动刷新，请使用 /扫码 手动登录"))
 }
 
+// handleAccounts 查看号池中各账号的登录状态，多墙时按墙分别列出
+func (b *QQBot) handleAccounts(ctx *zero.Ctx) {
+	var sb strings.Builder
+	sb.WriteString("📒 QQ空间号池")
+	for _, w := range b.walls.All() {
+		if b.walls.Multi() {
+			fmt.Fprintf(&sb, "\n\n【%s】", w.Name)
+		}
+		writePoolStatus(&sb, w.Pool)
+	}
+	ctx.Send(message.Text(sb.String()))
+}
+
+// writePoolStatus 写入号池中各账号的状态
+func writePoolStatus(sb *strings.Builder, pool *publish.Pool) {
+	primary := pool.Primary()
+	for i, acc := range pool.Accounts() {
+		status := "未登录"
+		if down, since, reason := acc.Health.State(); down {
+			status = fmt.Sprintf("%s 起失效（%s）", since.Format("01-02 15:04"), reason)
+		} else if acc == primary {
+			status = "主号，发布中"
+		} else if acc.LoggedIn() {
+			status = "备用"
+		}
+		fmt.Fprintf(sb, "\n%d. %s：%s", i+1, acc, status)
+	}
+	if primary == nil {
+		sb.WriteString("\n⚠️ 没有可用账号，发布已暂停")
+	}
+}
+
 // handleHelp
 func (b *QQBot) handleHelp(ctx *zero.Ctx) {
 	help := `📖 表白墙Bot使用指南
@@ -575,12 +821,19 @@ func (b *QQBot) handleHelp(ctx *zero.Ctx) {
 
 【管理命令】（仅管理员）
 /待审核             - 查看待审核稿件
-/看稿 <编号>        - 查看稿件详情（截图）
+/搜稿 <关键词>      - 搜索稿件内容/昵称
+/看稿 <编号>        - 查看稿件详情（截图+操作记录）
 /过稿 <编号>        - 通过并发布
 /过稿 1-4           - 批量通过 #1~#4
+/定时过稿 <编号> <时间> - 定时发布，如 /定时过稿 12 21:00
+/定时列表           - 查看定时稿件
+/取消定时 <编号>    - 取消定时并退回待审核
 /拒稿 <编号> [理由]  - 拒绝稿件
+/下架 <编号> [理由]  - 从QQ空间删除已发布的说说
+/重发 <编号>        - 重新发布失败的稿件
 /发说说 <内容>      - 直接发布到空间
-/扫码               - 扫码登录QQ空间`
+/扫码 [账号]        - 扫码登录QQ空间（多账号时指定账号名或QQ号）
+/账号               - 查看QQ空间号池状态`
 	ctx.Send(message.Text(help))
 }
 
@@ -588,6 +841,118 @@ func (b *QQBot) handleHelp(ctx *zero.Ctx) {
 // 辅助函数
 // ──────────────────────────────────────────
 
+// audit 记录由 QQ 命令触发的操作
+func (b *QQBot) audit(ctx *zero.Ctx, action string, postID int64, oldStatus, newStatus model.PostStatus, reason string) {
+	e := &model.AuditEvent{
+		Source:    model.SourceBot,
+		Action:    action,
+		PostID:    postID,
+		OldStatus: oldStatus,
+		NewStatus: newStatus,
+		Reason:    reason,
+	}
+	if ctx != nil && ctx.Event != nil {
+		e.ActorID = ctx.Event.UserID
+		if ctx.Event.Sender != nil {
+			e.ActorName = ctx.Event.Sender.NickName
+		}
+	}
+	if err := b.walls.Store().AddAuditEvent(e); err != nil {
+		log.Printf("[QQBot] 写入审计日志失败: %v", err)
+	}
+}
+
+// contributeWall 选择投稿进入的墙：群投稿按来源群选择；私聊投稿只有一个墙时进入该墙，
+// 否则进入投稿者所在来源群的墙，都不在时进入接收所有群的墙
+func (b *QQBot) contributeWall(ctx *zero.Ctx) *wall.Wall {
+	if !b.walls.Multi() {
+		return b.walls.Default()
+	}
+	if g := ctx.Event.GroupID; g != 0 {
+		return b.walls.ByGroup(g)
+	}
+	for _, w := range b.walls.All() {
+		for _, g := range w.Groups {
+			if ctx.GetGroupMemberInfo(g, ctx.Event.UserID, false).Get("user_id").Int() == ctx.Event.UserID {
+				return w
+			}
+		}
+	}
+	return b.walls.ByGroup(0)
+}
+
+// wallOf 命令所在群对应的墙：墙的管理群优先，其次是来源群。
+// 私聊、多个墙共用的管理群或不属于任何墙的群返回 nil，由调用方按全部墙处理
+func (b *QQBot) wallOf(ctx *zero.Ctx) *wall.Wall {
+	if !b.walls.Multi() {
+		return b.walls.Default()
+	}
+	g := ctx.Event.GroupID
+	if g == 0 {
+		return nil
+	}
+	if w := b.walls.ByManageGroup(g); w != nil {
+		return w
+	}
+	for _, w := range b.walls.All() {
+		if w.ManageGroup == g {
+			return nil // 共用的管理群
+		}
+	}
+	return b.walls.ByGroup(g)
+}
+
+// storeFor 命令所在群属于某个墙时只查询该墙的稿件，否则查询所有墙
+func (b *QQBot) storeFor(ctx *zero.Ctx) *store.Store {
+	if w := b.wallOf(ctx); w != nil {
+		return w.Store
+	}
+	return b.walls.Store()
+}
+
+// wallOfPost 稿件所属的墙，墙已从配置中移除时返回 nil
+func (b *QQBot) wallOfPost(p *model.Post) *wall.Wall {
+	return b.walls.Get(p.WallID)
+}
+
+// wallTag 多墙时在消息中标注稿件所属的墙，如 "【一中】"
+func (b *QQBot) wallTag(p *model.Post) string {
+	if !b.walls.Multi() {
+		return ""
+	}
+	if w := b.wallOfPost(p); w != nil {
+		return "【" + w.Name + "】"
+	}
+	return "【" + p.WallID + "】"
+}
+
+// wallBatch 属于同一个墙的稿件
+type wallBatch struct {
+	wall *wall.Wall
+	ids  []int64
+}
+
+// byWall 按所属的墙分组稿件，分组按墙的配置顺序排列；所属的墙未配置的稿件返回在 orphan 中
+func (b *QQBot) byWall(posts []*model.Post) (batches []wallBatch, orphan []*model.Post) {
+	for _, w := range b.walls.All() {
+		batch := wallBatch{wall: w}
+		for _, p := range posts {
+			if p.WallID == w.ID {
+				batch.ids = append(batch.ids, p.ID)
+			}
+		}
+		if len(batch.ids) > 0 {
+			batches = append(batches, batch)
+		}
+	}
+	for _, p := range posts {
+		if b.wallOfPost(p) == nil {
+			orphan = append(orphan, p)
+		}
+	}
+	return batches, orphan
+}
+
 func getArgs(ctx *zero.Ctx) string {
 	if args, ok := ctx.State["args"].(string); ok {
 		return strings.TrimSpace(args)
@@ -616,6 +981,32 @@ func extractImages(ctx *zero.Ctx) []string {
 	return images
 }
 
+// parseScheduleTime 解析定时时间: "21:00"（已过则为明天）、"01-02 21:00"、"2025-01-02 21:00"
+func parseScheduleTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.ParseInLocation("15:04", s, now.Location()); err == nil {
+		at := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+		return at, nil
+	}
+	if t, err := time.ParseInLocation("01-02 15:04", s, now.Location()); err == nil {
+		at := time.Date(now.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+		if !at.After(now) {
+			return time.Time{}, fmt.Errorf("时间 %s 已经过去", s)
+		}
+		return at, nil
+	}
+	if at, err := time.ParseInLocation("2006-01-02 15:04", s, now.Location()); err == nil {
+		if !at.After(now) {
+			return time.Time{}, fmt.Errorf("时间 %s 已经过去", s)
+		}
+		return at, nil
+	}
+	return time.Time{}, fmt.Errorf("无法识别的时间: %s", s)
+}
+
 func parseIDs(s string) ([]int64, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -659,28 +1050,21 @@ func parseIDs(s string) ([]int64, error) {
 	return ids, nil
 }
 
-// resolveImageURL 如果是 http 链接直接返回，如果是 fileID 则调用 Bot 解析
-func resolveImageURL(img string) string {
-	if strings.HasPrefix(img, "http") {
-		return img
+// sendRenderFailures 说说已发布或顺延时，提示其中渲染失败被跳过的稿件
+func sendRenderFailures(ctx *zero.Ctx, res *publish.Result) {
+	if len(res.Requeued) > 0 {
+		ctx.Send(message.Text(fmt.Sprintf("⚠️ 稿件 %s 渲染失败，稍后自动重试", postIDs(res.Requeued))))
 	}
-	var resolved string
-	zero.RangeBot(func(id int64, ctx *zero.Ctx) bool {
-		resolved = ctx.GetImage(img).Get("url").String()
-		return true
-	})
-	if resolved != "" {
-		return resolved
+	if len(res.Failed) > 0 {
+		ctx.Send(message.Text(fmt.Sprintf("❌ 稿件 %s 渲染失败，已标记为发布失败，可用 /重发 重新发布", postIDs(res.Failed))))
 	}
-	return img
 }
 
-// resolvePostImages 克隆 Post 并解析所有图片 URL (仅用于渲染，不保存回DB)
-func resolvePostImages(p *model.Post) *model.Post {
-	clone := *p
-	clone.Images = make([]string, len(p.Images))
-	for i, img := range p.Images {
-		clone.Images[i] = resolveImageURL(img)
+// postIDs 格式化稿件编号列表，如 "#1 #2"
+func postIDs(posts []*model.Post) string {
+	ids := make([]string, len(posts))
+	for i, p := range posts {
+		ids[i] = fmt.Sprintf("#%d", p.ID)
 	}
-	return &clone
+	return strings.Join(ids, " ")
 }
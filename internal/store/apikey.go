@@ -0,0 +1,97 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+// CreateAPIKey 保存 API 密钥，keyHash 为密钥明文的哈希（明文不入库）
+func (s *Store) CreateAPIKey(k *model.APIKey, keyHash string) error {
+	if k.CreateTime == 0 {
+		k.CreateTime = time.Now().Unix()
+	}
+	res, err := s.db.Exec(
+		"INSERT INTO api_keys (account_id,name,prefix,key_hash,scopes,create_time) VALUES (?,?,?,?,?,?)",
+		k.AccountID, k.Name, k.Prefix, keyHash, strings.Join(k.Scopes, ","), k.CreateTime,
+	)
+	if err != nil {
+		return err
+	}
+	k.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// GetAPIKeyByHash 按密钥哈希查找未吊销的密钥，不存在时返回 nil
+func (s *Store) GetAPIKeyByHash(keyHash string) (*model.APIKey, error) {
+	row := s.db.QueryRow(apiKeyCols("WHERE key_hash=? AND revoke_time=0"), keyHash)
+	k, err := scanAPIKey(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return k, err
+}
+
+// ListAPIKeys 列出账号的所有密钥（含已吊销的），accountID 为 0 时列出全部
+func (s *Store) ListAPIKeys(accountID int64) ([]*model.APIKey, error) {
+	where, args := "", []interface{}{}
+	if accountID > 0 {
+		where, args = "WHERE account_id=?", append(args, accountID)
+	}
+	rows, err := s.db.Query(apiKeyCols(where+" ORDER BY id DESC"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	var keys []*model.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey 吊销密钥，accountID 不为 0 时只能吊销该账号的密钥。返回是否吊销了有效的密钥
+func (s *Store) RevokeAPIKey(id, accountID int64) (bool, error) {
+	query := "UPDATE api_keys SET revoke_time=? WHERE id=? AND revoke_time=0"
+	args := []interface{}{time.Now().Unix(), id}
+	if accountID > 0 {
+		query += " AND account_id=?"
+		args = append(args, accountID)
+	}
+	res, err := s.db.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// TouchAPIKey 记录密钥最近使用时间，同一分钟内只写一次
+func (s *Store) TouchAPIKey(id int64) {
+	now := time.Now().Unix()
+	_, _ = s.db.Exec("UPDATE api_keys SET last_used=? WHERE id=? AND last_used<?", now, id, now-60)
+}
+
+func apiKeyCols(where string) string {
+	return "SELECT id,account_id,name,prefix,scopes,create_time,last_used,revoke_time FROM api_keys " + where
+}
+
+func scanAPIKey(sc rowScanner) (*model.APIKey, error) {
+	var k model.APIKey
+	var scopes string
+	if err := sc.Scan(&k.ID, &k.AccountID, &k.Name, &k.Prefix, &scopes, &k.CreateTime, &k.LastUsed, &k.RevokeTime); err != nil {
+		return nil, err
+	}
+	if scopes != "" {
+		k.Scopes = strings.Split(scopes, ",")
+	}
+	return &k, nil
+}
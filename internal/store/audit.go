@@ -0,0 +1,129 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+// AuditFilter 审计日志查询条件, 零值字段表示不过滤
+type AuditFilter struct {
+	PostID  int64
+	ActorID int64
+	Source  model.AuditSource
+	Action  string
+	Since   int64 // 起始时间 (含)
+	Until   int64 // 截止时间 (含)
+	Before  int64 // 只返回 id 小于该值的日志，用于游标分页
+}
+
+// AddAuditEvent 写入一条审计日志。
+// 墙视图中的日志归属该墙；不区分墙时按稿件所属的墙记录，与稿件无关的操作不属于任何墙。
+// 稿件状态变化同时写入回调发件箱（见 emitAuditWebhook）。
+func (s *Store) AddAuditEvent(e *model.AuditEvent) error {
+	if e.CreateTime == 0 {
+		e.CreateTime = time.Now().Unix()
+	}
+	if e.WallID == "" {
+		e.WallID = s.wall
+	}
+	if e.WallID == "" && e.PostID > 0 {
+		if err := s.db.QueryRow("SELECT wall_id FROM posts WHERE id=?", e.PostID).Scan(&e.WallID); err != nil && err != sql.ErrNoRows {
+			return err
+		}
+	}
+	res, err := s.db.Exec(
+		`INSERT INTO audit_events (actor_id,actor_name,source,action,post_id,old_status,new_status,reason,create_time,wall_id)
+		 VALUES (?,?,?,?,?,?,?,?,?,?)`,
+		e.ActorID, e.ActorName, string(e.Source), e.Action, e.PostID,
+		string(e.OldStatus), string(e.NewStatus), e.Reason, e.CreateTime, e.WallID,
+	)
+	if err != nil {
+		return err
+	}
+	e.ID, _ = res.LastInsertId()
+	s.emitAuditWebhook(e)
+	return nil
+}
+
+// ListAuditEvents 按条件分页列出审计日志（最新在前）
+func (s *Store) ListAuditEvents(f AuditFilter, limit, offset int) ([]*model.AuditEvent, error) {
+	where, args := f.where()
+	args = append(args, limit, offset)
+	rows, err := s.db.Query(auditCols(s.scoped(where)+" ORDER BY id DESC LIMIT ? OFFSET ?"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	return scanAuditEvents(rows)
+}
+
+// ListPostHistory 列出单条稿件的全部审计日志（按时间顺序）
+func (s *Store) ListPostHistory(postID int64) ([]*model.AuditEvent, error) {
+	rows, err := s.db.Query(auditCols(s.scoped("WHERE post_id=? ORDER BY id ASC")), postID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	return scanAuditEvents(rows)
+}
+
+func (f AuditFilter) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if f.PostID > 0 {
+		conds = append(conds, "post_id=?")
+		args = append(args, f.PostID)
+	}
+	if f.ActorID > 0 {
+		conds = append(conds, "actor_id=?")
+		args = append(args, f.ActorID)
+	}
+	if f.Source != "" {
+		conds = append(conds, "source=?")
+		args = append(args, string(f.Source))
+	}
+	if f.Action != "" {
+		conds = append(conds, "action=?")
+		args = append(args, f.Action)
+	}
+	if f.Since > 0 {
+		conds = append(conds, "create_time>=?")
+		args = append(args, f.Since)
+	}
+	if f.Until > 0 {
+		conds = append(conds, "create_time<=?")
+		args = append(args, f.Until)
+	}
+	if f.Before > 0 {
+		conds = append(conds, "id<?")
+		args = append(args, f.Before)
+	}
+	if len(conds) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conds, " AND "), args
+}
+
+func auditCols(where string) string {
+	return "SELECT id,actor_id,actor_name,source,action,post_id,old_status,new_status,reason,create_time,wall_id FROM audit_events " + where
+}
+
+func scanAuditEvents(rows *sql.Rows) ([]*model.AuditEvent, error) {
+	var events []*model.AuditEvent
+	for rows.Next() {
+		var e model.AuditEvent
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.ActorName, &e.Source, &e.Action, &e.PostID,
+			&e.OldStatus, &e.NewStatus, &e.Reason, &e.CreateTime, &e.WallID); err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
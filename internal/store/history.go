@@ -0,0 +1,64 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+// AddPublishRecord 记录一次成功发布的说说（合集记一条）及发布账号，用于发布额度统计和下架时选择账号
+func (s *Store) AddPublishRecord(tid string, uin int64, postCount int) error {
+	wall := s.wall
+	if wall == "" {
+		wall = model.DefaultWall
+	}
+	_, err := s.db.Exec(
+		"INSERT INTO publish_history (tid,uin,post_count,create_time,wall_id) VALUES (?,?,?,?,?)",
+		tid, uin, postCount, time.Now().Unix(), wall,
+	)
+	return err
+}
+
+// CountPublishedSince 统计 since 之后发布的说说条数
+func (s *Store) CountPublishedSince(since int64) (int, error) {
+	var n int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM publish_history "+s.scoped("WHERE create_time>=?"), since).Scan(&n)
+	return n, err
+}
+
+// PublishUIN 返回发布该说说的QQ号，没有发布记录时返回 0
+func (s *Store) PublishUIN(tid string) (int64, error) {
+	var uin int64
+	err := s.db.QueryRow(
+		"SELECT uin FROM publish_history "+s.scoped("WHERE tid=?")+" ORDER BY id DESC LIMIT 1", tid,
+	).Scan(&uin)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return uin, err
+}
+
+// PublishCountsByUIN 按发布账号统计 since 之后发布的说说条数
+func (s *Store) PublishCountsByUIN(since int64) (map[int64]int, error) {
+	rows, err := s.db.Query(
+		"SELECT uin, COUNT(*) FROM publish_history "+s.scoped("WHERE create_time>=?")+" GROUP BY uin", since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	counts := make(map[int64]int)
+	for rows.Next() {
+		var uin int64
+		var n int
+		if err := rows.Scan(&uin, &n); err != nil {
+			return nil, err
+		}
+		counts[uin] = n
+	}
+	return counts, rows.Err()
+}
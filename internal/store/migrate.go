@@ -0,0 +1,383 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// ErrSchemaTooNew 数据库版本高于当前程序支持的版本
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+
+// Migration 一次数据库结构升级
+//
+// 版本号必须严格递增, 已发布的步骤不能再修改, 只能追加新的步骤。
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// migrations 按版本排序的全部升级步骤
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "init",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS posts (
+				id          INTEGER PRIMARY KEY AUTOINCREMENT,
+				uin         INTEGER NOT NULL DEFAULT 0,
+				name        TEXT    NOT NULL DEFAULT '',
+				group_id    INTEGER NOT NULL DEFAULT 0,
+				text        TEXT    NOT NULL DEFAULT '',
+				images      TEXT    NOT NULL DEFAULT '[]',
+				anon        INTEGER NOT NULL DEFAULT 0,
+				status      TEXT    NOT NULL DEFAULT 'pending',
+				reason      TEXT    NOT NULL DEFAULT '',
+				tid         TEXT    NOT NULL DEFAULT '',
+				avatar_url  TEXT    NOT NULL DEFAULT '',
+				create_time INTEGER NOT NULL DEFAULT 0,
+				update_time INTEGER NOT NULL DEFAULT 0
+			);
+			CREATE INDEX IF NOT EXISTS idx_posts_status ON posts(status);
+
+			CREATE TABLE IF NOT EXISTS accounts (
+				id            INTEGER PRIMARY KEY AUTOINCREMENT,
+				username      TEXT NOT NULL UNIQUE,
+				password_hash TEXT NOT NULL,
+				salt          TEXT NOT NULL,
+				role          TEXT NOT NULL DEFAULT 'user',
+				create_time   INTEGER NOT NULL DEFAULT 0
+			);
+
+			CREATE TABLE IF NOT EXISTS sessions (
+				token      TEXT PRIMARY KEY,
+				account_id INTEGER NOT NULL,
+				expire_time INTEGER NOT NULL
+			);
+		`,
+	},
+	{
+		Version: 2,
+		Name:    "publish_lease",
+		SQL: `
+			ALTER TABLE posts ADD COLUMN lease_owner  TEXT    NOT NULL DEFAULT '';
+			ALTER TABLE posts ADD COLUMN lease_expire INTEGER NOT NULL DEFAULT 0;
+		`,
+	},
+	{
+		Version: 3,
+		Name:    "audit_events",
+		SQL: `
+			CREATE TABLE audit_events (
+				id          INTEGER PRIMARY KEY AUTOINCREMENT,
+				actor_id    INTEGER NOT NULL DEFAULT 0,
+				actor_name  TEXT    NOT NULL DEFAULT '',
+				source      TEXT    NOT NULL DEFAULT '',
+				action      TEXT    NOT NULL DEFAULT '',
+				post_id     INTEGER NOT NULL DEFAULT 0,
+				old_status  TEXT    NOT NULL DEFAULT '',
+				new_status  TEXT    NOT NULL DEFAULT '',
+				reason      TEXT    NOT NULL DEFAULT '',
+				create_time INTEGER NOT NULL DEFAULT 0
+			);
+			CREATE INDEX idx_audit_post ON audit_events(post_id);
+			CREATE INDEX idx_audit_time ON audit_events(create_time);
+		`,
+	},
+	{
+		Version: 4,
+		Name:    "posts_fts",
+		SQL: `
+			CREATE VIRTUAL TABLE posts_fts USING fts5(
+				text, name,
+				content='posts', content_rowid='id', tokenize='trigram'
+			);
+			CREATE TRIGGER posts_fts_ai AFTER INSERT ON posts BEGIN
+				INSERT INTO posts_fts(rowid, text, name) VALUES (new.id, new.text, new.name);
+			END;
+			CREATE TRIGGER posts_fts_ad AFTER DELETE ON posts BEGIN
+				INSERT INTO posts_fts(posts_fts, rowid, text, name) VALUES ('delete', old.id, old.text, old.name);
+			END;
+			CREATE TRIGGER posts_fts_au AFTER UPDATE OF text, name ON posts BEGIN
+				INSERT INTO posts_fts(posts_fts, rowid, text, name) VALUES ('delete', old.id, old.text, old.name);
+				INSERT INTO posts_fts(rowid, text, name) VALUES (new.id, new.text, new.name);
+			END;
+			INSERT INTO posts_fts(posts_fts) VALUES ('rebuild');
+		`,
+	},
+	{
+		Version: 5,
+		Name:    "soft_delete",
+		SQL: `
+			ALTER TABLE posts ADD COLUMN deleted_from TEXT    NOT NULL DEFAULT '';
+			ALTER TABLE posts ADD COLUMN deleted_by   TEXT    NOT NULL DEFAULT '';
+			ALTER TABLE posts ADD COLUMN delete_time  INTEGER NOT NULL DEFAULT 0;
+		`,
+	},
+	{
+		Version: 6,
+		Name:    "external_id",
+		SQL: `
+			ALTER TABLE posts ADD COLUMN external_id TEXT NOT NULL DEFAULT '';
+			CREATE UNIQUE INDEX idx_posts_external_id ON posts(external_id) WHERE external_id!='';
+		`,
+	},
+	{
+		Version: 7,
+		Name:    "qzone_sessions",
+		SQL: `
+			CREATE TABLE qzone_sessions (
+				uin            INTEGER PRIMARY KEY,
+				cookie         TEXT    NOT NULL DEFAULT '',
+				validated_time INTEGER NOT NULL DEFAULT 0,
+				update_time    INTEGER NOT NULL DEFAULT 0
+			);
+		`,
+	},
+	{
+		Version: 8,
+		Name:    "publish_at",
+		SQL: `
+			ALTER TABLE posts ADD COLUMN publish_at INTEGER NOT NULL DEFAULT 0;
+		`,
+	},
+	{
+		Version: 9,
+		Name:    "tid_index",
+		SQL: `
+			CREATE INDEX idx_posts_tid ON posts(tid) WHERE tid!='';
+		`,
+	},
+	{
+		Version: 10,
+		Name:    "clear_placeholder_tids",
+		SQL: `
+			-- 旧版本在QQ空间未返回 TID 时写入 published_<秒级时间戳>，同一秒发布的说说会被当成同一条；
+			-- 改为留空，已发布且 TID 为空表示 TID 未知
+			UPDATE posts SET tid='' WHERE tid LIKE 'published\_%' ESCAPE '\';
+		`,
+	},
+	{
+		Version: 11,
+		Name:    "publish_history",
+		SQL: `
+			CREATE TABLE publish_history (
+				id          INTEGER PRIMARY KEY AUTOINCREMENT,
+				tid         TEXT    NOT NULL DEFAULT '',
+				uin         INTEGER NOT NULL DEFAULT 0,
+				post_count  INTEGER NOT NULL DEFAULT 0,
+				create_time INTEGER NOT NULL DEFAULT 0
+			);
+			CREATE INDEX idx_publish_history_time ON publish_history(create_time);
+		`,
+	},
+	{
+		Version: 12,
+		Name:    "publish_attempts",
+		SQL: `
+			ALTER TABLE posts ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE posts ADD COLUMN next_attempt INTEGER NOT NULL DEFAULT 0;
+		`,
+	},
+	{
+		Version: 13,
+		Name:    "publish_history_tid",
+		SQL: `
+			CREATE INDEX idx_publish_history_tid ON publish_history(tid);
+		`,
+	},
+	{
+		Version: 14,
+		Name:    "wall_id",
+		SQL: `
+			ALTER TABLE posts ADD COLUMN wall_id TEXT NOT NULL DEFAULT 'default';
+			CREATE INDEX idx_posts_wall_status ON posts(wall_id, status);
+			-- 外部编号按墙去重，同一份文件可以分别导入不同的墙
+			DROP INDEX idx_posts_external_id;
+			CREATE UNIQUE INDEX idx_posts_external_id ON posts(wall_id, external_id) WHERE external_id!='';
+			ALTER TABLE audit_events ADD COLUMN wall_id TEXT NOT NULL DEFAULT 'default';
+			CREATE INDEX idx_audit_events_wall ON audit_events(wall_id, id);
+			ALTER TABLE publish_history ADD COLUMN wall_id TEXT NOT NULL DEFAULT 'default';
+			CREATE INDEX idx_publish_history_wall ON publish_history(wall_id, create_time);
+		`,
+	},
+	{
+		Version: 15,
+		Name:    "webhooks",
+		SQL: `
+			CREATE TABLE webhook_subscriptions (
+				id          INTEGER PRIMARY KEY AUTOINCREMENT,
+				url         TEXT    NOT NULL DEFAULT '',
+				secret      TEXT    NOT NULL DEFAULT '',
+				events      TEXT    NOT NULL DEFAULT '',
+				wall_id     TEXT    NOT NULL DEFAULT '',
+				enabled     INTEGER NOT NULL DEFAULT 1,
+				create_time INTEGER NOT NULL DEFAULT 0
+			);
+			CREATE TABLE webhook_outbox (
+				id              INTEGER PRIMARY KEY AUTOINCREMENT,
+				subscription_id INTEGER NOT NULL DEFAULT 0,
+				event_id        TEXT    NOT NULL DEFAULT '',
+				event           TEXT    NOT NULL DEFAULT '',
+				wall_id         TEXT    NOT NULL DEFAULT '',
+				payload         TEXT    NOT NULL DEFAULT '',
+				status          TEXT    NOT NULL DEFAULT 'pending',
+				attempts        INTEGER NOT NULL DEFAULT 0,
+				next_attempt    INTEGER NOT NULL DEFAULT 0,
+				response_code   INTEGER NOT NULL DEFAULT 0,
+				last_error      TEXT    NOT NULL DEFAULT '',
+				create_time     INTEGER NOT NULL DEFAULT 0,
+				update_time     INTEGER NOT NULL DEFAULT 0
+			);
+			CREATE INDEX idx_webhook_outbox_due ON webhook_outbox(status, next_attempt);
+			CREATE INDEX idx_webhook_outbox_sub ON webhook_outbox(subscription_id, id);
+		`,
+	},
+	{
+		Version: 16,
+		Name:    "api_keys",
+		SQL: `
+			CREATE TABLE api_keys (
+				id           INTEGER PRIMARY KEY AUTOINCREMENT,
+				account_id   INTEGER NOT NULL DEFAULT 0,
+				name         TEXT    NOT NULL DEFAULT '',
+				prefix       TEXT    NOT NULL DEFAULT '',
+				key_hash     TEXT    NOT NULL UNIQUE,
+				scopes       TEXT    NOT NULL DEFAULT '',
+				create_time  INTEGER NOT NULL DEFAULT 0,
+				last_used    INTEGER NOT NULL DEFAULT 0,
+				revoke_time  INTEGER NOT NULL DEFAULT 0
+			);
+			CREATE INDEX idx_api_keys_account ON api_keys(account_id);
+		`,
+	},
+	{
+		Version: 17,
+		Name:    "account_roles",
+		SQL: `
+			ALTER TABLE accounts ADD COLUMN disabled INTEGER NOT NULL DEFAULT 0;
+			-- 旧版本只有 admin 能登录后台，其余角色升级为停用的只读账号，由管理员按需启用和提升
+			UPDATE accounts SET role='viewer', disabled=1 WHERE role NOT IN ('admin','reviewer','viewer');
+		`,
+	},
+}
+
+// LatestSchemaVersion 当前程序支持的最高数据库版本
+func LatestSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// PendingMigrations 只读地检查数据库, 返回当前版本和尚未执行的升级步骤 (dry-run)。
+// 数据库文件不存在时返回包装了 os.ErrNotExist 的错误, 不会创建空文件
+func PendingMigrations(dbPath string) (int, []Migration, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return 0, nil, fmt.Errorf("stat database: %w", err)
+	}
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return 0, nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	current, err := schemaVersion(db)
+	if err != nil {
+		return 0, nil, err
+	}
+	if current > LatestSchemaVersion() {
+		return current, nil, fmt.Errorf("%w: db=%d, binary=%d", ErrSchemaTooNew, current, LatestSchemaVersion())
+	}
+	return current, pendingAfter(current), nil
+}
+
+// SchemaVersion 返回数据库当前版本
+func (s *Store) SchemaVersion() (int, error) {
+	return schemaVersion(s.db)
+}
+
+// migrate 依次在事务中执行尚未应用的升级步骤
+func (s *Store) migrate() error {
+	if _, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version      INTEGER PRIMARY KEY,
+			name         TEXT    NOT NULL DEFAULT '',
+			applied_time INTEGER NOT NULL DEFAULT 0
+		)
+	`); err != nil {
+		return fmt.Errorf("create schema_version: %w", err)
+	}
+
+	current, err := schemaVersion(s.db)
+	if err != nil {
+		return err
+	}
+	if current > LatestSchemaVersion() {
+		return fmt.Errorf("%w: db=%d, binary=%d", ErrSchemaTooNew, current, LatestSchemaVersion())
+	}
+
+	for _, m := range pendingAfter(current) {
+		if err := s.applyMigration(m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		log.Printf("[Store] 数据库已升级到 v%d (%s)", m.Version, m.Name)
+	}
+	return nil
+}
+
+func (s *Store) applyMigration(m Migration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.Exec(m.SQL); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO schema_version (version,name,applied_time) VALUES (?,?,?)",
+		m.Version, m.Name, time.Now().Unix(),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func schemaVersion(db *sql.DB) (int, error) {
+	var n int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='schema_version'",
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("read schema_version: %w", err)
+	}
+	if n == 0 {
+		return 0, nil
+	}
+
+	var v sql.NullInt64
+	if err := db.QueryRow("SELECT MAX(version) FROM schema_version").Scan(&v); err != nil {
+		return 0, fmt.Errorf("read schema_version: %w", err)
+	}
+	return int(v.Int64), nil
+}
+
+func pendingAfter(version int) []Migration {
+	var pending []Migration
+	for _, m := range migrations {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMigrate 测试数据库版本升级
+// 运行方法: go test -v ./internal/store/ -run TestMigrate
+func TestMigrate(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "data.db")
+
+	// 1. 数据库不存在时 dry-run 报错且不创建文件; 空文件视为新库, 全部步骤待执行且不创建任何表
+	if _, _, err := PendingMigrations(dbPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("数据库不存在时期望 os.ErrNotExist, 实际 %v", err)
+	}
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Fatalf("dry-run 不应创建数据库文件: %v", err)
+	}
+	if err := os.WriteFile(dbPath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	current, pending, err := PendingMigrations(dbPath)
+	if err != nil {
+		t.Fatalf("dry-run 失败: %v", err)
+	}
+	if current != 0 || len(pending) != len(migrations) {
+		t.Fatalf("新库应为 v0 且有 %d 个待执行步骤, 实际 v%d / %d", len(migrations), current, len(pending))
+	}
+
+	// 2. 打开即升级到最新版本
+	st, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	if v, _ := st.SchemaVersion(); v != LatestSchemaVersion() {
+		t.Fatalf("期望版本 v%d, 实际 v%d", LatestSchemaVersion(), v)
+	}
+	_ = st.Close()
+
+	// 3. 重复打开不会重复执行
+	if _, pending, _ = PendingMigrations(dbPath); len(pending) != 0 {
+		t.Fatalf("升级后仍有 %d 个待执行步骤", len(pending))
+	}
+	st, err = New(dbPath)
+	if err != nil {
+		t.Fatalf("重复打开失败: %v", err)
+	}
+
+	// 4. 数据库版本高于程序时拒绝启动
+	if _, err := st.db.Exec("INSERT INTO schema_version (version,name) VALUES (?, 'future')", LatestSchemaVersion()+1); err != nil {
+		t.Fatalf("写入版本失败: %v", err)
+	}
+	_ = st.Close()
+	if _, err := New(dbPath); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("期望 ErrSchemaTooNew, 实际 %v", err)
+	}
+}
+
+// TestMigrateLegacyData 测试旧数据升级：user 角色账号变为停用的只读账号（管理员不受影响），
+// 占位 TID published_<时间戳> 被清空为 TID 未知
+// 运行方法: go test -v ./internal/store/ -run TestMigrateLegacyData
+func TestMigrateLegacyData(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "data.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := &Store{db: db}
+	if _, err := db.Exec("CREATE TABLE schema_version (version INTEGER PRIMARY KEY, name TEXT NOT NULL DEFAULT '', applied_time INTEGER NOT NULL DEFAULT 0)"); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range migrations {
+		if m.Version >= 10 {
+			break
+		}
+		if err := legacy.applyMigration(m); err != nil {
+			t.Fatalf("migration %d: %v", m.Version, err)
+		}
+	}
+	if _, err := db.Exec("INSERT INTO accounts (username,password_hash,salt,role) VALUES ('root','','','admin'), ('bob','','','user')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO posts (text,status,tid) VALUES ('a','published','published_1700000000'), ('b','published','t1')"); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+
+	st, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("升级失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+
+	root, _ := st.GetAccount("root")
+	if root == nil || root.Role != "admin" || root.Disabled {
+		t.Fatalf("管理员不应受影响: %+v", root)
+	}
+	bob, _ := st.GetAccount("bob")
+	if bob == nil || bob.Role != "viewer" || !bob.Disabled {
+		t.Fatalf("user 账号应升级为停用的 viewer: %+v", bob)
+	}
+	if p, _ := st.GetPost(1); p == nil || p.TID != "" {
+		t.Fatalf("占位 TID 应被清空: %+v", p)
+	}
+	if p, _ := st.GetPost(2); p == nil || p.TID != "t1" {
+		t.Fatalf("真实 TID 不应受影响: %+v", p)
+	}
+}
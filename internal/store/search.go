@@ -0,0 +1,115 @@
+package store
+
+import (
+	"strings"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+// SearchQuery 投稿搜索条件, 零值字段表示不过滤
+type SearchQuery struct {
+	Keyword string           // 空格分隔的多个关键词, 全部命中才返回
+	Status  model.PostStatus // 为空时不含回收站
+	Since   int64            // 投稿时间起 (含)
+	Until   int64            // 投稿时间止 (含)
+	UIN     int64
+	GroupID int64
+	Before  int64 // 只返回 id 小于该值的投稿，用于游标分页
+}
+
+// SearchPosts 按关键词(FTS5)与条件搜索投稿（最新在前）
+//
+// posts_fts 使用 trigram 分词以支持中文, 少于 3 个字的关键词无法走索引, 退化为 LIKE 匹配。
+func (s *Store) SearchPosts(q SearchQuery, limit, offset int) ([]*model.Post, error) {
+	where, args := q.where()
+	args = append(args, limit, offset)
+	rows, err := s.db.Query(postCols(s.scoped(where)+" ORDER BY id DESC LIMIT ? OFFSET ?"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	return scanPosts(rows)
+}
+
+// EachPost 按 id 升序分批遍历符合条件的投稿, 每批最多 batch 条, 不会一次性载入整张表。
+// fn 返回错误时停止遍历并返回该错误。
+func (s *Store) EachPost(q SearchQuery, batch int, fn func(*model.Post) error) error {
+	where, args := q.where()
+	var lastID int64
+	for {
+		pageArgs := append(append([]interface{}{}, args...), lastID, batch)
+		rows, err := s.db.Query(postCols(s.scoped(where)+" AND id>? ORDER BY id ASC LIMIT ?"), pageArgs...)
+		if err != nil {
+			return err
+		}
+		posts, err := scanPosts(rows)
+		_ = rows.Close()
+		if err != nil {
+			return err
+		}
+		for _, p := range posts {
+			if err := fn(p); err != nil {
+				return err
+			}
+			lastID = p.ID
+		}
+		if len(posts) < batch {
+			return nil
+		}
+	}
+}
+
+func (q SearchQuery) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+
+	var phrases []string
+	for _, kw := range strings.Fields(q.Keyword) {
+		if len([]rune(kw)) >= 3 {
+			phrases = append(phrases, `"`+strings.ReplaceAll(kw, `"`, `""`)+`"`)
+			continue
+		}
+		like := "%" + escapeLike(kw) + "%"
+		conds = append(conds, `(text LIKE ? ESCAPE '\' OR name LIKE ? ESCAPE '\')`)
+		args = append(args, like, like)
+	}
+	if len(phrases) > 0 {
+		conds = append(conds, "id IN (SELECT rowid FROM posts_fts WHERE posts_fts MATCH ?)")
+		args = append(args, strings.Join(phrases, " AND "))
+	}
+
+	if q.Status != "" {
+		conds = append(conds, "status=?")
+		args = append(args, string(q.Status))
+	} else {
+		conds = append(conds, "status!='deleted'")
+	}
+	if q.Since > 0 {
+		conds = append(conds, "create_time>=?")
+		args = append(args, q.Since)
+	}
+	if q.Until > 0 {
+		conds = append(conds, "create_time<=?")
+		args = append(args, q.Until)
+	}
+	if q.UIN > 0 {
+		conds = append(conds, "uin=?")
+		args = append(args, q.UIN)
+	}
+	if q.GroupID > 0 {
+		conds = append(conds, "group_id=?")
+		args = append(args, q.GroupID)
+	}
+	if q.Before > 0 {
+		conds = append(conds, "id<?")
+		args = append(args, q.Before)
+	}
+	return "WHERE " + strings.Join(conds, " AND "), args
+}
+
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// QzoneSession 保存的 QQ空间登录态，Cookie 已由调用方加密
+type QzoneSession struct {
+	UIN           int64
+	Cookie        string // 密文
+	ValidatedTime int64  // 最近一次通过 GetMyInfo 校验的时间
+	UpdateTime    int64
+}
+
+// SaveQzoneSession 按 UIN 保存（覆盖）登录态
+func (s *Store) SaveQzoneSession(uin int64, sealedCookie string, validatedTime int64) error {
+	_, err := s.db.Exec(`
+		INSERT INTO qzone_sessions (uin,cookie,validated_time,update_time) VALUES (?,?,?,?)
+		ON CONFLICT(uin) DO UPDATE SET cookie=excluded.cookie, validated_time=excluded.validated_time, update_time=excluded.update_time`,
+		uin, sealedCookie, validatedTime, time.Now().Unix(),
+	)
+	return err
+}
+
+// LatestQzoneSession 返回最近校验通过的登录态，没有时返回 nil
+func (s *Store) LatestQzoneSession() (*QzoneSession, error) {
+	var qs QzoneSession
+	err := s.db.QueryRow(
+		"SELECT uin,cookie,validated_time,update_time FROM qzone_sessions ORDER BY validated_time DESC LIMIT 1",
+	).Scan(&qs.UIN, &qs.Cookie, &qs.ValidatedTime, &qs.UpdateTime)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &qs, nil
+}
+
+// GetQzoneSession 返回指定QQ号保存的登录态，没有时返回 nil
+func (s *Store) GetQzoneSession(uin int64) (*QzoneSession, error) {
+	var qs QzoneSession
+	err := s.db.QueryRow(
+		"SELECT uin,cookie,validated_time,update_time FROM qzone_sessions WHERE uin=?", uin,
+	).Scan(&qs.UIN, &qs.Cookie, &qs.ValidatedTime, &qs.UpdateTime)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &qs, nil
+}
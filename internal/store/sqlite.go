@@ -4,8 +4,12 @@ import (
 	"bufio"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -15,8 +19,11 @@ import (
 )
 
 // Store SQLite 持久化存储
+//
+// New 返回的 Store 不区分表白墙，ForWall 返回只读写单个墙稿件的视图，二者共享同一个数据库连接。
 type Store struct {
-	db *sql.DB
+	db   *sql.DB
+	wall string
 }
 
 // New 创建并初始化 SQLite 存储
@@ -33,46 +40,21 @@ func New(dbPath string) (*Store, error) {
 
 	s := &Store{db: db}
 	if err := s.migrate(); err != nil {
+		_ = db.Close()
 		return nil, fmt.Errorf("migrate: %w", err)
 	}
 	return s, nil
 }
 
-func (s *Store) migrate() error {
-	_, err := s.db.Exec(`
-		CREATE TABLE IF NOT EXISTS posts (
-			id          INTEGER PRIMARY KEY AUTOINCREMENT,
-			uin         INTEGER NOT NULL DEFAULT 0,
-			name        TEXT    NOT NULL DEFAULT '',
-			group_id    INTEGER NOT NULL DEFAULT 0,
-			text        TEXT    NOT NULL DEFAULT '',
-			images      TEXT    NOT NULL DEFAULT '[]',
-			anon        INTEGER NOT NULL DEFAULT 0,
-			status      TEXT    NOT NULL DEFAULT 'pending',
-			reason      TEXT    NOT NULL DEFAULT '',
-			tid         TEXT    NOT NULL DEFAULT '',
-			avatar_url  TEXT    NOT NULL DEFAULT '',
-			create_time INTEGER NOT NULL DEFAULT 0,
-			update_time INTEGER NOT NULL DEFAULT 0
-		);
-		CREATE INDEX IF NOT EXISTS idx_posts_status ON posts(status);
-
-		CREATE TABLE IF NOT EXISTS accounts (
-			id            INTEGER PRIMARY KEY AUTOINCREMENT,
-			username      TEXT NOT NULL UNIQUE,
-			password_hash TEXT NOT NULL,
-			salt          TEXT NOT NULL,
-			role          TEXT NOT NULL DEFAULT 'user',
-			create_time   INTEGER NOT NULL DEFAULT 0
-		);
-
-		CREATE TABLE IF NOT EXISTS sessions (
-			token      TEXT PRIMARY KEY,
-			account_id INTEGER NOT NULL,
-			expire_time INTEGER NOT NULL
-		);
-	`)
-	return err
+// ForWall 返回限定在表白墙 wallID 的存储视图：稿件查询、领取和统计只涉及该墙，
+// 新投稿、审计日志和发布记录归属该墙。wallID 只能包含字母、数字、下划线和短横线。
+func (s *Store) ForWall(wallID string) *Store {
+	return &Store{db: s.db, wall: wallID}
+}
+
+// WallID 视图所属的表白墙，不区分墙时为空
+func (s *Store) WallID() string {
+	return s.wall
 }
 
 // ──────────────────────────────────────────
@@ -80,6 +62,9 @@ func (s *Store) migrate() error {
 // ──────────────────────────────────────────
 
 // SavePost 保存投稿, 若 ID==0 则插入并回填 ID, 否则更新
+//
+// 更新时不写租约字段 (租约只由领取和 finishLease 修改); 审核等状态变化请用 SetPostStatus,
+// 避免覆盖已被 Worker 领取的稿件。
 func (s *Store) SavePost(p *model.Post) error {
 	imagesJSON, _ := json.Marshal(p.Images)
 	now := time.Now().Unix()
@@ -88,12 +73,19 @@ func (s *Store) SavePost(p *model.Post) error {
 		if p.CreateTime == 0 {
 			p.CreateTime = now
 		}
+		// 墙视图中的投稿总是归属该墙；不区分墙时使用稿件自带的墙，都为空时归入默认墙
+		if s.wall != "" {
+			p.WallID = s.wall
+		} else if p.WallID == "" {
+			p.WallID = model.DefaultWall
+		}
 		res, err := s.db.Exec(
-			`INSERT INTO posts (uin,name,group_id,text,images,anon,status,reason,tid,avatar_url,create_time,update_time)
-			 VALUES (?,?,?,?,?,?,?,?,?,?,?,?)`,
+			`INSERT INTO posts (uin,name,group_id,text,images,anon,status,reason,tid,avatar_url,create_time,update_time,lease_owner,lease_expire,deleted_from,deleted_by,delete_time,external_id,publish_at,wall_id)
+			 VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`,
 			p.UIN, p.Name, p.GroupID, p.Text, string(imagesJSON),
 			b2i(p.Anon), string(p.Status), p.Reason, p.TID, p.AvatarURL,
-			p.CreateTime, now,
+			p.CreateTime, now, p.LeaseOwner, p.LeaseExpire,
+			string(p.DeletedFrom), p.DeletedBy, p.DeleteTime, p.ExternalID, p.PublishAt, p.WallID,
 		)
 		if err != nil {
 			return err
@@ -101,11 +93,13 @@ func (s *Store) SavePost(p *model.Post) error {
 		p.ID, _ = res.LastInsertId()
 	} else {
 		_, err := s.db.Exec(
-			`UPDATE posts SET uin=?,name=?,group_id=?,text=?,images=?,anon=?,status=?,reason=?,tid=?,avatar_url=?,update_time=?
-			 WHERE id=?`,
+			`UPDATE posts SET uin=?,name=?,group_id=?,text=?,images=?,anon=?,status=?,reason=?,tid=?,avatar_url=?,update_time=?,
+			 deleted_from=?,deleted_by=?,delete_time=?,publish_at=?
+			 `+s.scoped("WHERE id=?"),
 			p.UIN, p.Name, p.GroupID, p.Text, string(imagesJSON),
 			b2i(p.Anon), string(p.Status), p.Reason, p.TID, p.AvatarURL,
-			now, p.ID,
+			now,
+			string(p.DeletedFrom), p.DeletedBy, p.DeleteTime, p.PublishAt, p.ID,
 		)
 		if err != nil {
 			return err
@@ -114,21 +108,136 @@ func (s *Store) SavePost(p *model.Post) error {
 	return nil
 }
 
+// ErrStatusConflict 稿件当前状态不允许该操作（已被 Worker 领取或被其他人处理）
+var ErrStatusConflict = errors.New("post status conflict")
+
+// SetPostStatus 仅当稿件当前状态在 from 中时改为 to, 同时写入理由和定时发布时间。
+// 与 ClaimApprovedPost 互斥: 稿件已被领取或状态已变化时返回 ErrStatusConflict
+func (s *Store) SetPostStatus(id int64, to model.PostStatus, reason string, publishAt int64, from ...model.PostStatus) error {
+	ph := make([]string, len(from))
+	args := []interface{}{string(to), reason, publishAt, time.Now().Unix(), id}
+	for i, st := range from {
+		ph[i] = "?"
+		args = append(args, string(st))
+	}
+	res, err := s.db.Exec(
+		`UPDATE posts SET status=?, reason=?, publish_at=?, update_time=?
+		 `+s.scoped(fmt.Sprintf("WHERE id=? AND status IN (%s)", strings.Join(ph, ","))),
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+// EditPost 修改稿件的文字、昵称和匿名设置, 仅当稿件当前状态在 from 中时生效。
+// 稿件已被 Worker 领取 (正在渲染发布) 或状态已变化时返回 ErrStatusConflict
+func (s *Store) EditPost(p *model.Post, from ...model.PostStatus) error {
+	ph := make([]string, len(from))
+	args := []interface{}{p.Text, p.Name, b2i(p.Anon), time.Now().Unix(), p.ID}
+	for i, st := range from {
+		ph[i] = "?"
+		args = append(args, string(st))
+	}
+	res, err := s.db.Exec(
+		`UPDATE posts SET text=?, name=?, anon=?, update_time=?
+		 `+s.scoped(fmt.Sprintf("WHERE id=? AND status IN (%s)", strings.Join(ph, ","))),
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+// GetPostByExternalID 按外部编号（导入来源中的 ID）查找投稿，不存在时返回 nil
+func (s *Store) GetPostByExternalID(externalID string) (*model.Post, error) {
+	row := s.db.QueryRow(postCols(s.scoped("WHERE external_id=?")), externalID)
+	return scanPost(row)
+}
+
 // GetPost 获取单条投稿
 func (s *Store) GetPost(id int64) (*model.Post, error) {
-	row := s.db.QueryRow(postCols("WHERE id=?"), id)
+	row := s.db.QueryRow(postCols(s.scoped("WHERE id=?")), id)
 	return scanPost(row)
 }
 
-// DeletePost 删除投稿
+// DeletePost 永久删除投稿行（不处理上传文件，见 PurgePost）
 func (s *Store) DeletePost(id int64) error {
-	_, err := s.db.Exec("DELETE FROM posts WHERE id=?", id)
+	_, err := s.db.Exec("DELETE FROM posts "+s.scoped("WHERE id=?"), id)
+	return err
+}
+
+// SoftDeletePost 将投稿移入回收站, 记录删除前状态、删除者与时间。
+// 与 ClaimApprovedPost 互斥: 发布中 (已被领取) 或已在回收站的稿件返回 false
+func (s *Store) SoftDeletePost(id int64, deletedBy string) (bool, error) {
+	now := time.Now().Unix()
+	res, err := s.db.Exec(
+		`UPDATE posts SET deleted_from=status, status='deleted', deleted_by=?, delete_time=?, update_time=?
+		 `+s.scoped("WHERE id=? AND status NOT IN ('deleted','publishing')"),
+		deletedBy, now, now, id,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RestorePost 从回收站恢复投稿到删除前的状态
+func (s *Store) RestorePost(id int64) error {
+	_, err := s.db.Exec(
+		`UPDATE posts SET status=CASE deleted_from WHEN '' THEN 'pending' ELSE deleted_from END,
+		 deleted_from='', deleted_by='', delete_time=0, update_time=?
+		 `+s.scoped("WHERE id=? AND status='deleted'"),
+		time.Now().Unix(), id,
+	)
 	return err
 }
 
+// PurgePost 永久删除投稿及其 uploadDir 下的本地图片
+func (s *Store) PurgePost(p *model.Post, uploadDir string) error {
+	if err := s.DeletePost(p.ID); err != nil {
+		return err
+	}
+	for _, img := range p.Images {
+		if strings.HasPrefix(img, "/uploads/") {
+			_ = os.Remove(filepath.Join(uploadDir, path.Base(img)))
+		}
+	}
+	return nil
+}
+
+// ListDeletedBefore 列出删除时间早于 before 的回收站稿件
+func (s *Store) ListDeletedBefore(before int64) ([]*model.Post, error) {
+	rows, err := s.db.Query(postCols(s.scoped("WHERE status='deleted' AND delete_time < ? ORDER BY id ASC")), before)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	return scanPosts(rows)
+}
+
 // ListByStatus 按状态列出投稿
 func (s *Store) ListByStatus(status model.PostStatus) ([]*model.Post, error) {
-	rows, err := s.db.Query(postCols("WHERE status=? ORDER BY id ASC"), string(status))
+	rows, err := s.db.Query(postCols(s.scoped("WHERE status=? ORDER BY id ASC")), string(status))
 	if err != nil {
 		return nil, err
 	}
@@ -149,7 +258,7 @@ func (s *Store) GetPostsByIDs(ids []int64) ([]*model.Post, error) {
 		ph[i] = "?"
 		args[i] = id
 	}
-	q := fmt.Sprintf(postCols("WHERE id IN (%s) ORDER BY id ASC"), strings.Join(ph, ","))
+	q := fmt.Sprintf(postCols(s.scoped("WHERE id IN (%s) ORDER BY id ASC")), strings.Join(ph, ","))
 	rows, err := s.db.Query(q, args...)
 	if err != nil {
 		return nil, err
@@ -160,10 +269,10 @@ func (s *Store) GetPostsByIDs(ids []int64) ([]*model.Post, error) {
 	return scanPosts(rows)
 }
 
-// GetApprovedPosts 获取已通过但还未发布(tid=”)的投稿
-func (s *Store) GetApprovedPosts(limit int) ([]*model.Post, error) {
+// ListAll 分页列出所有投稿（最新在前，不含回收站）
+func (s *Store) ListAll(limit, offset int) ([]*model.Post, error) {
 	rows, err := s.db.Query(
-		postCols("WHERE status='approved' AND tid='' ORDER BY id ASC LIMIT ?"), limit,
+		postCols(s.scoped("WHERE status!='deleted' ORDER BY id DESC LIMIT ? OFFSET ?")), limit, offset,
 	)
 	if err != nil {
 		return nil, err
@@ -174,32 +283,301 @@ func (s *Store) GetApprovedPosts(limit int) ([]*model.Post, error) {
 	return scanPosts(rows)
 }
 
-// ListAll 分页列出所有投稿（最新在前）
-func (s *Store) ListAll(limit, offset int) ([]*model.Post, error) {
+// CountByStatus 统计各状态数量
+func (s *Store) CountByStatus(status model.PostStatus) (int, error) {
+	var n int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM posts "+s.scoped("WHERE status=?"), string(status)).Scan(&n)
+	return n, err
+}
+
+// CountAll 统计全部投稿数量（不含回收站）
+func (s *Store) CountAll() (int, error) {
+	var n int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM posts " + s.scoped("WHERE status!='deleted'")).Scan(&n)
+	return n, err
+}
+
+// ──────────────────────────────────────────
+// 发布租约
+// ──────────────────────────────────────────
+
+// ErrLeaseLost 租约已过期或被其他 Worker 接管
+var ErrLeaseLost = errors.New("publish lease lost")
+
+// ClaimApprovedPost 原子地领取最早一条已到发布时间的稿件, 置为 publishing 并写入租约。
+// 定时稿件按 publish_at 先后领取, 退避中的稿件 (next_attempt 未到) 跳过; 没有可领取的稿件时返回 nil, nil。
+func (s *Store) ClaimApprovedPost(owner string, lease time.Duration) (*model.Post, error) {
+	now := time.Now()
+	var id int64
+	err := s.db.QueryRow(
+		`UPDATE posts SET status=?, lease_owner=?, lease_expire=?, update_time=?
+		 WHERE id = (SELECT id FROM posts `+s.scoped("WHERE status='approved' AND tid='' AND publish_at<=? AND next_attempt<=?")+`
+		             ORDER BY publish_at ASC, id ASC LIMIT 1)
+		   AND status='approved'
+		 RETURNING id`,
+		string(model.StatusPublishing), owner, now.Add(lease).Unix(), now.Unix(), now.Unix(), now.Unix(),
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetPost(id)
+}
+
+// ClaimApprovedPosts 原子领取最多 limit 条已到发布时间的稿件（合集模式），按发布时间先后返回
+func (s *Store) ClaimApprovedPosts(owner string, lease time.Duration, limit int) ([]*model.Post, error) {
+	now := time.Now()
 	rows, err := s.db.Query(
-		postCols("ORDER BY id DESC LIMIT ? OFFSET ?"), limit, offset,
+		`UPDATE posts SET status=?, lease_owner=?, lease_expire=?, update_time=?
+		 WHERE id IN (SELECT id FROM posts `+s.scoped("WHERE status='approved' AND tid='' AND publish_at<=? AND next_attempt<=?")+`
+		              ORDER BY publish_at ASC, id ASC LIMIT ?)
+		   AND status='approved'
+		 RETURNING id`,
+		string(model.StatusPublishing), owner, now.Add(lease).Unix(), now.Unix(), now.Unix(), now.Unix(), limit,
 	)
 	if err != nil {
 		return nil, err
 	}
+	ids, err := scanIDs(rows)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := s.GetPostsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(posts, func(i, j int) bool {
+		if posts[i].PublishAt != posts[j].PublishAt {
+			return posts[i].PublishAt < posts[j].PublishAt
+		}
+		return posts[i].ID < posts[j].ID
+	})
+	return posts, nil
+}
+
+// ClaimPendingPosts 原子领取指定的待审核稿件（过稿后立即发布），已被处理的稿件会被跳过，按 ID 升序返回
+func (s *Store) ClaimPendingPosts(ids []int64, owner string, lease time.Duration) ([]*model.Post, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	ph := make([]string, len(ids))
+	args := []interface{}{string(model.StatusPublishing), owner, now.Add(lease).Unix(), now.Unix()}
+	for i, id := range ids {
+		ph[i] = "?"
+		args = append(args, id)
+	}
+	rows, err := s.db.Query(fmt.Sprintf(
+		`UPDATE posts SET status=?, lease_owner=?, lease_expire=?, update_time=?
+		 %s
+		 RETURNING id`, s.scoped(fmt.Sprintf("WHERE id IN (%s) AND status='pending'", strings.Join(ph, ",")))), args...)
+	if err != nil {
+		return nil, err
+	}
+	claimed, err := scanIDs(rows)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetPostsByIDs(claimed)
+}
+
+// CountDueApproved 统计已到发布时间（且不在退避中）、等待 Worker 领取的稿件
+func (s *Store) CountDueApproved() (int, error) {
+	var n int
+	now := time.Now().Unix()
+	err := s.db.QueryRow("SELECT COUNT(*) FROM posts "+s.scoped("WHERE status='approved' AND tid='' AND publish_at<=? AND next_attempt<=?"), now, now).Scan(&n)
+	return n, err
+}
+
+// ListScheduled 列出尚未到发布时间的定时稿件（最早在前）
+func (s *Store) ListScheduled() ([]*model.Post, error) {
+	rows, err := s.db.Query(postCols(s.scoped("WHERE status='approved' AND publish_at>? ORDER BY publish_at ASC, id ASC")), time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
 	defer func() {
 		_ = rows.Close()
 	}()
 	return scanPosts(rows)
 }
 
-// CountByStatus 统计各状态数量
-func (s *Store) CountByStatus(status model.PostStatus) (int, error) {
+// CountScheduled 统计尚未到发布时间的定时稿件
+func (s *Store) CountScheduled() (int, error) {
 	var n int
-	err := s.db.QueryRow("SELECT COUNT(*) FROM posts WHERE status=?", string(status)).Scan(&n)
+	err := s.db.QueryRow("SELECT COUNT(*) FROM posts "+s.scoped("WHERE status='approved' AND publish_at>?"), time.Now().Unix()).Scan(&n)
 	return n, err
 }
 
-// CountAll 统计全部投稿数量
-func (s *Store) CountAll() (int, error) {
-	var n int
-	err := s.db.QueryRow("SELECT COUNT(*) FROM posts").Scan(&n)
-	return n, err
+// CancelSchedule 取消尚未发布的定时稿件, 退回待审核。
+// 与 ClaimApprovedPost 互斥: 已被 Worker 领取或已到时间的稿件返回 false。
+func (s *Store) CancelSchedule(id int64) (bool, error) {
+	now := time.Now().Unix()
+	res, err := s.db.Exec(
+		"UPDATE posts SET status='pending', publish_at=0, update_time=? "+s.scoped("WHERE id=? AND status='approved' AND publish_at>?"),
+		now, id, now,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ReleaseLeases 租约持有者放弃发布，将稿件退回 approved 等待下次领取（超出发布时段或额度时使用）
+func (s *Store) ReleaseLeases(ids []int64, owner string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	ph := make([]string, len(ids))
+	args := []interface{}{time.Now().Unix(), owner}
+	for i, id := range ids {
+		ph[i] = "?"
+		args = append(args, id)
+	}
+	_, err := s.db.Exec(fmt.Sprintf(
+		`UPDATE posts SET status='approved', lease_owner='', lease_expire=0, update_time=?
+		 WHERE status='publishing' AND lease_owner=? AND id IN (%s)`, strings.Join(ph, ",")), args...)
+	return err
+}
+
+// RenewLeases 租约持有者把稿件的租约延长到 lease 之后, 返回仍由 owner 持有的稿件数。
+// 少于 len(ids) 说明部分租约已过期被回收, 持有者应放弃发布
+func (s *Store) RenewLeases(ids []int64, owner string, lease time.Duration) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	ph := make([]string, len(ids))
+	args := []interface{}{time.Now().Add(lease).Unix(), owner}
+	for i, id := range ids {
+		ph[i] = "?"
+		args = append(args, id)
+	}
+	res, err := s.db.Exec(fmt.Sprintf(
+		`UPDATE posts SET lease_expire=?
+		 WHERE status='publishing' AND lease_owner=? AND id IN (%s)`, strings.Join(ph, ",")), args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// ForcePublished 说说已经发布但租约已丢失时仍回填 TID 并标记为已发布, 避免稿件被再次领取重复发布。
+// 期间被移入回收站的稿件保持删除, 只把删除前状态记为 published, 恢复后可以下架
+func (s *Store) ForcePublished(id int64, tid string) error {
+	_, err := s.db.Exec(
+		`UPDATE posts SET tid=?,
+		   status=CASE status WHEN 'deleted' THEN 'deleted' ELSE 'published' END,
+		   deleted_from=CASE status WHEN 'deleted' THEN 'published' ELSE deleted_from END,
+		   reason='', lease_owner='', lease_expire=0, update_time=?
+		 WHERE id=?`,
+		tid, time.Now().Unix(), id,
+	)
+	return err
+}
+
+// ListByTID 列出发布在同一条说说中的稿件（合集共享 TID）。TID 未知（为空）的稿件不属于任何说说
+func (s *Store) ListByTID(tid string) ([]*model.Post, error) {
+	if tid == "" {
+		return nil, nil
+	}
+	rows, err := s.db.Query(postCols(s.scoped("WHERE tid=? ORDER BY id ASC")), tid)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	return scanPosts(rows)
+}
+
+// RetractByTID 将同一条说说中已发布的稿件标记为已下架，返回被下架的稿件 ID
+func (s *Store) RetractByTID(tid, reason string) ([]int64, error) {
+	if tid == "" {
+		return nil, nil
+	}
+	rows, err := s.db.Query(
+		`UPDATE posts SET status='retracted', reason=?, update_time=?
+		 `+s.scoped("WHERE tid=? AND status='published'")+`
+		 RETURNING id`,
+		reason, time.Now().Unix(), tid,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanIDs(rows)
+}
+
+// RecoverExpiredLeases 将租约已过期的 publishing 稿件退回 approved (崩溃恢复), 返回被退回的稿件 ID
+func (s *Store) RecoverExpiredLeases() ([]int64, error) {
+	now := time.Now().Unix()
+	rows, err := s.db.Query(
+		`UPDATE posts SET status='approved', lease_owner='', lease_expire=0, update_time=?
+		 `+s.scoped("WHERE status='publishing' AND lease_expire < ?")+`
+		 RETURNING id`,
+		now, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanIDs(rows)
+}
+
+// CompletePublish 租约持有者将稿件标记为已发布并回填 TID
+func (s *Store) CompletePublish(id int64, owner, tid string) error {
+	return s.finishLease(id, owner, model.StatusPublished, tid, "", 0, 0)
+}
+
+// FailPublish 租约持有者将稿件标记为发布失败，发布轮数加一
+func (s *Store) FailPublish(id int64, owner, reason string) error {
+	return s.finishLease(id, owner, model.StatusFailed, "", reason, 1, 0)
+}
+
+// RetryLater 租约持有者发布失败后退回 approved，发布轮数加一，next 之前不会被再次领取
+func (s *Store) RetryLater(id int64, owner, reason string, next int64) error {
+	return s.finishLease(id, owner, model.StatusApproved, "", reason, 1, next)
+}
+
+// RequeueFailed 将发布失败的稿件退回 approved 重新发布，清零发布轮数，返回实际退回的稿件 ID
+func (s *Store) RequeueFailed(ids []int64) ([]int64, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	ph := make([]string, len(ids))
+	args := []interface{}{time.Now().Unix()}
+	for i, id := range ids {
+		ph[i] = "?"
+		args = append(args, id)
+	}
+	rows, err := s.db.Query(fmt.Sprintf(
+		`UPDATE posts SET status='approved', reason='', attempts=0, next_attempt=0, update_time=?
+		 %s
+		 RETURNING id`, s.scoped(fmt.Sprintf("WHERE id IN (%s) AND status='failed'", strings.Join(ph, ",")))), args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanIDs(rows)
+}
+
+func (s *Store) finishLease(id int64, owner string, status model.PostStatus, tid, reason string, attempt int, next int64) error {
+	res, err := s.db.Exec(
+		`UPDATE posts SET status=?, tid=?, reason=?, attempts=attempts+?, next_attempt=?, lease_owner='', lease_expire=0, update_time=?
+		 WHERE id=? AND status='publishing' AND lease_owner=?`,
+		string(status), tid, reason, attempt, next, time.Now().Unix(), id, owner,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
 }
 
 // ──────────────────────────────────────────
@@ -217,9 +595,9 @@ func (s *Store) CreateAccount(username, passwordHash, salt, role string) error {
 func (s *Store) GetAccount(username string) (*model.Account, error) {
 	var a model.Account
 	err := s.db.QueryRow(
-		"SELECT id,username,password_hash,salt,role,create_time FROM accounts WHERE username=?",
+		"SELECT id,username,password_hash,salt,role,disabled,create_time FROM accounts WHERE username=?",
 		username,
-	).Scan(&a.ID, &a.Username, &a.PasswordHash, &a.Salt, &a.Role, &a.CreateTime)
+	).Scan(&a.ID, &a.Username, &a.PasswordHash, &a.Salt, &a.Role, &a.Disabled, &a.CreateTime)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -229,9 +607,9 @@ func (s *Store) GetAccount(username string) (*model.Account, error) {
 func (s *Store) GetAccountByID(id int64) (*model.Account, error) {
 	var a model.Account
 	err := s.db.QueryRow(
-		"SELECT id,username,password_hash,salt,role,create_time FROM accounts WHERE id=?",
+		"SELECT id,username,password_hash,salt,role,disabled,create_time FROM accounts WHERE id=?",
 		id,
-	).Scan(&a.ID, &a.Username, &a.PasswordHash, &a.Salt, &a.Role, &a.CreateTime)
+	).Scan(&a.ID, &a.Username, &a.PasswordHash, &a.Salt, &a.Role, &a.Disabled, &a.CreateTime)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -253,6 +631,50 @@ func (s *Store) UpdateAccountPassword(username, passwordHash, salt string) error
 	return err
 }
 
+// ListAccounts 列出所有网页账号，按创建顺序
+func (s *Store) ListAccounts() ([]*model.Account, error) {
+	rows, err := s.db.Query("SELECT id,username,password_hash,salt,role,disabled,create_time FROM accounts ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	var list []*model.Account
+	for rows.Next() {
+		var a model.Account
+		if err := rows.Scan(&a.ID, &a.Username, &a.PasswordHash, &a.Salt, &a.Role, &a.Disabled, &a.CreateTime); err != nil {
+			return nil, err
+		}
+		list = append(list, &a)
+	}
+	return list, rows.Err()
+}
+
+// CountActiveAdmins 未停用的管理员数量，用于防止停用或降级最后一个管理员
+func (s *Store) CountActiveAdmins() (int, error) {
+	var n int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM accounts WHERE role=? AND disabled=0", model.RoleAdmin).Scan(&n)
+	return n, err
+}
+
+// SetAccountRole 修改账号角色
+func (s *Store) SetAccountRole(id int64, role string) error {
+	_, err := s.db.Exec("UPDATE accounts SET role=? WHERE id=?", role, id)
+	return err
+}
+
+// SetAccountDisabled 停用或启用账号，停用时同时删除该账号的所有会话
+func (s *Store) SetAccountDisabled(id int64, disabled bool) error {
+	if _, err := s.db.Exec("UPDATE accounts SET disabled=? WHERE id=?", b2i(disabled), id); err != nil {
+		return err
+	}
+	if disabled {
+		return s.DeleteAccountSessions(id)
+	}
+	return nil
+}
+
 // ──────────────────────────────────────────
 // Session CRUD
 // ──────────────────────────────────────────
@@ -288,10 +710,22 @@ func (s *Store) DeleteSession(token string) error {
 	return err
 }
 
+// DeleteAccountSessions 删除账号的所有会话（停用账号、重置密码后强制重新登录）
+func (s *Store) DeleteAccountSessions(accountID int64) error {
+	_, err := s.db.Exec("DELETE FROM sessions WHERE account_id=?", accountID)
+	return err
+}
+
 func (s *Store) CleanExpiredSessions() {
 	_, _ = s.db.Exec("DELETE FROM sessions WHERE expire_time < ?", time.Now().Unix())
 }
 
+// VacuumInto 在线导出一致的数据库快照到 path (目标文件必须不存在)
+func (s *Store) VacuumInto(path string) error {
+	_, err := s.db.Exec("VACUUM INTO ?", path)
+	return err
+}
+
 // Close 关闭数据库连接
 func (s *Store) Close() error {
 	return s.db.Close()
@@ -343,19 +777,35 @@ func CheckCensor(text string, words []string) (bool, string) {
 // ──────────────────────────────────────────
 
 func postCols(where string) string {
-	return "SELECT id,uin,name,group_id,text,images,anon,status,reason,tid,avatar_url,create_time,update_time FROM posts " + where
+	return "SELECT id,uin,name,group_id,text,images,anon,status,reason,tid,avatar_url,create_time,update_time,lease_owner,lease_expire,deleted_from,deleted_by,delete_time,external_id,publish_at,attempts,next_attempt,wall_id FROM posts " + where
 }
 
-func scanPost(row *sql.Row) (*model.Post, error) {
+// scoped 在墙视图中为 WHERE 子句加上 wall_id 条件（where 为空时生成 WHERE 子句），不区分墙时原样返回。
+// 墙 ID 只含字母、数字、下划线和短横线（见 config.WallEntry），直接拼入 SQL，避免调整各查询的参数顺序。
+func (s *Store) scoped(where string) string {
+	if s.wall == "" {
+		return where
+	}
+	cond := "wall_id='" + strings.ReplaceAll(s.wall, "'", "''") + "'"
+	if where == "" {
+		return "WHERE " + cond
+	}
+	return strings.Replace(where, "WHERE ", "WHERE "+cond+" AND ", 1)
+}
+
+// rowScanner 兼容 *sql.Row 与 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPostRow(sc rowScanner) (*model.Post, error) {
 	var p model.Post
 	var imgs string
 	var anon int
-	err := row.Scan(&p.ID, &p.UIN, &p.Name, &p.GroupID, &p.Text, &imgs, &anon,
-		&p.Status, &p.Reason, &p.TID, &p.AvatarURL, &p.CreateTime, &p.UpdateTime)
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	if err != nil {
+	if err := sc.Scan(&p.ID, &p.UIN, &p.Name, &p.GroupID, &p.Text, &imgs, &anon,
+		&p.Status, &p.Reason, &p.TID, &p.AvatarURL, &p.CreateTime, &p.UpdateTime,
+		&p.LeaseOwner, &p.LeaseExpire, &p.DeletedFrom, &p.DeletedBy, &p.DeleteTime, &p.ExternalID, &p.PublishAt,
+		&p.Attempts, &p.NextAttempt, &p.WallID); err != nil {
 		return nil, err
 	}
 	p.Anon = anon != 0
@@ -363,23 +813,42 @@ func scanPost(row *sql.Row) (*model.Post, error) {
 	return &p, nil
 }
 
+func scanPost(row *sql.Row) (*model.Post, error) {
+	p, err := scanPostRow(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
 func scanPosts(rows *sql.Rows) ([]*model.Post, error) {
 	var posts []*model.Post
 	for rows.Next() {
-		var p model.Post
-		var imgs string
-		var anon int
-		if err := rows.Scan(&p.ID, &p.UIN, &p.Name, &p.GroupID, &p.Text, &imgs, &anon,
-			&p.Status, &p.Reason, &p.TID, &p.AvatarURL, &p.CreateTime, &p.UpdateTime); err != nil {
+		p, err := scanPostRow(rows)
+		if err != nil {
 			return nil, err
 		}
-		p.Anon = anon != 0
-		_ = json.Unmarshal([]byte(imgs), &p.Images)
-		posts = append(posts, &p)
+		posts = append(posts, p)
 	}
 	return posts, rows.Err()
 }
 
+// scanIDs 读取 RETURNING id 的结果并关闭 rows
+func scanIDs(rows *sql.Rows) ([]int64, error) {
+	defer func() {
+		_ = rows.Close()
+	}()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 func b2i(b bool) int {
 	if b {
 		return 1
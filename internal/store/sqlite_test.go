@@ -0,0 +1,657 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	st, err := New(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	return st
+}
+
+// TestClaimApprovedPost 测试多个 Worker 并发领取时每条稿件只被领取一次
+// 运行方法: go test -v ./internal/store/ -run TestClaimApprovedPost
+func TestClaimApprovedPost(t *testing.T) {
+	st := newTestStore(t)
+	const total = 5
+	for i := 0; i < total; i++ {
+		if err := st.SavePost(&model.Post{Text: "hi", Status: model.StatusApproved}); err != nil {
+			t.Fatalf("保存失败: %v", err)
+		}
+	}
+
+	var mu sync.Mutex
+	claimed := map[int64]string{}
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(owner string) {
+			defer wg.Done()
+			for {
+				p, err := st.ClaimApprovedPost(owner, time.Minute)
+				if err != nil {
+					t.Errorf("领取失败: %v", err)
+					return
+				}
+				if p == nil {
+					return
+				}
+				mu.Lock()
+				if prev, ok := claimed[p.ID]; ok {
+					t.Errorf("稿件 #%d 被 %s 和 %s 重复领取", p.ID, prev, owner)
+				}
+				claimed[p.ID] = owner
+				mu.Unlock()
+			}
+		}(string(rune('A' + w)))
+	}
+	wg.Wait()
+	if len(claimed) != total {
+		t.Fatalf("期望领取 %d 条, 实际 %d 条", total, len(claimed))
+	}
+
+	// 只有租约持有者可以完成发布
+	for id, owner := range claimed {
+		if err := st.CompletePublish(id, "other", "tid"); !errors.Is(err, ErrLeaseLost) {
+			t.Fatalf("非持有者完成发布应返回 ErrLeaseLost, 实际 %v", err)
+		}
+		if err := st.CompletePublish(id, owner, "tid"); err != nil {
+			t.Fatalf("持有者完成发布失败: %v", err)
+		}
+		p, _ := st.GetPost(id)
+		if p.Status != model.StatusPublished || p.TID != "tid" || p.LeaseOwner != "" {
+			t.Fatalf("稿件 #%d 状态异常: %+v", id, p)
+		}
+	}
+}
+
+// TestRecoverExpiredLeases 测试租约过期的稿件被退回 approved
+func TestRecoverExpiredLeases(t *testing.T) {
+	st := newTestStore(t)
+	p := &model.Post{Text: "hi", Status: model.StatusApproved}
+	if err := st.SavePost(p); err != nil {
+		t.Fatalf("保存失败: %v", err)
+	}
+	if _, err := st.ClaimApprovedPost("crashed", -time.Second); err != nil {
+		t.Fatalf("领取失败: %v", err)
+	}
+	if ids, err := st.RecoverExpiredLeases(); err != nil || len(ids) != 1 || ids[0] != p.ID {
+		t.Fatalf("期望回收稿件 #%d, 实际 %v (%v)", p.ID, ids, err)
+	}
+	if err := st.FailPublish(p.ID, "crashed", "x"); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("过期租约不应再能修改稿件, 实际 %v", err)
+	}
+	got, _ := st.GetPost(p.ID)
+	if got.Status != model.StatusApproved {
+		t.Fatalf("期望 approved, 实际 %s", got.Status)
+	}
+}
+
+// TestSetPostStatus 测试审核只能修改指定状态的稿件：已被 Worker 领取的稿件不能被拒绝或修改内容，
+// SavePost 也不会清掉租约
+// 运行方法: go test -v ./internal/store/ -run TestSetPostStatus
+func TestSetPostStatus(t *testing.T) {
+	st := newTestStore(t)
+	p := &model.Post{Text: "hi", Status: model.StatusPending}
+	if err := st.SavePost(p); err != nil {
+		t.Fatalf("保存失败: %v", err)
+	}
+	p.Text = "hello"
+	if err := st.EditPost(p, model.StatusPending); err != nil {
+		t.Fatalf("修改待审稿件失败: %v", err)
+	}
+	if err := st.SetPostStatus(p.ID, model.StatusApproved, "", 0, model.ApprovableStatuses...); err != nil {
+		t.Fatalf("过稿失败: %v", err)
+	}
+	if _, err := st.ClaimApprovedPost("w", time.Minute); err != nil {
+		t.Fatalf("领取失败: %v", err)
+	}
+	p.Text = "changed"
+	if err := st.EditPost(p, model.StatusPending, model.StatusApproved); !errors.Is(err, ErrStatusConflict) {
+		t.Fatalf("发布中的稿件修改内容应返回 ErrStatusConflict, 实际 %v", err)
+	}
+	if err := st.SetPostStatus(p.ID, model.StatusRejected, "x", 0, model.RejectableStatuses...); !errors.Is(err, ErrStatusConflict) {
+		t.Fatalf("发布中的稿件拒稿应返回 ErrStatusConflict, 实际 %v", err)
+	}
+
+	got, _ := st.GetPost(p.ID)
+	if got.Text != "hello" {
+		t.Fatalf("发布中的稿件内容不应被修改, 实际 %q", got.Text)
+	}
+	got.Name = "改名"
+	if err := st.SavePost(got); err != nil {
+		t.Fatalf("保存失败: %v", err)
+	}
+	if err := st.CompletePublish(p.ID, "w", "tid"); err != nil {
+		t.Fatalf("SavePost 不应清掉租约, 完成发布失败: %v", err)
+	}
+}
+
+// TestRenewLeases 测试续约只对仍持有的租约生效；租约被回收后已发布的说说仍回填 TID，不会被再次领取
+// 运行方法: go test -v ./internal/store/ -run TestRenewLeases
+func TestRenewLeases(t *testing.T) {
+	st := newTestStore(t)
+	p := &model.Post{Text: "hi", Status: model.StatusApproved}
+	if err := st.SavePost(p); err != nil {
+		t.Fatalf("保存失败: %v", err)
+	}
+	if _, err := st.ClaimApprovedPost("w", -time.Second); err != nil {
+		t.Fatalf("领取失败: %v", err)
+	}
+	if n, err := st.RenewLeases([]int64{p.ID}, "w", time.Minute); err != nil || n != 1 {
+		t.Fatalf("续约应成功, 实际 %d (%v)", n, err)
+	}
+	if ids, _ := st.RecoverExpiredLeases(); len(ids) != 0 {
+		t.Fatalf("续约后的稿件不应被回收: %v", ids)
+	}
+
+	// 模拟租约过期被回收后由另一个 Worker 领取
+	if _, err := st.db.Exec("UPDATE posts SET lease_expire=0 WHERE id=?", p.ID); err != nil {
+		t.Fatalf("模拟过期失败: %v", err)
+	}
+	if ids, _ := st.RecoverExpiredLeases(); len(ids) != 1 {
+		t.Fatalf("过期租约应被回收: %v", ids)
+	}
+	if got, _ := st.ClaimApprovedPost("x", time.Minute); got == nil {
+		t.Fatalf("回收后应能被再次领取")
+	}
+	if n, _ := st.RenewLeases([]int64{p.ID}, "w", time.Minute); n != 0 {
+		t.Fatalf("租约被回收后不应续约成功")
+	}
+	if err := st.CompletePublish(p.ID, "w", "tid"); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("期望 ErrLeaseLost, 实际 %v", err)
+	}
+	if err := st.ForcePublished(p.ID, "tid"); err != nil {
+		t.Fatalf("回填 TID 失败: %v", err)
+	}
+	if got, _ := st.ClaimApprovedPost("y", time.Minute); got != nil {
+		t.Fatalf("已回填 TID 的稿件不应再被领取")
+	}
+	if got, _ := st.GetPost(p.ID); got.Status != model.StatusPublished || got.TID != "tid" {
+		t.Fatalf("稿件状态异常: %+v", got)
+	}
+}
+
+// TestSoftDeletePublishing 测试发布中的稿件不能移入回收站，删除失败后租约仍然有效
+// 运行方法: go test -v ./internal/store/ -run TestSoftDeletePublishing
+func TestSoftDeletePublishing(t *testing.T) {
+	st := newTestStore(t)
+	p := &model.Post{Text: "hi", Status: model.StatusApproved}
+	if err := st.SavePost(p); err != nil {
+		t.Fatalf("保存失败: %v", err)
+	}
+	if _, err := st.ClaimApprovedPost("w", time.Minute); err != nil {
+		t.Fatalf("领取失败: %v", err)
+	}
+	if ok, err := st.SoftDeletePost(p.ID, "admin"); err != nil || ok {
+		t.Fatalf("发布中的稿件不应被删除: %v %v", ok, err)
+	}
+	if err := st.CompletePublish(p.ID, "w", "tid"); err != nil {
+		t.Fatalf("删除失败后租约应仍然有效: %v", err)
+	}
+}
+
+// TestSearchPosts 测试全文索引随增删改同步
+func TestSearchPosts(t *testing.T) {
+	st := newTestStore(t)
+	p := &model.Post{Name: "小明", Text: "今天在图书馆遇见了你", Status: model.StatusPending}
+	if err := st.SavePost(p); err != nil {
+		t.Fatalf("保存失败: %v", err)
+	}
+	_ = st.SavePost(&model.Post{Name: "小红", Text: "食堂的饭真好吃", Status: model.StatusPublished})
+
+	count := func(q SearchQuery) int {
+		t.Helper()
+		posts, err := st.SearchPosts(q, 10, 0)
+		if err != nil {
+			t.Fatalf("搜索失败: %v", err)
+		}
+		return len(posts)
+	}
+
+	if n := count(SearchQuery{Keyword: "图书馆"}); n != 1 {
+		t.Fatalf("全文搜索期望 1 条, 实际 %d", n)
+	}
+	if n := count(SearchQuery{Keyword: "小"}); n != 2 {
+		t.Fatalf("短关键词期望 2 条, 实际 %d", n)
+	}
+	if n := count(SearchQuery{Keyword: "小", Status: model.StatusPublished}); n != 1 {
+		t.Fatalf("按状态过滤期望 1 条, 实际 %d", n)
+	}
+
+	p.Text = "今天在操场遇见了你"
+	_ = st.SavePost(p)
+	if n := count(SearchQuery{Keyword: "图书馆"}); n != 0 {
+		t.Fatalf("更新后旧内容不应命中, 实际 %d", n)
+	}
+	if n := count(SearchQuery{Keyword: "操场遇见"}); n != 1 {
+		t.Fatalf("更新后新内容期望 1 条, 实际 %d", n)
+	}
+
+	_ = st.DeletePost(p.ID)
+	if n := count(SearchQuery{Keyword: "操场遇见"}); n != 0 {
+		t.Fatalf("删除后不应命中, 实际 %d", n)
+	}
+}
+
+// TestScheduledPost 测试未到时间的定时稿件不会被领取, 且可以取消
+func TestScheduledPost(t *testing.T) {
+	st := newTestStore(t)
+	later := &model.Post{Text: "晚点发", Status: model.StatusApproved, PublishAt: time.Now().Add(time.Hour).Unix()}
+	due := &model.Post{Text: "到点了", Status: model.StatusApproved, PublishAt: time.Now().Add(-time.Minute).Unix()}
+	_ = st.SavePost(later)
+	_ = st.SavePost(due)
+
+	if n, _ := st.CountScheduled(); n != 1 {
+		t.Fatalf("期望 1 条定时稿件, 实际 %d", n)
+	}
+	p, err := st.ClaimApprovedPost("w", time.Minute)
+	if err != nil || p == nil || p.ID != due.ID {
+		t.Fatalf("应领取已到时间的 #%d, 实际 %+v (%v)", due.ID, p, err)
+	}
+	if p, _ := st.ClaimApprovedPost("w", time.Minute); p != nil {
+		t.Fatalf("未到时间的稿件不应被领取: #%d", p.ID)
+	}
+
+	if ok, err := st.CancelSchedule(due.ID); err != nil || ok {
+		t.Fatalf("已领取的稿件不应能取消定时 (%v)", err)
+	}
+	if ok, err := st.CancelSchedule(later.ID); err != nil || !ok {
+		t.Fatalf("取消定时失败 (%v)", err)
+	}
+	got, _ := st.GetPost(later.ID)
+	if got.Status != model.StatusPending || got.PublishAt != 0 {
+		t.Fatalf("取消后应退回待审核: %+v", got)
+	}
+}
+
+func TestClaimApprovedPosts(t *testing.T) {
+	st := newTestStore(t)
+	now := time.Now().Unix()
+	for i := 0; i < 5; i++ {
+		_ = st.SavePost(&model.Post{Text: "合集", Status: model.StatusApproved, PublishAt: now - int64(10-i)})
+	}
+	_ = st.SavePost(&model.Post{Text: "定时", Status: model.StatusApproved, PublishAt: now + 3600})
+
+	if n, _ := st.CountDueApproved(); n != 5 {
+		t.Fatalf("期望 5 条待发布, 实际 %d", n)
+	}
+	posts, err := st.ClaimApprovedPosts("w", time.Minute, 3)
+	if err != nil || len(posts) != 3 {
+		t.Fatalf("应领取 3 条, 实际 %d (%v)", len(posts), err)
+	}
+	for i, p := range posts {
+		if p.Status != model.StatusPublishing || p.LeaseOwner != "w" {
+			t.Fatalf("领取后状态错误: %+v", p)
+		}
+		if i > 0 && p.PublishAt < posts[i-1].PublishAt {
+			t.Fatalf("应按发布时间先后返回")
+		}
+	}
+	rest, _ := st.ClaimApprovedPosts("w", time.Minute, 9)
+	if len(rest) != 2 {
+		t.Fatalf("剩余应领取 2 条, 实际 %d", len(rest))
+	}
+	for _, p := range append(posts, rest...) {
+		if err := st.CompletePublish(p.ID, "w", "shared"); err != nil {
+			t.Fatalf("回填共享 TID 失败: %v", err)
+		}
+	}
+	if n, _ := st.CountByStatus(model.StatusPublished); n != 5 {
+		t.Fatalf("期望 5 条已发布, 实际 %d", n)
+	}
+}
+
+func TestRetractByTID(t *testing.T) {
+	st := newTestStore(t)
+	a := &model.Post{Text: "合集一", Status: model.StatusPublished, TID: "t1"}
+	b := &model.Post{Text: "合集二", Status: model.StatusPublished, TID: "t1"}
+	other := &model.Post{Text: "单条", Status: model.StatusPublished, TID: "t2"}
+	for _, p := range []*model.Post{a, b, other} {
+		_ = st.SavePost(p)
+	}
+
+	if posts, _ := st.ListByTID("t1"); len(posts) != 2 {
+		t.Fatalf("期望同一说说 2 条稿件, 实际 %d", len(posts))
+	}
+	ids, err := st.RetractByTID("t1", "当事人要求")
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("应下架 2 条, 实际 %v (%v)", ids, err)
+	}
+	got, _ := st.GetPost(a.ID)
+	if got.Status != model.StatusRetracted || got.Reason != "当事人要求" || got.TID != "t1" {
+		t.Fatalf("下架后状态错误: %+v", got)
+	}
+	if got, _ := st.GetPost(other.ID); got.Status != model.StatusPublished {
+		t.Fatalf("其他说说不应受影响: %+v", got)
+	}
+	if ids, _ := st.RetractByTID("t1", ""); len(ids) != 0 {
+		t.Fatalf("重复下架不应再更新: %v", ids)
+	}
+}
+
+func TestReleaseLeases(t *testing.T) {
+	st := newTestStore(t)
+	_ = st.SavePost(&model.Post{Text: "顺延", Status: model.StatusApproved})
+	posts, err := st.ClaimApprovedPosts("w", time.Minute, 9)
+	if err != nil || len(posts) != 1 {
+		t.Fatalf("应领取 1 条, 实际 %d (%v)", len(posts), err)
+	}
+	if err := st.ReleaseLeases([]int64{posts[0].ID}, "other"); err != nil {
+		t.Fatalf("释放租约失败: %v", err)
+	}
+	if got, _ := st.GetPost(posts[0].ID); got.Status != model.StatusPublishing {
+		t.Fatalf("非持有者不应释放租约: %+v", got)
+	}
+	if err := st.ReleaseLeases([]int64{posts[0].ID}, "w"); err != nil {
+		t.Fatalf("释放租约失败: %v", err)
+	}
+	if got, _ := st.GetPost(posts[0].ID); got.Status != model.StatusApproved || got.LeaseOwner != "" {
+		t.Fatalf("释放后应退回 approved: %+v", got)
+	}
+}
+
+func TestRetryLater(t *testing.T) {
+	st := newTestStore(t)
+	p := &model.Post{Text: "退避", Status: model.StatusApproved}
+	_ = st.SavePost(p)
+
+	claimed, _ := st.ClaimApprovedPost("w", time.Minute)
+	if claimed == nil {
+		t.Fatalf("应领取到稿件")
+	}
+	if err := st.RetryLater(p.ID, "w", "网络错误", time.Now().Add(time.Hour).Unix()); err != nil {
+		t.Fatalf("退避失败: %v", err)
+	}
+	got, _ := st.GetPost(p.ID)
+	if got.Status != model.StatusApproved || got.Attempts != 1 || !got.IsBackingOff() {
+		t.Fatalf("退避后应为 approved 且 attempts=1: %+v", got)
+	}
+	if again, _ := st.ClaimApprovedPost("w", time.Minute); again != nil {
+		t.Fatalf("退避中的稿件不应被领取")
+	}
+	if n, _ := st.CountDueApproved(); n != 0 {
+		t.Fatalf("退避中的稿件不应计入待发布, 实际 %d", n)
+	}
+
+	if _, err := st.db.Exec("UPDATE posts SET next_attempt=0 WHERE id=?", p.ID); err != nil {
+		t.Fatalf("重置退避时间失败: %v", err)
+	}
+	if again, _ := st.ClaimApprovedPost("w", time.Minute); again == nil || again.ID != p.ID {
+		t.Fatalf("退避结束后应能再次领取")
+	}
+	if err := st.FailPublish(p.ID, "w", "内容被拒"); err != nil {
+		t.Fatalf("标记失败出错: %v", err)
+	}
+	if got, _ := st.GetPost(p.ID); got.Status != model.StatusFailed || got.Attempts != 2 {
+		t.Fatalf("失败后 attempts 应为 2: %+v", got)
+	}
+
+	ids, err := st.RequeueFailed([]int64{p.ID, p.ID + 100})
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("应重发 1 条, 实际 %v (%v)", ids, err)
+	}
+	if got, _ := st.GetPost(p.ID); got.Status != model.StatusApproved || got.Attempts != 0 || got.Reason != "" {
+		t.Fatalf("重发后应清零: %+v", got)
+	}
+}
+
+// TestPublishHistoryByUIN 测试按账号记录发布历史，下架时据此找到发布账号
+func TestPublishHistoryByUIN(t *testing.T) {
+	st := newTestStore(t)
+	_ = st.AddPublishRecord("tid-a", 10001, 1)
+	_ = st.AddPublishRecord("tid-b", 20002, 3)
+	_ = st.AddPublishRecord("tid-c", 10001, 1)
+
+	if uin, err := st.PublishUIN("tid-b"); err != nil || uin != 20002 {
+		t.Fatalf("tid-b 应由 20002 发布, 实际 %d (%v)", uin, err)
+	}
+	if uin, err := st.PublishUIN("tid-x"); err != nil || uin != 0 {
+		t.Fatalf("没有发布记录时应返回 0, 实际 %d (%v)", uin, err)
+	}
+	counts, err := st.PublishCountsByUIN(0)
+	if err != nil || counts[10001] != 2 || counts[20002] != 1 {
+		t.Fatalf("按账号统计错误: %v (%v)", counts, err)
+	}
+	if n, _ := st.CountPublishedSince(0); n != 3 {
+		t.Fatalf("总发布数应为 3, 实际 %d", n)
+	}
+}
+
+// TestForWall 测试墙视图只读写本墙的稿件、审计日志和发布记录，不区分墙的存储能看到全部
+// 运行方法: go test -v ./internal/store/ -run TestForWall
+func TestForWall(t *testing.T) {
+	st := newTestStore(t)
+	a, b := st.ForWall("a"), st.ForWall("b")
+
+	pa := &model.Post{Text: "甲墙", Status: model.StatusApproved, WallID: "b"}
+	pb := &model.Post{Text: "乙墙", Status: model.StatusPending}
+	legacy := &model.Post{Text: "旧稿件", Status: model.StatusPending}
+	_ = a.SavePost(pa)
+	_ = b.SavePost(pb)
+	_ = st.SavePost(legacy)
+	if pa.WallID != "a" || pb.WallID != "b" || legacy.WallID != model.DefaultWall {
+		t.Fatalf("稿件应归属保存它的墙: %q %q %q", pa.WallID, pb.WallID, legacy.WallID)
+	}
+
+	if got, _ := a.GetPost(pb.ID); got != nil {
+		t.Fatalf("不应读到其他墙的稿件: %+v", got)
+	}
+	if got, _ := st.GetPost(pb.ID); got == nil || got.WallID != "b" {
+		t.Fatalf("不区分墙时应读到所有稿件: %+v", got)
+	}
+	if n, _ := a.CountAll(); n != 1 {
+		t.Fatalf("甲墙应有 1 条, 实际 %d", n)
+	}
+	if n, _ := st.CountAll(); n != 3 {
+		t.Fatalf("总共应有 3 条, 实际 %d", n)
+	}
+	if res, _ := b.SearchPosts(SearchQuery{Keyword: "甲墙"}, 10, 0); len(res) != 0 {
+		t.Fatalf("搜索不应返回其他墙的稿件: %d", len(res))
+	}
+
+	if posts, _ := b.ClaimApprovedPosts("w", time.Minute, 9); len(posts) != 0 {
+		t.Fatalf("乙墙不应领取甲墙的稿件: %d", len(posts))
+	}
+	if posts, _ := b.ClaimPendingPosts([]int64{pa.ID, pb.ID}, "w", time.Minute); len(posts) != 1 || posts[0].ID != pb.ID {
+		t.Fatalf("乙墙只应过稿本墙的稿件: %v", posts)
+	}
+	if ok, err := b.SoftDeletePost(pa.ID, "x"); err != nil || ok {
+		t.Fatalf("不应删除其他墙的稿件: %v %v", ok, err)
+	}
+	if got, _ := a.GetPost(pa.ID); got.Status != model.StatusApproved {
+		t.Fatalf("其他墙不应删除甲墙的稿件: %+v", got)
+	}
+
+	_ = st.AddAuditEvent(&model.AuditEvent{Action: model.ActionCreate, PostID: pa.ID})
+	_ = b.AddAuditEvent(&model.AuditEvent{Action: model.ActionCreate, PostID: pb.ID})
+	if events, _ := a.ListAuditEvents(AuditFilter{}, 10, 0); len(events) != 1 || events[0].WallID != "a" {
+		t.Fatalf("审计日志应按稿件所属的墙记录: %+v", events)
+	}
+
+	_ = a.AddPublishRecord("t1", 10001, 1)
+	_ = b.AddPublishRecord("t2", 20002, 1)
+	if n, _ := a.CountPublishedSince(0); n != 1 {
+		t.Fatalf("发布额度应按墙统计, 实际 %d", n)
+	}
+	if uin, _ := st.PublishUIN("t2"); uin != 20002 {
+		t.Fatalf("不区分墙时应查到所有发布记录, 实际 %d", uin)
+	}
+}
+
+// TestWebhookOutbox 测试稿件状态变化按订阅的事件和墙写入发件箱，领取后在租约内不会被重复领取，
+// 失败超过次数后停止投递，可以手动重新投递
+// 运行方法: go test -v ./internal/store/ -run TestWebhookOutbox
+func TestWebhookOutbox(t *testing.T) {
+	st := newTestStore(t)
+	all := &model.WebhookSubscription{URL: "http://a", Secret: "s", Enabled: true}
+	onlyB := &model.WebhookSubscription{URL: "http://b", Events: []string{model.EventPostPublished}, WallID: "b", Enabled: true}
+	off := &model.WebhookSubscription{URL: "http://c", Enabled: false}
+	for _, sub := range []*model.WebhookSubscription{all, onlyB, off} {
+		if err := st.CreateWebhookSubscription(sub); err != nil {
+			t.Fatalf("添加订阅失败: %v", err)
+		}
+	}
+
+	a, b := st.ForWall("a"), st.ForWall("b")
+	pa := &model.Post{Text: "甲墙", Status: model.StatusPending}
+	pb := &model.Post{Text: "乙墙", Status: model.StatusPublished}
+	_ = a.SavePost(pa)
+	_ = b.SavePost(pb)
+	_ = st.AddAuditEvent(&model.AuditEvent{Action: model.ActionCreate, PostID: pa.ID, Source: model.SourceBot})
+	_ = b.AddAuditEvent(&model.AuditEvent{Action: model.ActionPublish, PostID: pb.ID, Source: model.SourceWorker})
+	_ = b.AddAuditEvent(&model.AuditEvent{Action: model.ActionClaim, PostID: pb.ID, Source: model.SourceWorker})
+
+	deliveries, err := st.ClaimDueWebhooks(10, time.Minute)
+	if err != nil {
+		t.Fatalf("领取失败: %v", err)
+	}
+	if len(deliveries) != 3 {
+		t.Fatalf("应有 3 条投递（全部订阅 2 条 + 乙墙发布订阅 1 条）, 实际 %d", len(deliveries))
+	}
+	var published *model.WebhookDelivery
+	for _, d := range deliveries {
+		if d.SubscriptionID == off.ID {
+			t.Fatalf("停用的订阅不应产生投递")
+		}
+		if d.SubscriptionID == onlyB.ID {
+			published = d
+		}
+	}
+	if published == nil || published.Event != model.EventPostPublished || published.WallID != "b" {
+		t.Fatalf("乙墙订阅应收到 post.published: %+v", published)
+	}
+	var payload model.WebhookPayload
+	if err := json.Unmarshal([]byte(published.Payload), &payload); err != nil || payload.Post == nil || payload.Post.ID != pb.ID || payload.Actor.Source != model.SourceWorker {
+		t.Fatalf("回调内容错误: %s", published.Payload)
+	}
+	if again, _ := st.ClaimDueWebhooks(10, time.Minute); len(again) != 0 {
+		t.Fatalf("租约内不应重复领取: %d", len(again))
+	}
+
+	_ = st.WebhookAttemptFailed(published.ID, 500, "http 500", 0)
+	if got, _ := st.GetWebhookDelivery(published.ID); got.Status != model.DeliveryFailed || got.Attempts != 1 || got.ResponseCode != 500 {
+		t.Fatalf("应标记为失败: %+v", got)
+	}
+	if ok, _ := st.RedeliverWebhook(published.ID); !ok {
+		t.Fatalf("重新投递失败")
+	}
+	if again, _ := st.ClaimDueWebhooks(10, time.Minute); len(again) != 1 || again[0].ID != published.ID || again[0].Attempts != 0 {
+		t.Fatalf("重新投递后应立即可领取: %+v", again)
+	}
+
+	if err := st.DeleteWebhookSubscription(onlyB.ID); err != nil {
+		t.Fatalf("删除订阅失败: %v", err)
+	}
+	if list, _ := st.ListWebhookDeliveries(onlyB.ID, "", 10, 0); len(list) != 0 {
+		t.Fatalf("删除订阅应同时删除投递记录: %d", len(list))
+	}
+}
+
+// TestAPIKeys 测试 API 密钥按哈希查找，吊销后失效，只能吊销自己的密钥
+// 运行方法: go test -v ./internal/store/ -run TestAPIKeys
+func TestAPIKeys(t *testing.T) {
+	st := newTestStore(t)
+	k := &model.APIKey{AccountID: 1, Name: "脚本", Prefix: "qw_abcdef", Scopes: []string{model.ScopePostsRead, model.ScopePostsReview}}
+	if err := st.CreateAPIKey(k, "hash1"); err != nil {
+		t.Fatalf("创建失败: %v", err)
+	}
+	got, err := st.GetAPIKeyByHash("hash1")
+	if err != nil || got == nil || got.ID != k.ID || !got.HasScope(model.ScopePostsReview) || got.HasScope(model.ScopePostsWrite) {
+		t.Fatalf("按哈希查找错误: %+v %v", got, err)
+	}
+	if got, _ := st.GetAPIKeyByHash("nope"); got != nil {
+		t.Fatalf("不存在的密钥应返回 nil")
+	}
+	if ok, _ := st.RevokeAPIKey(k.ID, 2); ok {
+		t.Fatalf("不能吊销其他账号的密钥")
+	}
+	if ok, _ := st.RevokeAPIKey(k.ID, 1); !ok {
+		t.Fatalf("吊销失败")
+	}
+	if got, _ := st.GetAPIKeyByHash("hash1"); got != nil {
+		t.Fatalf("吊销后不应再查到密钥")
+	}
+	if keys, _ := st.ListAPIKeys(1); len(keys) != 1 || keys[0].RevokeTime == 0 {
+		t.Fatalf("列表应包含已吊销的密钥: %+v", keys)
+	}
+}
+
+// TestSearchCursor 测试按 Before 游标分页不重复、不遗漏
+// 运行方法: go test -v ./internal/store/ -run TestSearchCursor
+func TestSearchCursor(t *testing.T) {
+	st := newTestStore(t)
+	for i := 0; i < 5; i++ {
+		_ = st.SavePost(&model.Post{Text: "hi", Status: model.StatusPending})
+	}
+	var seen []int64
+	q := SearchQuery{}
+	for {
+		posts, err := st.SearchPosts(q, 2, 0)
+		if err != nil {
+			t.Fatalf("查询失败: %v", err)
+		}
+		for _, p := range posts {
+			seen = append(seen, p.ID)
+		}
+		if len(posts) < 2 {
+			break
+		}
+		q.Before = posts[len(posts)-1].ID
+	}
+	if len(seen) != 5 || seen[0] != 5 || seen[4] != 1 {
+		t.Fatalf("游标分页结果错误: %v", seen)
+	}
+}
+
+// TestAccountDisable 测试停用账号后会话失效、不再计入管理员，启用后恢复
+// 运行方法: go test -v ./internal/store/ -run TestAccountDisable
+func TestAccountDisable(t *testing.T) {
+	st := newTestStore(t)
+	if err := st.CreateAccount("admin", "h", "s", model.RoleAdmin); err != nil {
+		t.Fatalf("创建账号失败: %v", err)
+	}
+	a, _ := st.GetAccount("admin")
+	if err := st.CreateSession("token1", a.ID, time.Now().Add(time.Hour).Unix()); err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+	if n, _ := st.CountActiveAdmins(); n != 1 || !a.Can(model.PermAccounts) {
+		t.Fatalf("应有 1 个可用的管理员: %d", n)
+	}
+
+	if err := st.SetAccountDisabled(a.ID, true); err != nil {
+		t.Fatalf("停用失败: %v", err)
+	}
+	if id, _ := st.GetSession("token1"); id != 0 {
+		t.Fatalf("停用后会话应失效")
+	}
+	a, _ = st.GetAccountByID(a.ID)
+	if !a.Disabled || a.Can(model.PermView) {
+		t.Fatalf("停用的账号不应有任何权限: %+v", a)
+	}
+	if n, _ := st.CountActiveAdmins(); n != 0 {
+		t.Fatalf("停用的管理员不应计入: %d", n)
+	}
+
+	_ = st.SetAccountDisabled(a.ID, false)
+	_ = st.SetAccountRole(a.ID, model.RoleViewer)
+	list, err := st.ListAccounts()
+	if err != nil || len(list) != 1 || list[0].Disabled || list[0].Role != model.RoleViewer {
+		t.Fatalf("列表错误: %+v %v", list, err)
+	}
+	if !list[0].Can(model.PermView) || list[0].Can(model.PermReview) {
+		t.Fatalf("只读账号的权限错误")
+	}
+}
@@ -0,0 +1,279 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+// auditWebhookEvents 审计动作对应的回调事件，稿件状态变化都会写审计日志，据此发出事件
+var auditWebhookEvents = map[string]string{
+	model.ActionCreate:   model.EventPostCreated,
+	model.ActionApprove:  model.EventPostApproved,
+	model.ActionSchedule: model.EventPostApproved,
+	model.ActionReject:   model.EventPostRejected,
+	model.ActionPublish:  model.EventPostPublished,
+	model.ActionFail:     model.EventPostFailed,
+}
+
+// ──────────────────────────────────────────
+// 订阅
+// ──────────────────────────────────────────
+
+// CreateWebhookSubscription 添加回调订阅
+func (s *Store) CreateWebhookSubscription(sub *model.WebhookSubscription) error {
+	if sub.CreateTime == 0 {
+		sub.CreateTime = time.Now().Unix()
+	}
+	res, err := s.db.Exec(
+		"INSERT INTO webhook_subscriptions (url,secret,events,wall_id,enabled,create_time) VALUES (?,?,?,?,?,?)",
+		sub.URL, sub.Secret, strings.Join(sub.Events, ","), sub.WallID, b2i(sub.Enabled), sub.CreateTime,
+	)
+	if err != nil {
+		return err
+	}
+	sub.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// GetWebhookSubscription 获取订阅，不存在时返回 nil
+func (s *Store) GetWebhookSubscription(id int64) (*model.WebhookSubscription, error) {
+	row := s.db.QueryRow(subscriptionCols("WHERE id=?"), id)
+	sub, err := scanSubscription(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return sub, err
+}
+
+// ListWebhookSubscriptions 列出所有订阅
+func (s *Store) ListWebhookSubscriptions() ([]*model.WebhookSubscription, error) {
+	rows, err := s.db.Query(subscriptionCols("ORDER BY id ASC"))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	var subs []*model.WebhookSubscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// SetWebhookSubscriptionEnabled 启用或停用订阅，停用后不再产生新的投递，已在发件箱中的投递照常进行
+func (s *Store) SetWebhookSubscriptionEnabled(id int64, enabled bool) error {
+	_, err := s.db.Exec("UPDATE webhook_subscriptions SET enabled=? WHERE id=?", b2i(enabled), id)
+	return err
+}
+
+// DeleteWebhookSubscription 删除订阅及其发件箱中的投递记录
+func (s *Store) DeleteWebhookSubscription(id int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	if _, err := tx.Exec("DELETE FROM webhook_outbox WHERE subscription_id=?", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM webhook_subscriptions WHERE id=?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ──────────────────────────────────────────
+// 发件箱
+// ──────────────────────────────────────────
+
+// EmitWebhook 把事件写入发件箱，订阅了该事件和墙的每个启用的订阅各一条投递。
+// payload.WallID 为空时使用墙视图的墙 ID。返回写入的投递数。
+func (s *Store) EmitWebhook(payload *model.WebhookPayload) (int64, error) {
+	if payload.WallID == "" {
+		payload.WallID = s.wall
+	}
+	if payload.Time == 0 {
+		payload.Time = time.Now().Unix()
+	}
+	if payload.ID == "" {
+		payload.ID = fmt.Sprintf("%s-%d", strings.ReplaceAll(payload.Event, ".", "-"), time.Now().UnixNano())
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	res, err := s.db.Exec(
+		`INSERT INTO webhook_outbox (subscription_id,event_id,event,wall_id,payload,status,create_time,update_time)
+		 SELECT id,?,?,?,?,'pending',?,? FROM webhook_subscriptions
+		 WHERE enabled=1 AND (wall_id='' OR wall_id=?) AND (events='' OR instr(','||events||',', ?)>0)`,
+		payload.ID, payload.Event, payload.WallID, string(body), payload.Time, payload.Time,
+		payload.WallID, ","+payload.Event+",",
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// emitAuditWebhook 审计日志对应回调事件时写入发件箱，失败只记录日志，不影响审计日志本身
+func (s *Store) emitAuditWebhook(e *model.AuditEvent) {
+	event, ok := auditWebhookEvents[e.Action]
+	if !ok || e.PostID <= 0 {
+		return
+	}
+	post, err := s.ForWall("").GetPost(e.PostID)
+	if err != nil || post == nil {
+		log.Printf("[Store] 回调事件 %s 读取稿件 #%d 失败: %v", event, e.PostID, err)
+		return
+	}
+	_, err = s.EmitWebhook(&model.WebhookPayload{
+		ID:     fmt.Sprintf("audit-%d", e.ID),
+		Event:  event,
+		WallID: e.WallID,
+		Time:   e.CreateTime,
+		Post:   post,
+		Actor:  &model.WebhookActor{ID: e.ActorID, Name: e.ActorName, Source: e.Source},
+		Reason: e.Reason,
+	})
+	if err != nil {
+		log.Printf("[Store] 写入回调事件 %s 失败: %v", event, err)
+	}
+}
+
+// ClaimDueWebhooks 领取到期的待投递记录，领取时把下次投递时间推迟 lease，
+// 投递进程崩溃时租约到期后会被重新领取
+func (s *Store) ClaimDueWebhooks(limit int, lease time.Duration) ([]*model.WebhookDelivery, error) {
+	now := time.Now().Unix()
+	rows, err := s.db.Query(
+		`UPDATE webhook_outbox SET next_attempt=?, update_time=?
+		 WHERE id IN (SELECT id FROM webhook_outbox WHERE status='pending' AND next_attempt<=? ORDER BY id ASC LIMIT ?)
+		 RETURNING `+deliveryFields,
+		now+int64(lease.Seconds()), now, now, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+// WebhookDelivered 投递成功
+func (s *Store) WebhookDelivered(id int64, code int) error {
+	_, err := s.db.Exec(
+		"UPDATE webhook_outbox SET status='delivered', attempts=attempts+1, response_code=?, last_error='', update_time=? WHERE id=?",
+		code, time.Now().Unix(), id,
+	)
+	return err
+}
+
+// WebhookAttemptFailed 投递失败：next > 0 时在 next 重试，否则标记为 failed 不再重试
+func (s *Store) WebhookAttemptFailed(id int64, code int, errMsg string, next int64) error {
+	status := model.DeliveryPending
+	if next <= 0 {
+		status = model.DeliveryFailed
+	}
+	_, err := s.db.Exec(
+		"UPDATE webhook_outbox SET status=?, attempts=attempts+1, next_attempt=?, response_code=?, last_error=?, update_time=? WHERE id=?",
+		status, next, code, errMsg, time.Now().Unix(), id,
+	)
+	return err
+}
+
+// RedeliverWebhook 手动重新投递（任何状态），清零尝试次数后立即进入发件箱
+func (s *Store) RedeliverWebhook(id int64) (bool, error) {
+	res, err := s.db.Exec(
+		"UPDATE webhook_outbox SET status='pending', attempts=0, next_attempt=0, last_error='', update_time=? WHERE id=?",
+		time.Now().Unix(), id,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetWebhookDelivery 获取投递记录，不存在时返回 nil
+func (s *Store) GetWebhookDelivery(id int64) (*model.WebhookDelivery, error) {
+	rows, err := s.db.Query("SELECT "+deliveryFields+" FROM webhook_outbox WHERE id=?", id)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := scanDeliveries(rows)
+	if err != nil || len(deliveries) == 0 {
+		return nil, err
+	}
+	return deliveries[0], nil
+}
+
+// ListWebhookDeliveries 分页列出投递记录（最新在前），subscriptionID 为 0、status 为空时不过滤
+func (s *Store) ListWebhookDeliveries(subscriptionID int64, status string, limit, offset int) ([]*model.WebhookDelivery, error) {
+	var conds []string
+	var args []interface{}
+	if subscriptionID > 0 {
+		conds = append(conds, "subscription_id=?")
+		args = append(args, subscriptionID)
+	}
+	if status != "" {
+		conds = append(conds, "status=?")
+		args = append(args, status)
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, limit, offset)
+	rows, err := s.db.Query("SELECT "+deliveryFields+" FROM webhook_outbox "+where+" ORDER BY id DESC LIMIT ? OFFSET ?", args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+const deliveryFields = "id,subscription_id,event_id,event,wall_id,payload,status,attempts,next_attempt,response_code,last_error,create_time,update_time"
+
+// scanDeliveries 读取投递记录并关闭 rows
+func scanDeliveries(rows *sql.Rows) ([]*model.WebhookDelivery, error) {
+	defer func() {
+		_ = rows.Close()
+	}()
+	var deliveries []*model.WebhookDelivery
+	for rows.Next() {
+		var d model.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.Event, &d.WallID, &d.Payload, &d.Status,
+			&d.Attempts, &d.NextAttempt, &d.ResponseCode, &d.LastError, &d.CreateTime, &d.UpdateTime); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, rows.Err()
+}
+
+func subscriptionCols(where string) string {
+	return "SELECT id,url,secret,events,wall_id,enabled,create_time FROM webhook_subscriptions " + where
+}
+
+func scanSubscription(sc rowScanner) (*model.WebhookSubscription, error) {
+	var sub model.WebhookSubscription
+	var events string
+	var enabled int
+	if err := sc.Scan(&sub.ID, &sub.URL, &sub.Secret, &events, &sub.WallID, &enabled, &sub.CreateTime); err != nil {
+		return nil, err
+	}
+	sub.Enabled = enabled != 0
+	if events != "" {
+		sub.Events = strings.Split(events, ",")
+	}
+	return &sub, nil
+}
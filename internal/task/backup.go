@@ -0,0 +1,73 @@
+package task
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/backup"
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+// Backuper 按配置的间隔定时创建备份，并按保留份数清理旧备份。
+type Backuper struct {
+	cfg       config.BackupConfig
+	store     *store.Store
+	uploadDir string
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// NewBackuper creates a scheduled backup task.
+func NewBackuper(cfg config.BackupConfig, st *store.Store, uploadDir string) *Backuper {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Backuper{cfg: cfg, store: st, uploadDir: uploadDir, ctx: ctx, cancel: cancel}
+}
+
+func (b *Backuper) Start() {
+	if b.cfg.Interval.Duration <= 0 {
+		log.Println("[Backup] scheduled backup disabled (interval = 0)")
+		return
+	}
+	go b.run()
+	log.Printf("[Backup] started, interval=%v, keep=%d, dir=%s", b.cfg.Interval.Duration, b.cfg.Keep, b.cfg.Dir)
+}
+
+func (b *Backuper) Stop() { b.cancel() }
+
+func (b *Backuper) run() {
+	ticker := time.NewTicker(b.cfg.Interval.Duration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.ctx.Done():
+			log.Println("[Backup] stopped")
+			return
+		case <-ticker.C:
+			if _, err := RunBackup(b.cfg, b.store, b.uploadDir); err != nil {
+				log.Printf("[Backup] 定时备份失败: %v", err)
+			}
+		}
+	}
+}
+
+// RunBackup 立即创建一份备份并清理超出保留份数的旧备份，返回备份文件路径。
+// 定时任务、管理后台和 `wall backup` 命令共用。
+func RunBackup(cfg config.BackupConfig, st *store.Store, uploadDir string) (string, error) {
+	path, err := backup.Snapshot(st, uploadDir, cfg.Dir)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("[Backup] 已创建备份 %s", path)
+
+	removed, err := backup.Prune(cfg.Dir, cfg.Keep)
+	if err != nil {
+		log.Printf("[Backup] 清理旧备份失败: %v", err)
+	}
+	for _, name := range removed {
+		log.Printf("[Backup] 已删除旧备份 %s", name)
+	}
+	return path, nil
+}
@@ -0,0 +1,88 @@
+package task
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	qzone "github.com/guohuiyuan/qzone-go"
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/secret"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+// CookieKeyEnv 覆盖 qzone.cookie_key 的环境变量
+const CookieKeyEnv = "QZONEWALL_COOKIE_KEY"
+
+// CookieVault 把校验通过的 QQ空间 Cookie 加密保存到数据库，重启后恢复。
+// 未配置密钥时 Load/Save 均为空操作，可以安全地传入 nil。
+type CookieVault struct {
+	store *store.Store
+	box   *secret.Box
+}
+
+// NewCookieVault creates a cookie vault; the key comes from $QZONEWALL_COOKIE_KEY or qzone.cookie_key.
+func NewCookieVault(qzoneCfg config.QzoneConfig, st *store.Store) *CookieVault {
+	key := os.Getenv(CookieKeyEnv)
+	if key == "" {
+		key = qzoneCfg.CookieKey
+	}
+	box, err := secret.NewBox(key)
+	if err != nil {
+		log.Printf("[Cookie] 未配置 cookie_key / %s，不保存 Cookie，每次启动都需重新获取", CookieKeyEnv)
+		return &CookieVault{store: st}
+	}
+	return &CookieVault{store: st, box: box}
+}
+
+// Enabled 是否配置了密钥
+func (v *CookieVault) Enabled() bool {
+	return v != nil && v.box != nil
+}
+
+// Load 读取 uin 保存的 Cookie，uin 为 0 时读取最近一次保存的 Cookie；没有保存或无法解密时返回空字符串
+func (v *CookieVault) Load(uin int64) (string, *store.QzoneSession) {
+	if !v.Enabled() {
+		return "", nil
+	}
+	var qs *store.QzoneSession
+	var err error
+	if uin > 0 {
+		qs, err = v.store.GetQzoneSession(uin)
+	} else {
+		qs, err = v.store.LatestQzoneSession()
+	}
+	if err != nil {
+		log.Printf("[Cookie] 读取已保存的 Cookie 失败: %v", err)
+		return "", nil
+	}
+	if qs == nil {
+		return "", nil
+	}
+	plain, err := v.box.Open(qs.Cookie)
+	if err != nil {
+		log.Printf("[Cookie] 解密已保存的 Cookie 失败（密钥可能已更换）: %v", err)
+		return "", nil
+	}
+	return string(plain), qs
+}
+
+// Save 保存 client 当前的 Cookie 并记录校验时间，调用方应确保 Cookie 刚刚校验通过
+func (v *CookieVault) Save(client *qzone.Client) {
+	if !v.Enabled() || client == nil || client.UIN() <= 0 {
+		return
+	}
+	cookie := client.Session().Cookie()
+	if cookie == "" || strings.Contains(cookie, "p_skey=bootstrap") {
+		return
+	}
+	sealed, err := v.box.Seal([]byte(cookie))
+	if err != nil {
+		log.Printf("[Cookie] 加密 Cookie 失败: %v", err)
+		return
+	}
+	if err := v.store.SaveQzoneSession(client.UIN(), sealed, time.Now().Unix()); err != nil {
+		log.Printf("[Cookie] 保存 Cookie 失败: %v", err)
+	}
+}
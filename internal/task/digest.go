@@ -0,0 +1,134 @@
+package task
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+// digest 合集模式的调度状态，只由持有 Worker.digestMu 的协程访问。
+type digest struct {
+	cfg   config.DigestConfig
+	times []int // 每天发布时刻（距 0 点的分钟数，升序）
+	next  time.Time
+}
+
+func newDigest(cfg config.DigestConfig, now time.Time) *digest {
+	d := &digest{cfg: cfg}
+	for _, s := range cfg.Times {
+		t, err := time.Parse("15:04", strings.TrimSpace(s))
+		if err != nil {
+			log.Printf("[Worker] 忽略无效的合集发布时刻 %q", s)
+			continue
+		}
+		d.times = append(d.times, t.Hour()*60+t.Minute())
+	}
+	sort.Ints(d.times)
+
+	d.next = d.nextAfter(now)
+	return d
+}
+
+// scheduled 是否配置了按时间发布
+func (d *digest) scheduled() bool {
+	return len(d.times) > 0 || d.cfg.Interval.Duration > 0
+}
+
+// threshold 积压多少条立即发布；既没有时间表也没有阈值时攒满一组就发
+func (d *digest) threshold() int {
+	if d.cfg.Threshold > 0 {
+		return d.cfg.Threshold
+	}
+	if !d.scheduled() {
+		return d.cfg.MaxCards
+	}
+	return 0
+}
+
+// nextAfter 计算 t 之后的下一次定时发布时间；未配置时间表时返回零值。
+func (d *digest) nextAfter(t time.Time) time.Time {
+	if len(d.times) > 0 {
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		for offset := 0; offset <= 1; offset++ {
+			for _, m := range d.times {
+				at := day.AddDate(0, 0, offset).Add(time.Duration(m) * time.Minute)
+				if at.After(t) {
+					return at
+				}
+			}
+		}
+	}
+	if d.cfg.Interval.Duration > 0 {
+		return t.Add(d.cfg.Interval.Duration)
+	}
+	return time.Time{}
+}
+
+// due 判断当前是否应该发布合集，queued 为已到时间的待发布稿件数。
+// 到了定时时间但没有稿件时直接顺延到下一次。
+func (d *digest) due(now time.Time, queued int) bool {
+	timeUp := !d.next.IsZero() && !now.Before(d.next)
+	if queued == 0 {
+		if timeUp {
+			d.next = d.nextAfter(now)
+		}
+		return false
+	}
+	if n := d.threshold(); n > 0 && queued >= n {
+		return true
+	}
+	return timeUp
+}
+
+// pollDigest 合集模式：攒够稿件或到达定时时间后，把最多 max_cards 条稿件合并为一条说说发布，
+// 正文使用 digest.template 模板。登录态失效、不在发布时段或额度用完时不领取，到点的合集顺延到下一个可发布时间。
+func (w *Worker) pollDigest(workerID int) {
+	// 同一时间只有一个协程处理合集，避免多个协程各领一半。
+	if !w.digestMu.TryLock() {
+		return
+	}
+	defer w.digestMu.Unlock()
+
+	owner := w.leaseOwner(workerID)
+	w.recoverLeases(workerID, owner)
+	if !w.canPublish(workerID) {
+		return
+	}
+
+	queued, err := w.store.CountDueApproved()
+	if err != nil {
+		log.Printf("[Worker-%d] 统计待发布稿件失败: %v", workerID, err)
+		return
+	}
+	now := time.Now()
+	if !w.digest.due(now, queued) {
+		return
+	}
+
+	posts, err := w.store.ClaimApprovedPosts(owner, w.cfg.LeaseTimeout.Duration, w.cfg.Digest.MaxCards)
+	if err != nil {
+		log.Printf("[Worker-%d] 领取稿件失败: %v", workerID, err)
+		return
+	}
+	if len(posts) == 0 {
+		return
+	}
+	w.digest.next = w.digest.nextAfter(now)
+	for _, p := range posts {
+		w.audit(owner, model.ActionClaim, p.ID, model.StatusApproved, model.StatusPublishing, "合集")
+	}
+	log.Printf("[Worker-%d] 合集发布 %d 条稿件", workerID, len(posts))
+	w.publish(owner, posts)
+}
+
+// formatNext 格式化下一次定时发布时间，用于日志
+func formatNext(t time.Time) string {
+	if t.IsZero() {
+		return "无"
+	}
+	return t.Format("2006-01-02 15:04")
+}
@@ -0,0 +1,54 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+)
+
+// TestDigestNextAfter 合集定时发布时间计算
+// 运行方法: go test -v ./internal/task/
+func TestDigestNextAfter(t *testing.T) {
+	d := newDigest(config.DigestConfig{MaxCards: 9, Times: []string{"22:00", "12:00", "18:00"}}, time.Now())
+	base := time.Date(2024, 5, 1, 13, 0, 0, 0, time.Local)
+	if got := d.nextAfter(base); !got.Equal(time.Date(2024, 5, 1, 18, 0, 0, 0, time.Local)) {
+		t.Fatalf("13:00 之后应为 18:00, 实际 %v", got)
+	}
+	late := time.Date(2024, 5, 1, 23, 0, 0, 0, time.Local)
+	if got := d.nextAfter(late); !got.Equal(time.Date(2024, 5, 2, 12, 0, 0, 0, time.Local)) {
+		t.Fatalf("23:00 之后应为次日 12:00, 实际 %v", got)
+	}
+
+	d = newDigest(config.DigestConfig{MaxCards: 9, Interval: config.Duration{Duration: time.Hour}}, base)
+	if !d.next.Equal(base.Add(time.Hour)) {
+		t.Fatalf("按间隔发布时下一次应为 1 小时后, 实际 %v", d.next)
+	}
+}
+
+func TestDigestDue(t *testing.T) {
+	base := time.Date(2024, 5, 1, 13, 0, 0, 0, time.Local)
+	d := newDigest(config.DigestConfig{MaxCards: 9, Threshold: 5, Interval: config.Duration{Duration: time.Hour}}, base)
+
+	if d.due(base.Add(time.Minute), 4) {
+		t.Fatalf("未到时间且未达阈值不应发布")
+	}
+	if !d.due(base.Add(time.Minute), 5) {
+		t.Fatalf("达到阈值应立即发布")
+	}
+	if !d.due(base.Add(time.Hour), 1) {
+		t.Fatalf("到达定时时间应发布")
+	}
+	if d.due(base.Add(2*time.Hour), 0) {
+		t.Fatalf("没有稿件不应发布")
+	}
+	if !d.next.After(base.Add(2 * time.Hour)) {
+		t.Fatalf("没有稿件时应顺延下一次定时时间, 实际 %v", d.next)
+	}
+
+	// 既无时间表也无阈值时攒满 max_cards 再发。
+	d = newDigest(config.DigestConfig{MaxCards: 3}, base)
+	if d.due(base, 2) || !d.due(base, 3) {
+		t.Fatalf("未配置时间表时应在攒满 3 条后发布")
+	}
+}
@@ -12,17 +12,21 @@ import (
 
 	qzone "github.com/guohuiyuan/qzone-go"
 	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/publish"
 	"github.com/mdp/qrterminal/v3"
 	"github.com/tuotoo/qrcode"
 	zero "github.com/wdvxdr1123/ZeroBot"
-	"github.com/wdvxdr1123/ZeroBot/message"
 )
 
-// KeepAlive 定期校验 QQ 空间 Cookie 有效性并自动刷新。
+// breakerProbeInterval 熔断期间校验 Cookie 的间隔，重新登录后尽快恢复发布
+const breakerProbeInterval = time.Minute
+
+// KeepAlive 定期校验号池中一个账号的 Cookie 有效性并自动刷新，每个账号一个实例。
 type KeepAlive struct {
 	qzoneCfg config.QzoneConfig
-	botCfg   config.BotConfig
-	client   *qzone.Client
+	pool     *publish.Pool
+	account  *publish.Account
+	vault    *CookieVault
 	ctx      context.Context
 	cancel   context.CancelFunc
 }
@@ -32,77 +36,137 @@ type CookieResult struct {
 	Err    error
 }
 
-func NewKeepAlive(qzoneCfg config.QzoneConfig, botCfg config.BotConfig, client *qzone.Client) *KeepAlive {
+// NewKeepAlive 创建账号保活任务。Cookie 失效时断开该账号的熔断器（号池切换到备用号），
+// 校验通过后恢复，断开和恢复由号池通知管理员。
+func NewKeepAlive(qzoneCfg config.QzoneConfig, pool *publish.Pool, account *publish.Account, vault *CookieVault) *KeepAlive {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &KeepAlive{qzoneCfg: qzoneCfg, botCfg: botCfg, client: client, ctx: ctx, cancel: cancel}
+	return &KeepAlive{qzoneCfg: qzoneCfg, pool: pool, account: account, vault: vault, ctx: ctx, cancel: cancel}
 }
 
 func (k *KeepAlive) Start() {
 	if k.qzoneCfg.KeepAlive.Duration <= 0 {
-		log.Println("[KeepAlive] disabled (keep_alive <= 0)")
-		return
+		// 不定期保活，但熔断后仍需要校验 Cookie 以恢复发布
+		log.Printf("%s keep_alive <= 0, only probing while account is down", k.prefix())
+	} else {
+		log.Printf("%s started, interval=%v", k.prefix(), k.qzoneCfg.KeepAlive.Duration)
 	}
 	go k.run()
-	log.Printf("[KeepAlive] started, interval=%v", k.qzoneCfg.KeepAlive.Duration)
 }
 
 func (k *KeepAlive) Stop() { k.cancel() }
 
+func (k *KeepAlive) prefix() string {
+	return "[KeepAlive:" + k.account.Name + "]"
+}
+
 func (k *KeepAlive) run() {
-	ticker := time.NewTicker(k.qzoneCfg.KeepAlive.Duration)
-	defer ticker.Stop()
+	var tick <-chan time.Time
+	if k.qzoneCfg.KeepAlive.Duration > 0 {
+		ticker := time.NewTicker(k.qzoneCfg.KeepAlive.Duration)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	probe := time.NewTicker(breakerProbeInterval)
+	defer probe.Stop()
 
 	for {
 		select {
 		case <-k.ctx.Done():
-			log.Println("[KeepAlive] stopped")
+			log.Printf("%s stopped", k.prefix())
 			return
-		case <-ticker.C:
+		case <-tick:
 			k.check()
+		case <-probe.C:
+			if k.account.Health.Open() {
+				k.check()
+			}
 		}
 	}
 }
 
 func (k *KeepAlive) check() {
-	log.Println("[KeepAlive] validating cookie via GetUserInfo...")
-	if _, err := validateCookieWithUserInfo(k.ctx, k.client); err == nil {
-		log.Println("[KeepAlive] cookie valid")
+	log.Printf("%s validating cookie via GetUserInfo...", k.prefix())
+	if _, err := validateCookieWithUserInfo(k.ctx, k.account.Client); err == nil {
+		log.Printf("%s cookie valid", k.prefix())
+		k.healthy()
 		return
 	}
 
-	log.Println("[KeepAlive] cookie invalid, trying refresh from bot")
+	log.Printf("%s cookie invalid, trying refresh from bot", k.prefix())
 	if k.tryRefreshFromBot() {
-		return
+		if _, err := validateCookieWithUserInfo(k.ctx, k.account.Client); err == nil {
+			k.healthy()
+			return
+		}
+		log.Printf("%s cookie from bot still invalid", k.prefix())
 	}
 
-	k.notifyAdmin("⚠️ QQ空间 Cookie 已过期，请使用 /扫码 或 /刷新cookie 重新登录")
+	// 号池负责通知，同一次失效只通知一次
+	k.pool.Trip(k.account, "KeepAlive 校验失败")
+}
+
+// healthy Cookie 校验通过：保存 Cookie 并恢复该账号
+func (k *KeepAlive) healthy() {
+	k.vault.Save(k.account.Client)
+	k.pool.Reset(k.account)
 }
 
 func (k *KeepAlive) tryRefreshFromBot() bool {
-	var refreshed bool
-	zero.RangeBot(func(id int64, ctx *zero.Ctx) bool {
+	if k.account.Source == config.CookieSourceQR {
+		return false
+	}
+	cookie, ok := tryGetCookieFromBots(k.prefix(), k.account.UIN)
+	if !ok {
+		return false
+	}
+	if err := k.account.Client.UpdateCookie(cookie); err != nil {
+		log.Printf("%s refresh from bot failed: %v", k.prefix(), err)
+		return false
+	}
+	log.Printf("%s refreshed from bot, UIN=%d", k.prefix(), k.account.Client.UIN())
+	return true
+}
 
-		cookie := ctx.GetCookies("qzone.qq.com")
-		if cookie == "" {
-			return true
-		}
-		if err := k.client.UpdateCookie(cookie); err != nil {
-			log.Printf("[KeepAlive] refresh from bot(%d) failed: %v", id, err)
-			return true
+// Bootstrap 启动时为账号获取可用的 Cookie：已保存的 Cookie 仍然有效时直接使用（不再弹扫码），
+// 否则从机器人获取，terminalQR 为 true 时降级为终端扫码登录，其余账号需要在后台或通过 /扫码 登录。
+func Bootstrap(qzoneCfg config.QzoneConfig, account *publish.Account, vault *CookieVault, restored, terminalQR bool) {
+	prefix := "[Main:" + account.Name + "]"
+	if restored {
+		if err := EnsureCookieValidOnStartup(qzoneCfg, account); err == nil {
+			vault.Save(account.Client)
+			log.Printf("%s saved cookie still valid, skip bootstrap, uin=%d", prefix, account.Client.UIN())
+			return
 		}
-		log.Printf("[KeepAlive] refreshed from bot(%d), UIN=%d", id, k.client.UIN())
-		refreshed = true
-		return false
-	})
-	return refreshed
+		log.Printf("%s saved cookie invalid, fallback to cookie bootstrap", prefix)
+	}
+
+	log.Printf("%s async cookie bootstrap started", prefix)
+	res := <-TryGetCookieAsync(account.Config(), terminalQR)
+	if res.Err != nil {
+		log.Printf("%s async cookie bootstrap failed: %v", prefix, res.Err)
+		log.Printf("%s use /扫码 %s or web admin QR login to refresh cookie", prefix, account.Name)
+		return
+	}
+	if err := account.Client.UpdateCookie(res.Cookie); err != nil {
+		log.Printf("%s async cookie update failed: %v", prefix, err)
+		return
+	}
+	log.Printf("%s async cookie bootstrap success, uin=%d", prefix, account.Client.UIN())
+
+	if err := EnsureCookieValidOnStartup(qzoneCfg, account); err != nil {
+		log.Printf("%s startup cookie validation failed: %v", prefix, err)
+		return
+	}
+	vault.Save(account.Client)
 }
 
 // EnsureCookieValidOnStartup validates cookie once during startup and
 // attempts a single refresh flow when invalid.
-func EnsureCookieValidOnStartup(_ config.QzoneConfig, botCfg config.BotConfig, client *qzone.Client) error {
-	if client == nil {
+func EnsureCookieValidOnStartup(_ config.QzoneConfig, account *publish.Account) error {
+	if account == nil || account.Client == nil {
 		return fmt.Errorf("nil qzone client")
 	}
+	client := account.Client
 
 	log.Println("[Startup] validating cookie via GetUserInfo...")
 	info, err := validateCookieWithUserInfo(context.Background(), client)
@@ -123,7 +187,7 @@ func EnsureCookieValidOnStartup(_ config.QzoneConfig, botCfg config.BotConfig, c
 		return nil
 	}
 
-	refreshFn := RefreshCookie(botCfg)
+	refreshFn := RefreshCookie(account.Config())
 	newCookie, refreshErr := refreshFn()
 	if refreshErr != nil {
 		return fmt.Errorf("startup refresh failed: %w", refreshErr)
@@ -146,20 +210,17 @@ func validateCookieWithUserInfo(parent context.Context, client *qzone.Client) (*
 	return client.GetMyInfo(ctx)
 }
 
-func (k *KeepAlive) notifyAdmin(text string) {
-	if k.botCfg.ManageGroup <= 0 {
-		return
+// TryGetCookie sources cookie from two methods in fixed order:
+// 1) ZeroBot GetCookies (only the bot logged in as the account's UIN, skipped for source=qr)
+// 2) QR login in terminal (only when terminalQR is true)
+func TryGetCookie(account config.QzoneAccount, terminalQR bool) (string, error) {
+	if account.Source == config.CookieSourceQR {
+		if !terminalQR {
+			return "", fmt.Errorf("账号 %s 只支持扫码登录", account.Name)
+		}
+		return tryQRLogin()
 	}
-	zero.RangeBot(func(id int64, ctx *zero.Ctx) bool {
-		ctx.SendGroupMessage(k.botCfg.ManageGroup, message.Text(text))
-		return false
-	})
-}
 
-// TryGetCookie sources cookie from two methods in fixed order:
-// 1) ZeroBot GetCookies
-// 2) QR login
-func TryGetCookie(_ config.QzoneConfig) (string, error) {
 	// 优化：启动后先硬等待 2 秒。
 	// 原因：Bot 连接 WS 和同步 Cookie 需要几百毫秒到 1 秒的时间。
 	// 直接循环会导致第一次必定失败，不如先等一下，通常能一次命中。
@@ -169,7 +230,7 @@ func TryGetCookie(_ config.QzoneConfig) (string, error) {
 	const maxAttempts = 5
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
 		// 尝试从 Bot 获取
-		cookie, ok := tryGetCookieFromBots(fmt.Sprintf("[Init-%d]", attempt))
+		cookie, ok := tryGetCookieFromBots(fmt.Sprintf("[Init-%d]", attempt), account.UIN)
 		if ok {
 			log.Println("[Init] ✅ 成功从 Bot 获取到 Cookie")
 			return cookie, nil
@@ -180,6 +241,9 @@ func TryGetCookie(_ config.QzoneConfig) (string, error) {
 		}
 	}
 
+	if !terminalQR {
+		return "", fmt.Errorf("没有机器人返回账号 %s 的 Cookie", account.Name)
+	}
 	log.Println("[Init] 所有 Bot 均未返回有效 Cookie，降级使用二维码登录")
 	return tryQRLogin()
 }
@@ -187,50 +251,54 @@ func TryGetCookie(_ config.QzoneConfig) (string, error) {
 // TryGetCookieAsync runs the full cookie bootstrap flow in background:
 // 1) ZeroBot GetCookies retries
 // 2) fallback QR login
-func TryGetCookieAsync(qzoneCfg config.QzoneConfig) <-chan CookieResult {
+func TryGetCookieAsync(account config.QzoneAccount, terminalQR bool) <-chan CookieResult {
 	ch := make(chan CookieResult, 1)
 	go func() {
 		defer close(ch)
-		cookie, err := TryGetCookie(qzoneCfg)
+		cookie, err := TryGetCookie(account, terminalQR)
 		ch <- CookieResult{Cookie: cookie, Err: err}
 	}()
 	return ch
 }
 
-// RefreshCookie is used by qzone.WithOnSessionExpired callback.
-func RefreshCookie(botCfg config.BotConfig) func() (string, error) {
+// RefreshCookie is used by qzone.WithOnSessionExpired callback of each account.
+func RefreshCookie(account config.QzoneAccount) func() (string, error) {
 	return func() (string, error) {
-		log.Println("[SessionExpired] cookie expired, trying bot GetCookies...")
-		cookie, ok := tryGetCookieFromBots("[SessionExpired]")
-		if ok {
-			return cookie, nil
+		if account.Source != config.CookieSourceQR {
+			log.Printf("[SessionExpired] account %s cookie expired, trying bot GetCookies...", account.Name)
+			if cookie, ok := tryGetCookieFromBots("[SessionExpired]", account.UIN); ok {
+				return cookie, nil
+			}
 		}
 
-		if botCfg.ManageGroup > 0 {
-			zero.RangeBot(func(id int64, ctx *zero.Ctx) bool {
-				ctx.SendGroupMessage(botCfg.ManageGroup, message.Text("⚠️ QQ空间 Cookie 过期，GetCookies 刷新失败，请使用 /扫码 重新登录"))
-				return false
-			})
-		}
-		return "", fmt.Errorf("cookie refresh failed; please scan QR manually")
+		// 不在这里通知管理员：每个失败的请求都会触发回调，
+		// 由号池在账号失效时通知一次。
+		return "", fmt.Errorf("cookie refresh failed; please scan QR manually: %w", publish.ErrSessionExpired)
 	}
 }
 
-func tryGetCookieFromBots(prefix string) (string, bool) {
+// tryGetCookieFromBots 从机器人获取 Cookie，uin 大于 0 时只使用登录该QQ号的机器人
+func tryGetCookieFromBots(prefix string, uin int64) (string, bool) {
 	seenBots := 0
 	var cookie string
 	zero.RangeBot(func(id int64, ctx *zero.Ctx) bool {
+		if uin > 0 && id != uin {
+			return true
+		}
 		seenBots++
 		c := ctx.GetCookies("qzone.qq.com")
 		if c == "" {
 			log.Printf("%s bot(%d) GetCookies empty", prefix, id)
 			return true
 		}
-		log.Printf("%s bot(%d) GetCookies=%s", prefix, id, c)
+		// 不记录 Cookie 内容，避免日志泄露登录态
+		log.Printf("%s bot(%d) GetCookies ok", prefix, id)
 		cookie = c
 		return false
 	})
-	if seenBots == 0 {
+	if seenBots == 0 && uin > 0 {
+		log.Printf("%s no bot logged in as %d for GetCookies", prefix, uin)
+	} else if seenBots == 0 {
 		log.Printf("%s no bot context available for GetCookies", prefix)
 	}
 	return cookie, cookie != ""
@@ -0,0 +1,82 @@
+package task
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+// Purger 定期彻底删除回收站中超过保留天数的稿件及其上传图片。
+type Purger struct {
+	wallCfg   config.WallConfig
+	store     *store.Store
+	uploadDir string
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// NewPurger creates a recycle-bin purger.
+func NewPurger(wallCfg config.WallConfig, st *store.Store, uploadDir string) *Purger {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Purger{wallCfg: wallCfg, store: st, uploadDir: uploadDir, ctx: ctx, cancel: cancel}
+}
+
+func (p *Purger) Start() {
+	if p.wallCfg.RecycleDays < 0 {
+		log.Println("[Purger] disabled (recycle_days < 0)")
+		return
+	}
+	go p.run()
+	log.Printf("[Purger] started, recycle_days=%d", p.wallCfg.RecycleDays)
+}
+
+func (p *Purger) Stop() { p.cancel() }
+
+func (p *Purger) run() {
+	p.purge()
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			log.Println("[Purger] stopped")
+			return
+		case <-ticker.C:
+			p.purge()
+		}
+	}
+}
+
+func (p *Purger) purge() {
+	before := time.Now().AddDate(0, 0, -p.wallCfg.RecycleDays).Unix()
+	posts, err := p.store.ListDeletedBefore(before)
+	if err != nil {
+		log.Printf("[Purger] 查询回收站失败: %v", err)
+		return
+	}
+	for _, post := range posts {
+		if err := p.store.PurgePost(post, p.uploadDir); err != nil {
+			log.Printf("[Purger] 彻底删除稿件 #%d 失败: %v", post.ID, err)
+			continue
+		}
+		if err := p.store.AddAuditEvent(&model.AuditEvent{
+			ActorName: "purger",
+			Source:    model.SourceWorker,
+			Action:    model.ActionPurge,
+			PostID:    post.ID,
+			OldStatus: model.StatusDeleted,
+			Reason:    "超过回收站保留期",
+		}); err != nil {
+			log.Printf("[Purger] 写入审计日志失败: %v", err)
+		}
+	}
+	if len(posts) > 0 {
+		log.Printf("[Purger] 已彻底删除 %d 条过期稿件", len(posts))
+	}
+}
@@ -0,0 +1,139 @@
+package task
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+	"github.com/guohuiyuan/qzonewall-go/internal/notify"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+// 回调请求头，签名头见 notify.HeaderSignature
+const (
+	HeaderEvent    = "X-Wall-Event"    // 事件类型，如 post.published
+	HeaderDelivery = "X-Wall-Delivery" // 事件 ID，重试和重新投递时不变，接收方可据此去重
+)
+
+// webhookBatch 每轮最多投递的记录数
+const webhookBatch = 20
+
+// WebhookDispatcher 定期从发件箱领取到期的回调并投递，失败时按指数退避重试，
+// 超过最多次数后标记为 failed，可在网页后台手动重新投递。
+type WebhookDispatcher struct {
+	cfg    config.WebhookConfig
+	store  *store.Store
+	client *http.Client
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewWebhookDispatcher 创建回调投递任务，st 为不区分墙的存储
+func NewWebhookDispatcher(cfg config.WebhookConfig, st *store.Store) *WebhookDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WebhookDispatcher{
+		cfg:    cfg,
+		store:  st,
+		client: &http.Client{Timeout: cfg.Timeout.Duration},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (d *WebhookDispatcher) Start() {
+	go d.run()
+	log.Printf("[Webhook] started, poll=%v max_attempts=%d", d.cfg.PollInterval.Duration, d.cfg.Backoff.MaxAttempts)
+}
+
+func (d *WebhookDispatcher) Stop() { d.cancel() }
+
+func (d *WebhookDispatcher) run() {
+	ticker := time.NewTicker(d.cfg.PollInterval.Duration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			log.Println("[Webhook] stopped")
+			return
+		case <-ticker.C:
+			d.drain()
+		}
+	}
+}
+
+// drain 连续投递，直到发件箱中没有到期的回调
+func (d *WebhookDispatcher) drain() {
+	for d.ctx.Err() == nil {
+		if d.dispatch() < webhookBatch {
+			return
+		}
+	}
+}
+
+// dispatch 投递一批到期的回调，返回领取的记录数
+func (d *WebhookDispatcher) dispatch() int {
+	// 租约覆盖整批投递的最长耗时，进程中途退出时租约到期后重新投递
+	deliveries, err := d.store.ClaimDueWebhooks(webhookBatch, webhookBatch*d.cfg.Timeout.Duration+time.Minute)
+	if err != nil {
+		log.Printf("[Webhook] 读取发件箱失败: %v", err)
+		return 0
+	}
+	subs := make(map[int64]*model.WebhookSubscription)
+	for _, dl := range deliveries {
+		sub, ok := subs[dl.SubscriptionID]
+		if !ok {
+			if sub, err = d.store.GetWebhookSubscription(dl.SubscriptionID); err != nil {
+				log.Printf("[Webhook] 读取订阅 #%d 失败: %v", dl.SubscriptionID, err)
+				continue
+			}
+			subs[dl.SubscriptionID] = sub
+		}
+		d.deliver(sub, dl)
+	}
+	return len(deliveries)
+}
+
+// deliver 投递一条回调并记录结果
+func (d *WebhookDispatcher) deliver(sub *model.WebhookSubscription, dl *model.WebhookDelivery) {
+	if sub == nil {
+		_ = d.store.WebhookAttemptFailed(dl.ID, 0, "订阅已删除", 0)
+		return
+	}
+	header := http.Header{}
+	header.Set(HeaderEvent, dl.Event)
+	header.Set(HeaderDelivery, dl.EventID)
+	ctx, cancel := context.WithTimeout(d.ctx, d.cfg.Timeout.Duration)
+	code, err := notify.PostSigned(ctx, d.client, sub.URL, sub.Secret, []byte(dl.Payload), header)
+	cancel()
+	if err == nil {
+		if err := d.store.WebhookDelivered(dl.ID, code); err != nil {
+			log.Printf("[Webhook] 记录投递 #%d 结果失败: %v", dl.ID, err)
+		}
+		return
+	}
+
+	attempts := dl.Attempts + 1
+	var next int64
+	if attempts < d.cfg.Backoff.MaxAttempts {
+		next = time.Now().Add(d.backoff(attempts)).Unix()
+		log.Printf("[Webhook] 投递 #%d %s 到 %s 失败（第 %d 次），%v 后重试: %v", dl.ID, dl.Event, sub.URL, attempts, d.backoff(attempts), err)
+	} else {
+		log.Printf("[Webhook] 投递 #%d %s 到 %s 失败 %d 次，不再重试: %v", dl.ID, dl.Event, sub.URL, attempts, err)
+	}
+	if err := d.store.WebhookAttemptFailed(dl.ID, code, err.Error(), next); err != nil {
+		log.Printf("[Webhook] 记录投递 #%d 结果失败: %v", dl.ID, err)
+	}
+}
+
+// backoff 第 attempts 次失败后的等待时间：base * 2^(attempts-1)，不超过 max_delay
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.Backoff.BaseDelay.Duration
+	for i := 1; i < attempts && delay < d.cfg.Backoff.MaxDelay.Duration; i++ {
+		delay *= 2
+	}
+	return min(delay, d.cfg.Backoff.MaxDelay.Duration)
+}
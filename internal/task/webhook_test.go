@@ -0,0 +1,99 @@
+package task
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+	"github.com/guohuiyuan/qzonewall-go/internal/notify"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+// TestWebhookDispatcher 测试回调带签名和事件头投递，失败后退避重试，超过次数标记为失败
+// 运行方法: go test -v ./internal/task/ -run TestWebhookDispatcher
+func TestWebhookDispatcher(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	var mu sync.Mutex
+	var calls int
+	var got model.WebhookPayload
+	var deliveryID string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !notify.Verify("s3cret", r.Header.Get(notify.HeaderTimestamp), body, r.Header.Get(notify.HeaderSignature)) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get(HeaderEvent) != model.EventCookieExpired {
+			http.Error(w, "bad event", http.StatusBadRequest)
+			return
+		}
+		deliveryID = r.Header.Get(HeaderDelivery)
+		_ = json.Unmarshal(body, &got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sub := &model.WebhookSubscription{URL: srv.URL, Secret: "s3cret", Enabled: true}
+	bad := &model.WebhookSubscription{URL: srv.URL, Secret: "wrong", Enabled: true}
+	_ = st.CreateWebhookSubscription(sub)
+	_ = st.CreateWebhookSubscription(bad)
+	_, err = st.ForWall("a").EmitWebhook(&model.WebhookPayload{
+		Event:   model.EventCookieExpired,
+		Reason:  "code=-3000",
+		Account: &model.WebhookAccount{Name: "主号", UIN: 10001},
+	})
+	if err != nil {
+		t.Fatalf("写入发件箱失败: %v", err)
+	}
+
+	d := NewWebhookDispatcher(config.WebhookConfig{
+		Timeout: config.Duration{Duration: 5 * time.Second},
+		Backoff: config.BackoffConfig{MaxAttempts: 2, BaseDelay: config.Duration{Duration: time.Hour}, MaxDelay: config.Duration{Duration: time.Hour}},
+	}, st)
+	defer d.Stop()
+
+	if n := d.dispatch(); n != 2 {
+		t.Fatalf("应领取 2 条投递, 实际 %d", n)
+	}
+	list, _ := st.ListWebhookDeliveries(0, model.DeliveryPending, 10, 0)
+	if len(list) != 2 || list[0].Attempts != 1 || list[0].NextAttempt < time.Now().Add(50*time.Minute).Unix() {
+		t.Fatalf("第 1 次失败后应退避 1 小时: %+v", list)
+	}
+
+	// 手动重新投递：签名正确的订阅投递成功，签名错误的订阅失败后超过次数
+	for _, dl := range list {
+		_, _ = st.RedeliverWebhook(dl.ID)
+	}
+	d.cfg.Backoff.MaxAttempts = 1
+	d.dispatch()
+	ok, _ := st.ListWebhookDeliveries(sub.ID, model.DeliveryDelivered, 10, 0)
+	if len(ok) != 1 || ok[0].ResponseCode != http.StatusNoContent {
+		t.Fatalf("签名正确的订阅应投递成功: %+v", ok)
+	}
+	if got.Event != model.EventCookieExpired || got.WallID != "a" || got.Account == nil || got.Account.UIN != 10001 || deliveryID != got.ID {
+		t.Fatalf("回调内容错误: %+v (delivery=%s)", got, deliveryID)
+	}
+	failed, _ := st.ListWebhookDeliveries(bad.ID, model.DeliveryFailed, 10, 0)
+	if len(failed) != 1 || failed[0].ResponseCode != http.StatusUnauthorized || failed[0].Attempts != 1 {
+		t.Fatalf("签名错误的订阅应在超过次数后标记为失败: %+v", failed)
+	}
+}
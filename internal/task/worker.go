@@ -4,51 +4,49 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"strings"
 	"sync"
 	"time"
 
-	qzone "github.com/guohuiyuan/qzone-go"
 	"github.com/guohuiyuan/qzonewall-go/internal/config"
 	"github.com/guohuiyuan/qzonewall-go/internal/model"
-	"github.com/guohuiyuan/qzonewall-go/internal/render"
+	"github.com/guohuiyuan/qzonewall-go/internal/publish"
 	"github.com/guohuiyuan/qzonewall-go/internal/store"
-
-	zero "github.com/wdvxdr1123/ZeroBot" // 新增引入
 )
 
 // Worker 定时轮询已通过稿件并发布到 QQ 空间。
 type Worker struct {
-	cfg         config.WorkerConfig
-	wallCfg     config.WallConfig
-	client      *qzone.Client
-	store       *store.Store
-	renderer    *render.Renderer
-	ctx         context.Context
-	cancel      context.CancelFunc
-	wg          sync.WaitGroup
-	lastPublish time.Time
-	mu          sync.Mutex
+	cfg       config.WorkerConfig
+	store     *store.Store
+	publisher *publish.Service
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+
+	digest   *digest    // 合集模式调度状态，未启用时为 nil
+	digestMu sync.Mutex // 保证同一时间只有一个协程处理合集
+
+	quotaMu   sync.Mutex
+	quotaNext time.Time // 上次记录日志的顺延时间，避免每次轮询重复输出
 }
 
 // NewWorker creates a worker.
 func NewWorker(
 	cfg config.WorkerConfig,
-	wallCfg config.WallConfig,
-	client *qzone.Client,
 	st *store.Store,
-	renderer *render.Renderer,
+	publisher *publish.Service,
 ) *Worker {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &Worker{
-		cfg:      cfg,
-		wallCfg:  wallCfg,
-		client:   client,
-		store:    st,
-		renderer: renderer,
-		ctx:      ctx,
-		cancel:   cancel,
+	w := &Worker{
+		cfg:       cfg,
+		store:     st,
+		publisher: publisher,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	if cfg.Digest.Enable {
+		w.digest = newDigest(cfg.Digest, time.Now())
 	}
+	return w
 }
 
 // Start 启动 worker goroutine。
@@ -57,10 +55,14 @@ func (w *Worker) Start() {
 		w.wg.Add(1)
 		go w.run(i)
 	}
-	log.Printf("[Worker] 启动 %d 个工作协程，轮询间隔=%v", w.cfg.Workers, w.cfg.PollInterval.Duration)
+	log.Printf("[Worker] 启动 %d 个工作协程，轮询间隔=%v，表白墙=%s", w.cfg.Workers, w.cfg.PollInterval.Duration, w.store.WallID())
+	if w.digest != nil {
+		log.Printf("[Worker] 合集模式已启用：每条最多 %d 张卡片，积压阈值=%d，下次定时发布=%s",
+			w.cfg.Digest.MaxCards, w.digest.threshold(), formatNext(w.digest.next))
+	}
 }
 
-// Stop 优雅停止。
+// Stop 优雅停止：不再领取新稿件，等待进行中的发布完成。
 func (w *Worker) Stop() {
 	w.cancel()
 	w.wg.Wait()
@@ -80,146 +82,107 @@ func (w *Worker) run(id int) {
 			log.Printf("[Worker-%d] 收到停止信号", id)
 			return
 		case <-ticker.C:
-			w.pollAndPublish(id)
+			if w.digest != nil {
+				w.pollDigest(id)
+			} else {
+				w.pollAndPublish(id)
+			}
 		}
 	}
 }
 
 func (w *Worker) pollAndPublish(workerID int) {
-	// 拉取已通过但未发布的稿件 (tid='')。
-	posts, err := w.store.GetApprovedPosts(1)
+	owner := w.leaseOwner(workerID)
+	w.recoverLeases(workerID, owner)
+	if !w.canPublish(workerID) {
+		return
+	}
+
+	// 原子领取一条已通过但未发布的稿件 (tid='')。
+	post, err := w.store.ClaimApprovedPost(owner, w.cfg.LeaseTimeout.Duration)
 	if err != nil {
-		log.Printf("[Worker-%d] 查询失败: %v", workerID, err)
+		log.Printf("[Worker-%d] 领取稿件失败: %v", workerID, err)
 		return
 	}
-	if len(posts) == 0 {
+	if post == nil {
 		return
 	}
+	w.audit(owner, model.ActionClaim, post.ID, model.StatusApproved, model.StatusPublishing, "")
 
-	post := posts[0]
 	log.Printf("[Worker-%d] 处理稿件 #%d", workerID, post.ID)
-
-	// 频率限制。
-	w.waitRateLimit()
-
-	// Publish with retries.
-	var lastErr error
-	for retry := 0; retry <= w.cfg.RetryCount; retry++ {
-		if retry > 0 {
-			log.Printf("[Worker-%d] 重试第 %d 次...", workerID, retry)
-			time.Sleep(w.cfg.RetryDelay.Duration)
-		}
-
-		err := w.publish(post)
-		if err == nil {
-			log.Printf("[Worker-%d] 稿件 #%d 发布成功, tid=%s", workerID, post.ID, post.TID)
-			return
-		}
-		lastErr = err
-		log.Printf("[Worker-%d] 发布失败: %v", workerID, err)
-	}
-
-	// 所有重试失败后标记为失败。
-	post.Status = model.StatusFailed
-	post.Reason = fmt.Sprintf("发布失败: %v", lastErr)
-	if err := w.store.SavePost(post); err != nil {
-		log.Printf("[Worker-%d] 更新状态失败: %v", workerID, err)
-	}
-	log.Printf("[Worker-%d] 稿件 #%d 最终发布失败: %v", workerID, post.ID, lastErr)
+	w.publish(owner, []*model.Post{post})
 }
 
-// publish 发布到 QQ 空间。
-func (w *Worker) publish(post *model.Post) error {
-	// 构建说说文本。
-	text := post.Text
-	if w.wallCfg.ShowAuthor && !post.Anon {
-		text = fmt.Sprintf("【来自 %s 的投稿】\n\n%s", post.ShowName(), text)
-	}
-
-	// Only publish rendered screenshot, never raw images.
-	if !w.renderer.Available() {
-		return fmt.Errorf("publish: renderer not available")
+// publish 通过发布服务发布已领取的稿件。
+// 停止时不取消进行中的发布：QQ空间可能已经收到说说，中途取消会在下次启动时重复发布，Stop 等待其完成。
+func (w *Worker) publish(owner string, posts []*model.Post) {
+	res := w.publisher.Publish(context.WithoutCancel(w.ctx), posts, owner, publish.Actor{Name: owner, Source: model.SourceWorker})
+	switch {
+	case len(res.Deferred) > 0:
+		log.Printf("[Worker] %d 条稿件顺延发布: %v", len(res.Deferred), res.Err)
+	case res.Err != nil:
+		log.Printf("[Worker] 稿件最终发布失败: %v", res.Err)
 	}
+}
 
-	// 渲染前解析 file ID 为 URL
-	renderPost := w.resolvePostImages(post)
-	screenshot, err := w.renderer.RenderPost(renderPost)
-	if err != nil {
-		return fmt.Errorf("publish: render screenshot: %w", err)
+// canPublish 领取前检查号池、发布时段和额度，不可发布时稿件留在 approved 等待下一个可发布时间。
+// 没有可用账号时的日志和通知由号池负责，这里只跳过。
+func (w *Worker) canPublish(workerID int) bool {
+	if w.publisher.Pool().Primary() == nil {
+		return false
 	}
-
-	opt := &qzone.PublishOption{ImageBytes: [][]byte{screenshot}}
-
-	resp, err := w.client.Publish(w.ctx, text, opt)
+	st, err := w.publisher.QuotaStatus(time.Now())
 	if err != nil {
-		return fmt.Errorf("publish: %w", err)
-	}
-	if !resp.OK {
-		return fmt.Errorf("publish failed: code=%d, msg=%s", resp.Code, resp.Message)
+		log.Printf("[Worker-%d] 查询发布额度失败: %v", workerID, err)
+		return false
 	}
-
-	// 回填 TID。
-	if tid := resp.GetString("tid"); tid != "" {
-		post.TID = tid
-	} else if tid := resp.GetString("t1_tid"); tid != "" {
-		post.TID = tid
-	} else {
-		// Fallback when API does not return a tid.
-		post.TID = fmt.Sprintf("published_%d", time.Now().Unix())
+	if st.Allowed() {
+		return true
 	}
-
-	post.Status = model.StatusPublished
-	if err := w.store.SavePost(post); err != nil {
-		log.Printf("[Worker] 回填 TID 失败: %v", err)
+	w.quotaMu.Lock()
+	defer w.quotaMu.Unlock()
+	if !st.Next.Equal(w.quotaNext) {
+		w.quotaNext = st.Next
+		log.Printf("[Worker] %s，暂停发布至 %s", st.Reason, formatNext(st.Next))
 	}
-
-	// 记录发布时间。
-	w.mu.Lock()
-	w.lastPublish = time.Now()
-	w.mu.Unlock()
-
-	return nil
+	return false
 }
 
-// ── Image Resolution Helpers ──
-
-func (w *Worker) resolvePostImages(p *model.Post) *model.Post {
-	clone := *p
-	clone.Images = make([]string, len(p.Images))
-	for i, img := range p.Images {
-		clone.Images[i] = w.resolveImageURL(img)
+// recoverLeases 回收租约过期的稿件（上次崩溃或超时遗留）。
+func (w *Worker) recoverLeases(workerID int, owner string) {
+	ids, err := w.store.RecoverExpiredLeases()
+	if err != nil {
+		log.Printf("[Worker-%d] 回收过期租约失败: %v", workerID, err)
+		return
+	}
+	if len(ids) > 0 {
+		log.Printf("[Worker-%d] 回收 %d 条租约过期的稿件", workerID, len(ids))
+		for _, id := range ids {
+			w.audit(owner, model.ActionRecover, id, model.StatusPublishing, model.StatusApproved, "租约过期")
+		}
 	}
-	return &clone
 }
 
-func (w *Worker) resolveImageURL(img string) string {
-	if strings.HasPrefix(img, "http") {
-		return img
-	}
-	var resolved string
-	zero.RangeBot(func(id int64, ctx *zero.Ctx) bool {
-		resolved = ctx.GetImage(img).Get("url").String()
-		return true
+// audit 记录 Worker 触发的状态变更。
+func (w *Worker) audit(owner, action string, postID int64, oldStatus, newStatus model.PostStatus, reason string) {
+	err := w.store.AddAuditEvent(&model.AuditEvent{
+		ActorName: owner,
+		Source:    model.SourceWorker,
+		Action:    action,
+		PostID:    postID,
+		OldStatus: oldStatus,
+		NewStatus: newStatus,
+		Reason:    reason,
 	})
-	if resolved != "" {
-		return resolved
+	if err != nil {
+		log.Printf("[Worker] 写入审计日志失败: %v", err)
 	}
-	return img
 }
 
-// waitRateLimit 等待频率限制窗口。
-func (w *Worker) waitRateLimit() {
-	w.mu.Lock()
-	last := w.lastPublish
-	w.mu.Unlock()
-
-	if last.IsZero() {
-		return
-	}
-	elapsed := time.Since(last)
-	if elapsed < w.cfg.RateLimit.Duration {
-		wait := w.cfg.RateLimit.Duration - elapsed
-		log.Printf("[Worker] 频率限制，等待 %v", wait)
-		time.Sleep(wait)
+// leaseOwner 生成租约持有者标识（主机名+进程号+墙+协程编号），跨进程唯一。
+func (w *Worker) leaseOwner(workerID int) string {
+	if wall := w.store.WallID(); wall != "" {
+		return publish.LeaseOwner(fmt.Sprintf("worker-%s-%d", wall, workerID))
 	}
+	return publish.LeaseOwner(fmt.Sprintf("worker-%d", workerID))
 }
@@ -0,0 +1,204 @@
+// Package wall 多墙：同一进程中运行多个相互独立的表白墙。
+// 每个墙拥有自己的来源群、管理群、QQ空间账号、卡片主题和敏感词，稿件按墙 ID 隔离保存。
+package wall
+
+import (
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+	"github.com/guohuiyuan/qzonewall-go/internal/notify"
+	"github.com/guohuiyuan/qzonewall-go/internal/publish"
+	"github.com/guohuiyuan/qzonewall-go/internal/render"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+// Wall 一个表白墙
+type Wall struct {
+	ID          string
+	Name        string
+	Groups      []int64  // 来源群，为空表示接收其他墙未认领的所有群
+	ManageGroup int64    // 管理群
+	Admins      []string // 可以管理本墙的网页账号
+	Open        bool     // 未配置 walls 时的默认墙，所有账号都可以管理
+	CensorWords []string // 全局敏感词 + 本墙敏感词
+
+	Store     *store.Store     // 只读写本墙稿件的存储视图
+	Pool      *publish.Pool    // 本墙的QQ空间账号
+	Renderer  *render.Renderer // 使用本墙主题的渲染器
+	Publisher *publish.Service // 本墙的发布服务
+	Notifier  *notify.Notifier // 本墙的管理员通知
+}
+
+// New 按配置创建墙：存储视图限定在墙 ID，渲染器使用墙的主题，敏感词为全局与本墙合并，
+// 发布服务使用墙的号池 pool，账号失效和发布失败通过墙的通知渠道提醒管理员。
+func New(cfg *config.Config, entry config.WallEntry, st *store.Store, pool *publish.Pool, renderer *render.Renderer, uploadDir string) *Wall {
+	words := store.LoadCensorWords(cfg.Censor.Words, cfg.Censor.WordsFile)
+	words = append(words, store.LoadCensorWords(entry.Censor.Words, entry.Censor.WordsFile)...)
+	w := &Wall{
+		ID:          entry.ID,
+		Name:        entry.Name,
+		Groups:      entry.Groups,
+		ManageGroup: entry.ManageGroup,
+		Admins:      entry.Admins,
+		Open:        len(cfg.Walls) == 0,
+		CensorWords: words,
+		Store:       st.ForWall(entry.ID),
+		Pool:        pool,
+		Renderer:    renderer.WithTheme(entry.Theme),
+	}
+	w.Notifier = notify.New(cfg.Notify, w.String(), entry.ManageGroup, cfg.Bot.Zero.SuperUsers)
+	pool.Notify = w.Notifier.Func(notify.AlertCookie)
+	pool.OnTrip = w.cookieExpired
+	w.Publisher = publish.NewService(cfg.Worker, cfg.Wall, pool, w.Store, w.Renderer, uploadDir)
+	w.Publisher.Alert = w.Notifier.Func(notify.AlertPublishFailed)
+	return w
+}
+
+// cookieExpired 账号登录态失效时发出 cookie.expired 回调事件
+func (w *Wall) cookieExpired(acc *publish.Account, reason string) {
+	_, err := w.Store.EmitWebhook(&model.WebhookPayload{
+		Event:   model.EventCookieExpired,
+		Reason:  reason,
+		Account: &model.WebhookAccount{Name: acc.Name, UIN: acc.CurrentUIN()},
+	})
+	if err != nil {
+		log.Printf("[Wall] %s 写入回调事件 %s 失败: %v", w, model.EventCookieExpired, err)
+	}
+}
+
+// HasGroup 群是否为本墙的来源群
+func (w *Wall) HasGroup(groupID int64) bool {
+	return slices.Contains(w.Groups, groupID)
+}
+
+// CanManage 网页账号能否管理本墙：管理员可以管理所有墙，未配置 walls 时所有账号都可以管理默认墙，
+// 否则其他角色需要在 walls[].admins 中，停用的账号不能管理任何墙
+func (w *Wall) CanManage(a *model.Account) bool {
+	if a == nil || a.Disabled {
+		return false
+	}
+	return a.IsAdmin() || w.Open || slices.Contains(w.Admins, a.Username)
+}
+
+// String 用于日志和通知，如 "一中(school1)"
+func (w *Wall) String() string {
+	if w.Name == "" || w.Name == w.ID {
+		return w.ID
+	}
+	return w.Name + "(" + w.ID + ")"
+}
+
+// Registry 进程中的所有表白墙，按配置顺序排列，第一个为默认墙
+type Registry struct {
+	walls []*Wall
+	store *store.Store
+}
+
+// NewRegistry 创建空的墙列表，st 为不区分墙的存储
+func NewRegistry(st *store.Store) *Registry {
+	return &Registry{store: st}
+}
+
+// Add 添加一个墙
+func (r *Registry) Add(w *Wall) {
+	r.walls = append(r.walls, w)
+}
+
+// All 按配置顺序返回所有墙
+func (r *Registry) All() []*Wall {
+	return r.walls
+}
+
+// Multi 是否配置了多个墙，只有一个墙时界面和消息中不显示墙名
+func (r *Registry) Multi() bool {
+	return len(r.walls) > 1
+}
+
+// Store 不区分墙的存储，用于账号、会话、备份等全局数据
+func (r *Registry) Store() *store.Store {
+	return r.store
+}
+
+// Default 第一个墙
+func (r *Registry) Default() *Wall {
+	if len(r.walls) == 0 {
+		return nil
+	}
+	return r.walls[0]
+}
+
+// Get 按 ID 查找墙，id 为空时返回默认墙；找不到时返回 nil
+func (r *Registry) Get(id string) *Wall {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return r.Default()
+	}
+	for _, w := range r.walls {
+		if w.ID == id {
+			return w
+		}
+	}
+	return nil
+}
+
+// ByGroup 按来源群选择墙：优先选择 groups 中包含该群的墙，其次是 groups 为空的墙；都没有时返回 nil
+func (r *Registry) ByGroup(groupID int64) *Wall {
+	var fallback *Wall
+	for _, w := range r.walls {
+		if w.HasGroup(groupID) {
+			return w
+		}
+		if len(w.Groups) == 0 && fallback == nil {
+			fallback = w
+		}
+	}
+	return fallback
+}
+
+// ByManageGroup 返回以该群为管理群的墙；没有或多个墙共用该管理群时返回 nil，由调用方按全部墙处理
+func (r *Registry) ByManageGroup(groupID int64) *Wall {
+	if groupID <= 0 {
+		return nil
+	}
+	var found *Wall
+	for _, w := range r.walls {
+		if w.ManageGroup != groupID {
+			continue
+		}
+		if found != nil {
+			return nil
+		}
+		found = w
+	}
+	return found
+}
+
+// ForAccount 返回网页账号可以管理的墙
+func (r *Registry) ForAccount(a *model.Account) []*Wall {
+	var walls []*Wall
+	for _, w := range r.walls {
+		if w.CanManage(a) {
+			walls = append(walls, w)
+		}
+	}
+	return walls
+}
+
+// Account 按名称或QQ号在所有墙的号池中查找QQ空间账号，key 为空时返回默认墙的第一个账号
+func (r *Registry) Account(key string) (*Wall, *publish.Account) {
+	if strings.TrimSpace(key) == "" {
+		if w := r.Default(); w != nil {
+			return w, w.Pool.Get("")
+		}
+		return nil, nil
+	}
+	for _, w := range r.walls {
+		if acc := w.Pool.Get(key); acc != nil {
+			return w, acc
+		}
+	}
+	return nil, nil
+}
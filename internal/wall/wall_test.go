@@ -0,0 +1,49 @@
+package wall
+
+import (
+	"testing"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+// TestRegistryRouting 测试投稿按来源群路由到墙、管理群和网页账号的墙权限
+// 运行方法: go test -v ./internal/wall/ -run TestRegistryRouting
+func TestRegistryRouting(t *testing.T) {
+	r := NewRegistry(nil)
+	school := &Wall{ID: "school", Groups: []int64{100, 101}, ManageGroup: 900, Admins: []string{"alice"}}
+	city := &Wall{ID: "city", Groups: []int64{200}, ManageGroup: 900}
+	rest := &Wall{ID: "rest", ManageGroup: 901}
+	r.Add(school)
+	r.Add(city)
+	r.Add(rest)
+
+	cases := map[int64]*Wall{101: school, 200: city, 300: rest, 0: rest}
+	for group, want := range cases {
+		if got := r.ByGroup(group); got != want {
+			t.Fatalf("群 %d 应进入 %s, 实际 %v", group, want.ID, got)
+		}
+	}
+
+	if got := r.ByManageGroup(901); got != rest {
+		t.Fatalf("管理群 901 应属于 rest, 实际 %v", got)
+	}
+	if got := r.ByManageGroup(900); got != nil {
+		t.Fatalf("共用的管理群应返回 nil, 实际 %v", got)
+	}
+
+	if r.Get("") != school || r.Get("city") != city || r.Get("nope") != nil {
+		t.Fatalf("按 ID 查找墙错误")
+	}
+
+	alice := &model.Account{Username: "alice", Role: "user"}
+	if walls := r.ForAccount(alice); len(walls) != 1 || walls[0] != school {
+		t.Fatalf("alice 只能管理 school, 实际 %v", walls)
+	}
+	admin := &model.Account{Username: "root", Role: "admin"}
+	if walls := r.ForAccount(admin); len(walls) != 3 {
+		t.Fatalf("管理员应能管理所有墙, 实际 %d", len(walls))
+	}
+	if walls := r.ForAccount(nil); len(walls) != 0 {
+		t.Fatalf("未登录不能管理任何墙")
+	}
+}
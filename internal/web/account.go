@@ -0,0 +1,228 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+// accountView 账号管理列表中的一个网页账号
+type accountView struct {
+	*model.Account
+	RoleName    string             `json:"role_name"`
+	Permissions []model.Permission `json:"permissions"`
+	Walls       []string           `json:"walls"` // 可以管理的墙 ID
+}
+
+// handleAPIAccounts GET 列出所有网页账号，POST 创建账号（username、password、role）。
+// password 为空时自动生成，生成的密码只在创建成功的响应中返回一次
+func (s *Server) handleAPIAccounts(w http.ResponseWriter, r *http.Request) {
+	account := s.currentAccount(r)
+	if !account.Can(model.PermAccounts) {
+		jsonResp(w, 403, false, "无权限")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.store.ListAccounts()
+		if err != nil {
+			jsonResp(w, 500, false, "读取账号失败")
+			return
+		}
+		views := make([]accountView, len(list))
+		for i, a := range list {
+			views[i] = s.accountView(a)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok":       true,
+			"accounts": views,
+			"roles":    model.Roles,
+		})
+	case http.MethodPost:
+		username := strings.TrimSpace(r.FormValue("username"))
+		role := r.FormValue("role")
+		if username == "" || len(username) > 32 || strings.ContainsFunc(username, unicode.IsSpace) {
+			jsonResp(w, 400, false, "用户名不能为空、不能包含空格，最多 32 个字符")
+			return
+		}
+		if !model.ValidRole(role) {
+			jsonResp(w, 400, false, "未知的角色: "+role)
+			return
+		}
+		password, generated, ok := accountPassword(w, r.FormValue("password"))
+		if !ok {
+			return
+		}
+		if err := CreateAccount(s.store, username, password, role); err != nil {
+			jsonResp(w, 400, false, "创建账号失败: "+err.Error())
+			return
+		}
+		s.audit(account, model.ActionAccount, 0, "", "", fmt.Sprintf("创建账号 %s (%s)", username, role))
+		msg := fmt.Sprintf("已创建账号 %s（%s）", username, model.RoleName(role))
+		if role != model.RoleAdmin && len(s.walls.ForAccount(&model.Account{Username: username, Role: role})) == 0 {
+			msg += "。该账号还不能管理任何墙，请在系统设置 walls[].admins 中加入用户名"
+		}
+		accountPasswordResp(w, msg, password, generated)
+	default:
+		jsonResp(w, 405, false, "仅支持 GET/POST")
+	}
+}
+
+// handleAPIAccountRole 修改账号角色（id、role），不能修改自己的角色，也不能降级最后一个管理员
+func (s *Server) handleAPIAccountRole(w http.ResponseWriter, r *http.Request) {
+	account, target := s.accountTarget(w, r)
+	if target == nil {
+		return
+	}
+	role := r.FormValue("role")
+	if !model.ValidRole(role) {
+		jsonResp(w, 400, false, "未知的角色: "+role)
+		return
+	}
+	if target.ID == account.ID {
+		jsonResp(w, 400, false, "不能修改自己的角色")
+		return
+	}
+	if target.Role == role {
+		jsonResp(w, 200, true, "角色没有变化")
+		return
+	}
+	if !s.keepsAdmin(w, target) {
+		return
+	}
+	if err := s.store.SetAccountRole(target.ID, role); err != nil {
+		jsonResp(w, 500, false, "修改角色失败")
+		return
+	}
+	s.audit(account, model.ActionAccount, 0, "", "", fmt.Sprintf("账号 %s 角色 %s → %s", target.Username, target.Role, role))
+	jsonResp(w, 200, true, fmt.Sprintf("账号 %s 已改为%s", target.Username, model.RoleName(role)))
+}
+
+// handleAPIAccountDisable 停用（disabled=1）或启用（disabled=0）账号。
+// 停用后账号不能登录，已登录的会话立即失效，API 密钥也不能再使用
+func (s *Server) handleAPIAccountDisable(w http.ResponseWriter, r *http.Request) {
+	account, target := s.accountTarget(w, r)
+	if target == nil {
+		return
+	}
+	disabled := r.FormValue("disabled") != "0"
+	if disabled && target.ID == account.ID {
+		jsonResp(w, 400, false, "不能停用自己的账号")
+		return
+	}
+	if disabled && !s.keepsAdmin(w, target) {
+		return
+	}
+	if err := s.store.SetAccountDisabled(target.ID, disabled); err != nil {
+		jsonResp(w, 500, false, "操作失败")
+		return
+	}
+	verb := "启用"
+	if disabled {
+		verb = "停用"
+	}
+	s.audit(account, model.ActionAccount, 0, "", "", verb+"账号 "+target.Username)
+	jsonResp(w, 200, true, fmt.Sprintf("已%s账号 %s", verb, target.Username))
+}
+
+// handleAPIAccountReset 重置账号密码（id、password），password 为空时自动生成。
+// 重置后该账号已登录的会话全部失效
+func (s *Server) handleAPIAccountReset(w http.ResponseWriter, r *http.Request) {
+	account, target := s.accountTarget(w, r)
+	if target == nil {
+		return
+	}
+	password, generated, ok := accountPassword(w, r.FormValue("password"))
+	if !ok {
+		return
+	}
+	if err := ResetPassword(s.store, target.Username, password); err != nil {
+		jsonResp(w, 500, false, "重置密码失败")
+		return
+	}
+	s.audit(account, model.ActionAccount, 0, "", "", "重置账号 "+target.Username+" 的密码")
+	accountPasswordResp(w, "已重置账号 "+target.Username+" 的密码", password, generated)
+}
+
+// accountTarget 校验 POST 和账号管理权限，读取 id 对应的账号；失败时已写入响应并返回 nil
+func (s *Server) accountTarget(w http.ResponseWriter, r *http.Request) (account, target *model.Account) {
+	if r.Method != http.MethodPost {
+		jsonResp(w, 405, false, "仅支持 POST")
+		return nil, nil
+	}
+	account = s.currentAccount(r)
+	if !account.Can(model.PermAccounts) {
+		jsonResp(w, 403, false, "无权限")
+		return nil, nil
+	}
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		jsonResp(w, 400, false, "编号格式错误")
+		return nil, nil
+	}
+	target, err = s.store.GetAccountByID(id)
+	if err != nil || target == nil {
+		jsonResp(w, 404, false, "账号不存在")
+		return nil, nil
+	}
+	return account, target
+}
+
+// keepsAdmin target 是最后一个可用的管理员时拒绝停用或降级，避免没有人能管理系统
+func (s *Server) keepsAdmin(w http.ResponseWriter, target *model.Account) bool {
+	if !target.IsAdmin() || target.Disabled {
+		return true
+	}
+	n, err := s.store.CountActiveAdmins()
+	if err != nil {
+		jsonResp(w, 500, false, "读取账号失败")
+		return false
+	}
+	if n <= 1 {
+		jsonResp(w, 400, false, "至少需要保留一个可用的管理员")
+		return false
+	}
+	return true
+}
+
+// accountPassword 校验管理员设置的密码，为空时生成随机密码；失败时已写入响应
+func accountPassword(w http.ResponseWriter, password string) (string, bool, bool) {
+	if password == "" {
+		return randomHex(6), true, true
+	}
+	if len(password) < 6 {
+		jsonResp(w, 400, false, "密码至少6位")
+		return "", false, false
+	}
+	return password, false, true
+}
+
+// accountPasswordResp 成功响应，密码是自动生成的时候一并返回（只返回这一次）
+func accountPasswordResp(w http.ResponseWriter, msg, password string, generated bool) {
+	w.Header().Set("Content-Type", "application/json")
+	resp := map[string]interface{}{"ok": true, "message": msg}
+	if generated {
+		resp["password"] = password
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) accountView(a *model.Account) accountView {
+	v := accountView{Account: a, RoleName: model.RoleName(a.Role), Permissions: []model.Permission{}, Walls: []string{}}
+	for _, p := range model.Permissions {
+		if a.Can(p) {
+			v.Permissions = append(v.Permissions, p)
+		}
+	}
+	for _, wl := range s.walls.ForAccount(a) {
+		v.Walls = append(v.Walls, wl.ID)
+	}
+	return v
+}
@@ -0,0 +1,107 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+	"github.com/guohuiyuan/qzonewall-go/internal/publish"
+	"github.com/guohuiyuan/qzonewall-go/internal/render"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+	"github.com/guohuiyuan/qzonewall-go/internal/wall"
+)
+
+// TestRolePermissions 测试各角色只能调用有权限的接口：只读账号不能拒稿和接口投稿，审核员不能管理账号，
+// 管理员停用账号后该账号的会话立即失效，且不能停用最后一个管理员
+// 运行方法: go test -v ./internal/web/ -run TestRolePermissions
+func TestRolePermissions(t *testing.T) {
+	st, err := store.New(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+
+	// 未配置 walls 的单墙模式：所有账号都可以管理默认墙
+	cfg := &config.Config{}
+	walls := wall.NewRegistry(st)
+	walls.Add(wall.New(cfg, cfg.WallList()[0], st, publish.NewPool(), render.NewRenderer(), t.TempDir()))
+	s := &Server{prefix: "/wall", store: st, walls: walls}
+	mux := s.routes()
+
+	sessions := map[string]string{}
+	ids := map[string]int64{}
+	for name, role := range map[string]string{"boss": model.RoleAdmin, "rev": model.RoleReviewer, "view": model.RoleViewer} {
+		if err := CreateAccount(st, name, "123456", role); err != nil {
+			t.Fatalf("创建账号失败: %v", err)
+		}
+		a, _ := st.GetAccount(name)
+		token := randomHex(16)
+		if err := st.CreateSession(token, a.ID, time.Now().Add(time.Hour).Unix()); err != nil {
+			t.Fatalf("创建会话失败: %v", err)
+		}
+		sessions[name], ids[name] = token, a.ID
+	}
+	post := &model.Post{UIN: 10001, Text: "hello", Status: model.StatusPending}
+	if err := st.ForWall(model.DefaultWall).SavePost(post); err != nil {
+		t.Fatalf("保存稿件失败: %v", err)
+	}
+
+	call := func(user, path string, form url.Values) int {
+		req := httptest.NewRequest(http.MethodPost, "/wall"+path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: "session", Value: sessions[user]})
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	postID := url.Values{"id": {strconv.FormatInt(post.ID, 10)}}
+
+	login := httptest.NewRequest(http.MethodPost, "/wall/login", strings.NewReader(url.Values{"username": {"view"}, "password": {"123456"}}.Encode()))
+	login.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, login)
+	if rec.Code != http.StatusFound || len(rec.Result().Cookies()) == 0 {
+		t.Fatalf("单墙模式下只读账号应能登录，实际 %d", rec.Code)
+	}
+
+	viewKey := randomHex(16)
+	if err := st.CreateAPIKey(&model.APIKey{AccountID: ids["view"], Scopes: []string{model.ScopePostsWrite}}, hashAPIKey(viewKey)); err != nil {
+		t.Fatalf("创建 API 密钥失败: %v", err)
+	}
+	apiReq := httptest.NewRequest(http.MethodPost, "/wall/api/v1/posts", strings.NewReader(`{"text":"hi","uin":12345}`))
+	apiReq.Header.Set("X-API-Key", viewKey)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, apiReq)
+	if rec.Code != 403 {
+		t.Fatalf("只读账号的密钥不应能代人投稿，实际 %d", rec.Code)
+	}
+
+	if code := call("view", "/api/reject", postID); code != 403 {
+		t.Fatalf("只读账号拒稿应返回 403，实际 %d", code)
+	}
+	if code := call("rev", "/api/reject", postID); code != 200 {
+		t.Fatalf("审核员拒稿应成功，实际 %d", code)
+	}
+	if code := call("rev", "/api/accounts", url.Values{"username": {"x"}, "role": {model.RoleViewer}}); code != 403 {
+		t.Fatalf("审核员创建账号应返回 403，实际 %d", code)
+	}
+	if code := call("boss", "/api/accounts/disable", url.Values{"id": {strconv.FormatInt(ids["boss"], 10)}}); code != 400 {
+		t.Fatalf("不应能停用自己，实际 %d", code)
+	}
+	if code := call("boss", "/api/accounts/disable", url.Values{"id": {strconv.FormatInt(ids["rev"], 10)}}); code != 200 {
+		t.Fatalf("停用审核员失败，实际 %d", code)
+	}
+	if code := call("rev", "/api/reject", postID); code != 403 {
+		t.Fatalf("停用后的账号应返回 403，实际 %d", code)
+	}
+	if code := call("boss", "/api/accounts/role", url.Values{"id": {strconv.FormatInt(ids["view"], 10)}, "role": {"owner"}}); code != 400 {
+		t.Fatalf("未知角色应返回 400，实际 %d", code)
+	}
+}
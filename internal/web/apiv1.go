@@ -0,0 +1,659 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+	"github.com/guohuiyuan/qzonewall-go/internal/notify"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+	"github.com/guohuiyuan/qzonewall-go/internal/wall"
+)
+
+// /api/v1 是面向脚本和集成的接口：请求和响应都是 JSON，使用 API 密钥认证
+// （Authorization: Bearer <key> 或 X-API-Key 请求头），错误统一返回
+// {"error": {"code": "...", "message": "..."}}，列表使用游标分页。
+
+// apiKeyPrefix API 密钥明文的前缀，便于在日志和代码仓库中识别泄露的密钥
+const apiKeyPrefix = "qw_"
+
+// /api/v1 错误码
+const (
+	errInvalidRequest = "invalid_request" // 参数或请求体错误
+	errUnauthorized   = "unauthorized"    // 缺少或无效的 API 密钥
+	errForbidden      = "forbidden"       // 密钥没有所需的权限范围，账号角色没有所需的权限，或账号无权管理该墙
+	errNotFound       = "not_found"
+	errConflict       = "conflict" // 稿件当前状态不允许该操作
+	errInternal       = "internal"
+)
+
+// apiError /api/v1 的错误对象
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// apiPage /api/v1 列表响应，next_cursor 为空表示没有更多数据
+type apiPage struct {
+	Data       interface{} `json:"data"`
+	NextCursor string      `json:"next_cursor"`
+}
+
+// /api/v1 请求体，OpenAPI 文档中的请求体结构也由这些类型生成
+type (
+	v1PostCreate struct {
+		Text string `json:"text"`
+		Name string `json:"name,omitempty"` // 省略时为密钥所属的用户名
+		UIN  int64  `json:"uin,omitempty"`
+		Anon bool   `json:"anon,omitempty"`
+	}
+	// v1PostUpdate 只修改出现的字段
+	v1PostUpdate struct {
+		Text *string `json:"text,omitempty"`
+		Name *string `json:"name,omitempty"`
+		Anon *bool   `json:"anon,omitempty"`
+	}
+	v1Approve struct {
+		PublishAt string `json:"publish_at,omitempty"` // 定时发布时间，如 2025-01-02T21:00
+	}
+	v1Reject struct {
+		Reason string `json:"reason,omitempty"`
+	}
+)
+
+// apiRequest 认证通过的 /api/v1 请求
+type apiRequest struct {
+	account *model.Account
+	key     *model.APIKey
+}
+
+// registerAPIv1 注册 /api/v1 路由
+func (s *Server) registerAPIv1(mux *routeMux) {
+	mux.HandleFunc("GET "+s.url("/api/v1/me"), s.v1("", model.PermView, s.handleV1Me))
+	mux.HandleFunc("GET "+s.url("/api/v1/posts"), s.v1(model.ScopePostsRead, model.PermView, s.handleV1ListPosts))
+	// 接口投稿可以指定投稿者 uin，发布后会私聊通知该QQ号，只读账号不能代人投稿
+	mux.HandleFunc("POST "+s.url("/api/v1/posts"), s.v1(model.ScopePostsWrite, model.PermReview, s.handleV1CreatePost))
+	mux.HandleFunc("GET "+s.url("/api/v1/posts/{id}"), s.v1(model.ScopePostsRead, model.PermView, s.handleV1GetPost))
+	mux.HandleFunc("PATCH "+s.url("/api/v1/posts/{id}"), s.v1(model.ScopePostsWrite, model.PermReview, s.handleV1UpdatePost))
+	mux.HandleFunc("DELETE "+s.url("/api/v1/posts/{id}"), s.v1(model.ScopePostsWrite, model.PermDelete, s.handleV1DeletePost))
+	mux.HandleFunc("POST "+s.url("/api/v1/posts/{id}/approve"), s.v1(model.ScopePostsReview, model.PermReview, s.handleV1ApprovePost))
+	mux.HandleFunc("POST "+s.url("/api/v1/posts/{id}/reject"), s.v1(model.ScopePostsReview, model.PermReview, s.handleV1RejectPost))
+	mux.HandleFunc("GET "+s.url("/api/v1/audit"), s.v1(model.ScopeAuditRead, model.PermView, s.handleV1Audit))
+	mux.HandleFunc(s.url("/api/v1")+"/", func(w http.ResponseWriter, r *http.Request) {
+		apiFail(w, http.StatusNotFound, errNotFound, "接口不存在: "+r.Method+" "+r.URL.Path)
+	})
+}
+
+// v1 校验 API 密钥及权限范围 scope（为空时只要求密钥有效）、密钥所属账号的角色权限 perm 后调用 h
+func (s *Server) v1(scope string, perm model.Permission, h func(http.ResponseWriter, *http.Request, *apiRequest)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSpace(r.Header.Get("X-API-Key"))
+		if auth := r.Header.Get("Authorization"); key == "" && strings.HasPrefix(auth, "Bearer ") {
+			key = strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+		}
+		if key == "" {
+			apiFail(w, http.StatusUnauthorized, errUnauthorized, "缺少 API 密钥")
+			return
+		}
+		k, err := s.store.GetAPIKeyByHash(hashAPIKey(key))
+		if err != nil {
+			apiFail(w, http.StatusInternalServerError, errInternal, "读取 API 密钥失败")
+			return
+		}
+		var account *model.Account
+		if k != nil {
+			account, _ = s.store.GetAccountByID(k.AccountID)
+		}
+		if account == nil || account.Disabled {
+			apiFail(w, http.StatusUnauthorized, errUnauthorized, "API 密钥无效或已吊销")
+			return
+		}
+		if scope != "" && !k.HasScope(scope) {
+			apiFail(w, http.StatusForbidden, errForbidden, "API 密钥没有 "+scope+" 权限")
+			return
+		}
+		if !account.Can(perm) {
+			apiFail(w, http.StatusForbidden, errForbidden, fmt.Sprintf("账号角色 %s 没有 %s 权限", account.Role, perm))
+			return
+		}
+		s.store.TouchAPIKey(k.ID)
+		h(w, r, &apiRequest{account: account, key: k})
+	}
+}
+
+// v1Wall 按 wall 查询参数选择墙，省略时为账号可以管理的第一个墙；失败时已写入错误响应并返回 nil
+func (s *Server) v1Wall(w http.ResponseWriter, r *http.Request, req *apiRequest) *wall.Wall {
+	id := r.URL.Query().Get("wall")
+	if id == "" {
+		if walls := s.walls.ForAccount(req.account); len(walls) > 0 {
+			return walls[0]
+		}
+		apiFail(w, http.StatusForbidden, errForbidden, "账号没有可以管理的墙")
+		return nil
+	}
+	wl := s.walls.Get(id)
+	if wl == nil {
+		apiFail(w, http.StatusNotFound, errNotFound, "表白墙不存在: "+id)
+		return nil
+	}
+	if !wl.CanManage(req.account) {
+		apiFail(w, http.StatusForbidden, errForbidden, "账号无权管理表白墙 "+id)
+		return nil
+	}
+	return wl
+}
+
+// v1Post 读取路径中 {id} 对应的稿件及其所属的墙，账号必须能管理该墙；失败时已写入错误响应
+func (s *Server) v1Post(w http.ResponseWriter, r *http.Request, req *apiRequest) (*wall.Wall, *model.Post) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		apiFail(w, http.StatusBadRequest, errInvalidRequest, "稿件编号格式错误")
+		return nil, nil
+	}
+	post, err := s.store.GetPost(id)
+	if err != nil {
+		apiFail(w, http.StatusInternalServerError, errInternal, "读取稿件失败")
+		return nil, nil
+	}
+	// 无权管理的墙中的稿件同样返回 404，不暴露稿件是否存在
+	var wl *wall.Wall
+	if post != nil {
+		wl = s.walls.Get(post.WallID)
+	}
+	if wl == nil || !wl.CanManage(req.account) {
+		apiFail(w, http.StatusNotFound, errNotFound, fmt.Sprintf("稿件 #%d 不存在", id))
+		return nil, nil
+	}
+	return wl, post
+}
+
+// handleV1Me 返回密钥所属的账号和权限范围
+func (s *Server) handleV1Me(w http.ResponseWriter, r *http.Request, req *apiRequest) {
+	var walls []string
+	for _, wl := range s.walls.ForAccount(req.account) {
+		walls = append(walls, wl.ID)
+	}
+	apiJSON(w, http.StatusOK, map[string]interface{}{
+		"account": req.account,
+		"key":     req.key,
+		"walls":   walls,
+	})
+}
+
+// handleV1ListPosts 按条件列出稿件（最新在前）。
+// 查询参数同 /api/posts/search（q、status、since、until、uin、group_id），另有 wall、limit（默认 20，最多 100）和 cursor
+func (s *Server) handleV1ListPosts(w http.ResponseWriter, r *http.Request, req *apiRequest) {
+	wl := s.v1Wall(w, r, req)
+	if wl == nil {
+		return
+	}
+	q := r.URL.Query()
+	limit := 20
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			apiFail(w, http.StatusBadRequest, errInvalidRequest, "limit 必须在 1-100 之间")
+			return
+		}
+		limit = n
+	}
+	sq := parseSearchQuery(q)
+	if c := q.Get("cursor"); c != "" {
+		before, err := decodeCursor(c)
+		if err != nil {
+			apiFail(w, http.StatusBadRequest, errInvalidRequest, "cursor 无效")
+			return
+		}
+		sq.Before = before
+	}
+
+	// 多取一条判断是否还有下一页
+	posts, err := wl.Store.SearchPosts(sq, limit+1, 0)
+	if err != nil {
+		apiFail(w, http.StatusInternalServerError, errInternal, "查询稿件失败")
+		return
+	}
+	var page apiPage
+	if len(posts) > limit {
+		posts = posts[:limit]
+		page.NextCursor = encodeCursor(posts[limit-1].ID)
+	}
+	data := make([]*model.Post, len(posts))
+	for i, p := range posts {
+		data[i] = s.resolvePostImages(p)
+	}
+	page.Data = data
+	apiJSON(w, http.StatusOK, page)
+}
+
+// handleV1CreatePost 投稿，请求体 {"text", "name", "uin", "anon"}，进入待审核
+func (s *Server) handleV1CreatePost(w http.ResponseWriter, r *http.Request, req *apiRequest) {
+	wl := s.v1Wall(w, r, req)
+	if wl == nil {
+		return
+	}
+	var body v1PostCreate
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if strings.TrimSpace(body.Text) == "" {
+		apiFail(w, http.StatusBadRequest, errInvalidRequest, "text 不能为空")
+		return
+	}
+	if body.Name == "" {
+		body.Name = req.account.Username
+	}
+	post := &model.Post{
+		UIN:        body.UIN,
+		Name:       body.Name,
+		Text:       body.Text,
+		Anon:       body.Anon,
+		Status:     model.StatusPending,
+		CreateTime: time.Now().Unix(),
+	}
+	if err := wl.Store.SavePost(post); err != nil {
+		apiFail(w, http.StatusInternalServerError, errInternal, "保存失败")
+		return
+	}
+	s.apiAudit(req, model.ActionCreate, post.ID, "", model.StatusPending, "")
+	log.Printf("[Web] received post #%d via api key %s, wall=%s", post.ID, req.key.Prefix, wl.ID)
+	wl.Notifier.Notify(notify.AlertNewPost, fmt.Sprintf("📬 收到接口投稿 #%d\n%s", post.ID, post.Summary()))
+	apiJSON(w, http.StatusCreated, s.resolvePostImages(post))
+}
+
+// handleV1GetPost 获取单条稿件
+func (s *Server) handleV1GetPost(w http.ResponseWriter, r *http.Request, req *apiRequest) {
+	if _, post := s.v1Post(w, r, req); post != nil {
+		apiJSON(w, http.StatusOK, s.resolvePostImages(post))
+	}
+}
+
+// editableStatuses 可以通过 PATCH 修改内容的稿件状态，发布中和已发布的稿件内容已经提交到QQ空间
+var editableStatuses = []model.PostStatus{model.StatusPending, model.StatusApproved, model.StatusRejected, model.StatusFailed}
+
+// handleV1UpdatePost 修改稿件内容，请求体中出现的字段才会修改：{"text", "name", "anon"}
+func (s *Server) handleV1UpdatePost(w http.ResponseWriter, r *http.Request, req *apiRequest) {
+	wl, post := s.v1Post(w, r, req)
+	if post == nil {
+		return
+	}
+	var body v1PostUpdate
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if !slices.Contains(editableStatuses, post.Status) {
+		apiFail(w, http.StatusConflict, errConflict, fmt.Sprintf("稿件状态为 %s，不能修改", post.Status))
+		return
+	}
+	var changed []string
+	if body.Text != nil {
+		if strings.TrimSpace(*body.Text) == "" && len(post.Images) == 0 {
+			apiFail(w, http.StatusBadRequest, errInvalidRequest, "text 不能为空")
+			return
+		}
+		post.Text = *body.Text
+		changed = append(changed, "text")
+	}
+	if body.Name != nil {
+		post.Name = *body.Name
+		changed = append(changed, "name")
+	}
+	if body.Anon != nil {
+		post.Anon = *body.Anon
+		changed = append(changed, "anon")
+	}
+	if len(changed) == 0 {
+		apiFail(w, http.StatusBadRequest, errInvalidRequest, "没有要修改的字段")
+		return
+	}
+	if !v1StatusResult(w, wl.Store.EditPost(post, editableStatuses...)) {
+		return
+	}
+	s.apiAudit(req, model.ActionEdit, post.ID, post.Status, post.Status, "修改 "+strings.Join(changed, ","))
+	apiJSON(w, http.StatusOK, s.resolvePostImages(post))
+}
+
+// handleV1DeletePost 把稿件移入回收站（不会删除QQ空间的说说）
+func (s *Server) handleV1DeletePost(w http.ResponseWriter, r *http.Request, req *apiRequest) {
+	wl, post := s.v1Post(w, r, req)
+	if post == nil {
+		return
+	}
+	if post.Status == model.StatusDeleted {
+		apiFail(w, http.StatusConflict, errConflict, "稿件已在回收站中")
+		return
+	}
+	if post.Status == model.StatusPublishing {
+		apiFail(w, http.StatusConflict, errConflict, "稿件正在发布，不能删除")
+		return
+	}
+	ok, err := wl.Store.SoftDeletePost(post.ID, req.account.Username)
+	if err != nil {
+		apiFail(w, http.StatusInternalServerError, errInternal, "删除失败")
+		return
+	}
+	if !ok {
+		apiFail(w, http.StatusConflict, errConflict, "稿件的状态已变化（可能已在发布），请重新获取后再试")
+		return
+	}
+	s.apiAudit(req, model.ActionDelete, post.ID, post.Status, model.StatusDeleted, "")
+	s.v1Result(w, wl, post.ID)
+}
+
+// handleV1ApprovePost 过稿，请求体可选 {"publish_at": "2025-01-02T21:00"} 定时发布，
+// 省略时按 wall.publish_delay 延迟发布。只能通过待审核或已拒绝的稿件
+func (s *Server) handleV1ApprovePost(w http.ResponseWriter, r *http.Request, req *apiRequest) {
+	wl, post := s.v1Post(w, r, req)
+	if post == nil {
+		return
+	}
+	var body v1Approve
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if !slices.Contains(model.ApprovableStatuses, post.Status) {
+		apiFail(w, http.StatusConflict, errConflict, fmt.Sprintf("稿件状态为 %s，不能过稿", post.Status))
+		return
+	}
+	publishAt, err := s.publishAt(wl, body.PublishAt)
+	if err != nil {
+		apiFail(w, http.StatusBadRequest, errInvalidRequest, err.Error())
+		return
+	}
+
+	oldStatus := post.Status
+	if !v1StatusResult(w, wl.Store.SetPostStatus(post.ID, model.StatusApproved, "", publishAt, model.ApprovableStatuses...)) {
+		return
+	}
+	post.Status = model.StatusApproved
+	post.Reason = ""
+	post.PublishAt = publishAt
+	if publishAt > 0 {
+		s.apiAudit(req, model.ActionSchedule, post.ID, oldStatus, model.StatusApproved, "定时 "+time.Unix(publishAt, 0).Format("2006-01-02 15:04"))
+	} else {
+		s.apiAudit(req, model.ActionApprove, post.ID, oldStatus, model.StatusApproved, "")
+	}
+	apiJSON(w, http.StatusOK, s.resolvePostImages(post))
+}
+
+// handleV1RejectPost 拒稿，请求体可选 {"reason": "..."}。只能拒绝待审核或已通过未发布的稿件
+func (s *Server) handleV1RejectPost(w http.ResponseWriter, r *http.Request, req *apiRequest) {
+	wl, post := s.v1Post(w, r, req)
+	if post == nil {
+		return
+	}
+	var body v1Reject
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if !slices.Contains(model.RejectableStatuses, post.Status) {
+		apiFail(w, http.StatusConflict, errConflict, fmt.Sprintf("稿件状态为 %s，不能拒稿", post.Status))
+		return
+	}
+
+	oldStatus := post.Status
+	if !v1StatusResult(w, wl.Store.SetPostStatus(post.ID, model.StatusRejected, body.Reason, 0, model.RejectableStatuses...)) {
+		return
+	}
+	post.Status = model.StatusRejected
+	post.Reason = body.Reason
+	post.PublishAt = 0
+	s.apiAudit(req, model.ActionReject, post.ID, oldStatus, model.StatusRejected, body.Reason)
+	apiJSON(w, http.StatusOK, s.resolvePostImages(post))
+}
+
+// v1StatusResult 处理按条件修改稿件的结果：稿件已被 Worker 领取或被其他人处理时返回 409；失败时已写入响应
+func v1StatusResult(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, store.ErrStatusConflict) {
+		apiFail(w, http.StatusConflict, errConflict, "稿件的状态已变化（可能已在发布），请重新获取后再试")
+		return false
+	}
+	if err != nil {
+		apiFail(w, http.StatusInternalServerError, errInternal, "更新失败")
+		return false
+	}
+	return true
+}
+
+// handleV1Audit 列出操作日志（最新在前），查询参数同 /api/audit，分页使用 limit 和 cursor
+func (s *Server) handleV1Audit(w http.ResponseWriter, r *http.Request, req *apiRequest) {
+	wl := s.v1Wall(w, r, req)
+	if wl == nil {
+		return
+	}
+	q := r.URL.Query()
+	limit := 50
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			apiFail(w, http.StatusBadRequest, errInvalidRequest, "limit 必须在 1-200 之间")
+			return
+		}
+		limit = n
+	}
+	filter := store.AuditFilter{
+		Source: model.AuditSource(q.Get("source")),
+		Action: q.Get("action"),
+	}
+	filter.PostID, _ = strconv.ParseInt(q.Get("post_id"), 10, 64)
+	filter.ActorID, _ = strconv.ParseInt(q.Get("actor_id"), 10, 64)
+	filter.Since, filter.Until = parseDateRange(q)
+	if c := q.Get("cursor"); c != "" {
+		before, err := decodeCursor(c)
+		if err != nil {
+			apiFail(w, http.StatusBadRequest, errInvalidRequest, "cursor 无效")
+			return
+		}
+		filter.Before = before
+	}
+
+	events, err := wl.Store.ListAuditEvents(filter, limit+1, 0)
+	if err != nil {
+		apiFail(w, http.StatusInternalServerError, errInternal, "查询操作日志失败")
+		return
+	}
+	page := apiPage{Data: []*model.AuditEvent{}}
+	if len(events) > limit {
+		events = events[:limit]
+		page.NextCursor = encodeCursor(events[limit-1].ID)
+	}
+	if len(events) > 0 {
+		page.Data = events
+	}
+	apiJSON(w, http.StatusOK, page)
+}
+
+// v1Result 返回操作后的稿件
+func (s *Server) v1Result(w http.ResponseWriter, wl *wall.Wall, id int64) {
+	post, err := wl.Store.GetPost(id)
+	if err != nil || post == nil {
+		apiFail(w, http.StatusInternalServerError, errInternal, "读取稿件失败")
+		return
+	}
+	apiJSON(w, http.StatusOK, s.resolvePostImages(post))
+}
+
+// apiAudit 记录 /api/v1 触发的操作，操作者为密钥所属的账号
+func (s *Server) apiAudit(req *apiRequest, action string, postID int64, oldStatus, newStatus model.PostStatus, reason string) {
+	e := &model.AuditEvent{
+		ActorID:   req.account.ID,
+		ActorName: req.account.Username,
+		Source:    model.SourceAPI,
+		Action:    action,
+		PostID:    postID,
+		OldStatus: oldStatus,
+		NewStatus: newStatus,
+		Reason:    reason,
+	}
+	if err := s.store.AddAuditEvent(e); err != nil {
+		log.Printf("[Web] 写入审计日志失败: %v", err)
+	}
+}
+
+// publishAt 解析过稿时指定的发布时间，为空时按 wall.publish_delay 延迟发布，0 表示立即进入发布队列
+func (s *Server) publishAt(wl *wall.Wall, v string) (int64, error) {
+	if v == "" {
+		return wl.Publisher.DelayedPublishAt(time.Now()), nil
+	}
+	t, err := parseDateTime(v)
+	if err != nil {
+		return 0, errors.New("发布时间格式错误")
+	}
+	if !t.After(time.Now()) {
+		return 0, errors.New("发布时间必须晚于当前时间")
+	}
+	return t.Unix(), nil
+}
+
+// decodeJSON 解析 JSON 请求体到 v，请求体为空时保留 v 的零值；失败时已写入错误响应并返回 false
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && err != io.EOF {
+		apiFail(w, http.StatusBadRequest, errInvalidRequest, "请求体不是有效的 JSON: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func apiJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func apiFail(w http.ResponseWriter, status int, code, msg string) {
+	apiJSON(w, status, map[string]apiError{"error": {Code: code, Message: msg}})
+}
+
+// encodeCursor 游标为上一页最后一条记录的 ID，编码后对调用方不透明
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("id:" + strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(c string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil || !strings.HasPrefix(string(b), "id:") {
+		return 0, errors.New("invalid cursor")
+	}
+	return strconv.ParseInt(strings.TrimPrefix(string(b), "id:"), 10, 64)
+}
+
+// hashAPIKey API 密钥入库的哈希。密钥本身是高熵随机串，不需要加盐
+func hashAPIKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+// ──────────────────────────────────────────
+// API 密钥管理（后台页面，使用登录会话）
+// ──────────────────────────────────────────
+
+// handleAPIKeys GET 列出当前账号的 API 密钥（有账号管理权限时列出全部），
+// POST 创建密钥（name、scopes 可重复），明文只在创建成功的响应中返回一次
+func (s *Server) handleAPIKeys(w http.ResponseWriter, r *http.Request) {
+	account := s.currentAccount(r)
+	if account == nil || !s.canManageAny(account) {
+		jsonResp(w, 403, false, "无权限")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		var owner int64
+		if !account.Can(model.PermAccounts) {
+			owner = account.ID
+		}
+		keys, err := s.store.ListAPIKeys(owner)
+		if err != nil {
+			jsonResp(w, 500, false, "读取 API 密钥失败")
+			return
+		}
+		if keys == nil {
+			keys = []*model.APIKey{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok":     true,
+			"keys":   keys,
+			"scopes": model.APIScopes,
+		})
+	case http.MethodPost:
+		_ = r.ParseForm()
+		k := &model.APIKey{AccountID: account.ID, Name: strings.TrimSpace(r.FormValue("name"))}
+		for _, sc := range r.Form["scopes"] {
+			if !slices.Contains(model.APIScopes, sc) {
+				jsonResp(w, 400, false, "未知的权限范围: "+sc)
+				return
+			}
+			if !slices.Contains(k.Scopes, sc) {
+				k.Scopes = append(k.Scopes, sc)
+			}
+		}
+		if len(k.Scopes) == 0 {
+			jsonResp(w, 400, false, "至少选择一个权限范围")
+			return
+		}
+		key := apiKeyPrefix + randomHex(24)
+		k.Prefix = key[:len(apiKeyPrefix)+6]
+		if err := s.store.CreateAPIKey(k, hashAPIKey(key)); err != nil {
+			jsonResp(w, 500, false, "创建 API 密钥失败")
+			return
+		}
+		s.audit(account, model.ActionAPIKey, 0, "", "", fmt.Sprintf("创建密钥 %s… (%s)", k.Prefix, strings.Join(k.Scopes, ",")))
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok":      true,
+			"message": "已创建 API 密钥 " + k.Prefix + "…",
+			"id":      k.ID,
+			"key":     key,
+		})
+	default:
+		jsonResp(w, 405, false, "仅支持 GET/POST")
+	}
+}
+
+// handleAPIKeyRevoke 吊销 API 密钥，有账号管理权限时可以吊销任何账号的密钥
+func (s *Server) handleAPIKeyRevoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonResp(w, 405, false, "仅支持 POST")
+		return
+	}
+	account := s.currentAccount(r)
+	if account == nil || !s.canManageAny(account) {
+		jsonResp(w, 403, false, "无权限")
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		jsonResp(w, 400, false, "编号格式错误")
+		return
+	}
+	var owner int64
+	if !account.Can(model.PermAccounts) {
+		owner = account.ID
+	}
+	ok, err := s.store.RevokeAPIKey(id, owner)
+	if err != nil {
+		jsonResp(w, 500, false, "吊销失败")
+		return
+	}
+	if !ok {
+		jsonResp(w, 404, false, "密钥不存在或已吊销")
+		return
+	}
+	s.audit(account, model.ActionAPIKey, 0, "", "", fmt.Sprintf("吊销密钥 #%d", id))
+	jsonResp(w, 200, true, fmt.Sprintf("密钥 #%d 已吊销", id))
+}
@@ -0,0 +1,112 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+)
+
+// newConfigServer 创建只有一个管理员会话的 Server，返回调用 /api/config 的函数
+func newConfigServer(t *testing.T, cfg *config.Config, cfgPath string) func(method, body string) (int, string) {
+	t.Helper()
+	st, err := store.New(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	s := &Server{store: st, fullCfg: cfg, cfgPath: cfgPath}
+
+	if err := st.CreateAccount("boss", "", "", "admin"); err != nil {
+		t.Fatalf("创建账号失败: %v", err)
+	}
+	a, _ := st.GetAccount("boss")
+	token := randomHex(16)
+	if err := st.CreateSession(token, a.ID, time.Now().Add(time.Hour).Unix()); err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+	return func(method, body string) (int, string) {
+		req := httptest.NewRequest(method, "/api/config", strings.NewReader(body))
+		req.AddCookie(&http.Cookie{Name: "session", Value: token})
+		rec := httptest.NewRecorder()
+		s.handleAPIConfig(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+}
+
+// TestSaveConfigValidate 测试保存配置前先校验：重复的墙 ID 和不存在的通知渠道返回 400，不写入文件也不替换内存中的配置
+// 运行方法: go test -v ./internal/web/ -run TestSaveConfigValidate
+func TestSaveConfigValidate(t *testing.T) {
+	cfg := &config.Config{Wall: config.WallConfig{MaxTextLen: 100}}
+	cfgPath := filepath.Join(t.TempDir(), "config.json")
+	call := newConfigServer(t, cfg, cfgPath)
+	save := func(body string) int {
+		code, _ := call(http.MethodPost, body)
+		return code
+	}
+
+	for name, body := range map[string]string{
+		"重复的墙 ID": `{"walls":[{"id":"a"},{"id":"a"}]}`,
+		"不存在的渠道":  `{"notify":{"routes":{"publish_failed":["nope"]}}}`,
+	} {
+		if code := save(body); code != 400 {
+			t.Fatalf("%s应返回 400，实际 %d", name, code)
+		}
+	}
+	if _, err := os.Stat(cfgPath); !os.IsNotExist(err) {
+		t.Fatalf("校验失败时不应写入配置文件: %v", err)
+	}
+	if cfg.Wall.MaxTextLen != 100 {
+		t.Fatalf("校验失败时不应替换内存中的配置: %+v", cfg.Wall)
+	}
+
+	if code := save(`{"wall":{"max_text_len":200}}`); code != 200 {
+		t.Fatalf("合法配置应保存成功，实际 %d", code)
+	}
+	if cfg.Wall.MaxTextLen != 200 {
+		t.Fatalf("保存后应替换内存中的配置: %+v", cfg.Wall)
+	}
+}
+
+// TestConfigSecrets 测试读取配置时隐藏密钥，保存时留空的密钥沿用原值
+// 运行方法: go test -v ./internal/web/ -run TestConfigSecrets
+func TestConfigSecrets(t *testing.T) {
+	cfg := &config.Config{
+		Qzone: config.QzoneConfig{CookieKey: "vault-key"},
+		Bot:   config.BotConfig{WS: []config.WSConfig{{Url: "ws://127.0.0.1:3001", AccessToken: "ws-token"}}},
+		Notify: config.NotifyConfig{Channels: []config.NotifyChannel{
+			{Name: "mail", Type: config.NotifySMTP, Addr: "smtp:25", From: "a@b", To: []string{"c@d"}, Password: "smtp-pass"},
+		}},
+	}
+	call := newConfigServer(t, cfg, filepath.Join(t.TempDir(), "config.json"))
+
+	code, body := call(http.MethodGet, "")
+	if code != 200 {
+		t.Fatalf("读取配置失败: %d", code)
+	}
+	for _, secret := range []string{"vault-key", "ws-token", "smtp-pass"} {
+		if strings.Contains(body, secret) {
+			t.Fatalf("读取配置不应返回密钥 %s: %s", secret, body)
+		}
+	}
+
+	var resp struct {
+		Config json.RawMessage `json:"config"`
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := call(http.MethodPost, string(resp.Config)); code != 200 {
+		t.Fatalf("原样保存配置失败: %d", code)
+	}
+	if cfg.Qzone.CookieKey != "vault-key" || cfg.Bot.WS[0].AccessToken != "ws-token" || cfg.Notify.Channels[0].Password != "smtp-pass" {
+		t.Fatalf("留空的密钥应沿用原值: %+v", cfg)
+	}
+}
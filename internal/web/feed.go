@@ -0,0 +1,189 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/feed"
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+	"github.com/guohuiyuan/qzonewall-go/internal/store"
+	"github.com/guohuiyuan/qzonewall-go/internal/wall"
+)
+
+// feedPageSize 公开稿件页每页条数
+const feedPageSize = 20
+
+// handleFeedPage 公开的稿件页：已发布且未下架的稿件卡片，最新在前，分页
+func (s *Server) handleFeedPage(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	wl := s.walls.Get(q.Get("wall"))
+	if wl == nil {
+		http.NotFound(w, r)
+		return
+	}
+	page := parsePage(q)
+	total, err := wl.Store.CountByStatus(model.StatusPublished)
+	if err != nil {
+		http.Error(w, "读取稿件失败", http.StatusInternalServerError)
+		return
+	}
+	posts, err := wl.Store.SearchPosts(store.SearchQuery{Status: model.StatusPublished}, feedPageSize, (page-1)*feedPageSize)
+	if err != nil {
+		http.Error(w, "读取稿件失败", http.StatusInternalServerError)
+		return
+	}
+	items := make([]feed.Item, len(posts))
+	for i, p := range posts {
+		items[i] = s.feedItem(p, "")
+	}
+	s.renderFeed(w, wl, map[string]interface{}{
+		"Items":    items,
+		"Page":     page,
+		"PrevPage": page - 1,
+		"NextPage": page + 1,
+		"HasNext":  page*feedPageSize < total,
+		"Total":    total,
+	})
+}
+
+// handleFeedPost 单条稿件的公开页面，订阅中的条目链接到这里
+func (s *Server) handleFeedPost(w http.ResponseWriter, r *http.Request) {
+	wl, post := s.feedPost(r.PathValue("id"))
+	if post == nil {
+		http.NotFound(w, r)
+		return
+	}
+	s.renderFeed(w, wl, map[string]interface{}{
+		"Items":  []feed.Item{s.feedItem(post, "")},
+		"Single": true,
+	})
+}
+
+// handleFeedCard 稿件的卡片图片，与发布到QQ空间的卡片相同（匿名稿件不含头像和昵称），渲染后缓存到磁盘
+func (s *Server) handleFeedCard(w http.ResponseWriter, r *http.Request) {
+	wl, post := s.feedPost(strings.TrimSuffix(r.PathValue("file"), ".jpg"))
+	if post == nil {
+		http.NotFound(w, r)
+		return
+	}
+	img, err := s.cards.Get(post, wl.Publisher.RenderCard)
+	if err != nil {
+		log.Printf("[Web] 渲染稿件 #%d 的卡片失败: %v", post.ID, err)
+		http.Error(w, "卡片渲染失败", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	_, _ = w.Write(img)
+}
+
+// handleFeedRSS / handleFeedAtom / handleFeedJSON 最新 feed.Limit 条已发布稿件的订阅
+func (s *Server) handleFeedRSS(w http.ResponseWriter, r *http.Request) {
+	s.serveFeed(w, r, "rss.xml", feed.ContentTypeRSS, feed.WriteRSS)
+}
+
+func (s *Server) handleFeedAtom(w http.ResponseWriter, r *http.Request) {
+	s.serveFeed(w, r, "atom.xml", feed.ContentTypeAtom, feed.WriteAtom)
+}
+
+func (s *Server) handleFeedJSON(w http.ResponseWriter, r *http.Request) {
+	s.serveFeed(w, r, "feed.json", feed.ContentTypeJSON, feed.WriteJSON)
+}
+
+func (s *Server) serveFeed(w http.ResponseWriter, r *http.Request, name, contentType string, write func(io.Writer, *feed.Channel) error) {
+	wl := s.walls.Get(r.URL.Query().Get("wall"))
+	if wl == nil {
+		http.NotFound(w, r)
+		return
+	}
+	posts, err := wl.Store.SearchPosts(store.SearchQuery{Status: model.StatusPublished}, feed.Limit, 0)
+	if err != nil {
+		http.Error(w, "读取稿件失败", http.StatusInternalServerError)
+		return
+	}
+
+	// 订阅中的链接必须是绝对地址
+	base := requestBaseURL(r)
+	ch := &feed.Channel{
+		Title:       wl.Name,
+		Description: wl.Name + "已发布的投稿",
+		Link:        base + s.url("/feed") + s.feedQuery(wl),
+		FeedURL:     base + s.url("/feed/"+name) + s.feedQuery(wl),
+	}
+	for _, p := range posts {
+		ch.Items = append(ch.Items, s.feedItem(p, base))
+	}
+
+	var buf bytes.Buffer
+	if err := write(&buf, ch); err != nil {
+		log.Printf("[Web] 生成订阅 %s 失败: %v", name, err)
+		http.Error(w, "生成订阅失败", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// feedPost 按编号读取公开的稿件及其所属的墙，稿件不存在或未公开时返回 nil
+func (s *Server) feedPost(idStr string) (*wall.Wall, *model.Post) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+	post, err := s.store.GetPost(id)
+	if err != nil || !feed.Visible(post) {
+		return nil, nil
+	}
+	wl := s.walls.Get(post.WallID)
+	if wl == nil {
+		return nil, nil
+	}
+	return wl, post
+}
+
+// feedItem 生成订阅条目，base 为空时生成站内路径（网页使用），否则生成绝对地址（订阅使用）
+func (s *Server) feedItem(p *model.Post, base string) feed.Item {
+	return feed.NewItem(p,
+		base+s.url(fmt.Sprintf("/feed/%d", p.ID)),
+		base+s.url(fmt.Sprintf("/feed/cards/%d.jpg", p.ID)),
+	)
+}
+
+// feedQuery 非默认墙的页面和订阅地址需要带上 wall 参数
+func (s *Server) feedQuery(wl *wall.Wall) string {
+	if wl == s.walls.Default() {
+		return ""
+	}
+	return "?wall=" + url.QueryEscape(wl.ID)
+}
+
+func (s *Server) renderFeed(w http.ResponseWriter, wl *wall.Wall, data map[string]interface{}) {
+	data["Root"] = s.prefix
+	data["Wall"] = wl
+	data["Walls"] = s.walls.All()
+	data["MultiWall"] = s.walls.Multi()
+	data["WallQuery"] = s.feedQuery(wl)
+	s.renderTemplate(w, "feed.html", data)
+}
+
+// requestBaseURL 请求的站点地址，如 https://example.com，反向代理时取 X-Forwarded-Proto 和 X-Forwarded-Host
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if p := r.Header.Get("X-Forwarded-Proto"); p == "http" || p == "https" {
+		scheme = p
+	}
+	host := r.Host
+	if h := r.Header.Get("X-Forwarded-Host"); h != "" {
+		host = h
+	}
+	return scheme + "://" + host
+}
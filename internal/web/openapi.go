@@ -0,0 +1,649 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/backup"
+	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/importer"
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+// /api/openapi.json 提供 OpenAPI 3.1 文档。接口列表在 apiOps 中手工维护，请求体和响应的结构
+// 由实际序列化的 Go 类型通过反射生成，字段与接口返回的 JSON 保持一致。
+// TestOpenAPICoversRoutes 检查 routes() 注册的每个路由都写进了文档。
+
+// 接口的认证方式
+const (
+	authPublic  = iota // 无需登录
+	authLogin          // 登录会话，任意账号
+	authManager        // 登录会话，账号能管理请求的墙（wall 参数、后台选择的墙或能管理的第一个墙）
+	authAPIKey         // API 密钥，需要 apiOp.scope 权限范围（为空时只要求密钥有效）
+)
+
+// 非 JSON 响应，apiOp.resp 为组件名以外的值时使用
+const (
+	respHTML     = "text/html"
+	respPNG      = "image/png"
+	respRedirect = "redirect"
+	respFile     = "application/octet-stream"
+	respJPEG     = "image/jpeg"
+	respRSS      = "application/rss+xml"
+	respAtom     = "application/atom+xml"
+	respJSONFeed = "application/feed+json"
+)
+
+// apiParam 查询参数或表单字段，typ 为 string、integer、boolean、file 或 array（字符串数组，字段可重复）
+type apiParam struct {
+	name     string
+	typ      string
+	desc     string
+	required bool
+}
+
+// apiOp 文档中的一个接口
+type apiOp struct {
+	method       string
+	path         string // 不含路由前缀，路径参数写作 {id}
+	tag          string
+	summary      string
+	auth         int
+	perm         model.Permission // 账号角色需要的权限，见 model.Permissions
+	scope        string
+	query        []apiParam
+	form         []apiParam // 表单字段，含 file 类型时为 multipart/form-data
+	body         string     // JSON 请求体的组件名
+	optionalBody bool       // 请求体可以省略
+	resp         string     // 成功响应的组件名或 resp* 常量
+	status       int        // 成功状态码，默认 200
+}
+
+// 以下类型只用于生成文档，与对应接口用 map 拼出的 JSON 结构一致
+type (
+	docResult struct {
+		OK      bool   `json:"ok"`
+		Message string `json:"message"`
+	}
+	docPostResult struct {
+		docResult
+		PostID int64 `json:"post_id"`
+	}
+	docError struct {
+		Error apiError `json:"error"`
+	}
+	docPostList struct {
+		OK    bool          `json:"ok"`
+		Page  int           `json:"page"`
+		Posts []*model.Post `json:"posts"`
+	}
+	docAuditList struct {
+		OK     bool                `json:"ok"`
+		Page   int                 `json:"page"`
+		Events []*model.AuditEvent `json:"events"`
+	}
+	docQzoneStatus struct {
+		OK          bool               `json:"ok"`
+		Wall        string             `json:"wall"`
+		CookieValid bool               `json:"cookie_valid"`
+		UIN         int64              `json:"uin"`
+		PausedSince string             `json:"paused_since,omitempty"` // 号池全部熔断的时间
+		Accounts    []qzoneAccountView `json:"accounts,omitempty"`     // 只返回给能管理该墙的账号
+	}
+	docQRStatus struct {
+		Status  string `json:"status"` // 空、waiting、scanned、success、expired 或 error
+		Message string `json:"message"`
+		Account string `json:"account"`
+	}
+	docConfig struct {
+		OK     bool           `json:"ok"`
+		Config *config.Config `json:"config"`
+	}
+	docBackupList struct {
+		OK      bool          `json:"ok"`
+		Backups []backup.Info `json:"backups"`
+	}
+	docImportResult struct {
+		OK     bool             `json:"ok"`
+		Report *importer.Report `json:"report"`
+	}
+	docWebhookList struct {
+		OK            bool                         `json:"ok"`
+		Subscriptions []*model.WebhookSubscription `json:"subscriptions"`
+		Events        []string                     `json:"events"`
+	}
+	docWebhookCreated struct {
+		docResult
+		ID     int64  `json:"id"`
+		Secret string `json:"secret"` // 签名密钥，只在这里返回一次
+	}
+	docDeliveryList struct {
+		OK         bool                     `json:"ok"`
+		Page       int                      `json:"page"`
+		Deliveries []*model.WebhookDelivery `json:"deliveries"`
+	}
+	docAPIKeyList struct {
+		OK     bool            `json:"ok"`
+		Keys   []*model.APIKey `json:"keys"`
+		Scopes []string        `json:"scopes"`
+	}
+	docAPIKeyCreated struct {
+		docResult
+		ID  int64  `json:"id"`
+		Key string `json:"key"` // 密钥明文，只在这里返回一次
+	}
+	docAccountList struct {
+		OK       bool          `json:"ok"`
+		Accounts []accountView `json:"accounts"`
+		Roles    []string      `json:"roles"`
+	}
+	docAccountPassword struct {
+		docResult
+		Password string `json:"password,omitempty"` // 自动生成的密码，只在这里返回一次
+	}
+	docV1Me struct {
+		Account *model.Account `json:"account"`
+		Key     *model.APIKey  `json:"key"`
+		Walls   []string       `json:"walls"`
+	}
+	docV1PostPage struct {
+		Data       []*model.Post `json:"data"`
+		NextCursor string        `json:"next_cursor"` // 为空表示没有更多数据
+	}
+	docV1AuditPage struct {
+		Data       []*model.AuditEvent `json:"data"`
+		NextCursor string              `json:"next_cursor"`
+	}
+)
+
+// apiComponents 文档 components.schemas 中的类型，其他类型出现时内联展开
+var apiComponents = []struct {
+	name string
+	typ  interface{}
+}{
+	{"Post", model.Post{}},
+	{"Account", model.Account{}},
+	{"AuditEvent", model.AuditEvent{}},
+	{"APIKey", model.APIKey{}},
+	{"WebhookSubscription", model.WebhookSubscription{}},
+	{"WebhookDelivery", model.WebhookDelivery{}},
+	{"QzoneAccount", qzoneAccountView{}},
+	{"Result", docResult{}},
+	{"PostResult", docPostResult{}},
+	{"Error", docError{}},
+	{"PostList", docPostList{}},
+	{"AuditList", docAuditList{}},
+	{"QzoneStatus", docQzoneStatus{}},
+	{"QRStatus", docQRStatus{}},
+	{"Config", config.Config{}},
+	{"ConfigResult", docConfig{}},
+	{"BackupList", docBackupList{}},
+	{"ImportResult", docImportResult{}},
+	{"WebhookList", docWebhookList{}},
+	{"WebhookCreated", docWebhookCreated{}},
+	{"DeliveryList", docDeliveryList{}},
+	{"APIKeyList", docAPIKeyList{}},
+	{"APIKeyCreated", docAPIKeyCreated{}},
+	{"AccountList", docAccountList{}},
+	{"AccountPassword", docAccountPassword{}},
+	{"Me", docV1Me{}},
+	{"PostPage", docV1PostPage{}},
+	{"AuditPage", docV1AuditPage{}},
+	{"PostCreate", v1PostCreate{}},
+	{"PostUpdate", v1PostUpdate{}},
+	{"ApproveRequest", v1Approve{}},
+	{"RejectRequest", v1Reject{}},
+}
+
+// 常用参数
+var (
+	paramWall    = apiParam{"wall", "string", "表白墙编号，省略时为后台选择的墙或账号能管理的第一个墙", false}
+	paramID      = apiParam{"id", "integer", "稿件编号", true}
+	paramIDs     = apiParam{"ids", "string", "逗号分隔的稿件编号", true}
+	paramPage    = apiParam{"page", "integer", "页码，从 1 开始", false}
+	paramStatus  = apiParam{"status", "string", "稿件状态", false}
+	paramSince   = apiParam{"since", "string", "开始日期 YYYY-MM-DD", false}
+	paramUntil   = apiParam{"until", "string", "结束日期 YYYY-MM-DD（含当天）", false}
+	paramQzone   = apiParam{"account", "string", "QQ空间账号名称，省略时为主号", false}
+	paramCursor  = apiParam{"cursor", "string", "上一页响应中的 next_cursor", false}
+	paramAccount = apiParam{"id", "integer", "账号编号", true}
+	paramRole    = apiParam{"role", "string", "角色：admin、reviewer 或 viewer", true}
+	searchParams = []apiParam{
+		{"q", "string", "全文搜索关键词", false},
+		paramStatus, paramSince, paramUntil,
+		{"uin", "integer", "投稿者QQ", false},
+		{"group_id", "integer", "来源群号", false},
+	}
+	auditParams = []apiParam{
+		{"post_id", "integer", "稿件编号", false},
+		{"source", "string", "操作来源 web/bot/worker/cli/api", false},
+		{"action", "string", "操作类型", false},
+		{"actor_id", "integer", "操作者账号 ID 或QQ号", false},
+		paramSince, paramUntil,
+	}
+)
+
+// apiOps 所有页面和接口，新增路由时需要同步添加
+var apiOps = []apiOp{
+	// 页面
+	{method: "GET", path: "/", tag: "页面", summary: "跳转到投稿页（已登录时跳转到后台）", resp: respRedirect},
+	{method: "GET", path: "/login", tag: "页面", summary: "登录页", resp: respHTML},
+	{method: "POST", path: "/login", tag: "页面", summary: "登录，成功后设置会话 Cookie 并跳转到后台", resp: respRedirect,
+		form: []apiParam{{"username", "string", "用户名", true}, {"password", "string", "密码", true}}},
+	{method: "GET", path: "/logout", tag: "页面", summary: "退出登录", resp: respRedirect},
+	{method: "GET", path: "/submit", tag: "页面", summary: "投稿页", resp: respHTML,
+		query: []apiParam{{"wall", "string", "表白墙编号", false}, {"msg", "string", "提示信息", false}}},
+	{method: "GET", path: "/admin", tag: "页面", summary: "管理后台", auth: authManager, perm: model.PermView, resp: respHTML,
+		query: append([]apiParam{paramWall, paramPage, {"msg", "string", "提示信息", false}}, searchParams...)},
+	{method: "GET", path: "/icon.png", tag: "页面", summary: "站点图标", resp: respPNG},
+	{method: "GET", path: "/favicon.ico", tag: "页面", summary: "站点图标", resp: respPNG},
+	{method: "GET", path: "/uploads/{file}", tag: "页面", summary: "投稿上传的图片", resp: respFile},
+
+	// 公开稿件页和订阅，只包含已发布且未下架的稿件，匿名稿件不含QQ号、昵称和头像
+	{method: "GET", path: "/feed", tag: "订阅", summary: "公开稿件页，最新在前", resp: respHTML,
+		query: []apiParam{{"wall", "string", "表白墙编号，省略时为第一个墙", false}, paramPage}},
+	{method: "GET", path: "/feed/{id}", tag: "订阅", summary: "单条稿件的公开页面", resp: respHTML},
+	{method: "GET", path: "/feed/cards/{file}", tag: "订阅", summary: "稿件的卡片图片，文件名为 <稿件编号>.jpg", resp: respJPEG},
+	{method: "GET", path: "/feed/rss.xml", tag: "订阅", summary: "RSS 2.0 订阅（最新 50 条）", resp: respRSS,
+		query: []apiParam{{"wall", "string", "表白墙编号，省略时为第一个墙", false}}},
+	{method: "GET", path: "/feed/atom.xml", tag: "订阅", summary: "Atom 1.0 订阅（最新 50 条）", resp: respAtom,
+		query: []apiParam{{"wall", "string", "表白墙编号，省略时为第一个墙", false}}},
+	{method: "GET", path: "/feed/feed.json", tag: "订阅", summary: "JSON Feed 1.1 订阅（最新 50 条）", resp: respJSONFeed,
+		query: []apiParam{{"wall", "string", "表白墙编号，省略时为第一个墙", false}}},
+
+	// 公开接口
+	{method: "POST", path: "/api/submit", tag: "投稿", summary: "投稿，进入待审核。已登录时记录操作者", resp: "PostResult",
+		form: []apiParam{
+			{"text", "string", "文字内容，有图片时可以为空", false},
+			{"uin", "string", "昵称或QQ号", false},
+			{"anon", "boolean", "是否匿名（on 或 true）", false},
+			{"wall", "string", "表白墙编号", false},
+			{"images", "file", "图片，可重复", false},
+		}},
+	{method: "GET", path: "/api/health", tag: "系统", summary: "健康检查", resp: "Result"},
+	{method: "GET", path: "/api/qzone/status", tag: "QQ空间", summary: "QQ空间登录状态，号池各账号的状态只返回给能管理该墙的账号", resp: "QzoneStatus",
+		query: []apiParam{{"wall", "string", "表白墙编号", false}}},
+	{method: "GET", path: "/api/openapi.json", tag: "系统", summary: "本文档", resp: "object"},
+
+	// 审核（登录会话）
+	{method: "POST", path: "/api/approve", tag: "审核", summary: "过稿，可指定定时发布时间", auth: authManager, perm: model.PermReview, resp: "Result",
+		form: []apiParam{paramWall, paramID, {"publish_at", "string", "定时发布时间，如 2025-01-02T21:00，省略时按 wall.publish_delay 延迟发布", false}}},
+	{method: "POST", path: "/api/schedule/cancel", tag: "审核", summary: "取消定时发布，稿件回到待审核", auth: authManager, perm: model.PermReview, resp: "Result",
+		form: []apiParam{paramWall, paramID}},
+	{method: "POST", path: "/api/reject", tag: "审核", summary: "拒稿", auth: authManager, perm: model.PermReview, resp: "Result",
+		form: []apiParam{paramWall, paramID, {"reason", "string", "拒绝理由", false}}},
+	{method: "POST", path: "/api/delete", tag: "审核", summary: "把稿件移入回收站", auth: authManager, perm: model.PermDelete, resp: "Result",
+		form: []apiParam{paramWall, paramID}},
+	{method: "POST", path: "/api/retract", tag: "审核", summary: "下架已发布的稿件（删除QQ空间说说）。合辑中的稿件返回 409，force=1 时下架整条合辑", auth: authManager, perm: model.PermDelete, resp: "Result",
+		form: []apiParam{paramWall, paramID, {"reason", "string", "下架理由", false}, {"force", "string", "1 表示确认下架整条合辑", false}}},
+	{method: "POST", path: "/api/retry", tag: "审核", summary: "重新发布失败的稿件", auth: authManager, perm: model.PermPublish, resp: "Result",
+		form: []apiParam{paramWall, paramIDs}},
+	{method: "POST", path: "/api/restore", tag: "审核", summary: "从回收站恢复稿件", auth: authManager, perm: model.PermDelete, resp: "Result",
+		form: []apiParam{paramWall, paramID}},
+	{method: "POST", path: "/api/purge", tag: "审核", summary: "从回收站彻底删除稿件", auth: authManager, perm: model.PermDelete, resp: "Result",
+		form: []apiParam{paramWall, paramID}},
+	{method: "POST", path: "/api/approve/batch", tag: "审核", summary: "批量过稿", auth: authManager, perm: model.PermPublish, resp: "Result",
+		form: []apiParam{paramWall, paramIDs}},
+	{method: "POST", path: "/api/reject/batch", tag: "审核", summary: "批量拒稿", auth: authManager, perm: model.PermReview, resp: "Result",
+		form: []apiParam{paramWall, paramIDs, {"reason", "string", "拒绝理由", false}}},
+	{method: "GET", path: "/api/posts/search", tag: "审核", summary: "搜索稿件", auth: authManager, perm: model.PermView, resp: "PostList",
+		query: append([]apiParam{paramWall, paramPage}, searchParams...)},
+	{method: "GET", path: "/api/audit", tag: "审核", summary: "操作日志", auth: authManager, perm: model.PermView, resp: "AuditList",
+		query: append([]apiParam{paramWall, paramPage}, auditParams...)},
+	{method: "GET", path: "/api/export", tag: "数据", summary: "导出稿件", auth: authManager, perm: model.PermView, resp: respFile,
+		query: []apiParam{paramWall, {"format", "string", "jsonl、csv 或 zip（含卡片图片）", false}, paramStatus, paramSince, paramUntil}},
+	{method: "POST", path: "/api/import", tag: "数据", summary: "导入 JSON/CSV 文件中的稿件", auth: authManager, perm: model.PermPublish, resp: "ImportResult",
+		form: []apiParam{paramWall, {"file", "file", "JSON 或 CSV 文件", true}, {"source", "string", "来源名称，用于重复导入时去重", false}, {"dry_run", "string", "1 表示只返回将要执行的结果", false}, {"keep_approved", "string", "1 表示保留未发布的 approved 状态（导入后会被自动发布），默认按 pending 导入", false}}},
+
+	// 账号
+	{method: "POST", path: "/api/change-password", tag: "账号", summary: "修改当前账号的密码", auth: authLogin, resp: "Result",
+		form: []apiParam{{"old_password", "string", "原密码", true}, {"new_password", "string", "新密码", true}}},
+	{method: "GET", path: "/api/keys", tag: "账号", summary: "列出当前账号的 API 密钥（有 accounts 权限时列出全部）", auth: authManager, resp: "APIKeyList"},
+	{method: "POST", path: "/api/keys", tag: "账号", summary: "创建 API 密钥，明文只返回一次", auth: authManager, resp: "APIKeyCreated",
+		form: []apiParam{{"name", "string", "用途备注", false}, {"scopes", "array", "权限范围，可重复", true}}},
+	{method: "POST", path: "/api/keys/revoke", tag: "账号", summary: "吊销 API 密钥", auth: authManager, resp: "Result",
+		form: []apiParam{{"id", "integer", "密钥编号", true}}},
+	{method: "GET", path: "/api/accounts", tag: "账号", summary: "列出所有网页账号", auth: authLogin, perm: model.PermAccounts, resp: "AccountList"},
+	{method: "POST", path: "/api/accounts", tag: "账号", summary: "创建网页账号，省略密码时自动生成并只返回一次", auth: authLogin, perm: model.PermAccounts, resp: "AccountPassword",
+		form: []apiParam{{"username", "string", "用户名", true}, {"password", "string", "密码，至少 6 位", false}, paramRole}},
+	{method: "POST", path: "/api/accounts/role", tag: "账号", summary: "修改账号角色，不能修改自己的角色或降级最后一个管理员", auth: authLogin, perm: model.PermAccounts, resp: "Result",
+		form: []apiParam{paramAccount, paramRole}},
+	{method: "POST", path: "/api/accounts/disable", tag: "账号", summary: "停用或启用账号，停用后会话和 API 密钥立即失效", auth: authLogin, perm: model.PermAccounts, resp: "Result",
+		form: []apiParam{paramAccount, {"disabled", "string", "1 停用（默认），0 启用", false}}},
+	{method: "POST", path: "/api/accounts/reset", tag: "账号", summary: "重置账号密码，省略密码时自动生成并只返回一次", auth: authLogin, perm: model.PermAccounts, resp: "AccountPassword",
+		form: []apiParam{paramAccount, {"password", "string", "新密码，至少 6 位", false}}},
+
+	// 系统管理，需要对应的权限
+	{method: "GET", path: "/api/qrcode", tag: "QQ空间", summary: "获取扫码登录二维码", auth: authLogin, perm: model.PermQzone, resp: respPNG,
+		query: []apiParam{paramQzone}},
+	{method: "GET", path: "/api/qrcode/status", tag: "QQ空间", summary: "扫码登录进度", auth: authLogin, perm: model.PermQzone, resp: "QRStatus"},
+	{method: "POST", path: "/api/qzone/refresh", tag: "QQ空间", summary: "从机器人刷新QQ空间 Cookie", auth: authLogin, perm: model.PermQzone, resp: "Result",
+		form: []apiParam{paramQzone}},
+	{method: "GET", path: "/api/config", tag: "系统", summary: "读取配置（密钥留空）", auth: authLogin, perm: model.PermConfig, resp: "ConfigResult",
+		query: []apiParam{{"reload", "boolean", "true 时先从配置文件重新加载", false}}},
+	{method: "POST", path: "/api/config", tag: "系统", summary: "保存配置（留空的密钥不修改）", auth: authLogin, perm: model.PermConfig, body: "Config", resp: "Result"},
+	{method: "GET", path: "/api/backup", tag: "数据", summary: "列出备份", auth: authLogin, perm: model.PermConfig, resp: "BackupList"},
+	{method: "POST", path: "/api/backup", tag: "数据", summary: "立即备份", auth: authLogin, perm: model.PermConfig, resp: "Result"},
+	{method: "GET", path: "/api/backup/download", tag: "数据", summary: "下载备份文件", auth: authLogin, perm: model.PermConfig, resp: respFile,
+		query: []apiParam{{"name", "string", "备份文件名", true}}},
+	{method: "GET", path: "/api/webhooks", tag: "事件回调", summary: "列出回调订阅", auth: authLogin, perm: model.PermConfig, resp: "WebhookList"},
+	{method: "POST", path: "/api/webhooks", tag: "事件回调", summary: "添加回调订阅，签名密钥只返回一次", auth: authLogin, perm: model.PermConfig, resp: "WebhookCreated",
+		form: []apiParam{
+			{"url", "string", "回调地址", true},
+			{"secret", "string", "签名密钥，省略时自动生成", false},
+			{"wall", "string", "只接收该墙的事件", false},
+			{"events", "array", "订阅的事件，可重复，省略时为全部", false},
+		}},
+	{method: "POST", path: "/api/webhooks/toggle", tag: "事件回调", summary: "启用或停用回调订阅", auth: authLogin, perm: model.PermConfig, resp: "Result",
+		form: []apiParam{{"id", "integer", "订阅编号", true}, {"enabled", "boolean", "是否启用", true}}},
+	{method: "POST", path: "/api/webhooks/delete", tag: "事件回调", summary: "删除回调订阅", auth: authLogin, perm: model.PermConfig, resp: "Result",
+		form: []apiParam{{"id", "integer", "订阅编号", true}}},
+	{method: "GET", path: "/api/webhooks/deliveries", tag: "事件回调", summary: "投递记录", auth: authLogin, perm: model.PermConfig, resp: "DeliveryList",
+		query: []apiParam{{"subscription_id", "integer", "订阅编号", false}, {"status", "string", "pending、delivered 或 failed", false}, paramPage}},
+	{method: "POST", path: "/api/webhooks/redeliver", tag: "事件回调", summary: "重新投递", auth: authLogin, perm: model.PermConfig, resp: "Result",
+		form: []apiParam{{"id", "integer", "投递编号", true}}},
+
+	// /api/v1
+	{method: "GET", path: "/api/v1/me", tag: "v1", summary: "密钥所属的账号、权限范围和可以管理的墙", auth: authAPIKey, perm: model.PermView, resp: "Me"},
+	{method: "GET", path: "/api/v1/posts", tag: "v1", summary: "列出稿件（最新在前）", auth: authAPIKey, perm: model.PermView, scope: model.ScopePostsRead, resp: "PostPage",
+		query: append([]apiParam{paramWall, {"limit", "integer", "每页条数，默认 20，最多 100", false}, paramCursor}, searchParams...)},
+	{method: "POST", path: "/api/v1/posts", tag: "v1", summary: "投稿，进入待审核", auth: authAPIKey, perm: model.PermReview, scope: model.ScopePostsWrite, body: "PostCreate", resp: "Post", status: http.StatusCreated,
+		query: []apiParam{paramWall}},
+	{method: "GET", path: "/api/v1/posts/{id}", tag: "v1", summary: "获取稿件", auth: authAPIKey, perm: model.PermView, scope: model.ScopePostsRead, resp: "Post"},
+	{method: "PATCH", path: "/api/v1/posts/{id}", tag: "v1", summary: "修改稿件内容，只修改出现的字段。发布中和已发布的稿件返回 409", auth: authAPIKey, perm: model.PermReview, scope: model.ScopePostsWrite, body: "PostUpdate", resp: "Post"},
+	{method: "DELETE", path: "/api/v1/posts/{id}", tag: "v1", summary: "把稿件移入回收站", auth: authAPIKey, perm: model.PermDelete, scope: model.ScopePostsWrite, resp: "Post"},
+	{method: "POST", path: "/api/v1/posts/{id}/approve", tag: "v1", summary: "过稿，只能通过待审核或已拒绝的稿件", auth: authAPIKey, perm: model.PermReview, scope: model.ScopePostsReview, body: "ApproveRequest", optionalBody: true, resp: "Post"},
+	{method: "POST", path: "/api/v1/posts/{id}/reject", tag: "v1", summary: "拒稿，只能拒绝待审核或已通过未发布的稿件", auth: authAPIKey, perm: model.PermReview, scope: model.ScopePostsReview, body: "RejectRequest", optionalBody: true, resp: "Post"},
+	{method: "GET", path: "/api/v1/audit", tag: "v1", summary: "操作日志（最新在前）", auth: authAPIKey, perm: model.PermView, scope: model.ScopeAuditRead, resp: "AuditPage",
+		query: append([]apiParam{paramWall, {"limit", "integer", "每页条数，默认 50，最多 200", false}, paramCursor}, auditParams...)},
+}
+
+// handleOpenAPI 返回 OpenAPI 文档
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.openAPISpec())
+}
+
+// openAPISpec 生成 OpenAPI 3.1 文档
+func (s *Server) openAPISpec() map[string]interface{} {
+	g := &schemaGen{refs: make(map[reflect.Type]string)}
+	for _, c := range apiComponents {
+		g.refs[reflect.TypeOf(c.typ)] = c.name
+	}
+	schemas := make(map[string]interface{})
+	for _, c := range apiComponents {
+		schemas[c.name] = g.object(reflect.TypeOf(c.typ))
+	}
+
+	paths := make(map[string]map[string]interface{})
+	for _, op := range apiOps {
+		if paths[op.path] == nil {
+			paths[op.path] = make(map[string]interface{})
+		}
+		paths[op.path][strings.ToLower(op.method)] = op.spec()
+	}
+
+	server := s.prefix
+	if server == "" {
+		server = "/"
+	}
+	return map[string]interface{}{
+		"openapi": "3.1.0",
+		"info": map[string]interface{}{
+			"title":       "QzoneWall API",
+			"version":     "v1",
+			"description": "后台页面使用的 /api/* 接口（登录会话，响应为 {ok, message}）和面向集成的 /api/v1 接口（API 密钥，错误为 {error: {code, message}}）。",
+		},
+		"servers": []map[string]string{{"url": server}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"session": map[string]string{"type": "apiKey", "in": "cookie", "name": "session", "description": "登录后设置的会话 Cookie"},
+				"bearer":  map[string]string{"type": "http", "scheme": "bearer", "description": "API 密钥：Authorization: Bearer qw_..."},
+				"apiKey":  map[string]string{"type": "apiKey", "in": "header", "name": "X-API-Key", "description": "API 密钥"},
+			},
+		},
+	}
+}
+
+var pathParamRe = regexp.MustCompile(`\{(\w+)\}`)
+
+// spec 生成一个接口的 Operation 对象
+func (op apiOp) spec() map[string]interface{} {
+	o := map[string]interface{}{
+		"tags":        []string{op.tag},
+		"summary":     op.summary,
+		"operationId": operationID(op.method, op.path),
+	}
+
+	var params []map[string]interface{}
+	for _, m := range pathParamRe.FindAllStringSubmatch(op.path, -1) {
+		typ := "integer"
+		if m[1] == "file" {
+			typ = "string"
+		}
+		params = append(params, map[string]interface{}{"name": m[1], "in": "path", "required": true, "schema": map[string]string{"type": typ}})
+	}
+	for _, p := range op.query {
+		params = append(params, map[string]interface{}{"name": p.name, "in": "query", "required": p.required, "description": p.desc, "schema": p.schema()})
+	}
+	if params != nil {
+		o["parameters"] = params
+	}
+
+	switch {
+	case op.body != "":
+		o["requestBody"] = map[string]interface{}{
+			"required": !op.optionalBody,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": ref(op.body)}},
+		}
+	case op.form != nil:
+		ct := "application/x-www-form-urlencoded"
+		props := make(map[string]interface{})
+		var required []string
+		for _, p := range op.form {
+			if p.typ == "file" {
+				ct = "multipart/form-data"
+			}
+			sc := p.schema()
+			sc["description"] = p.desc
+			props[p.name] = sc
+			if p.required {
+				required = append(required, p.name)
+			}
+		}
+		sc := map[string]interface{}{"type": "object", "properties": props}
+		if required != nil {
+			sc["required"] = required
+		}
+		o["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{ct: map[string]interface{}{"schema": sc}},
+		}
+	}
+
+	status := op.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	var ok map[string]interface{}
+	switch op.resp {
+	case respRedirect:
+		status = http.StatusFound
+		ok = map[string]interface{}{"description": "跳转"}
+	case respHTML, respPNG, respFile, respJPEG, respRSS, respAtom, respJSONFeed:
+		ok = map[string]interface{}{
+			"description": "成功",
+			"content":     map[string]interface{}{op.resp: map[string]interface{}{"schema": map[string]string{"type": "string", "format": "binary"}}},
+		}
+	case "object":
+		ok = jsonContent("成功", map[string]interface{}{"type": "object"})
+	default:
+		ok = jsonContent("成功", ref(op.resp))
+	}
+	responses := map[string]interface{}{strconv.Itoa(status): ok}
+	if op.auth == authAPIKey {
+		responses["default"] = jsonContent("错误", ref("Error"))
+	} else if strings.HasPrefix(op.path, "/api/") {
+		responses["default"] = jsonContent("错误，ok 为 false", ref("Result"))
+	}
+	o["responses"] = responses
+
+	var desc []string
+	switch op.auth {
+	case authLogin, authManager:
+		scopes := []string{}
+		if op.perm != "" {
+			scopes = []string{string(op.perm)}
+			desc = append(desc, "账号角色需要 "+string(op.perm)+" 权限")
+		}
+		o["security"] = []map[string][]string{{"session": scopes}}
+	case authAPIKey:
+		scopes := []string{}
+		if op.scope != "" {
+			scopes = []string{op.scope}
+			desc = append(desc, "需要 "+op.scope+" 权限范围")
+		}
+		if op.perm != "" {
+			desc = append(desc, "密钥所属账号的角色需要 "+string(op.perm)+" 权限")
+		}
+		o["security"] = []map[string][]string{{"bearer": scopes}, {"apiKey": scopes}}
+	}
+	if op.auth == authManager {
+		desc = append(desc, "账号需要能管理请求的墙")
+	}
+	if len(desc) > 0 {
+		o["description"] = strings.Join(desc, "；")
+	}
+	return o
+}
+
+func (p apiParam) schema() map[string]interface{} {
+	switch p.typ {
+	case "file":
+		return map[string]interface{}{"type": "string", "format": "binary"}
+	case "array":
+		return map[string]interface{}{"type": "array", "items": map[string]string{"type": "string"}}
+	case "integer":
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	}
+	return map[string]interface{}{"type": p.typ}
+}
+
+// operationID 由方法和路径生成，如 POST /api/v1/posts/{id}/approve → postApiV1PostsIdApprove
+func operationID(method, p string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(p, func(r rune) bool { return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func ref(name string) map[string]string {
+	return map[string]string{"$ref": "#/components/schemas/" + name}
+}
+
+func jsonContent(desc string, schema interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"description": desc,
+		"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}},
+	}
+}
+
+// schemaGen 按 json 标签把 Go 类型转换为 JSON Schema，refs 中的类型生成 $ref
+type schemaGen struct {
+	refs map[reflect.Type]string
+}
+
+var (
+	marshalerType  = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	postStatusType = reflect.TypeOf(model.PostStatus(""))
+	sourceType     = reflect.TypeOf(model.AuditSource(""))
+)
+
+// schema 返回类型 t 的 schema，已注册为组件的结构体返回 $ref
+func (g *schemaGen) schema(t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if name, ok := g.refs[t]; ok {
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	if t.Implements(marshalerType) {
+		// 自定义序列化的类型（如 config.Duration）都编码为字符串
+		return map[string]interface{}{"type": "string"}
+	}
+	switch t {
+	case postStatusType:
+		return map[string]interface{}{"type": "string", "enum": []model.PostStatus{
+			model.StatusPending, model.StatusApproved, model.StatusPublishing, model.StatusRejected,
+			model.StatusFailed, model.StatusPublished, model.StatusDeleted, model.StatusRetracted,
+		}}
+	case sourceType:
+		return map[string]interface{}{"type": "string", "enum": []model.AuditSource{
+			model.SourceWeb, model.SourceBot, model.SourceWorker, model.SourceCLI, model.SourceAPI,
+		}}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.object(t)
+	}
+	return map[string]interface{}{}
+}
+
+// object 展开结构体的字段，匿名嵌入的结构体字段提升到外层
+func (g *schemaGen) object(t reflect.Type) map[string]interface{} {
+	props := make(map[string]interface{})
+	var required []string
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+				walk(f.Type)
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = g.schema(f.Type)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+	}
+	walk(t)
+	o := map[string]interface{}{"type": "object", "properties": props}
+	if required != nil {
+		o["required"] = required
+	}
+	return o
+}
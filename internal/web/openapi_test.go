@@ -0,0 +1,96 @@
+package web
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestOpenAPICoversRoutes 测试注册的每个路由都写进了 OpenAPI 文档，文档中的每个接口也都有对应的路由
+// 运行方法: go test -v ./internal/web/ -run TestOpenAPICoversRoutes
+func TestOpenAPICoversRoutes(t *testing.T) {
+	s := &Server{prefix: "/wall"}
+	mux := s.routes()
+
+	raw, err := json.Marshal(s.openAPISpec())
+	if err != nil {
+		t.Fatalf("文档序列化失败: %v", err)
+	}
+	var spec struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		t.Fatalf("文档不是有效的 JSON: %v", err)
+	}
+
+	// 路由模式形如 "GET /wall/api/v1/posts/{id}"、"/wall/api/audit"（不限方法）或 "/wall/uploads/"（子路径）
+	type route struct{ method, path string }
+	var routes []route
+	for _, p := range mux.patterns {
+		method, path, ok := strings.Cut(p, " ")
+		if !ok {
+			method, path = "", p
+		}
+		path = strings.TrimPrefix(path, s.prefix)
+		if path == "" {
+			path = "/"
+		}
+		routes = append(routes, route{method, path})
+	}
+	matches := func(rt route, method, path string) bool {
+		if rt.method != "" && rt.method != method {
+			return false
+		}
+		if strings.HasSuffix(rt.path, "/") && rt.path != "/" {
+			return strings.HasPrefix(path, rt.path)
+		}
+		return rt.path == path
+	}
+
+	for _, rt := range routes {
+		// /api/v1/ 子路径是未知接口的 404 兜底
+		if rt.path == "/api/v1/" {
+			continue
+		}
+		found := false
+		for path, ops := range spec.Paths {
+			for method := range ops {
+				if matches(rt, strings.ToUpper(method), path) {
+					found = true
+				}
+			}
+		}
+		if !found {
+			t.Errorf("路由 %s %s 没有写进 OpenAPI 文档", rt.method, rt.path)
+		}
+	}
+
+	for path, ops := range spec.Paths {
+		for method := range ops {
+			found := false
+			for _, rt := range routes {
+				if rt.path != "/api/v1/" && matches(rt, strings.ToUpper(method), path) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("文档中的 %s %s 没有对应的路由", strings.ToUpper(method), path)
+			}
+		}
+	}
+
+	post := spec.Components.Schemas["Post"].Properties
+	for _, field := range []string{"id", "text", "status", "anon", "publish_at"} {
+		if _, ok := post[field]; !ok {
+			t.Errorf("Post 结构缺少字段 %s", field)
+		}
+	}
+	if _, ok := spec.Components.Schemas["Account"].Properties["password_hash"]; ok {
+		t.Errorf("Account 结构不应包含密码哈希")
+	}
+}
@@ -7,27 +7,37 @@ import (
 	"embed"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"log"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	qzone "github.com/guohuiyuan/qzone-go"
+	"github.com/guohuiyuan/qzonewall-go/internal/backup"
 	"github.com/guohuiyuan/qzonewall-go/internal/config"
+	"github.com/guohuiyuan/qzonewall-go/internal/export"
+	"github.com/guohuiyuan/qzonewall-go/internal/feed"
+	"github.com/guohuiyuan/qzonewall-go/internal/importer"
 	"github.com/guohuiyuan/qzonewall-go/internal/model"
-	"github.com/guohuiyuan/qzonewall-go/internal/render"
+	"github.com/guohuiyuan/qzonewall-go/internal/notify"
+	"github.com/guohuiyuan/qzonewall-go/internal/publish"
 	"github.com/guohuiyuan/qzonewall-go/internal/store"
+	"github.com/guohuiyuan/qzonewall-go/internal/task"
+	"github.com/guohuiyuan/qzonewall-go/internal/wall"
 	zero "github.com/wdvxdr1123/ZeroBot"
 )
 
@@ -40,19 +50,22 @@ type Server struct {
 	wallCfg   config.WallConfig
 	fullCfg   *config.Config
 	cfgPath   string
-	store     *store.Store
-	qzClient  *qzone.Client
-	renderer  *render.Renderer
+	store     *store.Store // 不区分墙，用于账号、会话和备份
+	walls     *wall.Registry
+	vault     *task.CookieVault
 	tmpl      *template.Template
 	server    *http.Server
 	uploadDir string
+	cards     *feed.Cards // 公开稿件页的卡片图片缓存
 
 	// [新增] 路由前缀，例如 "/wall"。默认为 ""
 	prefix string
 
-	// QR 登录状态
+	// QR 登录状态，同一时间只为一个账号扫码
 	qrMu      sync.Mutex
 	qrCode    *qzone.QRCode
+	qrWall    *wall.Wall
+	qrAccount *publish.Account
 	qrStatus  string // "", "waiting", "scanned", "success", "expired", "error"
 	qrMessage string
 }
@@ -61,25 +74,28 @@ type Server struct {
 func NewServer(
 	fullCfg *config.Config,
 	cfgPath string,
-	st *store.Store,
-	qzClient *qzone.Client,
-	renderer *render.Renderer,
+	walls *wall.Registry,
 ) *Server {
 	return &Server{
 		cfg:       fullCfg.Web,
 		wallCfg:   fullCfg.Wall,
 		fullCfg:   fullCfg,
 		cfgPath:   cfgPath,
-		store:     st,
-		qzClient:  qzClient,
-		renderer:  renderer,
+		store:     walls.Store(),
+		walls:     walls,
 		uploadDir: "data/uploads",
+		cards:     feed.NewCards("data/cards"),
 		// [配置] 在这里设置你的二级路径前缀，例如 "/wall"
 		// 如果在根目录运行，请保持为空字符串 ""
 		prefix: "/wall",
 	}
 }
 
+// SetCookieVault 设置 Cookie 持久化，扫码登录成功后保存
+func (s *Server) SetCookieVault(vault *task.CookieVault) {
+	s.vault = vault
+}
+
 // [新增] 路径拼接辅助函数
 func (s *Server) url(p string) string {
 	return path.Join(s.prefix, p)
@@ -93,11 +109,14 @@ func (s *Server) Start() error {
 		},
 		"statusText": func(st model.PostStatus) string {
 			m := map[model.PostStatus]string{
-				model.StatusPending:   "待审核",
-				model.StatusApproved:  "已通过",
-				model.StatusRejected:  "已拒绝",
-				model.StatusFailed:    "失败",
-				model.StatusPublished: "已发布",
+				model.StatusPending:    "待审核",
+				model.StatusApproved:   "已通过",
+				model.StatusPublishing: "发布中",
+				model.StatusRejected:   "已拒绝",
+				model.StatusFailed:     "失败",
+				model.StatusPublished:  "已发布",
+				model.StatusDeleted:    "已删除",
+				model.StatusRetracted:  "已下架",
 			}
 			if v, ok := m[st]; ok {
 				return v
@@ -106,15 +125,19 @@ func (s *Server) Start() error {
 		},
 		"statusClass": func(st model.PostStatus) string {
 			m := map[model.PostStatus]string{
-				model.StatusPending:   "pending",
-				model.StatusApproved:  "approved",
-				model.StatusRejected:  "rejected",
-				model.StatusFailed:    "failed",
-				model.StatusPublished: "published",
+				model.StatusPending:    "pending",
+				model.StatusApproved:   "approved",
+				model.StatusPublishing: "publishing",
+				model.StatusRejected:   "rejected",
+				model.StatusFailed:     "failed",
+				model.StatusPublished:  "published",
+				model.StatusDeleted:    "deleted",
+				model.StatusRetracted:  "retracted",
 			}
 			return m[st]
 		},
 		"hasImages": func(imgs []string) bool { return len(imgs) > 0 },
+		"roleName":  model.RoleName,
 	}
 
 	var err error
@@ -131,7 +154,50 @@ func (s *Server) Start() error {
 		log.Printf("[Web] 初始化管理员账号失败: %v", err)
 	}
 
-	mux := http.NewServeMux()
+	mux := s.routes()
+
+	s.server = &http.Server{
+		Addr:    s.cfg.Addr,
+		Handler: mux,
+	}
+
+	go func() {
+		// 这里生成的本地 URL 可能不包含前缀，仅供控制台显示
+		urlStr := localWebURL(s.cfg.Addr)
+		if s.prefix != "" {
+			urlStr = strings.TrimRight(urlStr, "/") + s.prefix
+		}
+		log.Printf("[Web] 监听 %s (%s)", s.cfg.Addr, urlStr)
+		go func() {
+			time.Sleep(500 * time.Millisecond)
+			openBrowser(urlStr)
+		}()
+		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("[Web] 服务异常: %v", err)
+		}
+	}()
+	return nil
+}
+
+// routeMux 记录注册过的路由，OpenAPI 文档的测试据此检查是否有接口漏写
+type routeMux struct {
+	*http.ServeMux
+	patterns []string
+}
+
+func (m *routeMux) HandleFunc(pattern string, h func(http.ResponseWriter, *http.Request)) {
+	m.patterns = append(m.patterns, pattern)
+	m.ServeMux.HandleFunc(pattern, h)
+}
+
+func (m *routeMux) Handle(pattern string, h http.Handler) {
+	m.patterns = append(m.patterns, pattern)
+	m.ServeMux.Handle(pattern, h)
+}
+
+// routes 注册所有页面和接口路由
+func (s *Server) routes() *routeMux {
+	mux := &routeMux{ServeMux: http.NewServeMux()}
 
 	// [修改] 使用 s.url() 包裹所有路由路径
 	mux.HandleFunc(s.url("/"), s.handleIndex)
@@ -142,11 +208,24 @@ func (s *Server) Start() error {
 	mux.HandleFunc(s.url("/icon.png"), s.handleIcon)
 	mux.HandleFunc(s.url("/favicon.ico"), s.handleFavicon)
 
+	// 公开稿件页和订阅
+	mux.HandleFunc("GET "+s.url("/feed"), s.handleFeedPage)
+	mux.HandleFunc("GET "+s.url("/feed/{id}"), s.handleFeedPost)
+	mux.HandleFunc("GET "+s.url("/feed/cards/{file}"), s.handleFeedCard)
+	mux.HandleFunc("GET "+s.url("/feed/rss.xml"), s.handleFeedRSS)
+	mux.HandleFunc("GET "+s.url("/feed/atom.xml"), s.handleFeedAtom)
+	mux.HandleFunc("GET "+s.url("/feed/feed.json"), s.handleFeedJSON)
+
 	// API 路由
 	mux.HandleFunc(s.url("/api/submit"), s.handleAPISubmit)
 	mux.HandleFunc(s.url("/api/approve"), s.handleAPIApprove)
+	mux.HandleFunc(s.url("/api/schedule/cancel"), s.handleAPICancelSchedule)
 	mux.HandleFunc(s.url("/api/reject"), s.handleAPIReject)
 	mux.HandleFunc(s.url("/api/delete"), s.handleAPIDelete)
+	mux.HandleFunc(s.url("/api/retract"), s.handleAPIRetract)
+	mux.HandleFunc(s.url("/api/retry"), s.handleAPIRetry)
+	mux.HandleFunc(s.url("/api/restore"), s.handleAPIRestore)
+	mux.HandleFunc(s.url("/api/purge"), s.handleAPIPurge)
 	mux.HandleFunc(s.url("/api/approve/batch"), s.handleAPIBatchApprove)
 	mux.HandleFunc(s.url("/api/reject/batch"), s.handleAPIBatchReject)
 	mux.HandleFunc(s.url("/api/qrcode"), s.handleAPIQRCode)
@@ -156,6 +235,25 @@ func (s *Server) Start() error {
 	mux.HandleFunc(s.url("/api/qzone/refresh"), s.handleAPIQzoneRefresh)
 	mux.HandleFunc(s.url("/api/config"), s.handleAPIConfig)
 	mux.HandleFunc(s.url("/api/change-password"), s.handleAPIChangePassword)
+	mux.HandleFunc(s.url("/api/audit"), s.handleAPIAudit)
+	mux.HandleFunc(s.url("/api/webhooks"), s.handleAPIWebhooks)
+	mux.HandleFunc(s.url("/api/webhooks/toggle"), s.handleAPIWebhookToggle)
+	mux.HandleFunc(s.url("/api/webhooks/delete"), s.handleAPIWebhookDelete)
+	mux.HandleFunc(s.url("/api/webhooks/deliveries"), s.handleAPIWebhookDeliveries)
+	mux.HandleFunc(s.url("/api/webhooks/redeliver"), s.handleAPIWebhookRedeliver)
+	mux.HandleFunc(s.url("/api/posts/search"), s.handleAPISearch)
+	mux.HandleFunc(s.url("/api/backup"), s.handleAPIBackup)
+	mux.HandleFunc(s.url("/api/export"), s.handleAPIExport)
+	mux.HandleFunc(s.url("/api/import"), s.handleAPIImport)
+	mux.HandleFunc(s.url("/api/backup/download"), s.handleAPIBackupDownload)
+	mux.HandleFunc(s.url("/api/keys"), s.handleAPIKeys)
+	mux.HandleFunc(s.url("/api/keys/revoke"), s.handleAPIKeyRevoke)
+	mux.HandleFunc(s.url("/api/accounts"), s.handleAPIAccounts)
+	mux.HandleFunc(s.url("/api/accounts/role"), s.handleAPIAccountRole)
+	mux.HandleFunc(s.url("/api/accounts/disable"), s.handleAPIAccountDisable)
+	mux.HandleFunc(s.url("/api/accounts/reset"), s.handleAPIAccountReset)
+	mux.HandleFunc(s.url("/api/openapi.json"), s.handleOpenAPI)
+	s.registerAPIv1(mux)
 
 	// [修复] 静态资源处理
 	// 1. 拼接前缀，例如 "/wall" + "/uploads" -> "/wall/uploads"
@@ -167,27 +265,7 @@ func (s *Server) Start() error {
 	// 3. 注册 handler
 	mux.Handle(fsPath, http.StripPrefix(fsPath, http.FileServer(http.Dir(s.uploadDir))))
 
-	s.server = &http.Server{
-		Addr:    s.cfg.Addr,
-		Handler: mux,
-	}
-
-	go func() {
-		// 这里生成的本地 URL 可能不包含前缀，仅供控制台显示
-		urlStr := localWebURL(s.cfg.Addr)
-		if s.prefix != "" {
-			urlStr = strings.TrimRight(urlStr, "/") + s.prefix
-		}
-		log.Printf("[Web] 监听 %s (%s)", s.cfg.Addr, urlStr)
-		go func() {
-			time.Sleep(500 * time.Millisecond)
-			openBrowser(urlStr)
-		}()
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("[Web] 服务异常: %v", err)
-		}
-	}()
-	return nil
+	return mux
 }
 
 // Stop 停止服务。
@@ -210,7 +288,7 @@ func (s *Server) initAdmin() error {
 	salt := randomHex(16)
 	hash := hashPassword("admin123", salt)
 	log.Println("[Web] 初始化默认管理员: admin / admin123，请及时在管理后台修改密码")
-	return s.store.CreateAccount("admin", hash, salt, "admin")
+	return s.store.CreateAccount("admin", hash, salt, model.RoleAdmin)
 }
 
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
@@ -220,7 +298,7 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	account := s.currentAccount(r)
-	if account != nil && account.IsAdmin() {
+	if s.canManageAny(account) {
 		http.Redirect(w, r, s.url("/admin"), http.StatusFound)
 	} else {
 		http.Redirect(w, r, s.url("/submit"), http.StatusFound)
@@ -230,7 +308,7 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodGet {
 		account := s.currentAccount(r)
-		if account != nil && account.IsAdmin() {
+		if s.canManageAny(account) {
 			http.Redirect(w, r, s.url("/admin"), http.StatusFound)
 			return
 		}
@@ -251,8 +329,12 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		s.renderTemplate(w, "login.html", map[string]interface{}{"Error": "用户名或密码错误", "Root": s.prefix})
 		return
 	}
-	if !account.IsAdmin() {
-		s.renderTemplate(w, "login.html", map[string]interface{}{"Error": "仅管理员可登录", "Root": s.prefix})
+	if account.Disabled {
+		s.renderTemplate(w, "login.html", map[string]interface{}{"Error": "账号已停用，请联系管理员", "Root": s.prefix})
+		return
+	}
+	if !s.canManageAny(account) {
+		s.renderTemplate(w, "login.html", map[string]interface{}{"Error": "该账号还不能管理任何表白墙，请联系管理员", "Root": s.prefix})
 		return
 	}
 
@@ -290,17 +372,25 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleSubmitPage(w http.ResponseWriter, r *http.Request) {
 	account := s.currentAccount(r)
+	wl := s.walls.Get(r.URL.Query().Get("wall"))
+	if wl == nil {
+		http.NotFound(w, r)
+		return
+	}
 
 	var qzoneUIN int64
 	var qzoneOnline bool
-	if s.qzClient != nil {
-		qzoneUIN = s.qzClient.UIN()
-		qzoneOnline = s.isQzoneLoggedIn()
+	if acc := wl.Pool.Primary(); acc != nil {
+		qzoneUIN = acc.Client.UIN()
+		qzoneOnline = true
 	}
 
 	data := map[string]interface{}{
 		"Account":      account,
-		"IsAdmin":      account != nil && account.IsAdmin(),
+		"IsAdmin":      s.canManageAny(account),
+		"Wall":         wl,
+		"Walls":        s.walls.All(),
+		"MultiWall":    s.walls.Multi(),
 		"MaxImages":    s.wallCfg.MaxImages,
 		"MaxImageSize": s.wallCfg.MaxImageSize,
 		"Message":      r.URL.Query().Get("msg"),
@@ -313,18 +403,30 @@ func (s *Server) handleSubmitPage(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleAdminPage(w http.ResponseWriter, r *http.Request) {
 	account := s.currentAccount(r)
-	if account == nil || !account.IsAdmin() {
+	wl := s.currentWall(r, account)
+	if wl == nil || !account.Can(model.PermView) {
 		http.Redirect(w, r, s.url("/login"), http.StatusFound)
 		return
 	}
+	// 记住切换到的墙，之后的 API 请求默认操作该墙
+	if r.URL.Query().Get("wall") != "" {
+		http.SetCookie(w, &http.Cookie{Name: "wall", Value: wl.ID, Path: "/", MaxAge: 86400 * 30, HttpOnly: true})
+	}
 
-	statusFilter := r.URL.Query().Get("status")
+	q := r.URL.Query()
+	statusFilter := q.Get("status")
+	search := parseSearchQuery(q)
+	searching := search.Keyword != "" || search.Since > 0 || search.Until > 0 || search.UIN > 0 || search.GroupID > 0
 	var posts []*model.Post
 	var err error
-	if statusFilter != "" {
-		posts, err = s.store.ListByStatus(model.PostStatus(statusFilter))
+	if searching {
+		posts, err = wl.Store.SearchPosts(search, 100, 0)
+	} else if statusFilter == "scheduled" {
+		posts, err = wl.Store.ListScheduled()
+	} else if statusFilter != "" {
+		posts, err = wl.Store.ListByStatus(model.PostStatus(statusFilter))
 	} else {
-		posts, err = s.store.ListAll(100, 0)
+		posts, err = wl.Store.ListAll(100, 0)
 	}
 	if err != nil {
 		log.Printf("[Web] 查询投稿失败: %v", err)
@@ -335,28 +437,56 @@ func (s *Server) handleAdminPage(w http.ResponseWriter, r *http.Request) {
 		displayPosts[i] = s.resolvePostImages(p)
 	}
 
-	totalCount, _ := s.store.CountAll()
-	pendingCount, _ := s.store.CountByStatus(model.StatusPending)
-	approvedCount, _ := s.store.CountByStatus(model.StatusApproved)
-	rejectedCount, _ := s.store.CountByStatus(model.StatusRejected)
-	publishedCount, _ := s.store.CountByStatus(model.StatusPublished)
+	totalCount, _ := wl.Store.CountAll()
+	pendingCount, _ := wl.Store.CountByStatus(model.StatusPending)
+	approvedCount, _ := wl.Store.CountByStatus(model.StatusApproved)
+	rejectedCount, _ := wl.Store.CountByStatus(model.StatusRejected)
+	publishedCount, _ := wl.Store.CountByStatus(model.StatusPublished)
+	deletedCount, _ := wl.Store.CountByStatus(model.StatusDeleted)
+	retractedCount, _ := wl.Store.CountByStatus(model.StatusRetracted)
+	scheduledCount, _ := wl.Store.CountScheduled()
 
 	data := map[string]interface{}{
 		"Account":        account,
+		"Wall":           wl,
+		"Walls":          s.walls.ForAccount(account),
+		"Can":            permissionSet(account),
+		"Roles":          model.Roles,
 		"Posts":          displayPosts,
 		"TotalCount":     totalCount,
 		"PendingCount":   pendingCount,
 		"ApprovedCount":  approvedCount,
 		"RejectedCount":  rejectedCount,
 		"PublishedCount": publishedCount,
+		"DeletedCount":   deletedCount,
+		"RetractedCount": retractedCount,
+		"ScheduledCount": scheduledCount,
+		"RecycleDays":    s.wallCfg.RecycleDays,
 		"StatusFilter":   statusFilter,
-		"CookieValid":    s.isQzoneLoggedIn(),
-		"QzoneUIN":       int64(0),
-		"Message":        r.URL.Query().Get("msg"),
-		"Root":           s.prefix, // [修改] 注入 Root
+		"Searching":      searching,
+		"Search": map[string]string{
+			"q":        q.Get("q"),
+			"since":    q.Get("since"),
+			"until":    q.Get("until"),
+			"uin":      q.Get("uin"),
+			"group_id": q.Get("group_id"),
+		},
+		"CookieValid":   false,
+		"QzoneUIN":      int64(0),
+		"QzoneAccounts": s.qzoneAccounts(wl),
+		"Message":       r.URL.Query().Get("msg"),
+		"Root":          s.prefix, // [修改] 注入 Root
+	}
+	if acc := wl.Pool.Primary(); acc != nil {
+		data["CookieValid"] = true
+		data["QzoneUIN"] = acc.Client.UIN()
+	} else if since := pausedSince(wl.Pool); !since.IsZero() {
+		data["PausedSince"] = since.Format("01-02 15:04")
 	}
-	if s.qzClient != nil {
-		data["QzoneUIN"] = s.qzClient.UIN()
+	if quota, err := wl.Publisher.QuotaStatus(time.Now()); err != nil {
+		log.Printf("[Web] 查询发布额度失败: %v", err)
+	} else if quota.Limited() {
+		data["Quota"] = quota
 	}
 
 	s.renderTemplate(w, "admin.html", data)
@@ -374,6 +504,11 @@ func (s *Server) handleAPISubmit(w http.ResponseWriter, r *http.Request) {
 		jsonResp(w, 400, false, "请求体过大")
 		return
 	}
+	wl := s.walls.Get(r.FormValue("wall"))
+	if wl == nil {
+		jsonResp(w, 404, false, "表白墙不存在")
+		return
+	}
 
 	text := r.FormValue("text")
 	name := r.FormValue("uin")
@@ -453,12 +588,18 @@ func (s *Server) handleAPISubmit(w http.ResponseWriter, r *http.Request) {
 		Status:     model.StatusPending,
 		CreateTime: time.Now().Unix(),
 	}
-	if err := s.store.SavePost(post); err != nil {
+	if err := wl.Store.SavePost(post); err != nil {
 		jsonResp(w, 500, false, "保存失败")
 		return
 	}
+	s.audit(account, model.ActionCreate, post.ID, "", model.StatusPending, "")
 
-	log.Printf("[Web] received post #%d from %s", post.ID, name)
+	log.Printf("[Web] received post #%d from %s, wall=%s", post.ID, name, wl.ID)
+	tag := ""
+	if s.walls.Multi() {
+		tag = "【" + wl.Name + "】"
+	}
+	wl.Notifier.Notify(notify.AlertNewPost, fmt.Sprintf("📬 %s收到网页投稿 #%d\n%s", tag, post.ID, post.Summary()))
 	jsonRespData(w, 200, true, fmt.Sprintf("投稿成功，编号 #%d，等待审核", post.ID), post.ID)
 }
 
@@ -468,7 +609,8 @@ func (s *Server) handleAPIApprove(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	account := s.currentAccount(r)
-	if account == nil || !account.IsAdmin() {
+	wl := s.currentWall(r, account)
+	if wl == nil || !account.Can(model.PermReview) {
 		jsonResp(w, 403, false, "无权限")
 		return
 	}
@@ -479,27 +621,76 @@ func (s *Server) handleAPIApprove(w http.ResponseWriter, r *http.Request) {
 		jsonResp(w, 400, false, "编号格式错误")
 		return
 	}
-	post, err := s.store.GetPost(id)
+	post, err := wl.Store.GetPost(id)
 	if err != nil || post == nil {
 		jsonResp(w, 404, false, "稿件不存在")
 		return
 	}
 
-	post.Status = model.StatusApproved
-	if err := s.store.SavePost(post); err != nil {
-		jsonResp(w, 500, false, "更新失败")
+	// publish_at 为空时按 wall.publish_delay 延迟发布，否则定时发布
+	publishAt, err := s.publishAt(wl, r.FormValue("publish_at"))
+	if err != nil {
+		jsonResp(w, 400, false, err.Error())
+		return
+	}
+
+	if !slices.Contains(model.ApprovableStatuses, post.Status) {
+		jsonResp(w, 409, false, fmt.Sprintf("稿件 #%d 状态为 %s，不能过稿", id, post.Status))
+		return
+	}
+	oldStatus := post.Status
+	if !s.setPostStatus(w, wl.Store, id, model.StatusApproved, "", publishAt, model.ApprovableStatuses...) {
 		return
 	}
+	if publishAt > 0 {
+		at := time.Unix(publishAt, 0).Format("2006-01-02 15:04")
+		s.audit(account, model.ActionSchedule, post.ID, oldStatus, model.StatusApproved, "定时 "+at)
+		jsonResp(w, 200, true, fmt.Sprintf("稿件 #%d 已通过，将于 %s 发布", id, at))
+		return
+	}
+	s.audit(account, model.ActionApprove, post.ID, oldStatus, model.StatusApproved, "")
 	jsonResp(w, 200, true, fmt.Sprintf("稿件 #%d 已通过", id))
 }
 
+// handleAPICancelSchedule 取消定时稿件，退回待审核
+func (s *Server) handleAPICancelSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonResp(w, 405, false, "仅支持 POST")
+		return
+	}
+	account := s.currentAccount(r)
+	wl := s.currentWall(r, account)
+	if wl == nil || !account.Can(model.PermReview) {
+		jsonResp(w, 403, false, "无权限")
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		jsonResp(w, 400, false, "编号格式错误")
+		return
+	}
+	ok, err := wl.Store.CancelSchedule(id)
+	if err != nil {
+		jsonResp(w, 500, false, "取消失败")
+		return
+	}
+	if !ok {
+		jsonResp(w, 400, false, "稿件不是未到时间的定时稿件，可能已在发布")
+		return
+	}
+	s.audit(account, model.ActionCancel, id, model.StatusApproved, model.StatusPending, "")
+	jsonResp(w, 200, true, fmt.Sprintf("稿件 #%d 已取消定时，退回待审核", id))
+}
+
 func (s *Server) handleAPIReject(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		jsonResp(w, 405, false, "仅支持 POST")
 		return
 	}
 	account := s.currentAccount(r)
-	if account == nil || !account.IsAdmin() {
+	wl := s.currentWall(r, account)
+	if wl == nil || !account.Can(model.PermReview) {
 		jsonResp(w, 403, false, "无权限")
 		return
 	}
@@ -511,28 +702,46 @@ func (s *Server) handleAPIReject(w http.ResponseWriter, r *http.Request) {
 		jsonResp(w, 400, false, "编号格式错误")
 		return
 	}
-	post, err := s.store.GetPost(id)
+	post, err := wl.Store.GetPost(id)
 	if err != nil || post == nil {
 		jsonResp(w, 404, false, "稿件不存在")
 		return
 	}
 
-	post.Status = model.StatusRejected
-	post.Reason = reason
-	if err := s.store.SavePost(post); err != nil {
-		jsonResp(w, 500, false, "更新失败")
+	if !slices.Contains(model.RejectableStatuses, post.Status) {
+		jsonResp(w, 409, false, fmt.Sprintf("稿件 #%d 状态为 %s，不能拒绝", id, post.Status))
+		return
+	}
+	oldStatus := post.Status
+	if !s.setPostStatus(w, wl.Store, id, model.StatusRejected, reason, 0, model.RejectableStatuses...) {
 		return
 	}
+	s.audit(account, model.ActionReject, post.ID, oldStatus, model.StatusRejected, reason)
 	jsonResp(w, 200, true, fmt.Sprintf("稿件 #%d 已拒绝", id))
 }
 
+// setPostStatus 按条件修改稿件状态，稿件已被 Worker 领取或被其他人处理时返回 409；失败时已写入响应
+func (s *Server) setPostStatus(w http.ResponseWriter, st *store.Store, id int64, to model.PostStatus, reason string, publishAt int64, from ...model.PostStatus) bool {
+	err := st.SetPostStatus(id, to, reason, publishAt, from...)
+	if errors.Is(err, store.ErrStatusConflict) {
+		jsonResp(w, 409, false, fmt.Sprintf("稿件 #%d 的状态已变化（可能已在发布），请刷新后重试", id))
+		return false
+	}
+	if err != nil {
+		jsonResp(w, 500, false, "更新失败")
+		return false
+	}
+	return true
+}
+
 func (s *Server) handleAPIDelete(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		jsonResp(w, 405, false, "仅支持 POST")
 		return
 	}
 	account := s.currentAccount(r)
-	if account == nil || !account.IsAdmin() {
+	wl := s.currentWall(r, account)
+	if wl == nil || !account.Can(model.PermDelete) {
 		jsonResp(w, 403, false, "无权限")
 		return
 	}
@@ -543,127 +752,258 @@ func (s *Server) handleAPIDelete(w http.ResponseWriter, r *http.Request) {
 		jsonResp(w, 400, false, "编号格式错误")
 		return
 	}
-	post, err := s.store.GetPost(id)
+	post, err := wl.Store.GetPost(id)
 	if err != nil || post == nil {
 		jsonResp(w, 404, false, "稿件不存在")
 		return
 	}
 
-	for _, img := range post.Images {
-		if strings.HasPrefix(img, "/uploads/") {
-			fileName := strings.TrimPrefix(img, "/uploads/")
-			_ = os.Remove(filepath.Join(s.uploadDir, fileName))
-		}
+	if post.Status == model.StatusDeleted {
+		jsonResp(w, 400, false, "稿件已在回收站中")
+		return
+	}
+	if post.Status == model.StatusPublishing {
+		jsonResp(w, 409, false, fmt.Sprintf("稿件 #%d 正在发布，请等发布完成后再删除", id))
+		return
 	}
 
-	if err := s.store.DeletePost(id); err != nil {
+	ok, err := wl.Store.SoftDeletePost(id, account.Username)
+	if err != nil {
 		jsonResp(w, 500, false, "删除失败")
 		return
 	}
-	jsonResp(w, 200, true, fmt.Sprintf("稿件 #%d 已删除", id))
+	if !ok {
+		jsonResp(w, 409, false, fmt.Sprintf("稿件 #%d 的状态已变化（可能已在发布），请刷新后重试", id))
+		return
+	}
+	s.audit(account, model.ActionDelete, id, post.Status, model.StatusDeleted, "")
+	jsonResp(w, 200, true, fmt.Sprintf("稿件 #%d 已移入回收站", id))
 }
 
-func (s *Server) handleAPIBatchApprove(w http.ResponseWriter, r *http.Request) {
+// handleAPIRetract 下架已发布的稿件：按 TID 删除QQ空间说说并标记为 retracted。
+// 合集说说返回 409 提示会一起删除的稿件，前端确认后带 force=1 重新提交。
+func (s *Server) handleAPIRetract(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		jsonResp(w, 405, false, "仅支持 POST")
 		return
 	}
 	account := s.currentAccount(r)
-	if account == nil || !account.IsAdmin() {
+	wl := s.currentWall(r, account)
+	if wl == nil || !account.Can(model.PermDelete) {
 		jsonResp(w, 403, false, "无权限")
 		return
 	}
 
-	ids, err := parseBatchIDs(r.FormValue("ids"))
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
 	if err != nil {
-		jsonResp(w, 400, false, err.Error())
+		jsonResp(w, 400, false, "编号格式错误")
 		return
 	}
+	reason := strings.TrimSpace(r.FormValue("reason"))
+	force := r.FormValue("force") == "1"
 
-	posts, err := s.store.GetPostsByIDs(ids)
+	res, err := wl.Publisher.Retract(r.Context(), id, reason, force, publish.Actor{
+		ID:     account.ID,
+		Name:   account.Username,
+		Source: model.SourceWeb,
+	})
+	if errors.Is(err, publish.ErrSharedTID) {
+		jsonResp(w, 409, false, fmt.Sprintf("稿件 #%d 所在的说说是合集，包含 %s 共 %d 条稿件，下架会一起删除。确认继续吗？",
+			id, res.IDs(), len(res.Posts)))
+		return
+	}
+	if errors.Is(err, publish.ErrNotPublished) || errors.Is(err, publish.ErrNoTID) {
+		jsonResp(w, 400, false, err.Error())
+		return
+	}
 	if err != nil {
-		jsonResp(w, 500, false, "数据库查询失败: "+err.Error())
+		jsonResp(w, 500, false, err.Error())
 		return
 	}
+	jsonResp(w, 200, true, fmt.Sprintf("已从QQ空间删除说说，下架稿件 %s", res.IDs()))
+}
 
-	var validPosts []*model.Post
-	for _, p := range posts {
-		if p.Status == model.StatusPending {
-			validPosts = append(validPosts, p)
-		}
+// handleAPIRetry 重发发布失败的稿件（ids 逗号分隔），退回待发布队列由 Worker 重新发布
+func (s *Server) handleAPIRetry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonResp(w, 405, false, "仅支持 POST")
+		return
 	}
-
-	if len(validPosts) == 0 {
-		jsonResp(w, 400, false, "没有待审核的稿件，或已处理")
+	account := s.currentAccount(r)
+	wl := s.currentWall(r, account)
+	if wl == nil || !account.Can(model.PermPublish) {
+		jsonResp(w, 403, false, "无权限")
 		return
 	}
 
-	var summaryBuilder strings.Builder
-	fmt.Fprintf(&summaryBuilder, "【表白墙更新】 %s\n", time.Now().Format("01/02"))
-	summaryBuilder.WriteString("----------------\n")
-
-	var imagesData [][]byte
+	ids, err := parseBatchIDs(r.FormValue("ids"))
+	if err != nil {
+		jsonResp(w, 400, false, err.Error())
+		return
+	}
+	requeued, err := wl.Publisher.Retry(ids, publish.Actor{
+		ID:     account.ID,
+		Name:   account.Username,
+		Source: model.SourceWeb,
+	})
+	if err != nil {
+		jsonResp(w, 500, false, err.Error())
+		return
+	}
+	if len(requeued) == 0 {
+		jsonResp(w, 400, false, "没有发布失败的稿件，或已处理")
+		return
+	}
+	jsonResp(w, 200, true, fmt.Sprintf("已重新排队 %d 条稿件，将按顺序重新发布", len(requeued)))
+}
 
-	for _, post := range validPosts {
-		var imgData []byte
-		var renderErr error
+func (s *Server) handleAPIRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonResp(w, 405, false, "仅支持 POST")
+		return
+	}
+	account := s.currentAccount(r)
+	wl := s.currentWall(r, account)
+	if wl == nil || !account.Can(model.PermDelete) {
+		jsonResp(w, 403, false, "无权限")
+		return
+	}
 
-		if s.renderer != nil && s.renderer.Available() {
-			// [修复] 使用本地路径解析器，而不是 resolvePostImages
-			// resolvePostImages 会加上 /wall 前缀导致后端无法读取文件
-			renderPost := s.resolvePostImagesForRender(post)
-			imgData, renderErr = s.renderer.RenderPost(renderPost)
-		} else {
-			renderErr = fmt.Errorf("renderer not available")
-		}
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		jsonResp(w, 400, false, "编号格式错误")
+		return
+	}
+	post, err := wl.Store.GetPost(id)
+	if err != nil || post == nil {
+		jsonResp(w, 404, false, "稿件不存在")
+		return
+	}
+	if post.Status != model.StatusDeleted {
+		jsonResp(w, 400, false, "稿件不在回收站中")
+		return
+	}
 
-		if renderErr != nil || len(imgData) == 0 {
-			log.Printf("[Web] 渲染失败 #%d: %v", post.ID, renderErr)
-			continue
-		}
-		imagesData = append(imagesData, imgData)
+	if err := wl.Store.RestorePost(id); err != nil {
+		jsonResp(w, 500, false, "恢复失败")
+		return
+	}
+	restored, _ := wl.Store.GetPost(id)
+	var newStatus model.PostStatus
+	if restored != nil {
+		newStatus = restored.Status
+	}
+	s.audit(account, model.ActionRestore, id, model.StatusDeleted, newStatus, "")
+	jsonResp(w, 200, true, fmt.Sprintf("稿件 #%d 已恢复", id))
+}
 
-		content := []rune(post.Text)
-		if len(content) > 20 {
-			fmt.Fprintf(&summaryBuilder, "#%d: %s...\n", post.ID, string(content[:20]))
-		} else {
-			if post.Text == "" {
-				fmt.Fprintf(&summaryBuilder, "#%d: [图片]\n", post.ID)
-			} else {
-				fmt.Fprintf(&summaryBuilder, "#%d: %s\n", post.ID, post.Text)
-			}
-		}
+func (s *Server) handleAPIPurge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonResp(w, 405, false, "仅支持 POST")
+		return
+	}
+	account := s.currentAccount(r)
+	wl := s.currentWall(r, account)
+	if wl == nil || !account.Can(model.PermDelete) {
+		jsonResp(w, 403, false, "无权限")
+		return
+	}
 
-		post.Status = model.StatusPublished
-		_ = s.store.SavePost(post)
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		jsonResp(w, 400, false, "编号格式错误")
+		return
+	}
+	post, err := wl.Store.GetPost(id)
+	if err != nil || post == nil {
+		jsonResp(w, 404, false, "稿件不存在")
+		return
+	}
+	if post.Status != model.StatusDeleted {
+		jsonResp(w, 400, false, "只能彻底删除回收站中的稿件")
+		return
 	}
 
-	if len(imagesData) == 0 {
-		jsonResp(w, 500, false, "没有成功渲染的图片，取消发布")
+	if err := wl.Store.PurgePost(post, s.uploadDir); err != nil {
+		jsonResp(w, 500, false, "删除失败")
 		return
 	}
+	s.audit(account, model.ActionPurge, id, model.StatusDeleted, "", "")
+	jsonResp(w, 200, true, fmt.Sprintf("稿件 #%d 已彻底删除", id))
+}
 
-	summaryBuilder.WriteString("----------------\n")
-	summaryBuilder.WriteString("详情见图 👇")
-	finalText := summaryBuilder.String()
+func (s *Server) handleAPIBatchApprove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonResp(w, 405, false, "仅支持 POST")
+		return
+	}
+	account := s.currentAccount(r)
+	wl := s.currentWall(r, account)
+	if wl == nil || !account.Can(model.PermPublish) {
+		jsonResp(w, 403, false, "无权限")
+		return
+	}
 
-	opts := &qzone.PublishOption{
-		ImageBytes: imagesData,
+	ids, err := parseBatchIDs(r.FormValue("ids"))
+	if err != nil {
+		jsonResp(w, 400, false, err.Error())
+		return
 	}
 
-	_, publishErr := s.qzClient.Publish(context.Background(), finalText, opts)
+	// 发布持有发布锁并可能等待频率限制和重试，客户端断开时不能中途取消：
+	// QQ空间可能已经收到说说，取消后稿件会被 Worker 再次发布
+	results, err := wl.Publisher.Approve(context.WithoutCancel(r.Context()), ids, publish.Actor{
+		ID:     account.ID,
+		Name:   account.Username,
+		Source: model.SourceWeb,
+	})
+	if errors.Is(err, publish.ErrNothingToPublish) {
+		jsonResp(w, 400, false, "没有待审核的稿件，或已处理")
+		return
+	}
+	if err != nil {
+		jsonResp(w, 500, false, err.Error())
+		return
+	}
 
-	if publishErr != nil {
-		log.Printf("[Web] 发布说说失败: %v", publishErr)
-		for _, p := range validPosts {
-			p.Status = model.StatusPending
-			_ = s.store.SavePost(p)
+	published, failed, deferred, requeued := 0, 0, 0, 0
+	var lastErr, deferErr error
+	for _, res := range results {
+		published += len(res.Published)
+		failed += len(res.Failed)
+		deferred += len(res.Deferred)
+		requeued += len(res.Requeued)
+		if len(res.Deferred) > 0 {
+			deferErr = res.Err
+		} else if res.Err != nil {
+			lastErr = res.Err
+		}
+	}
+	if published == 0 && deferred == 0 {
+		msg := fmt.Sprintf("发布到QQ空间失败: %v", lastErr)
+		if requeued > 0 {
+			msg += fmt.Sprintf("，%d 条稿件稍后自动重试", requeued)
 		}
-		jsonResp(w, 500, false, "发布到QQ空间失败: "+publishErr.Error())
+		jsonResp(w, 500, false, msg)
 		return
 	}
-
-	jsonResp(w, 200, true, fmt.Sprintf("成功发布 %d 条稿件！", len(imagesData)))
+	msg := fmt.Sprintf("成功发布 %d 条稿件！", published)
+	if published == 0 {
+		msg = "稿件已通过。"
+	}
+	if deferred > 0 {
+		msg += fmt.Sprintf(" %d 条顺延发布（%v）", deferred, deferErr)
+	}
+	if requeued > 0 {
+		msg += fmt.Sprintf(" %d 条发布失败，稍后自动重试", requeued)
+		if lastErr != nil {
+			msg += fmt.Sprintf("（%v）", lastErr)
+		}
+	}
+	if failed > 0 {
+		msg += fmt.Sprintf(" %d 条发布失败，可在「失败」中查看", failed)
+	}
+	jsonResp(w, 200, true, msg)
 }
 
 func (s *Server) handleAPIBatchReject(w http.ResponseWriter, r *http.Request) {
@@ -672,7 +1012,8 @@ func (s *Server) handleAPIBatchReject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	account := s.currentAccount(r)
-	if account == nil || !account.IsAdmin() {
+	wl := s.currentWall(r, account)
+	if wl == nil || !account.Can(model.PermReview) {
 		jsonResp(w, 403, false, "无权限")
 		return
 	}
@@ -683,7 +1024,7 @@ func (s *Server) handleAPIBatchReject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	reason := strings.TrimSpace(r.FormValue("reason"))
-	updated, skipped, err := s.applyBatchStatus(ids, model.StatusRejected, reason)
+	updated, skipped, err := s.applyBatchStatus(wl.Store, account, ids, model.StatusRejected, reason)
 	if err != nil {
 		jsonResp(w, 500, false, "批量拒绝失败")
 		return
@@ -721,8 +1062,8 @@ func parseBatchIDs(raw string) ([]int64, error) {
 	return ids, nil
 }
 
-func (s *Server) applyBatchStatus(ids []int64, status model.PostStatus, reason string) (updated int, skipped int, err error) {
-	posts, err := s.store.GetPostsByIDs(ids)
+func (s *Server) applyBatchStatus(st *store.Store, account *model.Account, ids []int64, status model.PostStatus, reason string) (updated int, skipped int, err error) {
+	posts, err := st.GetPostsByIDs(ids)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -739,15 +1080,24 @@ func (s *Server) applyBatchStatus(ids []int64, status model.PostStatus, reason s
 			skipped++
 			continue
 		}
-		post.Status = status
+		oldStatus := post.Status
+		post.Reason = ""
 		if status == model.StatusRejected {
 			post.Reason = reason
-		} else {
-			post.Reason = ""
 		}
-		if err := s.store.SavePost(post); err != nil {
+		err := st.SetPostStatus(post.ID, status, post.Reason, 0, model.StatusPending)
+		if errors.Is(err, store.ErrStatusConflict) {
+			skipped++
+			continue
+		}
+		if err != nil {
 			return updated, skipped, err
 		}
+		action := model.ActionApprove
+		if status == model.StatusRejected {
+			action = model.ActionReject
+		}
+		s.audit(account, action, post.ID, oldStatus, status, post.Reason)
 		updated++
 	}
 	missing := len(ids) - len(posts)
@@ -759,11 +1109,17 @@ func (s *Server) applyBatchStatus(ids []int64, status model.PostStatus, reason s
 
 func (s *Server) handleAPIQRCode(w http.ResponseWriter, r *http.Request) {
 	account := s.currentAccount(r)
-	if account == nil || !account.IsAdmin() {
+	if !account.Can(model.PermQzone) {
 		jsonResp(w, 403, false, "无权限")
 		return
 	}
 
+	wl, acc := s.qzoneAccount(r, account, r.URL.Query().Get("account"))
+	if acc == nil {
+		jsonResp(w, 404, false, "账号不存在")
+		return
+	}
+
 	qr, err := qzone.GetQRCode()
 	if err != nil {
 		jsonResp(w, 500, false, "获取二维码失败: "+err.Error())
@@ -772,21 +1128,23 @@ func (s *Server) handleAPIQRCode(w http.ResponseWriter, r *http.Request) {
 
 	s.qrMu.Lock()
 	s.qrCode = qr
+	s.qrWall = wl
+	s.qrAccount = acc
 	s.qrStatus = "waiting"
 	s.qrMessage = ""
 	s.qrMu.Unlock()
 
-	go s.pollQRLogin()
+	go s.pollQRLogin(account)
 
 	w.Header().Set("Content-Type", "image/png")
 	_, _ = w.Write(qr.Image)
 }
 
-func (s *Server) pollQRLogin() {
+func (s *Server) pollQRLogin(account *model.Account) {
 	s.qrMu.Lock()
-	qr := s.qrCode
+	qr, wl, acc := s.qrCode, s.qrWall, s.qrAccount
 	s.qrMu.Unlock()
-	if qr == nil {
+	if qr == nil || acc == nil {
 		return
 	}
 
@@ -802,17 +1160,19 @@ func (s *Server) pollQRLogin() {
 		}
 		switch state {
 		case qzone.LoginSuccess:
-			if err := s.qzClient.UpdateCookie(cookie); err != nil {
+			if err := wl.Pool.Login(acc, cookie); err != nil {
 				s.qrMu.Lock()
 				s.qrStatus = "error"
-				s.qrMessage = "Cookie 更新失败: " + err.Error()
+				s.qrMessage = err.Error()
 				s.qrMu.Unlock()
 				return
 			}
 			s.qrMu.Lock()
 			s.qrStatus = "success"
-			s.qrMessage = fmt.Sprintf("登录成功, UIN=%d", s.qzClient.UIN())
+			s.qrMessage = fmt.Sprintf("账号 %s 登录成功, UIN=%d", acc.Name, acc.Client.UIN())
 			s.qrMu.Unlock()
+			s.vault.Save(acc.Client)
+			s.audit(account, model.ActionLogin, 0, "", "", fmt.Sprintf("扫码登录账号 %s UIN=%d", acc.Name, acc.Client.UIN()))
 			return
 		case qzone.LoginExpired:
 			s.qrMu.Lock()
@@ -834,15 +1194,25 @@ func (s *Server) pollQRLogin() {
 }
 
 func (s *Server) handleAPIQRStatus(w http.ResponseWriter, r *http.Request) {
+	if !s.currentAccount(r).Can(model.PermQzone) {
+		jsonResp(w, 403, false, "无权限")
+		return
+	}
+
 	s.qrMu.Lock()
 	status := s.qrStatus
 	msg := s.qrMessage
+	var name string
+	if s.qrAccount != nil {
+		name = s.qrAccount.Name
+	}
 	s.qrMu.Unlock()
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]string{
 		"status":  status,
 		"message": msg,
+		"account": name,
 	})
 }
 
@@ -852,19 +1222,31 @@ func (s *Server) handleAPIHealth(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleAPIQzoneStatus(w http.ResponseWriter, r *http.Request) {
 	// [修改] 允许公开访问此接口，以便 user.html 页面刷新状态
-	// 移除了管理员权限校验
+	// 移除了管理员权限校验；号池中各账号的状态只返回给管理员
 
-	uin := int64(0)
-	if s.qzClient != nil {
-		uin = s.qzClient.UIN()
+	wl := s.walls.Get(r.URL.Query().Get("wall"))
+	if wl == nil {
+		jsonResp(w, 404, false, "表白墙不存在")
+		return
+	}
+	resp := map[string]interface{}{
+		"ok":           true,
+		"wall":         wl.ID,
+		"cookie_valid": false,
+		"uin":          int64(0),
+	}
+	if acc := wl.Pool.Primary(); acc != nil {
+		resp["cookie_valid"] = true
+		resp["uin"] = acc.Client.UIN()
+	} else if since := pausedSince(wl.Pool); !since.IsZero() {
+		resp["paused_since"] = since.Format("01-02 15:04")
+	}
+	if wl.CanManage(s.currentAccount(r)) {
+		resp["accounts"] = s.qzoneAccounts(wl)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{
-		"ok":           true,
-		"cookie_valid": s.isQzoneLoggedIn(),
-		"uin":          uin,
-	})
+	_ = json.NewEncoder(w).Encode(resp)
 }
 
 func (s *Server) handleAPIQzoneRefresh(w http.ResponseWriter, r *http.Request) {
@@ -873,42 +1255,60 @@ func (s *Server) handleAPIQzoneRefresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	account := s.currentAccount(r)
-	if account == nil || !account.IsAdmin() {
+	if !account.Can(model.PermQzone) {
 		jsonResp(w, 403, false, "无权限")
 		return
 	}
+	wl, acc := s.qzoneAccount(r, account, r.FormValue("account"))
+	if acc == nil {
+		jsonResp(w, 404, false, "账号不存在")
+		return
+	}
+	if acc.Source == config.CookieSourceQR {
+		jsonResp(w, 400, false, "账号 "+acc.Name+" 只支持扫码登录")
+		return
+	}
 
 	var success bool
-	var uin int64
+	var lastErr error
 
 	zero.RangeBot(func(id int64, ctx *zero.Ctx) bool {
+		// 配置了QQ号的账号只使用登录该QQ号的机器人
+		if acc.UIN > 0 && id != acc.UIN {
+			return true
+		}
 
 		cookie := ctx.GetCookies("qzone.qq.com")
 		if cookie == "" {
 			return true
 		}
 
-		if err := s.qzClient.UpdateCookie(cookie); err != nil {
-			log.Printf("[Web] 从 Bot(%d) 刷新 Cookie 失败: %v", id, err)
+		if err := wl.Pool.Login(acc, cookie); err != nil {
+			log.Printf("[Web] 从 Bot(%d) 刷新账号 %s 的 Cookie 失败: %v", id, acc.Name, err)
+			lastErr = err
 			return true
 		}
 
-		uin = s.qzClient.UIN()
 		success = true
-		log.Printf("[Web] 成功从 Bot(%d) 拉取 Cookie, UIN=%d", id, uin)
+		log.Printf("[Web] 成功从 Bot(%d) 拉取账号 %s 的 Cookie, UIN=%d", id, acc.Name, acc.Client.UIN())
 		return false
 	})
 
-	if success {
-		jsonResp(w, 200, true, fmt.Sprintf("成功从 Bot 拉取 Cookie (UIN: %d)", uin))
-	} else {
+	switch {
+	case success:
+		s.vault.Save(acc.Client)
+		s.audit(account, model.ActionLogin, 0, "", "", fmt.Sprintf("从 Bot 刷新账号 %s 的 Cookie UIN=%d", acc.Name, acc.Client.UIN()))
+		jsonResp(w, 200, true, fmt.Sprintf("成功从 Bot 拉取 Cookie (账号: %s, UIN: %d)", acc.Name, acc.Client.UIN()))
+	case lastErr != nil:
+		jsonResp(w, 200, false, lastErr.Error())
+	default:
 		jsonResp(w, 200, false, "未能从任何 Bot 获取到有效 Cookie")
 	}
 }
 
 func (s *Server) handleAPIConfig(w http.ResponseWriter, r *http.Request) {
 	account := s.currentAccount(r)
-	if account == nil || !account.IsAdmin() {
+	if !account.Can(model.PermConfig) {
 		jsonResp(w, 403, false, "无权限")
 		return
 	}
@@ -930,7 +1330,7 @@ func (s *Server) handleAPIConfig(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(map[string]interface{}{
 			"ok":     true,
-			"config": s.fullCfg,
+			"config": s.fullCfg.Redacted(),
 		})
 
 	case http.MethodPost:
@@ -946,6 +1346,12 @@ func (s *Server) handleAPIConfig(w http.ResponseWriter, r *http.Request) {
 			jsonResp(w, 400, false, "JSON 格式错误: "+err.Error())
 			return
 		}
+		// 读取时隐藏了密钥，留空表示不修改
+		newCfg.KeepSecrets(s.fullCfg)
+		if err := newCfg.Validate(); err != nil {
+			jsonResp(w, 400, false, "配置错误: "+err.Error())
+			return
+		}
 
 		// 保存到文件
 		if err := newCfg.Save(s.cfgPath); err != nil {
@@ -957,6 +1363,7 @@ func (s *Server) handleAPIConfig(w http.ResponseWriter, r *http.Request) {
 		*s.fullCfg = newCfg
 		s.cfg = newCfg.Web
 		s.wallCfg = newCfg.Wall
+		s.audit(account, model.ActionConfig, 0, "", "", "保存配置")
 
 		jsonResp(w, 200, true, "配置已保存并生效。Bot/WS/Worker 等配置修改需重启后生效")
 
@@ -971,7 +1378,7 @@ func (s *Server) handleAPIChangePassword(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	account := s.currentAccount(r)
-	if account == nil || !account.IsAdmin() {
+	if account == nil {
 		jsonResp(w, 403, false, "无权限")
 		return
 	}
@@ -999,10 +1406,281 @@ func (s *Server) handleAPIChangePassword(w http.ResponseWriter, r *http.Request)
 		jsonResp(w, 500, false, "修改密码失败")
 		return
 	}
+	s.audit(account, model.ActionPassword, 0, "", "", "")
 
 	jsonResp(w, 200, true, "密码修改成功")
 }
 
+func (s *Server) handleAPIAudit(w http.ResponseWriter, r *http.Request) {
+	account := s.currentAccount(r)
+	wl := s.currentWall(r, account)
+	if wl == nil || !account.Can(model.PermView) {
+		jsonResp(w, 403, false, "无权限")
+		return
+	}
+
+	q := r.URL.Query()
+	filter := store.AuditFilter{
+		Source: model.AuditSource(q.Get("source")),
+		Action: q.Get("action"),
+	}
+	filter.PostID, _ = strconv.ParseInt(q.Get("post_id"), 10, 64)
+	filter.ActorID, _ = strconv.ParseInt(q.Get("actor_id"), 10, 64)
+	filter.Since, filter.Until = parseDateRange(q)
+	page := parsePage(q)
+	const pageSize = 50
+
+	// 管理员查看所有墙和全局操作的日志，墙管理员只能查看本墙的日志
+	st := wl.Store
+	if account.IsAdmin() {
+		st = s.store
+	}
+	events, err := st.ListAuditEvents(filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		jsonResp(w, 500, false, "查询审计日志失败")
+		return
+	}
+	if events == nil {
+		events = []*model.AuditEvent{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":     true,
+		"page":   page,
+		"events": events,
+	})
+}
+
+// handleAPIBackup GET 列出已有备份, POST 立即创建一份备份
+func (s *Server) handleAPIBackup(w http.ResponseWriter, r *http.Request) {
+	account := s.currentAccount(r)
+	if !account.Can(model.PermConfig) {
+		jsonResp(w, 403, false, "无权限")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		infos, err := backup.List(s.fullCfg.Backup.Dir)
+		if err != nil {
+			jsonResp(w, 500, false, "读取备份目录失败")
+			return
+		}
+		if infos == nil {
+			infos = []backup.Info{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok":      true,
+			"backups": infos,
+		})
+	case http.MethodPost:
+		p, err := task.RunBackup(s.fullCfg.Backup, s.store, s.uploadDir)
+		if err != nil {
+			log.Printf("[Web] 备份失败: %v", err)
+			jsonResp(w, 500, false, "备份失败: "+err.Error())
+			return
+		}
+		s.audit(account, model.ActionBackup, 0, "", "", filepath.Base(p))
+		jsonResp(w, 200, true, "备份完成: "+filepath.Base(p))
+	default:
+		jsonResp(w, 405, false, "仅支持 GET/POST")
+	}
+}
+
+// handleAPIExport 按状态和日期导出投稿，format 为 jsonl/csv/zip
+func (s *Server) handleAPIExport(w http.ResponseWriter, r *http.Request) {
+	account := s.currentAccount(r)
+	wl := s.currentWall(r, account)
+	if wl == nil || !account.Can(model.PermView) {
+		jsonResp(w, 403, false, "无权限")
+		return
+	}
+
+	q := r.URL.Query()
+	format, err := export.ParseFormat(q.Get("format"))
+	if err != nil {
+		jsonResp(w, 400, false, "不支持的导出格式")
+		return
+	}
+	filter := store.SearchQuery{Status: model.PostStatus(q.Get("status"))}
+	filter.Since, filter.Until = parseDateRange(q)
+
+	exp := export.NewExporter(wl.Store, wl.Renderer, s.uploadDir)
+	exp.ResolveImage = publish.ResolveImageURL
+
+	// 边查边写，开始输出后出错只能中断响应
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", `attachment; filename="`+format.FileName(time.Now())+`"`)
+	n, err := exp.Export(w, format, filter)
+	if err != nil {
+		log.Printf("[Web] 导出失败 (已写出 %d 条): %v", n, err)
+		return
+	}
+	log.Printf("[Web] %s 导出 %d 条投稿 (%s)", account.Username, n, format)
+}
+
+// handleAPIImport 上传 JSON/CSV 文件导入投稿，dry_run=1 时只返回将要执行的结果
+func (s *Server) handleAPIImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		jsonResp(w, 405, false, "仅支持 POST")
+		return
+	}
+	account := s.currentAccount(r)
+	wl := s.currentWall(r, account)
+	if wl == nil || !account.Can(model.PermPublish) {
+		jsonResp(w, 403, false, "无权限")
+		return
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		jsonResp(w, 400, false, "解析上传文件失败")
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		jsonResp(w, 400, false, "请选择要导入的文件")
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	opt := importer.Options{
+		Source:       strings.TrimSpace(r.FormValue("source")),
+		DryRun:       r.FormValue("dry_run") == "1",
+		KeepApproved: r.FormValue("keep_approved") == "1",
+		ActorID:      account.ID,
+		ActorName:    account.Username,
+		AuditSource:  model.SourceWeb,
+	}
+	report, err := importer.NewImporter(wl.Store, s.uploadDir).Import(file, importer.FormatOf(header.Filename), opt)
+	if err != nil {
+		jsonResp(w, 400, false, "导入中止: "+err.Error())
+		return
+	}
+	log.Printf("[Web] %s 导入 %s: 插入 %d, 跳过 %d, 失败 %d (dry_run=%v)",
+		account.Username, header.Filename, report.Inserted, report.Skipped, report.Failed, opt.DryRun)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":     true,
+		"report": report,
+	})
+}
+
+// handleAPIBackupDownload 下载指定的备份文件
+func (s *Server) handleAPIBackupDownload(w http.ResponseWriter, r *http.Request) {
+	account := s.currentAccount(r)
+	if !account.Can(model.PermConfig) {
+		jsonResp(w, 403, false, "无权限")
+		return
+	}
+
+	// 只允许下载备份目录中列出的文件，避免路径穿越
+	name := r.URL.Query().Get("name")
+	infos, err := backup.List(s.fullCfg.Backup.Dir)
+	if err != nil {
+		jsonResp(w, 500, false, "读取备份目录失败")
+		return
+	}
+	for _, info := range infos {
+		if info.Name == name {
+			w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+			http.ServeFile(w, r, filepath.Join(s.fullCfg.Backup.Dir, name))
+			return
+		}
+	}
+	jsonResp(w, 404, false, "备份不存在")
+}
+
+func (s *Server) handleAPISearch(w http.ResponseWriter, r *http.Request) {
+	account := s.currentAccount(r)
+	wl := s.currentWall(r, account)
+	if wl == nil || !account.Can(model.PermView) {
+		jsonResp(w, 403, false, "无权限")
+		return
+	}
+
+	q := r.URL.Query()
+	page := parsePage(q)
+	const pageSize = 50
+
+	posts, err := wl.Store.SearchPosts(parseSearchQuery(q), pageSize, (page-1)*pageSize)
+	if err != nil {
+		jsonResp(w, 500, false, "搜索失败: "+err.Error())
+		return
+	}
+	displayPosts := make([]*model.Post, len(posts))
+	for i, p := range posts {
+		displayPosts[i] = s.resolvePostImages(p)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":    true,
+		"page":  page,
+		"posts": displayPosts,
+	})
+}
+
+// parseSearchQuery 从查询参数解析搜索条件: q, status, since, until, uin, group_id
+func parseSearchQuery(q url.Values) store.SearchQuery {
+	sq := store.SearchQuery{
+		Keyword: strings.TrimSpace(q.Get("q")),
+		Status:  model.PostStatus(q.Get("status")),
+	}
+	sq.Since, sq.Until = parseDateRange(q)
+	sq.UIN, _ = strconv.ParseInt(q.Get("uin"), 10, 64)
+	sq.GroupID, _ = strconv.ParseInt(q.Get("group_id"), 10, 64)
+	return sq
+}
+
+// parseDateRange 解析 since/until (YYYY-MM-DD) 为时间戳, until 包含当天
+func parseDateRange(q url.Values) (since, until int64) {
+	if t, err := time.ParseInLocation("2006-01-02", q.Get("since"), time.Local); err == nil {
+		since = t.Unix()
+	}
+	if t, err := time.ParseInLocation("2006-01-02", q.Get("until"), time.Local); err == nil {
+		until = t.Add(24*time.Hour - time.Second).Unix()
+	}
+	return since, until
+}
+
+// parseDateTime 解析 datetime-local 输入（2006-01-02T15:04）或 2006-01-02 15:04，按本地时间
+func parseDateTime(v string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02T15:04:05", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid datetime: %s", v)
+}
+
+func parsePage(q url.Values) int {
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	return page
+}
+
+// audit 记录由 Web 触发的操作，account 为空时视为匿名投稿者。
+func (s *Server) audit(account *model.Account, action string, postID int64, oldStatus, newStatus model.PostStatus, reason string) {
+	e := &model.AuditEvent{
+		Source:    model.SourceWeb,
+		Action:    action,
+		PostID:    postID,
+		OldStatus: oldStatus,
+		NewStatus: newStatus,
+		Reason:    reason,
+	}
+	if account != nil {
+		e.ActorID = account.ID
+		e.ActorName = account.Username
+	}
+	if err := s.store.AddAuditEvent(e); err != nil {
+		log.Printf("[Web] 写入审计日志失败: %v", err)
+	}
+}
+
 func (s *Server) handleIcon(w http.ResponseWriter, r *http.Request) {
 	icon, err := templateFS.ReadFile("templates/icon.png")
 	if err != nil {
@@ -1028,12 +1706,57 @@ func (s *Server) currentAccount(r *http.Request) *model.Account {
 		return nil
 	}
 	account, err := s.store.GetAccountByID(accountID)
-	if err != nil {
+	if err != nil || account == nil || account.Disabled {
 		return nil
 	}
 	return account
 }
 
+// currentWall 返回请求操作的墙：依次取 wall 参数、后台切换时保存的 wall Cookie，
+// 账号无权管理时回退到它能管理的第一个墙；未登录或没有可管理的墙时返回 nil
+func (s *Server) currentWall(r *http.Request, account *model.Account) *wall.Wall {
+	if account == nil {
+		return nil
+	}
+	id := r.FormValue("wall")
+	if id == "" {
+		if c, err := r.Cookie("wall"); err == nil {
+			id = c.Value
+		}
+	}
+	if wl := s.walls.Get(id); wl != nil && wl.CanManage(account) {
+		return wl
+	}
+	if walls := s.walls.ForAccount(account); len(walls) > 0 {
+		return walls[0]
+	}
+	return nil
+}
+
+// qzoneAccount 按名称或QQ号查找QQ空间账号，key 为空时使用当前墙的第一个账号
+func (s *Server) qzoneAccount(r *http.Request, account *model.Account, key string) (*wall.Wall, *publish.Account) {
+	if strings.TrimSpace(key) == "" {
+		if wl := s.currentWall(r, account); wl != nil {
+			return wl, wl.Pool.Get("")
+		}
+	}
+	return s.walls.Account(key)
+}
+
+// canManageAny 账号是否可以管理至少一个墙，决定能否登录后台
+func (s *Server) canManageAny(account *model.Account) bool {
+	return len(s.walls.ForAccount(account)) > 0
+}
+
+// permissionSet 账号拥有的权限，供模板按权限显示按钮和面板，如 {{if .Can.config}}
+func permissionSet(account *model.Account) map[string]bool {
+	set := make(map[string]bool, len(model.Permissions))
+	for _, p := range model.Permissions {
+		set[string(p)] = account.Can(p)
+	}
+	return set
+}
+
 func hashPassword(password, salt string) string {
 	h := sha256.New()
 	h.Write([]byte(salt + password))
@@ -1073,13 +1796,35 @@ func (s *Server) renderTemplate(w http.ResponseWriter, name string, data interfa
 }
 
 func (s *Server) RegisterUser(username, password string) error {
-	existing, _ := s.store.GetAccount(username)
+	return CreateAccount(s.store, username, password, model.RoleReviewer)
+}
+
+// CreateAccount 创建网页账号，role 见 model.Roles。admin 可以管理所有墙，
+// 其他角色需要在 walls[].admins 中才能管理对应的墙
+func CreateAccount(st *store.Store, username, password, role string) error {
+	if !model.ValidRole(role) {
+		return fmt.Errorf("未知的角色: %s", role)
+	}
+	existing, _ := st.GetAccount(username)
 	if existing != nil {
 		return fmt.Errorf("用户名已存在")
 	}
 	salt := randomHex(16)
 	hash := hashPassword(password, salt)
-	return s.store.CreateAccount(username, hash, salt, "user")
+	return st.CreateAccount(username, hash, salt, role)
+}
+
+// ResetPassword 重置网页账号的密码，该账号已登录的会话全部失效
+func ResetPassword(st *store.Store, username, password string) error {
+	account, err := st.GetAccount(username)
+	if err != nil || account == nil {
+		return fmt.Errorf("账号不存在: %s", username)
+	}
+	salt := randomHex(16)
+	if err := st.UpdateAccountPassword(username, hashPassword(password, salt), salt); err != nil {
+		return err
+	}
+	return st.DeleteAccountSessions(account.ID)
 }
 
 func (s *Server) SetCookieFile(cookieFile string) {
@@ -1122,12 +1867,61 @@ func openBrowser(url string) {
 	_ = exec.Command(cmd, args...).Start()
 }
 
-func (s *Server) isQzoneLoggedIn() bool {
-	if s.qzClient == nil || s.qzClient.UIN() <= 0 {
-		return false
+// qzoneAccountView 号池中一个账号的状态，用于后台展示
+type qzoneAccountView struct {
+	Wall     string `json:"wall"`
+	Name     string `json:"name"`
+	UIN      int64  `json:"uin"`
+	Source   string `json:"source"`
+	LoggedIn bool   `json:"logged_in"`
+	Down     bool   `json:"down"`             // 登录态已熔断
+	Since    string `json:"since,omitempty"`  // 熔断时间
+	Reason   string `json:"reason,omitempty"` // 熔断原因
+	Primary  bool   `json:"primary"`          // 当前用于发布的主号
+	Today    int    `json:"today"`            // 今日发布的说说条数
+}
+
+// qzoneAccounts 墙的号池中各账号的登录状态和今日发布数
+func (s *Server) qzoneAccounts(wl *wall.Wall) []qzoneAccountView {
+	now := time.Now()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	counts, err := wl.Store.PublishCountsByUIN(day.Unix())
+	if err != nil {
+		log.Printf("[Web] 统计各账号发布数失败: %v", err)
+	}
+
+	primary := wl.Pool.Primary()
+	var views []qzoneAccountView
+	for _, acc := range wl.Pool.Accounts() {
+		down, since, reason := acc.Health.State()
+		v := qzoneAccountView{
+			Wall:     wl.ID,
+			Name:     acc.Name,
+			UIN:      acc.CurrentUIN(),
+			Source:   acc.Source,
+			LoggedIn: acc.LoggedIn(),
+			Down:     down,
+			Reason:   reason,
+			Primary:  acc == primary,
+			Today:    counts[acc.CurrentUIN()],
+		}
+		if down {
+			v.Since = since.Format("01-02 15:04")
+		}
+		views = append(views, v)
+	}
+	return views
+}
+
+// pausedSince 号池没有可用账号时，返回最早失效账号的熔断时间；账号只是未登录时返回零值
+func pausedSince(pool *publish.Pool) time.Time {
+	var first time.Time
+	for _, acc := range pool.Accounts() {
+		if down, since, _ := acc.Health.State(); down && (first.IsZero() || since.Before(first)) {
+			first = since
+		}
 	}
-	raw := s.qzClient.Session().Cookie()
-	return !strings.Contains(raw, "p_skey=bootstrap")
+	return first
 }
 
 // ── Image Resolution Helpers ──
@@ -1140,50 +1934,8 @@ func (s *Server) resolvePostImages(p *model.Post) *model.Post {
 		if strings.HasPrefix(img, "/uploads/") {
 			clone.Images[i] = s.url(img)
 		} else {
-			clone.Images[i] = s.resolveImageURL(img)
+			clone.Images[i] = publish.ResolveImageURL(img)
 		}
 	}
 	return &clone
 }
-
-// [修改] resolvePostImagesForRender 专用于后端渲染
-func (s *Server) resolvePostImagesForRender(p *model.Post) *model.Post {
-	clone := *p
-	clone.Images = make([]string, len(p.Images))
-
-	// 获取 uploads 文件夹的绝对路径，例如 /home/appuser/uploads
-	// 如果 s.uploadDir 本身就是相对路径 "uploads"，Abs 会把它变成绝对路径
-	absUploadDir, err := filepath.Abs(s.uploadDir)
-	if err != nil {
-		absUploadDir = s.uploadDir // 降级处理
-	}
-
-	for i, img := range p.Images {
-		// 数据库中存储的是 "/uploads/xxx.jpg"
-		if strings.HasPrefix(img, "/uploads/") {
-			// 1. 提取文件名 (xxx.jpg)
-			// 【修改点】：使用 path.Base 而不是 filepath.Base
-			// path.Base 专门处理 "/" 分隔符，不管你在 Windows 还是 Linux 都不会错
-			filename := path.Base(img)
-			// 2. 拼接成容器内的绝对路径: /home/appuser/uploads/xxx.jpg
-			clone.Images[i] = filepath.Join(absUploadDir, filename)
-		} else {
-			clone.Images[i] = s.resolveImageURL(img)
-		}
-	}
-	return &clone
-}
-func (s *Server) resolveImageURL(img string) string {
-	if strings.HasPrefix(img, "http") {
-		return img
-	}
-	var resolved string
-	zero.RangeBot(func(id int64, ctx *zero.Ctx) bool {
-		resolved = ctx.GetImage(img).Get("url").String()
-		return true
-	})
-	if resolved != "" {
-		return resolved
-	}
-	return img
-}
@@ -0,0 +1,199 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/guohuiyuan/qzonewall-go/internal/model"
+)
+
+// handleAPIWebhooks GET 列出回调订阅，POST 添加订阅（需要 config 权限）。
+// 添加时 secret 为空则自动生成，密钥只在添加成功的响应中返回一次。
+func (s *Server) handleAPIWebhooks(w http.ResponseWriter, r *http.Request) {
+	account := s.currentAccount(r)
+	if !account.Can(model.PermConfig) {
+		jsonResp(w, 403, false, "无权限")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		subs, err := s.store.ListWebhookSubscriptions()
+		if err != nil {
+			jsonResp(w, 500, false, "读取回调订阅失败")
+			return
+		}
+		if subs == nil {
+			subs = []*model.WebhookSubscription{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok":            true,
+			"subscriptions": subs,
+			"events":        model.WebhookEvents,
+		})
+	case http.MethodPost:
+		_ = r.ParseForm()
+		sub := &model.WebhookSubscription{
+			URL:     strings.TrimSpace(r.FormValue("url")),
+			Secret:  strings.TrimSpace(r.FormValue("secret")),
+			WallID:  strings.TrimSpace(r.FormValue("wall")),
+			Enabled: true,
+		}
+		if u, err := url.Parse(sub.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			jsonResp(w, 400, false, "回调地址必须是 http:// 或 https:// 开头的网址")
+			return
+		}
+		if sub.WallID != "" && s.walls.Get(sub.WallID) == nil {
+			jsonResp(w, 400, false, "墙不存在: "+sub.WallID)
+			return
+		}
+		for _, e := range r.Form["events"] {
+			if !slices.Contains(model.WebhookEvents, e) {
+				jsonResp(w, 400, false, "未知的事件类型: "+e)
+				return
+			}
+			sub.Events = append(sub.Events, e)
+		}
+		if sub.Secret == "" {
+			sub.Secret = randomHex(24)
+		}
+		if err := s.store.CreateWebhookSubscription(sub); err != nil {
+			jsonResp(w, 500, false, "添加回调订阅失败")
+			return
+		}
+		s.audit(account, model.ActionConfig, 0, "", "", "添加回调 "+sub.URL)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok":      true,
+			"message": fmt.Sprintf("已添加回调 #%d", sub.ID),
+			"id":      sub.ID,
+			"secret":  sub.Secret,
+		})
+	default:
+		jsonResp(w, 405, false, "仅支持 GET/POST")
+	}
+}
+
+// handleAPIWebhookToggle 启用或停用回调订阅
+func (s *Server) handleAPIWebhookToggle(w http.ResponseWriter, r *http.Request) {
+	account, sub := s.webhookSubscription(w, r)
+	if sub == nil {
+		return
+	}
+	enabled := r.FormValue("enabled") == "1" || r.FormValue("enabled") == "true"
+	if err := s.store.SetWebhookSubscriptionEnabled(sub.ID, enabled); err != nil {
+		jsonResp(w, 500, false, "操作失败")
+		return
+	}
+	action := "停用"
+	if enabled {
+		action = "启用"
+	}
+	s.audit(account, model.ActionConfig, 0, "", "", action+"回调 "+sub.URL)
+	jsonResp(w, 200, true, fmt.Sprintf("回调 #%d 已%s", sub.ID, action))
+}
+
+// handleAPIWebhookDelete 删除回调订阅及其投递记录
+func (s *Server) handleAPIWebhookDelete(w http.ResponseWriter, r *http.Request) {
+	account, sub := s.webhookSubscription(w, r)
+	if sub == nil {
+		return
+	}
+	if err := s.store.DeleteWebhookSubscription(sub.ID); err != nil {
+		jsonResp(w, 500, false, "删除失败")
+		return
+	}
+	s.audit(account, model.ActionConfig, 0, "", "", "删除回调 "+sub.URL)
+	jsonResp(w, 200, true, fmt.Sprintf("回调 #%d 已删除", sub.ID))
+}
+
+// handleAPIWebhookDeliveries 分页列出投递记录，可按订阅和状态筛选
+func (s *Server) handleAPIWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	account := s.currentAccount(r)
+	if !account.Can(model.PermConfig) {
+		jsonResp(w, 403, false, "无权限")
+		return
+	}
+
+	q := r.URL.Query()
+	subID, _ := strconv.ParseInt(q.Get("subscription_id"), 10, 64)
+	page := parsePage(q)
+	const pageSize = 30
+	deliveries, err := s.store.ListWebhookDeliveries(subID, q.Get("status"), pageSize, (page-1)*pageSize)
+	if err != nil {
+		jsonResp(w, 500, false, "查询投递记录失败")
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*model.WebhookDelivery{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":         true,
+		"page":       page,
+		"deliveries": deliveries,
+	})
+}
+
+// handleAPIWebhookRedeliver 手动重新投递一条记录（成功或失败的都可以），立即进入发件箱
+func (s *Server) handleAPIWebhookRedeliver(w http.ResponseWriter, r *http.Request) {
+	account := s.currentAccount(r)
+	if !account.Can(model.PermConfig) {
+		jsonResp(w, 403, false, "无权限")
+		return
+	}
+	if r.Method != http.MethodPost {
+		jsonResp(w, 405, false, "仅支持 POST")
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		jsonResp(w, 400, false, "编号格式错误")
+		return
+	}
+	ok, err := s.store.RedeliverWebhook(id)
+	if err != nil {
+		jsonResp(w, 500, false, "操作失败")
+		return
+	}
+	if !ok {
+		jsonResp(w, 404, false, "投递记录不存在")
+		return
+	}
+	jsonResp(w, 200, true, fmt.Sprintf("投递 #%d 已重新排队", id))
+}
+
+// webhookSubscription 校验 config 权限并按表单中的 id 读取订阅，失败时已写入响应并返回 nil
+func (s *Server) webhookSubscription(w http.ResponseWriter, r *http.Request) (*model.Account, *model.WebhookSubscription) {
+	account := s.currentAccount(r)
+	if !account.Can(model.PermConfig) {
+		jsonResp(w, 403, false, "无权限")
+		return nil, nil
+	}
+	if r.Method != http.MethodPost {
+		jsonResp(w, 405, false, "仅支持 POST")
+		return nil, nil
+	}
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		jsonResp(w, 400, false, "编号格式错误")
+		return nil, nil
+	}
+	sub, err := s.store.GetWebhookSubscription(id)
+	if err != nil {
+		jsonResp(w, 500, false, "读取回调订阅失败")
+		return nil, nil
+	}
+	if sub == nil {
+		jsonResp(w, 404, false, "回调订阅不存在")
+		return nil, nil
+	}
+	return account, sub
+}